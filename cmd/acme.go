@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"certwiz/internal/config"
+	"certwiz/pkg/cert"
+	"certwiz/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	acmeDirectory  string
+	acmeStaging    bool
+	acmeAccountDir string
+	acmeCABundle   string
+	acmeEmail      string
+	acmeAgreeTOS   bool
+	acmeTimeout    time.Duration
+
+	acmeDomains    []string
+	acmeCSR        string
+	acmeKeyAlg     string
+	acmeKeySize    int
+	acmeChallenge  string
+	acmeHTTPListen string
+	acmeDNSHook    string
+	acmeOutput     string
+
+	acmeEncryptKey      bool
+	acmeKeyPasswordFile string
+	acmeKeyPasswordEnv  string
+)
+
+var acmeCmd = &cobra.Command{
+	Use:   "acme",
+	Short: "Obtain certificates from an ACME CA such as Let's Encrypt",
+	Long: `Obtain certificates from an ACME (RFC 8555) CA such as Let's Encrypt.
+
+The account key and registration for each CA are kept under
+~/.config/certwiz/acme/<host>/ (or --account-dir), so an account is
+registered once and reused. 'cert acme issue' registers one automatically
+if needed.
+
+--directory selects the CA (default: Let's Encrypt). --staging uses Let's
+Encrypt's staging CA, whose certificates are untrusted but whose rate
+limits are generous; use it while testing. A local test CA such as Pebble
+serves its directory over TLS with its own root: pass it with --ca-bundle.`,
+}
+
+var acmeRegisterCmd = &cobra.Command{
+	Use:   "register",
+	Short: "Register an ACME account",
+	Long: `Register an account with the ACME CA, or show the one already registered.
+
+A P-256 account key is generated on first use. Most CAs require accepting
+their terms of service with --agree-tos.
+
+Examples:
+  cert acme register --email admin@example.com --agree-tos
+  cert acme register --staging --email admin@example.com --agree-tos
+  cert acme register --directory https://localhost:14000/dir --ca-bundle pebble.minica.pem --agree-tos`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fail := func(err error) error {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		opts, err := acmeOptions()
+		if err != nil {
+			return fail(err)
+		}
+		ctx, stop := acmeContext()
+		defer stop()
+
+		account, err := cert.RegisterACMEAccount(ctx, opts)
+		if err != nil {
+			return fail(err)
+		}
+
+		if jsonOutput {
+			printJSON(account.ToJSON())
+			return nil
+		}
+		if account.Created {
+			ui.ShowSuccess("ACME account registered")
+		} else {
+			ui.ShowInfo("Using the ACME account already registered")
+		}
+		fmt.Println()
+		fmt.Printf("  Account:   %s\n", account.URI)
+		fmt.Printf("  Directory: %s\n", account.DirectoryURL)
+		if len(account.Contact) > 0 {
+			fmt.Printf("  Contact:   %s\n", strings.Join(account.Contact, ", "))
+		}
+		fmt.Printf("  Key:       %s\n", account.KeyPath)
+		return nil
+	},
+}
+
+var acmeIssueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "Obtain a certificate from the ACME CA",
+	Long: `Obtain a certificate for one or more domains from the ACME CA.
+
+A CSR and private key are generated for the --domain names (the first is
+the common name), as 'cert csr' would; or pass your own with --csr, whose
+DNS names and IP addresses are ordered. Each name is then validated:
+
+  http-01  certwiz answers the CA itself on --http-listen (default :80).
+           The CA connects to port 80 of each name, so run it on the host
+           the names point to, or forward port 80 to it.
+  dns-01   --dns-hook runs "<hook> present <domain> <record> <value>" to
+           publish a TXT record, and "<hook> cleanup ..." to remove it. The
+           same values are set as CERTWIZ_ACME_ACTION, CERTWIZ_ACME_DOMAIN,
+           CERTWIZ_ACME_RECORD, and CERTWIZ_ACME_VALUE. present should return
+           once the record is visible. Wildcard names need dns-01.
+
+The certificate is written to <name>.crt, its issuer chain to
+<name>-chain.crt, and both to <name>-fullchain.crt, which is what most
+servers want.
+
+Examples:
+  # HTTP-01 on this host
+  cert acme issue --domain example.com --domain www.example.com --email admin@example.com --agree-tos
+
+  # DNS-01 with a hook script, for a wildcard
+  cert acme issue --domain example.com --domain '*.example.com' --challenge dns-01 --dns-hook ./dns-hook.sh
+
+  # Your own CSR, against Let's Encrypt staging
+  cert acme issue --csr example.com.csr --staging --output /etc/ssl/example/
+
+  # Against a local Pebble test CA
+  cert acme issue --domain test.example --directory https://localhost:14000/dir \
+    --ca-bundle pebble.minica.pem --http-listen :5002 --agree-tos`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fail := func(err error) error {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		var validationErr error
+		switch {
+		case acmeCSR == "" && len(acmeDomains) == 0:
+			validationErr = fmt.Errorf("give the names to certify with --domain, or a CSR with --csr")
+		case acmeCSR != "" && len(acmeDomains) > 0:
+			validationErr = fmt.Errorf("--domain and --csr cannot be used together: the CSR lists the names")
+		case acmeChallenge != cert.ACMEChallengeHTTP01 && acmeChallenge != cert.ACMEChallengeDNS01:
+			validationErr = fmt.Errorf("invalid --challenge %q: use http-01 or dns-01", acmeChallenge)
+		case acmeChallenge == cert.ACMEChallengeDNS01 && acmeDNSHook == "":
+			validationErr = fmt.Errorf("--challenge dns-01 requires --dns-hook")
+		}
+		if validationErr == nil && acmeCSR == "" {
+			validationErr = cert.ValidateKeyAlgorithm(acmeKeyAlg)
+		}
+		if validationErr != nil {
+			return fail(validationErr)
+		}
+
+		opts, err := acmeOptions()
+		if err != nil {
+			return fail(err)
+		}
+
+		outputDir := acmeOutput
+		if outputDir == "" {
+			outputDir = "."
+		}
+		var files []string
+		csrPath := acmeCSR
+		name := strings.TrimSuffix(filepath.Base(acmeCSR), filepath.Ext(acmeCSR))
+		if acmeCSR == "" {
+			password, err := newKeyPassword(acmeEncryptKey, acmeKeyPasswordFile, acmeKeyPasswordEnv)
+			if err != nil {
+				return fail(err)
+			}
+			var sans []string
+			for _, d := range acmeDomains {
+				if net.ParseIP(d) != nil {
+					d = "ip:" + d
+				}
+				sans = append(sans, d)
+			}
+			name = sanitizeFilename(acmeDomains[0])
+			csrPath = filepath.Join(outputDir, name+".csr")
+			keyPath := filepath.Join(outputDir, name+".key")
+			err = cert.GenerateCSR(cert.CSROptions{
+				CommonName:   acmeDomains[0],
+				SANs:         processSANs(sans),
+				KeyAlgorithm: acmeKeyAlg,
+				KeySize:      acmeKeySize,
+				KeyPassword:  password,
+			}, csrPath, keyPath)
+			if err != nil {
+				return fail(fmt.Errorf("failed to generate CSR: %w", err))
+			}
+			files = append(files, csrPath, keyPath)
+		}
+
+		ctx, stop := acmeContext()
+		defer stop()
+
+		result, err := cert.ObtainACMECertificate(ctx, cert.ACMEOrderOptions{
+			ACMEOptions:   opts,
+			CSRPath:       csrPath,
+			Challenge:     acmeChallenge,
+			HTTPListen:    acmeHTTPListen,
+			DNSHook:       acmeDNSHook,
+			CertPath:      filepath.Join(outputDir, name+".crt"),
+			ChainPath:     filepath.Join(outputDir, name+"-chain.crt"),
+			FullChainPath: filepath.Join(outputDir, name+"-fullchain.crt"),
+		})
+		if err != nil {
+			return fail(fmt.Errorf("failed to obtain certificate: %w", err))
+		}
+		files = append(files, result.Files...)
+
+		if jsonOutput {
+			printJSON(cert.JSONOperationResult{
+				Success: true,
+				Message: fmt.Sprintf("Certificate issued for %s", strings.Join(result.Identifiers, ", ")),
+				Files:   files,
+			})
+			return nil
+		}
+
+		fmt.Println()
+		ui.ShowSuccess(fmt.Sprintf("Certificate issued for %s", strings.Join(result.Identifiers, ", ")))
+		fmt.Println()
+		fmt.Printf("%s Files created:\n", getEmoji("📁", "[FILES]"))
+		for _, f := range files {
+			fmt.Printf("  %s\n", f)
+		}
+		fmt.Println()
+		ui.DisplayCertificate(result.Certificate, false)
+		return nil
+	},
+}
+
+// acmeOptions builds the CA and account settings shared by the acme commands
+func acmeOptions() (cert.ACMEOptions, error) {
+	opts := cert.ACMEOptions{
+		DirectoryURL: acmeDirectory,
+		AccountDir:   acmeAccountDir,
+		CABundle:     acmeCABundle,
+		Email:        acmeEmail,
+		AgreeTOS:     acmeAgreeTOS,
+	}
+	if acmeStaging {
+		if acmeDirectory != "" && acmeDirectory != cert.LetsEncryptDirectoryURL {
+			return opts, fmt.Errorf("--staging and --directory cannot be used together")
+		}
+		opts.DirectoryURL = cert.LetsEncryptStagingDirectoryURL
+	}
+	if opts.AccountDir == "" {
+		dir, err := config.Dir()
+		if err != nil {
+			return opts, fmt.Errorf("cannot locate the account directory (use --account-dir): %w", err)
+		}
+		opts.AccountDir = filepath.Join(dir, "acme")
+	}
+	if !jsonOutput {
+		opts.Log = func(format string, args ...interface{}) {
+			ui.ShowInfo(fmt.Sprintf(format, args...))
+		}
+	}
+	return opts, nil
+}
+
+// acmeContext bounds an ACME operation by --timeout and interrupts
+func acmeContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := context.WithTimeout(ctx, acmeTimeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+func init() {
+	acmeCmd.PersistentFlags().StringVar(&acmeDirectory, "directory", cert.LetsEncryptDirectoryURL, "ACME directory URL of the CA")
+	acmeCmd.PersistentFlags().BoolVar(&acmeStaging, "staging", false, "Use the Let's Encrypt staging CA")
+	acmeCmd.PersistentFlags().StringVar(&acmeAccountDir, "account-dir", "", "Directory for account keys and registrations (default: ~/.config/certwiz/acme)")
+	acmeCmd.PersistentFlags().StringVar(&acmeCABundle, "ca-bundle", "", "Trust only these roots for the CA's HTTPS endpoint (e.g. Pebble's test root)")
+	acmeCmd.PersistentFlags().StringVar(&acmeEmail, "email", "", "Contact email for a new account")
+	acmeCmd.PersistentFlags().BoolVar(&acmeAgreeTOS, "agree-tos", false, "Accept the CA's terms of service when registering")
+	acmeCmd.PersistentFlags().DurationVar(&acmeTimeout, "timeout", 5*time.Minute, "Give up after this long")
+
+	acmeIssueCmd.Flags().StringSliceVar(&acmeDomains, "domain", nil, "Domain name or IP address to certify (repeatable; the first is the common name)")
+	acmeIssueCmd.Flags().StringVar(&acmeCSR, "csr", "", "Use an existing CSR instead of generating one")
+	acmeIssueCmd.Flags().StringVar(&acmeKeyAlg, "key-algorithm", cert.KeyAlgorithmRSA, keyAlgorithmFlagUsage)
+	acmeIssueCmd.Flags().IntVarP(&acmeKeySize, "key-size", "k", 2048, "RSA key size in bits")
+	acmeIssueCmd.Flags().StringVar(&acmeChallenge, "challenge", cert.ACMEChallengeHTTP01, "Challenge type: http-01 or dns-01")
+	acmeIssueCmd.Flags().StringVar(&acmeHTTPListen, "http-listen", cert.DefaultACMEHTTPListen, "Address for the built-in HTTP-01 responder")
+	acmeIssueCmd.Flags().StringVar(&acmeDNSHook, "dns-hook", "", "Command that publishes and removes DNS-01 TXT records")
+	acmeIssueCmd.Flags().StringVarP(&acmeOutput, "output", "o", "", "Output directory for the certificate, chain, and generated key")
+	acmeIssueCmd.Flags().BoolVar(&acmeEncryptKey, "encrypt-key", false, "Encrypt the generated private key with a password (prompted for)")
+	acmeIssueCmd.Flags().StringVar(&acmeKeyPasswordFile, "key-password-file", "", "Encrypt the generated private key with the password in a file")
+	acmeIssueCmd.Flags().StringVar(&acmeKeyPasswordEnv, "key-password-env", "", "Encrypt the generated private key with the password in an environment variable")
+
+	acmeCmd.AddCommand(acmeRegisterCmd)
+	acmeCmd.AddCommand(acmeIssueCmd)
+	rootCmd.AddCommand(acmeCmd)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"certwiz/pkg/cert"
+)
+
+func TestACMECommandValidation(t *testing.T) {
+	tmpDir := t.TempDir()
+	accountDir := filepath.Join(tmpDir, "accounts")
+	// Nothing listens here, so anything that gets past validation fails fast
+	directory := "https://127.0.0.1:1/dir"
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "Issue without domains or a CSR",
+			args:    []string{"acme", "issue", "--directory", directory},
+			wantErr: "--domain",
+		},
+		{
+			name:    "Issue with both domains and a CSR",
+			args:    []string{"acme", "issue", "--directory", directory, "--domain", "example.com", "--csr", "example.csr"},
+			wantErr: "cannot be used together",
+		},
+		{
+			name:    "Issue with an unknown challenge",
+			args:    []string{"acme", "issue", "--directory", directory, "--domain", "example.com", "--challenge", "tls-alpn-01"},
+			wantErr: "invalid --challenge",
+		},
+		{
+			name:    "DNS-01 without a hook",
+			args:    []string{"acme", "issue", "--directory", directory, "--domain", "example.com", "--challenge", "dns-01"},
+			wantErr: "--dns-hook",
+		},
+		{
+			name:    "Staging with another directory",
+			args:    []string{"acme", "register", "--directory", directory, "--staging"},
+			wantErr: "--staging",
+		},
+		{
+			name:    "Register with an unreachable CA",
+			args:    []string{"acme", "register", "--directory", directory, "--account-dir", accountDir, "--timeout", "5s", "--json"},
+			wantErr: "ACME directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset flags that persist between Execute calls
+			acmeStaging = false
+			acmeDomains = nil
+			acmeCSR = ""
+			acmeChallenge = cert.ACMEChallengeHTTP01
+			acmeDNSHook = ""
+			acmeAccountDir = ""
+			jsonOutput = false
+			defer func() { jsonOutput = false }()
+
+			cmd := rootCmd
+			cmd.SetArgs(tt.args)
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The account key is created before the CA is contacted, so a retry
+	// registers with the same key
+	if _, err := os.Stat(filepath.Join(cert.ACMEAccountPath(accountDir, directory), "account.key")); err != nil {
+		t.Errorf("Account key was not written: %v", err)
+	}
+}
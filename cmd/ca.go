@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"certwiz/pkg/cert"
 	"certwiz/pkg/ui"
@@ -15,8 +17,32 @@ var (
 	caOrg     string
 	caCountry string
 	caDays    int
+	caKeyAlg  string
 	caKeySize int
 	caOutput  string
+
+	caEncryptKey      bool
+	caKeyPasswordFile string
+	caKeyPasswordEnv  string
+	caKeyURI          string
+
+	caParentCert   string
+	caParentKey    string
+	caParentKeyURI string
+	caPathLen      int
+	caPermitDNS    []string
+	caExcludeDNS   []string
+	caPermitIP     []string
+	caExcludeIP    []string
+	caBundle       bool
+
+	caParentKeyPasswordFile string
+	caParentKeyPasswordEnv  string
+
+	caListCA        string
+	caListStatus    string
+	caListExpiresIn string
+	caListSubject   string
 )
 
 var caCmd = &cobra.Command{
@@ -27,6 +53,32 @@ var caCmd = &cobra.Command{
 A CA certificate can be used to sign other certificates, creating a chain of trust.
 This is useful for internal PKI, development environments, or testing.
 
+With --parent-cert and --parent-key, an intermediate CA signed by that parent
+is created instead, so the root can be kept offline. The intermediate is
+recorded in the parent's issuance index, and --bundle writes the full chain
+(<name>-ca-chain.crt) for servers and clients to use.
+
+--path-len limits how many intermediate CAs may follow this one (0: it can
+only sign end-entity certificates). Name constraints (--permit-dns,
+--exclude-dns, --permit-ip, --exclude-ip) restrict the names the CA and
+everything below it may issue for.
+
+--encrypt-key writes the CA key as encrypted PKCS#8 (PBES2, AES-256-CBC)
+with a password that is prompted for, or taken from --key-password-file or
+--key-password-env. An encrypted parent key is unlocked the same way, with
+a prompt or --parent-key-password-file/--parent-key-password-env.
+
+--ca-key-uri creates the CA for a key that already exists outside a file,
+such as on an HSM, and writes no key file; --parent-key-uri signs an
+intermediate with a parent key held the same way. Key URIs are pkcs11:
+(RFC 7512, in builds with -tags pkcs11), exec: or unix: for an external
+signer, or file:; see 'cert sign --help'. The PIN or key password comes from
+the URI or the matching --key-password-* or --parent-key-password-* flags.
+
+Next to the CA certificate, an issuance index (<name>-ca.index.json) and a
+directory for issued certificates (<name>-ca.certs/) are created. 'cert sign'
+records every certificate it issues there; list them with 'cert ca list'.
+
 Examples:
   # Create a basic CA certificate
   cert ca --cn "My Company CA"
@@ -38,7 +90,25 @@ Examples:
   cert ca --cn "Internal CA" --days 3650
   
   # Create a CA with larger key size for extra security
-  cert ca --cn "Secure CA" --key-size 4096 --output /etc/pki/`,
+  cert ca --cn "Secure CA" --key-size 4096 --output /etc/pki/
+
+  # Create a CA with an ECDSA P-384 key
+  cert ca --cn "EC Root CA" --key-algorithm ecdsa-p384
+
+  # Create a CA whose key is encrypted on disk
+  cert ca --cn "Internal CA" --encrypt-key
+  cert ca --cn "Internal CA" --key-password-env CA_KEY_PASS
+
+  # Create an intermediate for daily signing, limited to one domain
+  cert ca --cn "Example Issuing CA" --parent-cert Example_Root_CA-ca.crt \
+    --parent-key Example_Root_CA-ca.key --path-len 0 \
+    --permit-dns example.com --permit-ip 10.0.0.0/8 --bundle
+
+  # Create a root for a key generated on an HSM, and an intermediate it signs
+  cert ca --cn "HSM Root CA" --ca-key-uri "pkcs11:token=CA;object=root" --key-password-env HSM_PIN
+  cert ca --cn "Issuing CA" --parent-cert HSM_Root_CA-ca.crt \
+    --parent-key-uri "pkcs11:token=CA;object=root" --parent-key-password-env HSM_PIN`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if caCN == "" {
 			err := fmt.Errorf("common name (--cn) is required")
@@ -48,13 +118,81 @@ Examples:
 			return err
 		}
 
+		if err := cert.ValidateKeyAlgorithm(caKeyAlg); err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+
+		if caBundle && caParentCert == "" {
+			err := fmt.Errorf("--bundle requires --parent-cert and --parent-key")
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+
+		var flagErr error
+		switch {
+		case caParentKey != "" && caParentKeyURI != "":
+			flagErr = fmt.Errorf("--parent-key and --parent-key-uri can't be used together")
+		case caKeyURI != "" && caEncryptKey:
+			flagErr = fmt.Errorf("--encrypt-key can't be used with --ca-key-uri, which writes no key file")
+		}
+		if flagErr != nil {
+			if jsonOutput {
+				printJSONError(flagErr)
+			}
+			return flagErr
+		}
+
+		// With --ca-key-uri the password flags unlock the existing key
+		// rather than encrypting a new one
+		var password string
+		var err error
+		if caKeyURI != "" {
+			password, err = resolvePassword("", caKeyPasswordFile, caKeyPasswordEnv)
+		} else {
+			password, err = newKeyPassword(caEncryptKey, caKeyPasswordFile, caKeyPasswordEnv)
+		}
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+		var parentPassword string
+		if caParentKey != "" || caParentKeyURI != "" {
+			parentPassword, err = keyPassword(caParentKey, "parent-key", caParentKeyPasswordFile, caParentKeyPasswordEnv)
+			if err != nil {
+				if jsonOutput {
+					printJSONError(err)
+				}
+				return err
+			}
+		}
+
 		// Prepare options
 		options := cert.CAOptions{
-			CommonName:   caCN,
-			Organization: caOrg,
-			Country:      caCountry,
-			Days:         caDays,
-			KeySize:      caKeySize,
+			CommonName:          caCN,
+			Organization:        caOrg,
+			Country:             caCountry,
+			Days:                caDays,
+			KeyAlgorithm:        caKeyAlg,
+			KeySize:             caKeySize,
+			KeyPassword:         password,
+			KeyURI:              caKeyURI,
+			MaxPathLen:          caPathLen,
+			MaxPathLenZero:      caPathLen == 0,
+			PermittedDNSDomains: caPermitDNS,
+			ExcludedDNSDomains:  caExcludeDNS,
+			PermittedIPRanges:   caPermitIP,
+			ExcludedIPRanges:    caExcludeIP,
+			ParentCert:          caParentCert,
+			ParentKey:           caParentKey,
+			ParentKeyURI:        caParentKeyURI,
+			ParentKeyPassword:   parentPassword,
 		}
 
 		// Set output path
@@ -69,8 +207,11 @@ Examples:
 
 		certPath := filepath.Join(caOutput, sanitizeCAFilename(caCN)+"-ca.crt")
 		keyPath := filepath.Join(caOutput, sanitizeCAFilename(caCN)+"-ca.key")
+		if caBundle {
+			options.BundlePath = filepath.Join(caOutput, sanitizeCAFilename(caCN)+"-ca-chain.crt")
+		}
 
-		err := cert.GenerateCA(options, certPath, keyPath)
+		err = cert.GenerateCA(options, certPath, keyPath)
 		if err != nil {
 			err = fmt.Errorf("failed to generate CA: %w", err)
 			if jsonOutput {
@@ -80,20 +221,38 @@ Examples:
 		}
 
 		if jsonOutput {
+			files := []string{certPath, keyPath}
+			if caKeyURI != "" {
+				files = files[:1]
+			}
+			if options.BundlePath != "" {
+				files = append(files, options.BundlePath)
+			}
 			printJSON(cert.JSONOperationResult{
 				Success: true,
 				Message: "Certificate Authority generated successfully",
-				Files:   []string{certPath, keyPath},
+				Files:   files,
 			})
 			return nil
 		}
 
 		// Display success message
 		ui.ShowSuccess("Certificate Authority generated successfully!")
+		if caParentCert != "" {
+			fmt.Printf("  Intermediate CA signed by %s (recorded in %s)\n", caParentCert, cert.CAIndexPath(caParentCert))
+		}
 		fmt.Println()
 		fmt.Printf("%s Files created:\n", getEmoji("📁", "[FILES]"))
 		fmt.Printf("  %s CA Certificate: %s\n", getEmoji("🏛️", "[CERT]"), certPath)
-		fmt.Printf("  %s CA Private Key: %s\n", getEmoji("🔑", "[KEY]"), keyPath)
+		if caKeyURI != "" {
+			fmt.Printf("  %s CA Private Key: %s (not written)\n", getEmoji("🔑", "[KEY]"), caKeyURI)
+		} else {
+			fmt.Printf("  %s CA Private Key: %s\n", getEmoji("🔑", "[KEY]"), keyPath)
+		}
+		fmt.Printf("  %s Issuance Index: %s\n", getEmoji("🗂️", "[INDEX]"), cert.CAIndexPath(certPath))
+		if options.BundlePath != "" {
+			fmt.Printf("  %s Chain Bundle:   %s\n", getEmoji("🔗", "[CHAIN]"), options.BundlePath)
+		}
 		fmt.Println()
 		fmt.Printf("%s Security Notes:\n", getEmoji("⚠️", "[WARNING]"))
 		fmt.Println("  • Keep the CA private key extremely secure")
@@ -104,6 +263,7 @@ Examples:
 		fmt.Println("  1. Distribute the CA certificate to clients that need to trust it")
 		fmt.Println("  2. Use 'cert sign' command to sign CSRs with this CA")
 		fmt.Println("  3. Keep the CA key secure and backed up")
+		fmt.Println("  4. Use 'cert ca list' to see what the CA has issued")
 
 		// Display the CA certificate details
 		fmt.Println()
@@ -119,14 +279,113 @@ Examples:
 	},
 }
 
+var caListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List certificates issued by a CA",
+	Long: `List the certificates recorded in a CA's issuance index by 'cert sign',
+soonest expiry first.
+
+The index is found next to the CA certificate (ca.crt -> ca.index.json).
+Revoked certificates are marked by 'cert revoke'.
+
+Examples:
+  # Everything the CA has issued
+  cert ca list --ca My_Company_CA-ca.crt
+
+  # Valid certificates expiring within 30 days
+  cert ca list --ca ca.crt --expires-in 30d
+
+  # Revoked certificates for a host
+  cert ca list --ca ca.crt --status revoked --subject api.example.com
+
+  # Serial numbers as JSON
+  cert ca list --ca ca.crt --json | jq -r '.certificates[].serial_number'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		if caListCA == "" {
+			err = fmt.Errorf("CA certificate (--ca) is required")
+		} else {
+			err = cert.ValidateCertStatus(caListStatus)
+		}
+		var expiresIn time.Duration
+		if err == nil {
+			expiresIn, err = parseExpiryWindow(caListExpiresIn)
+		}
+		var idx *cert.CAIndex
+		indexPath := cert.CAIndexPath(caListCA)
+		if err == nil {
+			idx, err = cert.LoadCAIndex(indexPath)
+		}
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		status := caListStatus
+		if status == "all" {
+			status = ""
+		}
+		entries := idx.List(cert.IndexListOptions{
+			Status:        status,
+			ExpiresWithin: expiresIn,
+			Subject:       caListSubject,
+		})
+
+		if jsonOutput {
+			now := time.Now()
+			list := cert.JSONCAList{
+				CASubject:    idx.CASubject,
+				Index:        indexPath,
+				Total:        len(entries),
+				Certificates: []cert.JSONCAIndexEntry{},
+			}
+			for i := range entries {
+				list.Certificates = append(list.Certificates, entries[i].ToJSON(now))
+			}
+			printJSON(list)
+			return nil
+		}
+
+		ui.DisplayCAIndex(idx.CASubject, entries)
+		return nil
+	},
+}
+
 func init() {
 	caCmd.Flags().StringVar(&caCN, "cn", "", "Common Name for the CA (required)")
 	caCmd.Flags().StringVar(&caOrg, "org", "", "Organization name")
 	caCmd.Flags().StringVar(&caCountry, "country", "", "Country (2-letter code)")
 	caCmd.Flags().IntVarP(&caDays, "days", "d", 3650, "Validity period in days (default 10 years)")
+	caCmd.Flags().StringVar(&caKeyAlg, "key-algorithm", cert.KeyAlgorithmRSA, keyAlgorithmFlagUsage)
 	caCmd.Flags().IntVarP(&caKeySize, "key-size", "k", 4096, "RSA key size in bits")
 	caCmd.Flags().StringVarP(&caOutput, "output", "o", "", "Output directory for CA files")
+	caCmd.Flags().BoolVar(&caEncryptKey, "encrypt-key", false, "Encrypt the CA private key with a password (prompted for)")
+	caCmd.Flags().StringVar(&caKeyPasswordFile, "key-password-file", "", "Encrypt the CA private key with the password in a file (with --ca-key-uri: the key's password or PIN)")
+	caCmd.Flags().StringVar(&caKeyPasswordEnv, "key-password-env", "", "Encrypt the CA private key with the password in an environment variable (with --ca-key-uri: the key's password or PIN)")
+	caCmd.Flags().StringVar(&caKeyURI, "ca-key-uri", "", "Use an existing CA key instead of generating one: pkcs11:, exec:, unix:, or file: URI")
+	caCmd.Flags().StringVar(&caParentCert, "parent-cert", "", "Parent CA certificate; creates an intermediate CA signed by it")
+	caCmd.Flags().StringVar(&caParentKey, "parent-key", "", "Parent CA private key")
+	caCmd.Flags().StringVar(&caParentKeyURI, "parent-key-uri", "", "Parent CA signing key URI instead of --parent-key: pkcs11:, exec:, unix:, or file:")
+	caCmd.Flags().StringVar(&caParentKeyPasswordFile, "parent-key-password-file", "", "Read the parent key's password (or PKCS#11 PIN) from a file")
+	caCmd.Flags().StringVar(&caParentKeyPasswordEnv, "parent-key-password-env", "", "Read the parent key's password (or PKCS#11 PIN) from an environment variable")
+	caCmd.Flags().IntVar(&caPathLen, "path-len", -1, "Maximum number of intermediate CAs below this one (-1: unlimited, or the parent's limit minus one)")
+	caCmd.Flags().StringSliceVar(&caPermitDNS, "permit-dns", []string{}, "Name constraint: permitted DNS domain (can be used multiple times)")
+	caCmd.Flags().StringSliceVar(&caExcludeDNS, "exclude-dns", []string{}, "Name constraint: excluded DNS domain (can be used multiple times)")
+	caCmd.Flags().StringSliceVar(&caPermitIP, "permit-ip", []string{}, "Name constraint: permitted IP range in CIDR notation (can be used multiple times)")
+	caCmd.Flags().StringSliceVar(&caExcludeIP, "exclude-ip", []string{}, "Name constraint: excluded IP range in CIDR notation (can be used multiple times)")
+	caCmd.Flags().BoolVar(&caBundle, "bundle", false, "Also write the full chain (intermediate + parent) to <name>-ca-chain.crt")
+
+	caListCmd.Flags().StringVar(&caListCA, "ca", "", "Path to the CA certificate (required)")
+	caListCmd.Flags().StringVar(&caListStatus, "status", "all", "Filter by status: all, "+strings.Join(cert.CertStatuses, ", "))
+	caListCmd.Flags().StringVar(&caListExpiresIn, "expires-in", "", "Only valid certificates expiring within this window (e.g. 30d, 720h)")
+	caListCmd.Flags().StringVar(&caListSubject, "subject", "", "Filter by subject or SAN (case-insensitive substring)")
 
+	caCmd.AddCommand(caListCmd)
 	rootCmd.AddCommand(caCmd)
 }
 
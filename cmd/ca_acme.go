@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"certwiz/pkg/cert"
+	"certwiz/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	caACMECA       string
+	caACMECAKey    string
+	caACMECAKeyURI string
+	caACMEListen   string
+	caACMEHosts    []string
+	caACMETLSCert  string
+	caACMETLSKey   string
+	caACMEStateDir string
+	caACMEDays     int
+	caACMEProfile  string
+	caACMEAuto     bool
+	caACMEHTTP01   int
+	caACMEURL      string
+
+	caACMECAKeyPasswordFile  string
+	caACMECAKeyPasswordEnv   string
+	caACMETLSKeyPasswordFile string
+	caACMETLSKeyPasswordEnv  string
+)
+
+var caServeACMECmd = &cobra.Command{
+	Use:   "serve-acme",
+	Short: "Run an ACME server that issues certificates from a CA",
+	Long: `Run an ACME (RFC 8555) server backed by a CA, so ACME clients such as
+cert-manager, Caddy, certbot, and 'cert acme' can get certificates from it.
+
+Certificates are signed like 'cert sign' does: with the --profile (server by
+default) for --days, and recorded in the CA's issuance index, so 'cert ca
+list' and 'cert revoke' work on them. Clients can also revoke through ACME,
+which updates the CA's CRL.
+
+Accounts, orders, and issued certificates are stored in --state-dir
+(<name>-ca.acme/ next to the CA certificate by default) and survive restarts.
+
+Domains are validated with HTTP-01: the server fetches
+http://<domain>/.well-known/acme-challenge/<token> from port 80 (or
+--http01-port). Wildcards would need DNS-01, which isn't offered.
+
+--auto-approve skips validation: every order is approved, wildcards
+included, for anyone who can reach the server. Use it only on development
+networks.
+
+The server speaks HTTPS only. Unless --tls-cert and --tls-key are given, it
+issues itself a certificate from the CA for --hostname (this host's name and
+localhost by default), kept in memory. Clients must trust the CA certificate:
+cert-manager's caBundle, Caddy's trusted_roots, or --ca-bundle for 'cert
+acme'.
+
+Examples:
+  # Serve ACME for a development CA
+  cert ca serve-acme --ca Dev_CA-ca.crt --ca-key Dev_CA-ca.key --hostname ca.dev.internal
+
+  # Approve everything, for a local cluster
+  cert ca serve-acme --ca ca.crt --ca-key ca.key --auto-approve
+
+  # Behind a reverse proxy
+  cert ca serve-acme --ca ca.crt --ca-key ca.key --listen 127.0.0.1:8443 --external-url https://acme.example.internal
+
+  # Get a certificate from it
+  cert acme issue --directory https://ca.dev.internal:8443/directory --ca-bundle Dev_CA-ca.crt --domain app.dev.internal`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fail := func(err error) error {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		switch {
+		case caACMECA == "":
+			return fail(fmt.Errorf("CA certificate (--ca) is required"))
+		case caACMECAKey == "" && caACMECAKeyURI == "":
+			return fail(fmt.Errorf("CA private key (--ca-key or --ca-key-uri) is required"))
+		case caACMECAKey != "" && caACMECAKeyURI != "":
+			return fail(fmt.Errorf("--ca-key and --ca-key-uri can't be used together"))
+		case (caACMETLSCert == "") != (caACMETLSKey == ""):
+			return fail(fmt.Errorf("--tls-cert and --tls-key must be given together"))
+		case caACMEDays <= 0:
+			return fail(fmt.Errorf("invalid --days %d: must be positive", caACMEDays))
+		case caACMEHTTP01 <= 0 || caACMEHTTP01 > 65535:
+			return fail(fmt.Errorf("invalid --http01-port %d", caACMEHTTP01))
+		case caACMEURL != "" && !strings.HasPrefix(caACMEURL, "https://"):
+			return fail(fmt.Errorf("--external-url must be an https:// URL"))
+		}
+
+		customProfiles, err := configProfiles()
+		if err != nil {
+			return fail(err)
+		}
+		profile, err := cert.FindProfile(caACMEProfile, customProfiles)
+		if err != nil {
+			return fail(err)
+		}
+		days := caACMEDays
+		if profile.Days > 0 && !cmd.Flags().Changed("days") {
+			days = profile.Days
+		}
+		password, err := keyPassword(caACMECAKey, "ca-key", caACMECAKeyPasswordFile, caACMECAKeyPasswordEnv)
+		if err != nil {
+			return fail(err)
+		}
+
+		opts := cert.ACMEServerOptions{
+			CACert:        caACMECA,
+			CAKey:         caACMECAKey,
+			CAKeyURI:      caACMECAKeyURI,
+			CAKeyPassword: password,
+			StateDir:      caACMEStateDir,
+			Days:          days,
+			Profile:       profile,
+			AutoApprove:   caACMEAuto,
+			HTTP01Port:    caACMEHTTP01,
+			ExternalURL:   caACMEURL,
+		}
+		if !jsonOutput {
+			opts.Log = func(format string, args ...interface{}) {
+				fmt.Printf("%s %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+			}
+		}
+		server, err := cert.NewACMEServer(opts)
+		if err != nil {
+			return fail(err)
+		}
+		defer server.Close()
+
+		var serving tls.Certificate
+		hosts := caACMEHosts
+		if caACMETLSCert != "" {
+			var tlsPassword string
+			if tlsPassword, err = keyPassword(caACMETLSKey, "tls-key", caACMETLSKeyPasswordFile, caACMETLSKeyPasswordEnv); err != nil {
+				return fail(err)
+			}
+			serving, err = loadACMEServingCertificate(caACMETLSCert, caACMETLSKey, tlsPassword)
+		} else {
+			if len(hosts) == 0 {
+				hosts = defaultACMEServerHosts()
+			}
+			serving, err = server.ServingCertificate(hosts)
+		}
+		if err != nil {
+			return fail(fmt.Errorf("failed to load the server's TLS certificate: %w", err))
+		}
+
+		httpServer := &http.Server{
+			Addr:              caACMEListen,
+			Handler:           server,
+			ReadHeaderTimeout: 10 * time.Second,
+			TLSConfig:         &tls.Config{Certificates: []tls.Certificate{serving}, MinVersion: tls.VersionTLS12},
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = httpServer.Shutdown(shutdownCtx)
+		}()
+
+		if !jsonOutput {
+			directory := caACMEURL
+			if directory == "" {
+				directory = "https://" + acmeServerAddress(caACMEListen, hosts)
+			}
+			ui.ShowInfo(fmt.Sprintf("Serving ACME for %s at %s/directory", caACMECA, strings.TrimSuffix(directory, "/")))
+			ui.ShowInfo(fmt.Sprintf("State is kept in %s", server.StateDir()))
+			if caACMEAuto {
+				fmt.Printf("%s Auto-approve is on: anyone who can reach this server gets certificates for any name\n", getEmoji("⚠️", "[WARNING]"))
+			}
+		}
+		if err := httpServer.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fail(fmt.Errorf("failed to serve ACME: %w", err))
+		}
+		return nil
+	},
+}
+
+// loadACMEServingCertificate reads --tls-cert, with any intermediates after
+// it, and its key in any format LoadPrivateKey accepts
+func loadACMEServingCertificate(certPath, keyPath, password string) (tls.Certificate, error) {
+	certs, err := cert.InspectFileAll(certPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	key, err := cert.LoadPrivateKey(keyPath, password)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !pub.Equal(certs[0].PublicKey) {
+		return tls.Certificate{}, fmt.Errorf("%s does not match %s", keyPath, certPath)
+	}
+	serving := tls.Certificate{PrivateKey: key, Leaf: certs[0].Certificate}
+	for _, c := range certs {
+		serving.Certificate = append(serving.Certificate, c.Raw)
+	}
+	return serving, nil
+}
+
+// defaultACMEServerHosts names the server's own certificate after this host
+func defaultACMEServerHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil && name != "" && name != "localhost" {
+		hosts = append([]string{name}, hosts...)
+	}
+	return hosts
+}
+
+// acmeServerAddress is the host:port clients would use for a listen
+// address, naming the first host when it listens on all interfaces
+func acmeServerAddress(listen string, hosts []string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return listen
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+		if len(hosts) > 0 {
+			host = hosts[0]
+		}
+	}
+	if port == "443" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func init() {
+	caServeACMECmd.Flags().StringVar(&caACMECA, "ca", "", "Path to the CA certificate (required)")
+	caServeACMECmd.Flags().StringVar(&caACMECAKey, "ca-key", "", "Path to the CA private key")
+	caServeACMECmd.Flags().StringVar(&caACMECAKeyURI, "ca-key-uri", "", "CA signing key URI instead of --ca-key: pkcs11:, exec:, unix:, or file:")
+	caServeACMECmd.Flags().StringVar(&caACMECAKeyPasswordFile, "ca-key-password-file", "", "Read the CA key's password (or PKCS#11 PIN) from a file")
+	caServeACMECmd.Flags().StringVar(&caACMECAKeyPasswordEnv, "ca-key-password-env", "", "Read the CA key's password (or PKCS#11 PIN) from an environment variable")
+	caServeACMECmd.Flags().StringVar(&caACMEListen, "listen", cert.DefaultACMEServerListen, "Address to serve HTTPS on")
+	caServeACMECmd.Flags().StringSliceVar(&caACMEHosts, "hostname", []string{}, "Name for the server's own certificate (can be used multiple times; default: this host and localhost)")
+	caServeACMECmd.Flags().StringVar(&caACMETLSCert, "tls-cert", "", "Serve with this certificate instead of one issued from the CA")
+	caServeACMECmd.Flags().StringVar(&caACMETLSKey, "tls-key", "", "Private key for --tls-cert")
+	caServeACMECmd.Flags().StringVar(&caACMETLSKeyPasswordFile, "tls-key-password-file", "", "Read an encrypted --tls-key's password from a file")
+	caServeACMECmd.Flags().StringVar(&caACMETLSKeyPasswordEnv, "tls-key-password-env", "", "Read an encrypted --tls-key's password from an environment variable")
+	caServeACMECmd.Flags().StringVar(&caACMEStateDir, "state-dir", "", "Directory for accounts, orders, and certificates (default <name>-ca.acme next to the CA)")
+	caServeACMECmd.Flags().IntVarP(&caACMEDays, "days", "d", cert.DefaultACMEServerDays, "Validity of issued certificates in days")
+	caServeACMECmd.Flags().StringVar(&caACMEProfile, "profile", "server", "Certificate profile for issued certificates (see 'cert sign --list-profiles')")
+	caServeACMECmd.Flags().BoolVar(&caACMEAuto, "auto-approve", false, "Approve every order without a challenge (development only)")
+	caServeACMECmd.Flags().IntVar(&caACMEHTTP01, "http01-port", 80, "Port to fetch HTTP-01 challenges from")
+	caServeACMECmd.Flags().StringVar(&caACMEURL, "external-url", "", "Base URL clients reach the server at, when behind a proxy")
+
+	caCmd.AddCommand(caServeACMECmd)
+}
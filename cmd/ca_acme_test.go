@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"certwiz/pkg/cert"
+)
+
+func TestCAServeACMEValidation(t *testing.T) {
+	tmpDir := t.TempDir()
+	caCert := filepath.Join(tmpDir, "Dev_CA-ca.crt")
+	caKey := filepath.Join(tmpDir, "Dev_CA-ca.key")
+	if err := cert.GenerateCA(cert.CAOptions{CommonName: "Dev CA", Days: 365, KeyAlgorithm: cert.KeyAlgorithmECDSAP256}, caCert, caKey); err != nil {
+		t.Fatalf("GenerateCA failed: %v", err)
+	}
+	leafCert := filepath.Join(tmpDir, "leaf.crt")
+	if err := cert.Generate(cert.GenerateOptions{CommonName: "leaf", Days: 30, KeyAlgorithm: cert.KeyAlgorithmECDSAP256, OutputDir: tmpDir}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := cert.Generate(cert.GenerateOptions{CommonName: "secure", Days: 30, KeyAlgorithm: cert.KeyAlgorithmECDSAP256, OutputDir: tmpDir, KeyPassword: "secret"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	secureCert, secureKey := filepath.Join(tmpDir, "secure.crt"), filepath.Join(tmpDir, "secure.key")
+	t.Setenv("ACME_TLS_KEY_PASS", "wrong")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"Missing CA", []string{"ca", "serve-acme", "--ca-key", caKey}, "--ca"},
+		{"Missing CA key", []string{"ca", "serve-acme", "--ca", caCert}, "--ca-key"},
+		{"Key and key URI", []string{"ca", "serve-acme", "--ca", caCert, "--ca-key", caKey, "--ca-key-uri", "file:" + caKey}, "can't be used together"},
+		{"TLS cert without key", []string{"ca", "serve-acme", "--ca", caCert, "--ca-key", caKey, "--tls-cert", caCert}, "--tls-key"},
+		{"TLS key mismatch", []string{"ca", "serve-acme", "--ca", caCert, "--ca-key", caKey, "--tls-cert", leafCert, "--tls-key", caKey}, "does not match"},
+		{"Encrypted TLS key without password", []string{"ca", "serve-acme", "--ca", caCert, "--ca-key", caKey, "--tls-cert", secureCert, "--tls-key", secureKey}, "--tls-key-password-file"},
+		{"Encrypted TLS key with wrong password", []string{"ca", "serve-acme", "--ca", caCert, "--ca-key", caKey, "--tls-cert", secureCert, "--tls-key", secureKey, "--tls-key-password-env", "ACME_TLS_KEY_PASS"}, "failed to load the server's TLS certificate"},
+		{"HTTP URL", []string{"ca", "serve-acme", "--ca", caCert, "--ca-key", caKey, "--external-url", "http://acme.internal"}, "https://"},
+		{"Unknown profile", []string{"ca", "serve-acme", "--ca", caCert, "--ca-key", caKey, "--profile", "nope"}, "nope"},
+		{"Not a CA", []string{"ca", "serve-acme", "--ca", leafCert, "--ca-key", filepath.Join(tmpDir, "leaf.key"), "--state-dir", filepath.Join(tmpDir, "state")}, "not a CA"},
+		{"Bad listen address", []string{"ca", "serve-acme", "--ca", caCert, "--ca-key", caKey, "--listen", "256.0.0.1:8443", "--hostname", "localhost"}, "failed to serve ACME"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset flags that persist between Execute calls
+			caACMECA, caACMECAKey, caACMECAKeyURI = "", "", ""
+			caACMETLSCert, caACMETLSKey = "", ""
+			caACMETLSKeyPasswordFile, caACMETLSKeyPasswordEnv = "", ""
+			caACMEURL, caACMEStateDir = "", ""
+			caACMEProfile = "server"
+			caACMEListen = cert.DefaultACMEServerListen
+			caACMEHosts = nil
+
+			cmd := rootCmd
+			cmd.SetArgs(tt.args)
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
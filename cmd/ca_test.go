@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"bytes"
 	"certwiz/pkg/cert"
+	"crypto/x509"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -41,6 +43,10 @@ func TestCACommand(t *testing.T) {
 			t.Errorf("CA key file was not created: %s", keyPath)
 		}
 
+		if _, err := cert.LoadCAIndex(cert.CAIndexPath(certPath)); err != nil {
+			t.Errorf("CA index was not created: %v", err)
+		}
+
 		// Verify the certificate is a CA
 		caCert, err := cert.InspectFile(certPath)
 		if err != nil {
@@ -99,6 +105,129 @@ func TestCACommand(t *testing.T) {
 		}
 	})
 
+	// Test CA with an ECDSA key
+	t.Run("ECDSACA", func(t *testing.T) {
+		caCN = "EC Root CA"
+		caOrg = ""
+		caCountry = ""
+		caOutput = tmpDir
+		caKeyAlg = "ecdsa-p384"
+		caDays = 365
+		defer func() { caKeyAlg = cert.KeyAlgorithmRSA }()
+
+		if err := caCmd.RunE(caCmd, []string{}); err != nil {
+			t.Fatalf("ECDSA CA generation failed: %v", err)
+		}
+
+		caCert, err := cert.InspectFile(filepath.Join(tmpDir, "EC_Root_CA-ca.crt"))
+		if err != nil {
+			t.Fatalf("Failed to inspect CA certificate: %v", err)
+		}
+		if caCert.SignatureAlgorithm != x509.ECDSAWithSHA384 {
+			t.Errorf("Signature algorithm = %s, want ECDSA-SHA384", caCert.SignatureAlgorithm)
+		}
+	})
+
+	// Test unsupported key algorithm
+	t.Run("InvalidKeyAlgorithm", func(t *testing.T) {
+		caCN = "Bad CA"
+		caOutput = tmpDir
+		caKeyAlg = "dsa"
+		defer func() { caKeyAlg = cert.KeyAlgorithmRSA }()
+
+		if err := caCmd.RunE(caCmd, []string{}); err == nil {
+			t.Error("Expected error for unsupported key algorithm, but got none")
+		}
+	})
+
+	// Test an intermediate signed by the EC root, with constraints and a bundle
+	t.Run("IntermediateCA", func(t *testing.T) {
+		caCN = "EC Issuing CA"
+		caOutput = tmpDir
+		caKeyAlg = "ecdsa-p256"
+		caDays = 365
+		caParentCert = filepath.Join(tmpDir, "EC_Root_CA-ca.crt")
+		caParentKey = filepath.Join(tmpDir, "EC_Root_CA-ca.key")
+		caPathLen = 0
+		caPermitDNS = []string{"example.com"}
+		caBundle = true
+		defer func() {
+			caKeyAlg = cert.KeyAlgorithmRSA
+			caParentCert, caParentKey, caPathLen, caPermitDNS, caBundle = "", "", -1, []string{}, false
+		}()
+
+		if err := caCmd.RunE(caCmd, []string{}); err != nil {
+			t.Fatalf("Intermediate CA generation failed: %v", err)
+		}
+
+		inter, err := cert.InspectFile(filepath.Join(tmpDir, "EC_Issuing_CA-ca.crt"))
+		if err != nil {
+			t.Fatalf("Failed to inspect intermediate CA: %v", err)
+		}
+		if inter.Issuer.CommonName != "EC Root CA" || !inter.MaxPathLenZero || len(inter.PermittedDNSDomains) != 1 {
+			t.Errorf("Intermediate issuer %q, path length zero %v, permitted DNS %v", inter.Issuer.CommonName, inter.MaxPathLenZero, inter.PermittedDNSDomains)
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, "EC_Issuing_CA-ca-chain.crt")); err != nil {
+			t.Errorf("Chain bundle was not written: %v", err)
+		}
+
+		// The intermediate can't issue further CAs
+		caCN = "EC Sub CA"
+		caParentCert = filepath.Join(tmpDir, "EC_Issuing_CA-ca.crt")
+		caParentKey = filepath.Join(tmpDir, "EC_Issuing_CA-ca.key")
+		caPathLen = -1
+		if err := caCmd.RunE(caCmd, []string{}); err == nil {
+			t.Error("Expected error for a CA below a path length 0 intermediate, but got none")
+		}
+	})
+
+	// Test a root for an existing key, and an intermediate signed through a key URI
+	t.Run("KeyURI", func(t *testing.T) {
+		caCN = "URI Root CA"
+		caOutput = tmpDir
+		caKeyURI = "file:" + filepath.Join(tmpDir, "EC_Root_CA-ca.key")
+		defer func() { caKeyURI, caParentCert, caParentKeyURI, caEncryptKey = "", "", "", false }()
+
+		if err := caCmd.RunE(caCmd, []string{}); err != nil {
+			t.Fatalf("CA generation with --ca-key-uri failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, "URI_Root_CA-ca.key")); !os.IsNotExist(err) {
+			t.Error("--ca-key-uri should not write a key file")
+		}
+
+		caEncryptKey = true
+		if err := caCmd.RunE(caCmd, []string{}); err == nil {
+			t.Error("Expected error for --encrypt-key with --ca-key-uri, but got none")
+		}
+
+		caCN = "URI Issuing CA"
+		caKeyURI, caEncryptKey = "", false
+		caParentCert = filepath.Join(tmpDir, "URI_Root_CA-ca.crt")
+		caParentKeyURI = "file:" + filepath.Join(tmpDir, "EC_Root_CA-ca.key")
+		if err := caCmd.RunE(caCmd, []string{}); err != nil {
+			t.Fatalf("Intermediate CA generation with --parent-key-uri failed: %v", err)
+		}
+		inter, err := cert.InspectFile(filepath.Join(tmpDir, "URI_Issuing_CA-ca.crt"))
+		if err != nil {
+			t.Fatalf("Failed to inspect intermediate CA: %v", err)
+		}
+		if inter.Issuer.CommonName != "URI Root CA" {
+			t.Errorf("Intermediate issuer = %q, want URI Root CA", inter.Issuer.CommonName)
+		}
+	})
+
+	// Test --bundle without a parent
+	t.Run("BundleWithoutParent", func(t *testing.T) {
+		caCN = "Bundle CA"
+		caOutput = tmpDir
+		caBundle = true
+		defer func() { caBundle = false }()
+
+		if err := caCmd.RunE(caCmd, []string{}); err == nil {
+			t.Error("Expected error for --bundle without --parent-cert, but got none")
+		}
+	})
+
 	// Test missing common name
 	t.Run("MissingCN", func(t *testing.T) {
 		caCN = ""
@@ -110,3 +239,59 @@ func TestCACommand(t *testing.T) {
 		}
 	})
 }
+
+func TestCAListCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	caCertPath := filepath.Join(tmpDir, "ca.crt")
+	caKeyPath := filepath.Join(tmpDir, "ca.key")
+	if err := cert.GenerateCA(cert.CAOptions{CommonName: "List Test CA", Days: 365, KeyAlgorithm: cert.KeyAlgorithmECDSAP256}, caCertPath, caKeyPath); err != nil {
+		t.Fatalf("Failed to generate CA: %v", err)
+	}
+	csrPath := filepath.Join(tmpDir, "app.csr")
+	if err := cert.GenerateCSR(cert.CSROptions{CommonName: "app.example.com", KeyAlgorithm: cert.KeyAlgorithmECDSAP256}, csrPath, filepath.Join(tmpDir, "app.key")); err != nil {
+		t.Fatalf("Failed to generate CSR: %v", err)
+	}
+	if err := cert.SignCSR(cert.SignOptions{CSRPath: csrPath, CACert: caCertPath, CAKey: caKeyPath, Days: 20}, filepath.Join(tmpDir, "app.crt")); err != nil {
+		t.Fatalf("Failed to sign CSR: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"List all", []string{"ca", "list", "--ca", caCertPath}, false},
+		{"List expiring as JSON", []string{"ca", "list", "--ca", caCertPath, "--expires-in", "30d", "--json"}, false},
+		{"List by status and subject", []string{"ca", "list", "--ca", caCertPath, "--status", "revoked", "--subject", "app"}, false},
+		{"Invalid status", []string{"ca", "list", "--ca", caCertPath, "--status", "pending"}, true},
+		{"Invalid expiry window", []string{"ca", "list", "--ca", caCertPath, "--expires-in", "soon"}, true},
+		{"Missing CA", []string{"ca", "list"}, true},
+		{"CA without an index", []string{"ca", "list", "--ca", filepath.Join(tmpDir, "other.crt")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset flags that persist between Execute calls
+			caListCA = ""
+			caListStatus = "all"
+			caListExpiresIn = ""
+			caListSubject = ""
+			jsonOutput = false
+			defer func() { jsonOutput = false }()
+
+			cmd := rootCmd
+			cmd.SetArgs(tt.args)
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			if tt.wantErr && err == nil {
+				t.Error("Expected error but got none")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -12,21 +12,48 @@ import (
 )
 
 var (
-	convertFormat string
+	convertFormat       string
+	convertKey          string
+	convertPassword     string
+	convertPasswordFile string
+	convertPasswordEnv  string
+	convertKeyFormat    string
+	convertLegacy       bool
 )
 
 var convertCmd = &cobra.Command{
     Use:   "convert [input] [output]",
-    Short: "Convert certificate between formats",
-	Long: `Convert a certificate file between PEM and DER formats.
+    Short: "Convert certificates and private keys between formats",
+	Long: `Convert a certificate file between PEM, DER, and PKCS#12 (PFX) formats.
 
-The input format is automatically detected. The output format is specified
-using the --format flag.
+The input format is automatically detected (.p12 and .pfx files are read as
+PKCS#12). The output format is specified using the --format flag. Every
+certificate in a bundle is kept: a PEM full chain converted to DER becomes
+concatenated DER certificates, and converts back to the same PEM chain.
+
+PKCS#12 output bundles the certificate, any additional certificates in the
+input as the chain, and the private key from --key (or from the input PEM).
+Converting a PKCS#12 file to PEM writes the certificate, chain, and key.
+
+Private key files are converted too. --key-format selects the encoding:
+pkcs8 (default), pkcs1 (RSA only), sec1 (EC only), or encrypted-pkcs8
+(PBES2 with AES-256-CBC). The same --key-format applies to the key written
+when converting PKCS#12 to PEM.
+
+Passwords for PKCS#12 files and encrypted keys can be given with --password,
+--password-file, or --password-env (the name of an environment variable).
 
 Examples:
   cert convert cert.pem cert.der --format der
   cert convert cert.der cert.pem --format pem
-  cert convert server.crt server.der --format der`,
+  cert convert server.crt server.der --format der
+  cert convert fullchain.pem server.p12 --format p12 --key server.key --password-env P12_PASS
+  cert convert server.pfx server.pem --format pem --password-file pass.txt
+  cert convert server.crt server.p12 --format p12 --key server.key --legacy
+  cert convert fullchain.pem fullchain.der --format der
+  cert convert server.key server-rsa.key --key-format pkcs1
+  cert convert server.key server.enc.key --key-format encrypted-pkcs8 --password-env KEY_PASS
+  cert convert server.enc.key server.key.der --format der --password-file pass.txt`,
 	Args: cobra.ExactArgs(2),
     RunE: func(cmd *cobra.Command, args []string) error {
         inputPath := args[0]
@@ -43,10 +70,22 @@ Examples:
             return err
         }
 
+		password, err := resolvePassword(convertPassword, convertPasswordFile, convertPasswordEnv)
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
 		// Detect input format for display purposes
 		var inputFormat string
-		if data, err := os.ReadFile(inputPath); err == nil {
-			if strings.Contains(string(data), "-----BEGIN CERTIFICATE-----") {
+		if cert.IsPKCS12Path(inputPath) {
+			inputFormat = "pkcs12"
+		} else if data, err := os.ReadFile(inputPath); err == nil {
+			if strings.Contains(string(data), "-----BEGIN ") {
 				inputFormat = "pem"
 			} else {
 				inputFormat = "der"
@@ -59,7 +98,16 @@ Examples:
 			ui.ShowInfo("Converting certificate format...")
 		}
 
-        if err := cert.Convert(inputPath, outputPath, convertFormat); err != nil {
+        err = cert.ConvertWithOptions(cert.ConvertOptions{
+            InputPath:  inputPath,
+            OutputPath: outputPath,
+            Format:     convertFormat,
+            KeyPath:    convertKey,
+            KeyFormat:  convertKeyFormat,
+            Password:   password,
+            Legacy:     convertLegacy,
+        })
+        if err != nil {
             if jsonOutput {
                 printJSONError(err)
             } else {
@@ -82,5 +130,11 @@ Examples:
 }
 
 func init() {
-	convertCmd.Flags().StringVar(&convertFormat, "format", "pem", "Output format (pem or der)")
+	convertCmd.Flags().StringVar(&convertFormat, "format", "pem", "Output format (pem, der, or p12)")
+	convertCmd.Flags().StringVar(&convertKey, "key", "", "Private key to include in PKCS#12 output")
+	convertCmd.Flags().StringVar(&convertKeyFormat, "key-format", cert.KeyFormatPKCS8, "Private key encoding: "+strings.Join(cert.KeyFormats, ", "))
+	convertCmd.Flags().StringVar(&convertPassword, "password", "", "Password for PKCS#12 files or encrypted keys")
+	convertCmd.Flags().StringVar(&convertPasswordFile, "password-file", "", "Read the password from a file")
+	convertCmd.Flags().StringVar(&convertPasswordEnv, "password-env", "", "Read the password from an environment variable")
+	convertCmd.Flags().BoolVar(&convertLegacy, "legacy", false, "Use legacy 3DES/SHA-1 PKCS#12 encryption for older Windows and Java")
 }
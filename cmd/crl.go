@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+
+	"certwiz/pkg/cert"
+	"certwiz/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	crlCA        string
+	crlCAKey     string
+	crlDB        string
+	crlOutput    string
+	crlDays      int
+	crlInspectCA string
+
+	crlCAKeyPasswordFile string
+	crlCAKeyPasswordEnv  string
+)
+
+var crlCmd = &cobra.Command{
+	Use:   "crl",
+	Short: "Issue and inspect Certificate Revocation Lists (CRLs)",
+	Long: `Issue and inspect Certificate Revocation Lists (CRLs).
+
+Use 'cert revoke' to revoke a certificate; it reissues the CRL automatically.
+'cert crl generate' reissues the CRL without revoking anything, to refresh
+its next update time before clients consider it stale.`,
+}
+
+var crlGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Issue a new CRL from the CA's revocation database",
+	Long: `Issue a new CRL listing every certificate revoked with 'cert revoke',
+signed with the CA key and with the next CRL number. An encrypted CA key is
+unlocked with a password that is prompted for, or read from
+--ca-key-password-file or --ca-key-password-env.
+
+Examples:
+  # Reissue the CRL (ca.crt -> ca.crl)
+  cert crl generate --ca ca.crt --ca-key ca.key
+
+  # Reissue with a 30-day next update to a specific path
+  cert crl generate --ca ca.crt --ca-key ca.key --days 30 --output /var/www/pki/ca.crl`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var validationErr error
+		switch {
+		case crlCA == "":
+			validationErr = fmt.Errorf("CA certificate (--ca) is required")
+		case crlCAKey == "":
+			validationErr = fmt.Errorf("CA private key (--ca-key) is required")
+		}
+		if validationErr != nil {
+			if jsonOutput {
+				printJSONError(validationErr)
+			}
+			return validationErr
+		}
+
+		password, err := keyPassword(crlCAKey, "ca-key", crlCAKeyPasswordFile, crlCAKeyPasswordEnv)
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+
+		crlPath := crlOutput
+		if crlPath == "" {
+			crlPath = cert.DefaultCRLPath(crlCA)
+		}
+
+		info, err := cert.GenerateCRL(cert.CRLOptions{
+			CACert:        crlCA,
+			CAKey:         crlCAKey,
+			CAKeyPassword: password,
+			DBPath:        crlDB,
+			Days:          crlDays,
+		}, crlPath)
+		if err != nil {
+			err = fmt.Errorf("failed to generate CRL: %w", err)
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+
+		if jsonOutput {
+			printJSON(cert.JSONOperationResult{
+				Success: true,
+				Message: fmt.Sprintf("CRL %s issued with %d revoked certificate(s)", info.Number, len(info.Entries)),
+				Files:   []string{crlPath},
+			})
+			return nil
+		}
+
+		ui.ShowSuccess(fmt.Sprintf("CRL %s issued: %s", info.Number, crlPath))
+		fmt.Println()
+		ui.DisplayCRL(info)
+		return nil
+	},
+}
+
+var crlInspectCmd = &cobra.Command{
+	Use:   "inspect [crl-file]",
+	Short: "Display a CRL and its revoked certificates",
+	Long: `Display a Certificate Revocation List (PEM or DER): issuer, CRL number,
+update times, and every revoked serial number with its date and reason.
+
+With --ca, the CRL's signature is verified against the issuing CA.
+
+Examples:
+  cert crl inspect ca.crl
+  cert crl inspect ca.crl --ca ca.crt
+  cert crl inspect ca.crl --json | jq '.revoked[].serial_number'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info, err := cert.InspectCRLFile(args[0])
+		if err == nil && crlInspectCA != "" {
+			var issuer *cert.Certificate
+			issuer, err = cert.InspectFile(crlInspectCA)
+			if err == nil {
+				if sigErr := info.CheckSignature(issuer.Certificate); sigErr != nil {
+					err = fmt.Errorf("CRL signature does not verify with %s: %w", crlInspectCA, sigErr)
+				}
+			}
+		}
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		if jsonOutput {
+			printJSON(info.ToJSON())
+		} else {
+			ui.DisplayCRL(info)
+		}
+		return nil
+	},
+}
+
+func init() {
+	crlGenerateCmd.Flags().StringVar(&crlCA, "ca", "", "Path to the CA certificate (required)")
+	crlGenerateCmd.Flags().StringVar(&crlCAKey, "ca-key", "", "Path to the CA private key (required)")
+	crlGenerateCmd.Flags().StringVar(&crlCAKeyPasswordFile, "ca-key-password-file", "", "Read the CA key's password from a file")
+	crlGenerateCmd.Flags().StringVar(&crlCAKeyPasswordEnv, "ca-key-password-env", "", "Read the CA key's password from an environment variable")
+	crlGenerateCmd.Flags().StringVar(&crlDB, "db", "", "Revocation database (default: <ca>.revoked.json)")
+	crlGenerateCmd.Flags().StringVarP(&crlOutput, "output", "o", "", "Output path for the CRL (default: <ca>.crl)")
+	crlGenerateCmd.Flags().IntVarP(&crlDays, "days", "d", cert.DefaultCRLDays, "Days until the CRL's next update")
+
+	crlInspectCmd.Flags().StringVar(&crlInspectCA, "ca", "", "CA certificate to verify the CRL signature")
+
+	crlCmd.AddCommand(crlGenerateCmd)
+	crlCmd.AddCommand(crlInspectCmd)
+	rootCmd.AddCommand(crlCmd)
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"certwiz/internal/testutil"
+	"certwiz/pkg/cert"
+)
+
+func TestRevokeAndCRLCommands(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	caCertPath := filepath.Join(tmpDir, "ca.crt")
+	caKeyPath := filepath.Join(tmpDir, "ca.key")
+	if err := cert.GenerateCA(cert.CAOptions{CommonName: "Revoke Test CA", Days: 365, KeyAlgorithm: cert.KeyAlgorithmECDSAP256}, caCertPath, caKeyPath); err != nil {
+		t.Fatalf("Failed to generate CA: %v", err)
+	}
+	csrPath := filepath.Join(tmpDir, "server.csr")
+	if err := cert.GenerateCSR(cert.CSROptions{CommonName: "server.example.com", KeyAlgorithm: cert.KeyAlgorithmECDSAP256}, csrPath, filepath.Join(tmpDir, "server.key")); err != nil {
+		t.Fatalf("Failed to generate CSR: %v", err)
+	}
+	certPath := filepath.Join(tmpDir, "server.crt")
+	if err := cert.SignCSR(cert.SignOptions{CSRPath: csrPath, CACert: caCertPath, CAKey: caKeyPath, Days: 365}, certPath); err != nil {
+		t.Fatalf("Failed to sign CSR: %v", err)
+	}
+	crlPath := filepath.Join(tmpDir, "ca.crl")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "Issue an empty CRL",
+			args:    []string{"crl", "generate", "--ca", caCertPath, "--ca-key", caKeyPath},
+			wantErr: false,
+		},
+		{
+			name:    "Verify before revocation",
+			args:    []string{"verify", certPath, "--ca", caCertPath, "--crl", crlPath},
+			wantErr: false,
+		},
+		{
+			name:    "Revoke with an invalid reason",
+			args:    []string{"revoke", certPath, "--ca", caCertPath, "--ca-key", caKeyPath, "--reason", "stolen"},
+			wantErr: true,
+		},
+		{
+			name:    "Revoke without a certificate or serial",
+			args:    []string{"revoke", "--ca", caCertPath, "--ca-key", caKeyPath},
+			wantErr: true,
+		},
+		{
+			name:    "Revoke the certificate",
+			args:    []string{"revoke", certPath, "--ca", caCertPath, "--ca-key", caKeyPath, "--reason", "keyCompromise"},
+			wantErr: false,
+		},
+		{
+			name:    "Revoke the certificate again",
+			args:    []string{"revoke", certPath, "--ca", caCertPath, "--ca-key", caKeyPath, "--json"},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect the CRL with its CA",
+			args:    []string{"crl", "inspect", crlPath, "--ca", caCertPath},
+			wantErr: false,
+		},
+		{
+			name:    "Inspect the CRL with the wrong CA",
+			args:    []string{"crl", "inspect", crlPath, "--ca", testutil.TestdataPath("ca.pem"), "--json"},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect a certificate as a CRL",
+			args:    []string{"crl", "inspect", certPath},
+			wantErr: true,
+		},
+		{
+			name:    "Verify after revocation",
+			args:    []string{"verify", certPath, "--ca", caCertPath, "--crl", crlPath},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset flags that persist between Execute calls
+			revokeReason = "unspecified"
+			revokeSerial = ""
+			crlInspectCA = ""
+			verifyCRL = ""
+			verifyCA = ""
+			jsonOutput = false
+			defer func() { jsonOutput = false }()
+
+			cmd := rootCmd
+			cmd.SetArgs(tt.args)
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			if tt.wantErr && err == nil {
+				t.Error("Expected error but got none")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	if _, err := os.Stat(cert.DefaultRevocationDBPath(caCertPath)); err != nil {
+		t.Errorf("Revocation database was not written: %v", err)
+	}
+}
@@ -21,8 +21,13 @@ var (
 	csrLocality string
 	csrEmail    string
 	csrSANs     []string
+	csrKeyAlg   string
 	csrKeySize  int
 	csrOutput   string
+
+	csrEncryptKey      bool
+	csrKeyPasswordFile string
+	csrKeyPasswordEnv  string
 )
 
 var csrCmd = &cobra.Command{
@@ -44,7 +49,15 @@ Examples:
   cert csr --cn example.com --san example.com --san www.example.com --san api.example.com
   
   # CSR with custom output directory and key size
-  cert csr --cn secure.example.com --key-size 4096 --output /etc/ssl/`,
+  cert csr --cn secure.example.com --key-size 4096 --output /etc/ssl/
+
+  # CSR with an ECDSA P-256 or Ed25519 key
+  cert csr --cn example.com --key-algorithm ecdsa-p256
+  cert csr --cn example.com --key-algorithm ed25519
+
+  # CSR with a password-protected key (prompted for, or from a file or env var)
+  cert csr --cn example.com --encrypt-key
+  cert csr --cn example.com --key-password-file key.pass`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if csrCN == "" {
 			err := fmt.Errorf("common name (--cn) is required")
@@ -54,6 +67,21 @@ Examples:
 			return err
 		}
 
+		if err := cert.ValidateKeyAlgorithm(csrKeyAlg); err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+
+		password, err := newKeyPassword(csrEncryptKey, csrKeyPasswordFile, csrKeyPasswordEnv)
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+
 		// Prepare options
 		options := cert.CSROptions{
 			CommonName:         csrCN,
@@ -64,7 +92,9 @@ Examples:
 			Locality:           csrLocality,
 			EmailAddress:       csrEmail,
 			SANs:               processSANs(csrSANs),
+			KeyAlgorithm:       csrKeyAlg,
 			KeySize:            csrKeySize,
+			KeyPassword:        password,
 		}
 
 		// Set output path
@@ -80,7 +110,7 @@ Examples:
 		csrPath := filepath.Join(csrOutput, sanitizeFilename(csrCN)+".csr")
 		keyPath := filepath.Join(csrOutput, sanitizeFilename(csrCN)+".key")
 
-		err := cert.GenerateCSR(options, csrPath, keyPath)
+		err = cert.GenerateCSR(options, csrPath, keyPath)
 		if err != nil {
 			err = fmt.Errorf("failed to generate CSR: %w", err)
 			if jsonOutput {
@@ -130,8 +160,12 @@ func init() {
 	csrCmd.Flags().StringVar(&csrLocality, "locality", "", "Locality or City")
 	csrCmd.Flags().StringVar(&csrEmail, "email", "", "Email Address")
 	csrCmd.Flags().StringSliceVar(&csrSANs, "san", []string{}, "Subject Alternative Name (can be used multiple times)")
+	csrCmd.Flags().StringVar(&csrKeyAlg, "key-algorithm", cert.KeyAlgorithmRSA, keyAlgorithmFlagUsage)
 	csrCmd.Flags().IntVarP(&csrKeySize, "key-size", "k", 2048, "RSA key size in bits")
 	csrCmd.Flags().StringVarP(&csrOutput, "output", "o", "", "Output directory for CSR and key files")
+	csrCmd.Flags().BoolVar(&csrEncryptKey, "encrypt-key", false, "Encrypt the private key with a password (prompted for)")
+	csrCmd.Flags().StringVar(&csrKeyPasswordFile, "key-password-file", "", "Encrypt the private key with the password in a file")
+	csrCmd.Flags().StringVar(&csrKeyPasswordEnv, "key-password-env", "", "Encrypt the private key with the password in an environment variable")
 
 	rootCmd.AddCommand(csrCmd)
 }
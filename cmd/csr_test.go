@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"certwiz/pkg/cert"
 )
 
 func TestCSRCommand(t *testing.T) {
@@ -80,6 +82,33 @@ func TestCSRCommand(t *testing.T) {
 		}
 	})
 
+	// Test CSR with an Ed25519 key
+	t.Run("CSRWithEd25519", func(t *testing.T) {
+		csrCN = "ed.example.com"
+		csrOrg = ""
+		csrCountry = ""
+		csrState = ""
+		csrOutput = tmpDir
+		csrKeyAlg = "ed25519"
+		defer func() { csrKeyAlg = "rsa" }()
+
+		if err := csrCmd.RunE(csrCmd, []string{}); err != nil {
+			t.Fatalf("CSR generation with Ed25519 failed: %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, "ed.example.com.csr"))
+		if err != nil {
+			t.Fatalf("Failed to read CSR: %v", err)
+		}
+		info, err := cert.ParseCSR(data)
+		if err != nil {
+			t.Fatalf("Failed to parse CSR: %v", err)
+		}
+		if info.PublicKeyAlgorithm != "Ed25519" {
+			t.Errorf("Public key algorithm = %s, want Ed25519", info.PublicKeyAlgorithm)
+		}
+	})
+
 	// Test missing common name
 	t.Run("MissingCN", func(t *testing.T) {
 		csrCN = ""
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"certwiz/pkg/cert"
+	"certwiz/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffPort    int
+	diffTimeout string
+	diffProxy   string
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [old] [new]",
+	Short: "Compare two certificates field by field",
+	Long: `Compare two certificates and show what changed: subject, issuer, serial,
+validity, key algorithm and size, signature algorithm, SANs, key usages, and
+extensions. List fields show which entries were added and removed.
+
+Each side can be a certificate file, "-" for stdin, or a URL or host
+(fetched like cert inspect, honoring HTTPS_PROXY). Files with several
+certificates are compared by their first certificate.
+
+Examples:
+  cert diff old.pem new.pem
+  cert diff old.pem example.com
+  cert diff example.com:443 staging.example.com:443
+  openssl s_client -connect example.com:443 </dev/null | cert diff old.pem -
+  cert diff old.pem new.pem --json`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fail := func(err error) error {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		if args[0] == "-" && args[1] == "-" {
+			return fail(fmt.Errorf("only one side can be read from stdin"))
+		}
+		timeout, err := time.ParseDuration(diffTimeout)
+		if err != nil {
+			return fail(fmt.Errorf("invalid --timeout value %q: %w", diffTimeout, err))
+		}
+		proxy, err := parseProxyFlag(diffProxy)
+		if err != nil {
+			return fail(err)
+		}
+
+		oldCert, err := loadDiffCertificate(args[0], timeout, proxy)
+		if err != nil {
+			return fail(err)
+		}
+		newCert, err := loadDiffCertificate(args[1], timeout, proxy)
+		if err != nil {
+			return fail(err)
+		}
+
+		diff := cert.DiffCertificates(oldCert, newCert)
+		if jsonOutput {
+			printJSON(diff.ToJSON())
+		} else {
+			ui.DisplayCertificateDiff(diff)
+		}
+		return nil
+	},
+}
+
+// loadDiffCertificate reads one side of a diff from stdin ("-"), a file,
+// or a remote host
+func loadDiffCertificate(target string, timeout time.Duration, proxy *url.URL) (*cert.Certificate, error) {
+	if target == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		certs, err := cert.InspectData(data, "stdin")
+		if err != nil {
+			return nil, err
+		}
+		return certs[0], nil
+	}
+
+	if _, err := os.Stat(target); err == nil {
+		certs, err := cert.InspectFileAll(target)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target, err)
+		}
+		return certs[0], nil
+	}
+
+	host, port := cert.SplitHostTarget(target, diffPort)
+	c, _, err := cert.InspectURLWithConnectOptions(host, port, cert.ConnectOptions{
+		Timeout:              timeout,
+		Proxy:                proxy,
+		ProxyFromEnvironment: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", target, err)
+	}
+	return c, nil
+}
+
+func init() {
+	diffCmd.Flags().IntVar(&diffPort, "port", 443, "Default port for hosts without one")
+	diffCmd.Flags().StringVar(&diffTimeout, "timeout", "5s", "Network timeout for remote certificates (e.g., 5s, 2s)")
+	diffCmd.Flags().StringVar(&diffProxy, "proxy", "", "Tunnel through a proxy: http://host:port or socks5://host:port (default from HTTPS_PROXY)")
+
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"certwiz/internal/testutil"
+)
+
+func TestDiffCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "Diff two certificates",
+			args:    []string{"diff", testutil.TestdataPath("valid.pem"), testutil.TestdataPath("expired.pem")},
+			wantErr: false,
+		},
+		{
+			name:    "Diff a certificate with itself as JSON",
+			args:    []string{"diff", testutil.TestdataPath("valid.pem"), testutil.TestdataPath("valid.der"), "--json"},
+			wantErr: false,
+		},
+		{
+			name:    "Diff with an invalid certificate",
+			args:    []string{"diff", testutil.TestdataPath("valid.pem"), testutil.TestdataPath("invalid.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "Diff with both sides on stdin",
+			args:    []string{"diff", "-", "-"},
+			wantErr: true,
+		},
+		{
+			name:    "Diff with invalid timeout",
+			args:    []string{"diff", testutil.TestdataPath("valid.pem"), "example.com", "--timeout", "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "Diff with one argument",
+			args:    []string{"diff", testutil.TestdataPath("valid.pem")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset flags that persist between Execute calls
+			diffTimeout = "5s"
+			jsonOutput = false
+			defer func() { jsonOutput = false }()
+
+			cmd := rootCmd
+			cmd.SetArgs(tt.args)
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			if tt.wantErr && err == nil {
+				t.Error("Expected error but got none")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"certwiz/internal/config"
+	"certwiz/pkg/cert"
+	"certwiz/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultExporterListen is the default address of the metrics endpoint
+const defaultExporterListen = ":9793"
+
+var (
+	exporterListen      string
+	exporterFiles       []string
+	exporterTargets     []string
+	exporterPort        int
+	exporterInterval    string
+	exporterTLSInterval string
+	exporterTimeout     string
+	exporterCA          string
+	exporterProxy       string
+)
+
+var exporterCmd = &cobra.Command{
+	Use:   "exporter",
+	Short: "Serve certificate expiry metrics for Prometheus",
+	Long: `Serve a Prometheus /metrics endpoint for certificate files and remote
+targets: expiry time, days to expiry, chain validity, and which TLS versions
+each target accepts.
+
+Probes run in the background and /metrics serves the cached results, so
+scrapes are fast and don't hit the targets. Certificates are re-inspected
+every --interval and TLS versions re-checked every --tls-interval.
+
+Files and targets come from --file and --target and from the exporter
+section of the config file; settings given as flags override the config.
+
+  exporter:
+    listen: ":9793"
+    files: [/etc/ssl/certs/server.pem]
+    targets: [example.com, api.example.com:8443]
+    interval: 5m
+    tls_interval: 1h
+
+Examples:
+  cert exporter --target example.com --target api.example.com:8443
+  cert exporter --file /etc/nginx/certs/fullchain.pem --listen 127.0.0.1:9793
+  cert exporter --target internal.svc --ca company-root.pem --interval 1m
+  cert exporter   # everything from the config file`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fail := func(err error) error {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		listen, opts, err := exporterOptions(cmd, config.Load().Exporter)
+		if err != nil {
+			return fail(err)
+		}
+		exporter := cert.NewExporter(opts)
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", exporter)
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintln(w, `<html><head><title>certwiz exporter</title></head><body><h1>certwiz exporter</h1><p><a href="/metrics">Metrics</a></p></body></html>`)
+		})
+		server := &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go exporter.Run(ctx)
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(shutdownCtx)
+		}()
+
+		if !jsonOutput {
+			ui.ShowInfo(fmt.Sprintf("Serving metrics for %d file(s) and %d target(s) on http://%s/metrics", len(opts.Files), len(opts.Targets), listen))
+		}
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fail(fmt.Errorf("failed to serve metrics: %w", err))
+		}
+		return nil
+	},
+}
+
+// exporterOptions combines the exporter flags with the config file's
+// exporter section. Files and targets from both are monitored; other
+// settings given as flags override the config.
+func exporterOptions(cmd *cobra.Command, cfg config.ExporterConfig) (string, cert.ExporterOptions, error) {
+	setting := func(flag, value, configured string) string {
+		if !cmd.Flags().Changed(flag) && configured != "" {
+			return configured
+		}
+		return value
+	}
+	duration := func(flag, value string) (time.Duration, error) {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid --%s value %q: must be a positive duration such as 5m", flag, value)
+		}
+		return d, nil
+	}
+
+	opts := cert.ExporterOptions{
+		Files:   append(append([]string{}, cfg.Files...), exporterFiles...),
+		Targets: append(append([]string{}, cfg.Targets...), exporterTargets...),
+		Port:    exporterPort,
+	}
+	if len(opts.Files) == 0 && len(opts.Targets) == 0 {
+		return "", opts, fmt.Errorf("nothing to export: give --file or --target, or list them in the config file")
+	}
+
+	var err error
+	if opts.Interval, err = duration("interval", setting("interval", exporterInterval, cfg.Interval)); err != nil {
+		return "", opts, err
+	}
+	if opts.TLSInterval, err = duration("tls-interval", setting("tls-interval", exporterTLSInterval, cfg.TLSInterval)); err != nil {
+		return "", opts, err
+	}
+	if opts.Connect.Timeout, err = duration("timeout", setting("timeout", exporterTimeout, cfg.Timeout)); err != nil {
+		return "", opts, err
+	}
+
+	if ca := setting("ca", exporterCA, cfg.CA); ca != "" {
+		roots, err := cert.LoadCertPool(ca)
+		if err != nil {
+			return "", opts, err
+		}
+		opts.Chain = cert.ChainOptions{Roots: roots, RootsSource: ca}
+	}
+	if opts.Connect.Proxy, err = parseProxyFlag(exporterProxy); err != nil {
+		return "", opts, err
+	}
+	opts.Connect.ProxyFromEnvironment = true
+
+	return setting("listen", exporterListen, cfg.Listen), opts, nil
+}
+
+func init() {
+	exporterCmd.Flags().StringVar(&exporterListen, "listen", defaultExporterListen, "Address to serve /metrics on")
+	exporterCmd.Flags().StringSliceVar(&exporterFiles, "file", nil, "Certificate file to monitor (repeatable)")
+	exporterCmd.Flags().StringSliceVar(&exporterTargets, "target", nil, "Host, host:port, or URL to monitor (repeatable)")
+	exporterCmd.Flags().IntVar(&exporterPort, "port", 443, "Default port for targets without one")
+	exporterCmd.Flags().StringVar(&exporterInterval, "interval", cert.DefaultExporterInterval.String(), "How often to re-inspect certificates (e.g., 5m)")
+	exporterCmd.Flags().StringVar(&exporterTLSInterval, "tls-interval", cert.DefaultExporterTLSInterval.String(), "How often to re-check targets' TLS versions (e.g., 1h)")
+	exporterCmd.Flags().StringVar(&exporterTimeout, "timeout", "5s", "Network timeout per probe (e.g., 5s, 2s)")
+	exporterCmd.Flags().StringVar(&exporterCA, "ca", "", "CA bundle to validate chains against (default: system roots)")
+	exporterCmd.Flags().StringVar(&exporterProxy, "proxy", "", "Tunnel through a proxy: http://host:port or socks5://host:port (default from HTTPS_PROXY)")
+
+	rootCmd.AddCommand(exporterCmd)
+}
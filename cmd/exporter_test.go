@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"certwiz/internal/config"
+	"certwiz/internal/testutil"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExporterCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "Exporter with invalid interval",
+			args:    []string{"exporter", "--target", "example.com", "--interval", "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "Exporter with zero TLS interval",
+			args:    []string{"exporter", "--target", "example.com", "--tls-interval", "0s"},
+			wantErr: true,
+		},
+		{
+			name:    "Exporter with missing CA bundle",
+			args:    []string{"exporter", "--file", testutil.TestdataPath("valid.pem"), "--ca", testutil.TestdataPath("missing.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "Exporter with unsupported proxy scheme",
+			args:    []string{"exporter", "--target", "example.com", "--proxy", "ftp://proxy.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "Exporter with unusable listen address",
+			args:    []string{"exporter", "--file", testutil.TestdataPath("valid.pem"), "--listen", "256.0.0.1:0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset flags that persist between Execute calls
+			exporterListen = defaultExporterListen
+			exporterFiles = nil
+			exporterTargets = nil
+			exporterInterval = "5m"
+			exporterTLSInterval = "1h"
+			exporterCA = ""
+			exporterProxy = ""
+			jsonOutput = false
+			defer func() { jsonOutput = false }()
+
+			cmd := rootCmd
+			cmd.SetArgs(tt.args)
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			if tt.wantErr && err == nil {
+				t.Error("Expected error but got none")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestExporterOptions(t *testing.T) {
+	// A fresh command, so no flag is marked as changed by earlier tests
+	newCmd := func() *cobra.Command {
+		c := &cobra.Command{}
+		c.Flags().StringVar(&exporterListen, "listen", defaultExporterListen, "")
+		c.Flags().StringSliceVar(&exporterFiles, "file", nil, "")
+		c.Flags().StringSliceVar(&exporterTargets, "target", nil, "")
+		c.Flags().IntVar(&exporterPort, "port", 443, "")
+		c.Flags().StringVar(&exporterInterval, "interval", "5m", "")
+		c.Flags().StringVar(&exporterTLSInterval, "tls-interval", "1h", "")
+		c.Flags().StringVar(&exporterTimeout, "timeout", "5s", "")
+		c.Flags().StringVar(&exporterCA, "ca", "", "")
+		c.Flags().StringVar(&exporterProxy, "proxy", "", "")
+		return c
+	}
+	cfg := config.ExporterConfig{
+		Listen:   "127.0.0.1:9000",
+		Files:    []string{"/etc/ssl/server.pem"},
+		Targets:  []string{"example.com"},
+		Interval: "10m",
+		Timeout:  "3s",
+	}
+
+	// Config settings apply; flag lists are added to the config's
+	cmd := newCmd()
+	_ = cmd.Flags().Set("target", "api.example.com:8443")
+	listen, opts, err := exporterOptions(cmd, cfg)
+	if err != nil {
+		t.Fatalf("exporterOptions failed: %v", err)
+	}
+	if listen != "127.0.0.1:9000" || opts.Interval != 10*time.Minute || opts.TLSInterval != time.Hour || opts.Connect.Timeout != 3*time.Second {
+		t.Errorf("listen %q, interval %v, TLS interval %v, timeout %v", listen, opts.Interval, opts.TLSInterval, opts.Connect.Timeout)
+	}
+	if len(opts.Files) != 1 || len(opts.Targets) != 2 || opts.Targets[1] != "api.example.com:8443" {
+		t.Errorf("files %v, targets %v", opts.Files, opts.Targets)
+	}
+	if !opts.Connect.ProxyFromEnvironment {
+		t.Error("HTTPS_PROXY should be honored")
+	}
+
+	// Flags override config settings
+	cmd = newCmd()
+	_ = cmd.Flags().Set("interval", "1m")
+	_ = cmd.Flags().Set("listen", ":9100")
+	listen, opts, err = exporterOptions(cmd, cfg)
+	if err != nil {
+		t.Fatalf("exporterOptions failed: %v", err)
+	}
+	if listen != ":9100" || opts.Interval != time.Minute {
+		t.Errorf("listen %q, interval %v; want flag values", listen, opts.Interval)
+	}
+
+	// Nothing configured
+	if _, _, err := exporterOptions(newCmd(), config.ExporterConfig{}); err == nil {
+		t.Error("Expected an error with no files or targets")
+	}
+}
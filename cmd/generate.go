@@ -11,11 +11,16 @@ import (
 )
 
 var (
-	generateCN      string
-	generateDays    int
-	generateKeySize int
-	generateSANs    []string
-	generateOutput  string
+	generateCN           string
+	generateDays         int
+	generateKeyAlgorithm string
+	generateKeySize      int
+	generateSANs         []string
+	generateOutput       string
+
+	generateEncryptKey      bool
+	generateKeyPasswordFile string
+	generateKeyPasswordEnv  string
 )
 
 var generateCmd = &cobra.Command{
@@ -26,11 +31,18 @@ var generateCmd = &cobra.Command{
 The certificate and private key will be saved in the output directory
 with filenames based on the common name.
 
+With --encrypt-key the private key is written as encrypted PKCS#8 (PBES2,
+AES-256-CBC) with a password that is prompted for, or taken from
+--key-password-file or --key-password-env, which imply --encrypt-key.
+
 Examples:
   cert generate --cn example.com
   cert generate --cn myserver --days 730 --key-size 4096
+  cert generate --cn example.com --key-algorithm ecdsa-p256
   cert generate --cn example.com --san *.example.com --san www.example.com
-  cert generate --cn server --san IP:192.168.1.100 --san localhost`,
+  cert generate --cn server --san IP:192.168.1.100 --san localhost
+  cert generate --cn example.com --encrypt-key
+  cert generate --cn example.com --key-password-env KEY_PASS`,
     RunE: func(cmd *cobra.Command, args []string) error {
         if generateCN == "" {
             err := fmt.Errorf("missing required flag: --cn")
@@ -42,16 +54,37 @@ Examples:
             return err
         }
 
+		if err := cert.ValidateKeyAlgorithm(generateKeyAlgorithm); err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		password, err := newKeyPassword(generateEncryptKey, generateKeyPasswordFile, generateKeyPasswordEnv)
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
 		opts := cert.GenerateOptions{
-			CommonName: generateCN,
-			Days:       generateDays,
-			KeySize:    generateKeySize,
-			SANs:       generateSANs,
-			OutputDir:  generateOutput,
+			CommonName:   generateCN,
+			Days:         generateDays,
+			KeyAlgorithm: generateKeyAlgorithm,
+			KeySize:      generateKeySize,
+			SANs:         generateSANs,
+			OutputDir:    generateOutput,
+			KeyPassword:  password,
 		}
 
 		if !jsonOutput {
-			ui.ShowInfo("Generating RSA private key...")
+			ui.ShowInfo(fmt.Sprintf("Generating %s private key...", cert.KeyAlgorithmDisplayName(generateKeyAlgorithm)))
 			ui.ShowInfo("Creating self-signed certificate...")
 		}
 
@@ -85,9 +118,13 @@ Examples:
 func init() {
 	generateCmd.Flags().StringVar(&generateCN, "cn", "", "Common Name for the certificate (required)")
 	generateCmd.Flags().IntVar(&generateDays, "days", 365, "Validity period in days")
+	generateCmd.Flags().StringVar(&generateKeyAlgorithm, "key-algorithm", cert.KeyAlgorithmRSA, keyAlgorithmFlagUsage)
 	generateCmd.Flags().IntVar(&generateKeySize, "key-size", 2048, "RSA key size in bits")
 	generateCmd.Flags().StringSliceVar(&generateSANs, "san", []string{}, "Subject Alternative Name (can be used multiple times)")
 	generateCmd.Flags().StringVar(&generateOutput, "output", ".", "Output directory")
+	generateCmd.Flags().BoolVar(&generateEncryptKey, "encrypt-key", false, "Encrypt the private key with a password (prompted for)")
+	generateCmd.Flags().StringVar(&generateKeyPasswordFile, "key-password-file", "", "Encrypt the private key with the password in a file")
+	generateCmd.Flags().StringVar(&generateKeyPasswordEnv, "key-password-env", "", "Encrypt the private key with the password in an environment variable")
 
 	_ = generateCmd.MarkFlagRequired("cn")
 }
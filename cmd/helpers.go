@@ -1,13 +1,22 @@
 package cmd
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"strings"
 
 	env "certwiz/internal/environ"
 	"certwiz/pkg/cert"
+
+	"golang.org/x/term"
 )
 
+// keyAlgorithmFlagUsage is the shared help text for --key-algorithm flags
+var keyAlgorithmFlagUsage = "Key algorithm: " + strings.Join(cert.KeyAlgorithms, ", ")
+
 // getEmoji returns an emoji or ASCII equivalent based on config and environment
 func getEmoji(emoji, ascii string) string {
 	// Check config first (if loaded)
@@ -31,3 +40,130 @@ func printJSON(v interface{}) {
 func printJSONError(err error) {
     printJSON(cert.JSONOperationResult{Success: false, Error: err.Error()})
 }
+
+// resolvePassword returns a password from the first source that is set:
+// the flag value, the first line of a file, or a named environment variable.
+// It returns an empty password when no source is set.
+func resolvePassword(value, file, envVar string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		return strings.TrimRight(strings.SplitN(string(data), "\n", 2)[0], "\r"), nil
+	}
+	if envVar != "" {
+		password, ok := os.LookupEnv(envVar)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", envVar)
+		}
+		return password, nil
+	}
+	return "", nil
+}
+
+// newKeyPassword returns the password to encrypt a newly generated private
+// key with, from --key-password-file or --key-password-env, or prompted for
+// when only --encrypt-key is set. An empty password leaves the key
+// unencrypted.
+func newKeyPassword(encrypt bool, file, envVar string) (string, error) {
+	if file != "" || envVar != "" {
+		password, err := resolvePassword("", file, envVar)
+		if err == nil && password == "" {
+			err = fmt.Errorf("the private key password is empty")
+		}
+		return password, err
+	}
+	if !encrypt {
+		return "", nil
+	}
+	return promptPassword("New private key password", true,
+		"--encrypt-key needs a terminal to prompt; use --key-password-file or --key-password-env")
+}
+
+// keyPassword returns the password for an existing private key, from the
+// --<flag>-password-file or --<flag>-password-env flags, or prompted for
+// when the key is encrypted and neither is set. Keys opened from a URI have
+// no keyPath and only take the flags.
+func keyPassword(keyPath, flag, file, envVar string) (string, error) {
+	if file != "" || envVar != "" {
+		return resolvePassword("", file, envVar)
+	}
+	data, err := os.ReadFile(keyPath)
+	if err != nil || !cert.IsEncryptedPrivateKey(data) {
+		return "", nil // unreadable keys are reported when they are loaded
+	}
+	return promptPassword(fmt.Sprintf("Password for %s", keyPath), false,
+		fmt.Sprintf("%s is encrypted; use --%s-password-file or --%s-password-env", keyPath, flag, flag))
+}
+
+// promptPassword reads a password from the terminal without echoing it,
+// asking twice when confirm is set. Without a terminal it fails with
+// noTerminal, which should name the non-interactive alternatives.
+func promptPassword(prompt string, confirm bool, noTerminal string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("%s", noTerminal)
+	}
+
+	read := func(prompt string) (string, error) {
+		fmt.Fprintf(os.Stderr, "%s: ", prompt)
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		return string(password), nil
+	}
+
+	password, err := read(prompt)
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", fmt.Errorf("the password is empty")
+	}
+	if confirm {
+		again, err := read("Confirm password")
+		if err != nil {
+			return "", err
+		}
+		if again != password {
+			return "", fmt.Errorf("the passwords do not match")
+		}
+	}
+	return password, nil
+}
+
+// loadClientCertificate loads the --client-cert/--client-key pair, or
+// returns nil when no client certificate was given. An encrypted key's
+// password comes from passwordFile, passwordEnv, or a prompt.
+func loadClientCertificate(certPath, keyPath, passwordFile, passwordEnv string) (*tls.Certificate, error) {
+	if certPath == "" {
+		if keyPath != "" {
+			return nil, fmt.Errorf("--client-key requires --client-cert")
+		}
+		return nil, nil
+	}
+	keyFile := keyPath
+	if keyFile == "" {
+		keyFile = certPath // the key may be in the certificate file
+	}
+	password, err := keyPassword(keyFile, "client-key", passwordFile, passwordEnv)
+	if err != nil {
+		return nil, err
+	}
+	return cert.LoadClientCertificate(certPath, keyPath, password)
+}
+
+// parseProxyFlag parses a --proxy value, or returns nil when it is empty
+// (HTTPS_PROXY and NO_PROXY then apply)
+func parseProxyFlag(value string) (*url.URL, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return cert.ParseProxyURL(value)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"certwiz/pkg/cert"
+)
+
+func TestResolvePassword(t *testing.T) {
+	tmpDir := t.TempDir()
+	passFile := filepath.Join(tmpDir, "pass.txt")
+	if err := os.WriteFile(passFile, []byte("from-file\r\nignored\n"), 0600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+	t.Setenv("CERTWIZ_TEST_PASSWORD", "from-env")
+
+	tests := []struct {
+		name    string
+		value   string
+		file    string
+		envVar  string
+		want    string
+		wantErr bool
+	}{
+		{"flag wins", "from-flag", passFile, "CERTWIZ_TEST_PASSWORD", "from-flag", false},
+		{"file first line", "", passFile, "CERTWIZ_TEST_PASSWORD", "from-file", false},
+		{"environment variable", "", "", "CERTWIZ_TEST_PASSWORD", "from-env", false},
+		{"no source", "", "", "", "", false},
+		{"missing file", "", filepath.Join(tmpDir, "missing"), "", "", true},
+		{"unset variable", "", "", "CERTWIZ_TEST_UNSET_PASSWORD", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePassword(tt.value, tt.file, tt.envVar)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolvePassword() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKeyPassword(t *testing.T) {
+	tmpDir := t.TempDir()
+	passFile := filepath.Join(tmpDir, "pass.txt")
+	emptyFile := filepath.Join(tmpDir, "empty.txt")
+	if err := os.WriteFile(passFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+	if err := os.WriteFile(emptyFile, nil, 0600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+	t.Setenv("CERTWIZ_TEST_PASSWORD", "from-env")
+
+	tests := []struct {
+		name    string
+		encrypt bool
+		file    string
+		envVar  string
+		want    string
+		wantErr string
+	}{
+		{"unencrypted", false, "", "", "", ""},
+		{"file implies encryption", false, passFile, "", "from-file", ""},
+		{"environment variable", true, "", "CERTWIZ_TEST_PASSWORD", "from-env", ""},
+		{"empty password", false, emptyFile, "", "", "empty"},
+		// Tests don't run on a terminal, so the prompt can't be shown
+		{"prompt without a terminal", true, "", "", "", "--key-password-file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newKeyPassword(tt.encrypt, tt.file, tt.envVar)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("newKeyPassword() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyPassword(t *testing.T) {
+	tmpDir := t.TempDir()
+	plainCert, plainKey := filepath.Join(tmpDir, "plain.crt"), filepath.Join(tmpDir, "plain.key")
+	encCert, encKey := filepath.Join(tmpDir, "enc.crt"), filepath.Join(tmpDir, "enc.key")
+	if err := cert.GenerateCA(cert.CAOptions{CommonName: "Plain", Days: 1, KeyAlgorithm: cert.KeyAlgorithmECDSAP256}, plainCert, plainKey); err != nil {
+		t.Fatalf("GenerateCA failed: %v", err)
+	}
+	if err := cert.GenerateCA(cert.CAOptions{CommonName: "Encrypted", Days: 1, KeyAlgorithm: cert.KeyAlgorithmECDSAP256, KeyPassword: "pw"}, encCert, encKey); err != nil {
+		t.Fatalf("GenerateCA failed: %v", err)
+	}
+	t.Setenv("CERTWIZ_TEST_PASSWORD", "pw")
+
+	// Unencrypted and missing keys need no password
+	for _, path := range []string{plainKey, filepath.Join(tmpDir, "missing.key")} {
+		if got, err := keyPassword(path, "ca-key", "", ""); err != nil || got != "" {
+			t.Errorf("keyPassword(%s) = %q, %v; want no password", path, got, err)
+		}
+	}
+
+	if got, err := keyPassword(encKey, "ca-key", "", "CERTWIZ_TEST_PASSWORD"); err != nil || got != "pw" {
+		t.Errorf("keyPassword() = %q, %v; want the password from the environment", got, err)
+	}
+
+	// Without a terminal, the error names the flags to use instead
+	_, err := keyPassword(encKey, "ca-key", "", "")
+	if err == nil || !strings.Contains(err.Error(), "--ca-key-password-file") {
+		t.Errorf("Expected an error naming --ca-key-password-file, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+    "crypto/x509"
     "fmt"
     "io"
     "net"
@@ -16,12 +17,26 @@ import (
 )
 
 var (
-    inspectFull    bool
-    inspectPort    int
-    inspectChain   bool
-    inspectConnect string
-    inspectTimeout string
-    inspectSigAlg  string
+    inspectFull       bool
+    inspectPort       int
+    inspectChain      bool
+    inspectConnect    string
+    inspectTimeout    string
+    inspectSigAlg     string
+    inspectStartTLS   string
+    inspectClientCert string
+    inspectClientKey  string
+    inspectProxy      string
+    inspectCA         string
+    inspectOCSP       bool
+    inspectCTLogList  string
+
+    inspectPassword     string
+    inspectPasswordFile string
+    inspectPasswordEnv  string
+
+    inspectClientKeyPasswordFile string
+    inspectClientKeyPasswordEnv  string
 )
 
 var inspectCmd = &cobra.Command{
@@ -31,14 +46,45 @@ var inspectCmd = &cobra.Command{
 
 If the argument is a valid file path, it will read and parse the certificate file.
 Files containing multiple certificates (e.g. fullchain.pem) are supported; use
---chain to display all of them. Use "-" to read from stdin.
+--chain to display all of them. PKCS#12 files (.p12/.pfx) show the bundled
+key and certificates. Use "-" to read from stdin.
+
+With --chain, the chain is also analyzed: each issuer link and signature is
+checked, wrong order, missing intermediates, duplicated or unused
+certificates, and unnecessarily sent roots are flagged, and the validated
+path to the system roots (or the --ca bundle) is shown.
+
+With --ocsp, the certificate's revocation status is queried from the OCSP
+responder in its Authority Information Access extension. The issuer is taken
+from the presented chain, the --ca bundle, or the AIA CA Issuers URL.
+
+Signed Certificate Timestamps (SCTs) embedded in the certificate, sent in the
+TLS handshake, or stapled in the OCSP response are shown with --full. With
+--ct-log-list, their signatures are verified against a CT log list in the
+v3 JSON format (e.g. https://www.gstatic.com/ct/log_list/v3/log_list.json)
+and the certificate is checked against the browser CT policy.
+
 If the argument looks like a URL or domain name, it will connect to the remote
-server and retrieve its certificate.
+server and retrieve its certificate. For servers that require mutual TLS, pass
+a client certificate with --client-cert and --client-key. When the server asks
+for a client certificate, the CA names and signature schemes it accepts are shown.
+
+Connections go through the proxy in HTTPS_PROXY unless NO_PROXY exempts the
+host; --proxy sets one explicitly (http://, https://, socks5://, or socks5h://).
+The TLS handshake runs end to end through the tunnel, so SNI is unchanged.
+
+With --starttls, the connection starts in plaintext and is upgraded with the
+protocol's STARTTLS exchange (smtp, imap, pop3, ldap, ftp, or postgres)
+before the TLS handshake. The port defaults to the protocol's standard port.
 
 Examples:
   cert inspect cert.pem
   cert inspect cert.der --full
   cert inspect fullchain.pem --chain
+  cert inspect internal.example.com --chain --ca company-root.pem
+  cert inspect example.com --ocsp
+  cert inspect example.com --full --ct-log-list log_list.json
+  cert inspect server.p12 --password-env P12_PASS --chain
   openssl s_client -connect example.com:443 </dev/null | cert inspect -
   cert inspect google.com
   cert inspect https://example.com:8443 --port 8443
@@ -46,7 +92,13 @@ Examples:
   cert inspect google.com --connect localhost:8080
   cert inspect api.example.com --connect tunnel.local --port 443
   cert inspect cloudflare.com --sig-alg ecdsa
-  cert inspect cloudflare.com --sig-alg rsa`,
+  cert inspect cloudflare.com --sig-alg rsa
+  cert inspect mail.example.com --starttls smtp
+  cert inspect mail.example.com:587 --starttls smtp
+  cert inspect db.example.com --starttls postgres
+  cert inspect internal.svc --client-cert client.crt --client-key client.key
+  cert inspect example.com --proxy http://proxy.corp:3128
+  cert inspect example.com --proxy socks5h://127.0.0.1:1080`,
 	Args: cobra.ExactArgs(1),
     RunE: func(cmd *cobra.Command, args []string) error {
         target := args[0]
@@ -74,12 +126,13 @@ Examples:
 				return err
 			}
 
-			displayLocalCertificates(certs)
-			return nil
+			return displayLocalCertificates(certs)
 		}
 
 		// Determine if target is a file or URL
-		if _, err := os.Stat(target); err == nil {
+		if _, err := os.Stat(target); err == nil && cert.IsPKCS12Path(target) {
+			return inspectPKCS12(target)
+		} else if err == nil {
 			// It's a file (possibly a bundle with multiple certificates)
             certs, err := cert.InspectFileAll(target)
             if err != nil {
@@ -91,10 +144,22 @@ Examples:
                 return err
             }
 
-            displayLocalCertificates(certs)
+            return displayLocalCertificates(certs)
         } else {
 			// It's a URL/hostname
+			if err := cert.ValidateStartTLS(inspectStartTLS); err != nil {
+				if jsonOutput {
+					printJSONError(err)
+				} else {
+					ui.ShowError(err.Error())
+				}
+				return err
+			}
+
 			port := inspectPort
+			if inspectStartTLS != "" && !cmd.Flags().Changed("port") {
+				port = cert.StartTLSDefaultPort(inspectStartTLS)
+			}
 			connectHost := ""
 
 			// Extract port from target if specified (URLs with a scheme are
@@ -130,8 +195,35 @@ Examples:
                 timeout = d
             }
 
-			// Use the enhanced function that supports connect host, timeout, and signature algorithm preference
-            certificate, chain, err := cert.InspectURLWithOptions(target, port, connectHost, timeout, inspectSigAlg)
+            clientCert, err := loadClientCertificate(inspectClientCert, inspectClientKey, inspectClientKeyPasswordFile, inspectClientKeyPasswordEnv)
+            if err != nil {
+                if jsonOutput {
+                    printJSONError(err)
+                } else {
+                    ui.ShowError(err.Error())
+                }
+                return err
+            }
+            proxy, err := parseProxyFlag(inspectProxy)
+            if err != nil {
+                if jsonOutput {
+                    printJSONError(err)
+                } else {
+                    ui.ShowError(err.Error())
+                }
+                return err
+            }
+
+			// Connect with the --connect host, timeout, signature algorithm preference, STARTTLS protocol, client certificate, and proxy
+            certificate, chain, err := cert.InspectURLWithConnectOptions(target, port, cert.ConnectOptions{
+                ConnectHost:          connectHost,
+                Timeout:              timeout,
+                SigAlg:               inspectSigAlg,
+                StartTLS:             inspectStartTLS,
+                ClientCertificate:    clientCert,
+                Proxy:                proxy,
+                ProxyFromEnvironment: true,
+            })
             if err != nil {
                 if jsonOutput {
                     printJSONError(err)
@@ -141,6 +233,43 @@ Examples:
                 return err
             }
 
+            var analysis *cert.ChainAnalysis
+            if inspectChain {
+                analysis, err = analyzeChain(append([]*cert.Certificate{certificate}, chain...))
+                if err != nil {
+                    if jsonOutput {
+                        printJSONError(err)
+                    } else {
+                        ui.ShowError(err.Error())
+                    }
+                    return err
+                }
+            }
+
+            var ocspResult *cert.OCSPResult
+            if inspectOCSP {
+                ocspResult, err = checkOCSP(append([]*cert.Certificate{certificate}, chain...), timeout)
+                if err != nil {
+                    if jsonOutput {
+                        printJSONError(err)
+                    } else {
+                        ui.ShowError(err.Error())
+                    }
+                    return err
+                }
+            }
+
+            if inspectCTLogList != "" {
+                if err := verifyCT(append([]*cert.Certificate{certificate}, chain...), timeout); err != nil {
+                    if jsonOutput {
+                        printJSONError(err)
+                    } else {
+                        ui.ShowError(err.Error())
+                    }
+                    return err
+                }
+            }
+
             if jsonOutput {
                 jsonCert := certificate.ToJSON()
 
@@ -148,21 +277,69 @@ Examples:
 				if inspectChain && len(chain) > 0 {
 					jsonCert.Chain = chainSummaries(chain)
 				}
+				if analysis != nil {
+					ja := analysis.ToJSON()
+					jsonCert.ChainAnalysis = &ja
+				}
+				if ocspResult != nil {
+					jo := ocspResult.ToJSON()
+					jsonCert.OCSP = &jo
+				}
 
                 printJSON(jsonCert)
             } else {
                 ui.DisplayCertificate(certificate, inspectFull)
+                if certificate.ClientCertRequest != nil {
+                    ui.DisplayClientCertRequest(certificate.ClientCertRequest)
+                }
 
                 // Display chain if requested
                 if inspectChain && len(chain) > 0 {
                     ui.DisplayCertificateChain(chain)
                 }
+                if analysis != nil {
+                    ui.DisplayChainAnalysis(analysis)
+                }
+                if ocspResult != nil {
+                    ui.DisplayOCSPResult(ocspResult)
+                }
             }
         }
         return nil
     },
 }
 
+// inspectPKCS12 displays the contents of a PKCS#12 (PFX) archive
+func inspectPKCS12(path string) error {
+	password, err := resolvePassword(inspectPassword, inspectPasswordFile, inspectPasswordEnv)
+	if err != nil {
+		if jsonOutput {
+			printJSONError(err)
+		} else {
+			ui.ShowError(err.Error())
+		}
+		return err
+	}
+
+	bundle, certs, err := cert.InspectPKCS12(path, password)
+	if err != nil {
+		if jsonOutput {
+			printJSONError(err)
+		} else {
+			ui.ShowError(err.Error())
+		}
+		return err
+	}
+
+	if jsonOutput {
+		printJSON(bundle.ToJSON())
+		return nil
+	}
+
+	ui.DisplayPKCS12Bundle(bundle)
+	return displayLocalCertificates(certs)
+}
+
 // chainSummaries converts chain certificates to their JSON summary form
 func chainSummaries(chain []*cert.Certificate) []cert.JSONCertSummary {
 	summaries := make([]cert.JSONCertSummary, 0, len(chain))
@@ -182,30 +359,139 @@ func chainSummaries(chain []*cert.Certificate) []cert.JSONCertSummary {
 // displayLocalCertificates renders certificates parsed from a file or stdin.
 // The first certificate is shown in full; any additional bundle certificates
 // are shown with --chain or surfaced via a hint so they aren't silently hidden.
-func displayLocalCertificates(certs []*cert.Certificate) {
+// With --chain the certificates are also run through chain analysis.
+func displayLocalCertificates(certs []*cert.Certificate) error {
 	certificate := certs[0]
 	rest := certs[1:]
 
+	var analysis *cert.ChainAnalysis
+	if inspectChain {
+		var err error
+		if analysis, err = analyzeChain(certs); err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+	}
+
+	var ocspResult *cert.OCSPResult
+	if inspectOCSP || inspectCTLogList != "" {
+		timeout, err := time.ParseDuration(inspectTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid --timeout value %q: %w", inspectTimeout, err)
+		}
+		if inspectOCSP {
+			ocspResult, err = checkOCSP(certs, timeout)
+		}
+		if err == nil && inspectCTLogList != "" {
+			err = verifyCT(certs, timeout)
+		}
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+	}
+
 	if jsonOutput {
 		jsonCert := certificate.ToJSON()
 		if inspectChain && len(rest) > 0 {
 			jsonCert.Chain = chainSummaries(rest)
 		}
+		if analysis != nil {
+			ja := analysis.ToJSON()
+			jsonCert.ChainAnalysis = &ja
+		}
+		if ocspResult != nil {
+			jo := ocspResult.ToJSON()
+			jsonCert.OCSP = &jo
+		}
 		printJSON(jsonCert)
-		return
+		return nil
 	}
 
 	ui.DisplayCertificate(certificate, inspectFull)
 
-	if len(rest) == 0 {
-		return
-	}
 	if inspectChain {
 		ui.DisplayCertificateChain(rest)
-	} else {
+		ui.DisplayChainAnalysis(analysis)
+	} else if len(rest) > 0 {
 		fmt.Println()
 		ui.ShowInfo(fmt.Sprintf("Contains %d certificates; showing the first. Use --chain to see the rest.", len(certs)))
 	}
+	if ocspResult != nil {
+		ui.DisplayOCSPResult(ocspResult)
+	}
+	return nil
+}
+
+// checkOCSP queries the OCSP responder for the first certificate
+func checkOCSP(certs []*cert.Certificate, timeout time.Duration) (*cert.OCSPResult, error) {
+	issuer, err := findIssuer(certs, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return cert.CheckOCSP(certs[0].Certificate, issuer, cert.OCSPOptions{Timeout: timeout})
+}
+
+// verifyCT verifies the first certificate's SCTs against the --ct-log-list
+// log list. Embedded SCTs need the issuer; if it can't be found they are
+// reported as unverified rather than failing the inspection.
+func verifyCT(certs []*cert.Certificate, timeout time.Duration) error {
+	logs, err := cert.LoadCTLogList(inspectCTLogList)
+	if err != nil {
+		return err
+	}
+	var issuer *x509.Certificate
+	for _, sct := range certs[0].SCTs {
+		if sct.Source == cert.SCTSourceEmbedded {
+			issuer, _ = findIssuer(certs, timeout)
+			break
+		}
+	}
+	cert.VerifyCT(certs[0], issuer, logs, time.Now())
+	return nil
+}
+
+// findIssuer finds the first certificate's issuer among the other
+// certificates, the --ca bundle, and finally the AIA CA Issuers URL
+func findIssuer(certs []*cert.Certificate, timeout time.Duration) (*x509.Certificate, error) {
+	var candidates []*x509.Certificate
+	for _, c := range certs[1:] {
+		candidates = append(candidates, c.Certificate)
+	}
+	if inspectCA != "" {
+		caCerts, err := cert.InspectFileAll(inspectCA)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range caCerts {
+			candidates = append(candidates, c.Certificate)
+		}
+	}
+
+	return cert.FindIssuer(certs[0].Certificate, candidates, timeout)
+}
+
+// analyzeChain runs chain analysis against the --ca bundle, or the system
+// roots when --ca is not set
+func analyzeChain(certs []*cert.Certificate) (*cert.ChainAnalysis, error) {
+	opts := cert.ChainOptions{}
+	if inspectCA != "" {
+		roots, err := cert.LoadCertPool(inspectCA)
+		if err != nil {
+			return nil, err
+		}
+		opts.Roots = roots
+		opts.RootsSource = inspectCA
+	}
+	return cert.AnalyzeChain(certs, opts), nil
 }
 
 func init() {
@@ -215,4 +501,16 @@ func init() {
     inspectCmd.Flags().StringVar(&inspectConnect, "connect", "", "Connect to a different host (e.g., localhost:8080) while validating the cert for the target hostname")
     inspectCmd.Flags().StringVar(&inspectTimeout, "timeout", "5s", "Network timeout for remote inspection (e.g., 5s, 2s)")
     inspectCmd.Flags().StringVar(&inspectSigAlg, "sig-alg", "auto", "Preferred signature algorithm: auto, ecdsa, or rsa (TLS 1.2 only)")
+    inspectCmd.Flags().StringVar(&inspectStartTLS, "starttls", "", "Upgrade a plaintext connection with STARTTLS first: smtp, imap, pop3, ldap, ftp, or postgres")
+    inspectCmd.Flags().StringVar(&inspectClientCert, "client-cert", "", "Client certificate for servers that require mutual TLS")
+    inspectCmd.Flags().StringVar(&inspectClientKey, "client-key", "", "Private key for --client-cert (defaults to a key in the certificate file)")
+    inspectCmd.Flags().StringVar(&inspectClientKeyPasswordFile, "client-key-password-file", "", "Read an encrypted client key's password from a file")
+    inspectCmd.Flags().StringVar(&inspectClientKeyPasswordEnv, "client-key-password-env", "", "Read an encrypted client key's password from an environment variable")
+    inspectCmd.Flags().StringVar(&inspectProxy, "proxy", "", "Tunnel through a proxy: http://host:port or socks5://host:port (default from HTTPS_PROXY)")
+    inspectCmd.Flags().StringVar(&inspectCA, "ca", "", "CA bundle to validate the chain against (with --chain) and to find the OCSP issuer")
+    inspectCmd.Flags().BoolVar(&inspectOCSP, "ocsp", false, "Check revocation status with the certificate's OCSP responder")
+    inspectCmd.Flags().StringVar(&inspectCTLogList, "ct-log-list", "", "CT log list (v3 JSON) to verify SCTs and check the browser CT policy against")
+    inspectCmd.Flags().StringVar(&inspectPassword, "password", "", "Password for PKCS#12 (.p12/.pfx) files")
+    inspectCmd.Flags().StringVar(&inspectPasswordFile, "password-file", "", "Read the PKCS#12 password from a file")
+    inspectCmd.Flags().StringVar(&inspectPasswordEnv, "password-env", "", "Read the PKCS#12 password from an environment variable")
 }
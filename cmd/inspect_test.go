@@ -7,6 +7,7 @@ import (
 )
 
 func TestInspectCommand(t *testing.T) {
+	t.Setenv("CERTWIZ_TEST_CLIENT_KEY_PASSWORD", "wrong")
 	tests := []struct {
 		name             string
 		args             []string
@@ -44,6 +45,73 @@ func TestInspectCommand(t *testing.T) {
 				"Certificate Extensions",
 			},
 		},
+		{
+			name:    "Inspect PKCS#12 with wrong password",
+			args:    []string{"inspect", testutil.TestdataPath("bundle.p12"), "--password", "wrong"},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect PKCS#12 file",
+			args:    []string{"inspect", testutil.TestdataPath("bundle.p12"), "--password", "changeit", "--chain"},
+			wantErr: false,
+			expectedOutput: []string{
+				"PKCS#12 Bundle",
+				"chain.example.com",
+			},
+		},
+		{
+			name:    "Inspect bundle with chain analysis against a CA",
+			args:    []string{"inspect", testutil.TestdataPath("fullchain.pem"), "--chain", "--ca", testutil.TestdataPath("ca.pem")},
+			wantErr: false,
+			expectedOutput: []string{
+				"Chain Analysis",
+			},
+		},
+		{
+			name:    "Inspect with unreadable CA bundle",
+			args:    []string{"inspect", testutil.TestdataPath("fullchain.pem"), "--chain", "--ca", testutil.TestdataPath("invalid.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect OCSP for certificate without an issuer or responder",
+			args:    []string{"inspect", testutil.TestdataPath("valid.pem"), "--ocsp"},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect with missing CT log list",
+			args:    []string{"inspect", testutil.TestdataPath("valid.pem"), "--ct-log-list", testutil.TestdataPath("missing.json")},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect with unsupported STARTTLS protocol",
+			args:    []string{"inspect", "127.0.0.1", "--starttls", "gopher"},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect with client key but no client certificate",
+			args:    []string{"inspect", "127.0.0.1", "--client-key", testutil.TestdataPath("valid.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect with client certificate without a key",
+			args:    []string{"inspect", "127.0.0.1", "--client-cert", testutil.TestdataPath("valid.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect with an encrypted client key and no password",
+			args:    []string{"inspect", "127.0.0.1", "--client-cert", testutil.TestdataPath("valid.pem"), "--client-key", testutil.TestdataPath("valid-encrypted.key")},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect with an encrypted client key and a wrong password",
+			args:    []string{"inspect", "127.0.0.1", "--client-cert", testutil.TestdataPath("valid.pem"), "--client-key", testutil.TestdataPath("valid-encrypted.key"), "--client-key-password-env", "CERTWIZ_TEST_CLIENT_KEY_PASSWORD"},
+			wantErr: true,
+		},
+		{
+			name:    "Inspect with unsupported proxy scheme",
+			args:    []string{"inspect", "127.0.0.1", "--proxy", "ftp://proxy.example.com"},
+			wantErr: true,
+		},
 		{
 			name:    "Inspect with no arguments",
 			args:    []string{"inspect"},
@@ -69,6 +137,13 @@ func TestInspectCommand(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create new root command for each test to reset state
+			inspectCA = ""
+			inspectOCSP = false
+			inspectCTLogList = ""
+			inspectStartTLS = ""
+			inspectClientCert, inspectClientKey = "", ""
+			inspectClientKeyPasswordFile, inspectClientKeyPasswordEnv = "", ""
+			inspectProxy = ""
 			cmd := rootCmd
 			cmd.SetArgs(tt.args)
 
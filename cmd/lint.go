@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"certwiz/internal/config"
+	"certwiz/pkg/cert"
+	"certwiz/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintChain     bool
+	lintSelect    []string
+	lintSuppress  []string
+	lintListRules bool
+	lintPort      int
+	lintTimeout   string
+	lintProxy     string
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [file|host]",
+	Short: "Check a certificate against CA/Browser Forum and RFC 5280 rules",
+	Long: `Check a certificate against the CA/Browser Forum Baseline Requirements and
+RFC 5280: validity over 398 days, missing SANs, a common name that isn't a
+SAN, weak keys, SHA-1 signatures, bad serial numbers, missing key
+identifiers, and extensions with the wrong critical flag.
+
+Every finding names its rule and severity. Errors break a MUST of the
+requirements; warnings break a SHOULD. The command exits non-zero when any
+error is found. Use --list-rules to see every rule.
+
+The target is a certificate file, "-" for stdin, or a URL or host. Only the
+first certificate is linted unless --chain is given.
+
+Rules are chosen with --select and --suppress, or in the config file:
+
+  lint:
+    select: [validity-too-long, weak-key, sha1-signature]
+    suppress: [ski-missing]
+
+--select replaces the configured selection; --suppress adds to it. The same
+rules run in cert verify, where findings are reported as warnings.
+
+Examples:
+  cert lint server.crt
+  cert lint example.com --chain
+  cert lint server.crt --suppress ski-missing,key-usage-not-critical
+  cert lint server.crt --select weak-key,sha1-signature --json
+  cert lint --list-rules`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fail := func(err error) error {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		if lintListRules {
+			rules := cert.LintRules()
+			if jsonOutput {
+				list := make([]cert.JSONLintRule, 0, len(rules))
+				for _, r := range rules {
+					list = append(list, r.ToJSON())
+				}
+				printJSON(list)
+				return nil
+			}
+			ui.DisplayLintRules(rules)
+			return nil
+		}
+		if len(args) == 0 {
+			return fail(fmt.Errorf("a certificate file or host is required"))
+		}
+
+		opts := lintOptions(cmd, config.Load().Lint)
+		if err := cert.ValidateLintOptions(opts); err != nil {
+			return fail(err)
+		}
+		certs, err := loadLintCertificates(args[0])
+		if err != nil {
+			return fail(err)
+		}
+		if !lintChain {
+			certs = certs[:1]
+		}
+
+		var results []*cert.LintResult
+		for _, c := range certs {
+			result, err := cert.Lint(c, opts)
+			if err != nil {
+				return fail(err)
+			}
+			results = append(results, result)
+		}
+
+		report := cert.LintReportToJSON(results)
+		if jsonOutput {
+			printJSON(report)
+		} else {
+			for i, result := range results {
+				if i > 0 {
+					fmt.Println()
+				}
+				ui.DisplayLintResult(result)
+			}
+		}
+
+		// Surface errors as a failure to drive non-zero exit via main
+		if !report.Passed {
+			return fmt.Errorf("lint found %d error(s) and %d warning(s)", report.Errors, report.Warnings)
+		}
+		return nil
+	},
+}
+
+// lintOptions combines the lint flags with the config file's lint section:
+// --select replaces the configured selection, --suppress adds to it
+func lintOptions(cmd *cobra.Command, cfg config.LintConfig) cert.LintOptions {
+	opts := cert.LintOptions{
+		Select:   cfg.Select,
+		Suppress: append(append([]string{}, cfg.Suppress...), lintSuppress...),
+	}
+	if cmd.Flags().Changed("select") {
+		opts.Select = lintSelect
+	}
+	return opts
+}
+
+// loadLintCertificates reads the certificates to lint from stdin ("-"), a
+// file, or a remote host's served chain
+func loadLintCertificates(target string) ([]*cert.Certificate, error) {
+	if target == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		return cert.InspectData(data, "stdin")
+	}
+	if _, err := os.Stat(target); err == nil {
+		return cert.InspectFileAll(target)
+	}
+
+	timeout, err := time.ParseDuration(lintTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --timeout value %q: %w", lintTimeout, err)
+	}
+	proxy, err := parseProxyFlag(lintProxy)
+	if err != nil {
+		return nil, err
+	}
+	host, port := cert.SplitHostTarget(target, lintPort)
+	c, chain, err := cert.InspectURLWithConnectOptions(host, port, cert.ConnectOptions{
+		Timeout:              timeout,
+		Proxy:                proxy,
+		ProxyFromEnvironment: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]*cert.Certificate{c}, chain...), nil
+}
+
+func init() {
+	lintCmd.Flags().BoolVar(&lintChain, "chain", false, "Lint every certificate in the file or served chain, not just the first")
+	lintCmd.Flags().StringSliceVar(&lintSelect, "select", nil, "Only run these rules (comma-separated IDs; replaces the config selection)")
+	lintCmd.Flags().StringSliceVar(&lintSuppress, "suppress", nil, "Skip these rules (comma-separated IDs; added to the config)")
+	lintCmd.Flags().BoolVar(&lintListRules, "list-rules", false, "List the available rules and exit")
+	lintCmd.Flags().IntVar(&lintPort, "port", 443, "Port for hosts without one")
+	lintCmd.Flags().StringVar(&lintTimeout, "timeout", "5s", "Network timeout for remote certificates (e.g., 5s, 2s)")
+	lintCmd.Flags().StringVar(&lintProxy, "proxy", "", "Tunnel through a proxy: http://host:port or socks5://host:port (default from HTTPS_PROXY)")
+
+	rootCmd.AddCommand(lintCmd)
+}
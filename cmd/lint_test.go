@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"certwiz/internal/testutil"
+)
+
+func TestLintCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "Lint a clean certificate",
+			args:    []string{"lint", testutil.TestdataPath("valid.pem")},
+			wantErr: false,
+		},
+		{
+			name:    "Lint a certificate without SANs",
+			args:    []string{"lint", testutil.TestdataPath("chain-server.pem"), "--json"},
+			wantErr: true,
+		},
+		{
+			name:    "Lint with the failing rules suppressed",
+			args:    []string{"lint", testutil.TestdataPath("chain-server.pem"), "--suppress", "san-missing,cn-not-in-san"},
+			wantErr: false,
+		},
+		{
+			name:    "Lint only selected rules",
+			args:    []string{"lint", testutil.TestdataPath("intermediate.pem"), "--select", "weak-key,sha1-signature"},
+			wantErr: false,
+		},
+		{
+			name:    "Lint the whole chain",
+			args:    []string{"lint", testutil.TestdataPath("fullchain.pem"), "--chain", "--select", "weak-key"},
+			wantErr: false,
+		},
+		{
+			name:    "Lint with an unknown rule",
+			args:    []string{"lint", testutil.TestdataPath("valid.pem"), "--suppress", "no-such-rule"},
+			wantErr: true,
+		},
+		{
+			name:    "Lint an invalid certificate",
+			args:    []string{"lint", testutil.TestdataPath("invalid.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "Lint with invalid timeout",
+			args:    []string{"lint", "example.com", "--timeout", "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "Lint without a target",
+			args:    []string{"lint"},
+			wantErr: true,
+		},
+		{
+			name:    "List rules",
+			args:    []string{"lint", "--list-rules"},
+			wantErr: false,
+		},
+		{
+			name:    "List rules as JSON",
+			args:    []string{"lint", "--list-rules", "--json"},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset flags that persist between Execute calls
+			lintChain = false
+			lintSelect = nil
+			lintSuppress = nil
+			lintListRules = false
+			lintTimeout = "5s"
+			jsonOutput = false
+			defer func() { jsonOutput = false }()
+			for _, name := range []string{"select", "suppress"} {
+				lintCmd.Flags().Lookup(name).Changed = false
+			}
+
+			cmd := rootCmd
+			cmd.SetArgs(tt.args)
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			if tt.wantErr && err == nil {
+				t.Error("Expected error but got none")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"certwiz/pkg/cert"
+	"certwiz/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	revokeCA     string
+	revokeCAKey  string
+	revokeSerial string
+	revokeReason string
+	revokeDB     string
+	revokeCRLOut string
+	revokeDays   int
+
+	revokeCAKeyPasswordFile string
+	revokeCAKeyPasswordEnv  string
+)
+
+var revokeCmd = &cobra.Command{
+	Use:   "revoke [certificate]",
+	Short: "Revoke a certificate issued by a local CA and reissue its CRL",
+	Long: `Revoke a certificate signed with 'cert sign' and publish an updated
+Certificate Revocation List (CRL) signed with the CA key.
+
+Revoked serial numbers and reasons are tracked in a revocation database
+next to the CA certificate (ca.crt -> ca.revoked.json). Every revocation
+reissues the full CRL with the next CRL number (ca.crt -> ca.crl).
+
+An encrypted CA key is unlocked with a password that is prompted for, or
+read from --ca-key-password-file or --ca-key-password-env.
+
+Reasons: ` + strings.Join(cert.RevocationReasonNames(), ", ") + `
+
+Examples:
+  # Revoke a certificate
+  cert revoke server.crt --ca ca.crt --ca-key ca.key
+
+  # Revoke with a reason
+  cert revoke server.crt --ca ca.crt --ca-key ca.key --reason keyCompromise
+
+  # Revoke by serial number when the certificate is not at hand
+  cert revoke --serial 3f:a2:91:0c --ca ca.crt --ca-key ca.key
+
+  # Write the CRL somewhere else, valid for 30 days
+  cert revoke server.crt --ca ca.crt --ca-key ca.key --crl-out /var/www/pki/ca.crl --days 30`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		certPath := ""
+		if len(args) == 1 {
+			certPath = args[0]
+		}
+
+		var validationErr error
+		switch {
+		case revokeCA == "":
+			validationErr = fmt.Errorf("CA certificate (--ca) is required")
+		case revokeCAKey == "":
+			validationErr = fmt.Errorf("CA private key (--ca-key) is required")
+		case certPath == "" && revokeSerial == "":
+			validationErr = fmt.Errorf("a certificate file or --serial is required")
+		case certPath != "" && revokeSerial != "":
+			validationErr = fmt.Errorf("specify either a certificate file or --serial, not both")
+		}
+		if validationErr != nil {
+			if jsonOutput {
+				printJSONError(validationErr)
+			}
+			return validationErr
+		}
+
+		reason, err := cert.ParseRevocationReason(revokeReason)
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+
+		password, err := keyPassword(revokeCAKey, "ca-key", revokeCAKeyPasswordFile, revokeCAKeyPasswordEnv)
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+
+		crlPath := revokeCRLOut
+		if crlPath == "" {
+			crlPath = cert.DefaultCRLPath(revokeCA)
+		}
+		dbPath := revokeDB
+		if dbPath == "" {
+			dbPath = cert.DefaultRevocationDBPath(revokeCA)
+		}
+
+		entry, crl, err := cert.Revoke(cert.RevokeOptions{
+			CACert:        revokeCA,
+			CAKey:         revokeCAKey,
+			CAKeyPassword: password,
+			DBPath:        dbPath,
+			Days:          revokeDays,
+			CertPath:      certPath,
+			Serial:        revokeSerial,
+			Reason:        reason,
+		}, crlPath)
+		if err != nil {
+			err = fmt.Errorf("failed to revoke certificate: %w", err)
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+
+		if jsonOutput {
+			printJSON(cert.JSONOperationResult{
+				Success: true,
+				Message: fmt.Sprintf("Certificate %s revoked (%s); CRL %s issued",
+					entry.SerialNumber.Text(16), cert.RevocationReasonName(entry.Reason), crl.Number),
+				Files: []string{crlPath, dbPath},
+			})
+			return nil
+		}
+
+		ui.ShowSuccess(fmt.Sprintf("Certificate %s revoked (%s)", entry.SerialNumber.Text(16), cert.RevocationReasonName(entry.Reason)))
+		fmt.Println()
+		fmt.Printf("%s Files updated:\n", getEmoji("📁", "[FILES]"))
+		fmt.Printf("  %s CRL: %s\n", getEmoji("📜", "[CRL]"), crlPath)
+		fmt.Printf("  %s Revocation database: %s\n", getEmoji("🗂️", "[DB]"), dbPath)
+		fmt.Println()
+		fmt.Printf("%s Next steps:\n", getEmoji("📋", "[NEXT]"))
+		fmt.Println("  1. Publish the CRL where clients fetch it")
+		fmt.Printf("  2. Reissue it with 'cert crl generate' before the next update (%d days)\n", daysOrDefault(revokeDays))
+		fmt.Println()
+		ui.DisplayCRL(crl)
+
+		return nil
+	},
+}
+
+// daysOrDefault returns the CRL validity in days, applying the default
+func daysOrDefault(days int) int {
+	if days <= 0 {
+		return cert.DefaultCRLDays
+	}
+	return days
+}
+
+func init() {
+	revokeCmd.Flags().StringVar(&revokeCA, "ca", "", "Path to the CA certificate that issued the certificate (required)")
+	revokeCmd.Flags().StringVar(&revokeCAKey, "ca-key", "", "Path to the CA private key (required)")
+	revokeCmd.Flags().StringVar(&revokeCAKeyPasswordFile, "ca-key-password-file", "", "Read the CA key's password from a file")
+	revokeCmd.Flags().StringVar(&revokeCAKeyPasswordEnv, "ca-key-password-env", "", "Read the CA key's password from an environment variable")
+	revokeCmd.Flags().StringVar(&revokeSerial, "serial", "", "Serial number (hex) to revoke instead of a certificate file")
+	revokeCmd.Flags().StringVar(&revokeReason, "reason", "unspecified", "Revocation reason (e.g. keyCompromise, superseded)")
+	revokeCmd.Flags().StringVar(&revokeDB, "db", "", "Revocation database (default: <ca>.revoked.json)")
+	revokeCmd.Flags().StringVar(&revokeCRLOut, "crl-out", "", "Output path for the reissued CRL (default: <ca>.crl)")
+	revokeCmd.Flags().IntVarP(&revokeDays, "days", "d", cert.DefaultCRLDays, "Days until the CRL's next update")
+
+	rootCmd.AddCommand(revokeCmd)
+}
@@ -65,18 +65,26 @@ func TestCommandStructure(t *testing.T) {
 	// Verify all expected commands are registered
 	// Note: Cobra automatically adds "completion" and "help" commands
 	expectedCommands := []string{
+		"acme",       // ACME certificate issuance
 		"ca",         // Certificate Authority generation
 		"completion", // Auto-added by Cobra
 		"convert",
+		"crl", // CRL generation and inspection
 		"csr", // Certificate Signing Request generation
+		"diff", // Certificate comparison
+		"exporter", // Prometheus metrics endpoint
 		"generate",
 		"help", // Auto-added by Cobra
 		"inspect",
+		"lint",   // Certificate linting
+		"revoke", // Revoke certificates and reissue the CRL
+		"scan",   // Batch expiry scanning
 		"sign", // Sign CSRs with CA
 		"tls",   // TLS version testing
 		"update",
 		"verify",
 		"version",
+		"watch", // Certificate change monitoring
 	}
 
 	commands := rootCmd.Commands()
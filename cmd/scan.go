@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"certwiz/pkg/cert"
+	"certwiz/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanHostsFile string
+	scanPort      int
+	scanTimeout   string
+	scanWorkers   int
+	scanExpiresIn string
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan [path|host...]",
+	Short: "Scan directories and hosts for expiring certificates",
+	Long: `Scan certificate files and remote hosts in one pass and list every
+certificate sorted by expiry, soonest first.
+
+Arguments that exist on disk are scanned as files; directories are walked
+recursively for .pem, .crt, .cer, .cert, and .der files. Any other argument
+is treated as a host (hostname, host:port, or URL). Hosts can also be read
+from a file with --hosts-file, one per line ("-" reads stdin; blank lines
+and # comments are ignored).
+
+Targets are inspected concurrently by up to --workers workers. The command
+exits non-zero when any certificate is expired or expires within
+--expires-in, or when a target cannot be scanned, which makes it suitable
+for cron jobs and CI.
+
+Examples:
+  cert scan /etc/ssl/private
+  cert scan ./certs --expires-in 14d
+  cert scan google.com github.com:443
+  cert scan --hosts-file hosts.txt --workers 20 --timeout 3s
+  cert scan ./certs --hosts-file hosts.txt --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fail := func(err error) error {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		threshold, err := parseExpiryWindow(scanExpiresIn)
+		if err != nil {
+			return fail(err)
+		}
+
+		timeout, err := time.ParseDuration(scanTimeout)
+		if err != nil {
+			return fail(fmt.Errorf("invalid --timeout value %q: %w", scanTimeout, err))
+		}
+
+		if scanWorkers < 1 {
+			return fail(fmt.Errorf("--workers must be at least 1"))
+		}
+
+		var paths, hosts []string
+		for _, arg := range args {
+			if _, err := os.Stat(arg); err == nil {
+				paths = append(paths, arg)
+			} else {
+				hosts = append(hosts, arg)
+			}
+		}
+
+		if scanHostsFile != "" {
+			listed, err := readHostsFile(scanHostsFile)
+			if err != nil {
+				return fail(err)
+			}
+			hosts = append(hosts, listed...)
+		}
+
+		if len(paths) == 0 && len(hosts) == 0 {
+			return fail(fmt.Errorf("nothing to scan: give a path or host, or use --hosts-file"))
+		}
+
+		if !jsonOutput {
+			ui.ShowInfo(fmt.Sprintf("Scanning %d path(s) and %d host(s)...", len(paths), len(hosts)))
+		}
+
+		report, err := cert.Scan(cert.ScanOptions{
+			Paths:     paths,
+			Hosts:     hosts,
+			Port:      scanPort,
+			Timeout:   timeout,
+			Workers:   scanWorkers,
+			Threshold: threshold,
+		})
+		if err != nil {
+			return fail(err)
+		}
+
+		if jsonOutput {
+			printJSON(report.ToJSON())
+		} else {
+			ui.DisplayScanReport(report)
+		}
+
+		// Surface findings as an error to drive non-zero exit via main
+		if report.Failed() {
+			return fmt.Errorf("scan found %d expiring certificate(s) and %d error(s)", report.Expiring(), report.Errors())
+		}
+		return nil
+	},
+}
+
+// readHostsFile reads a host list from a file, or from stdin when path is "-"
+func readHostsFile(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open hosts file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+	return cert.ReadHostList(r)
+}
+
+func init() {
+	scanCmd.Flags().StringVar(&scanHostsFile, "hosts-file", "", "File with one host per line (- for stdin)")
+	scanCmd.Flags().IntVar(&scanPort, "port", 443, "Default port for hosts without one")
+	scanCmd.Flags().StringVar(&scanTimeout, "timeout", "5s", "Network timeout per host (e.g., 5s, 2s)")
+	scanCmd.Flags().IntVar(&scanWorkers, "workers", cert.DefaultScanWorkers, "Maximum number of concurrent inspections")
+	scanCmd.Flags().StringVar(&scanExpiresIn, "expires-in", "30d", "Fail if any certificate expires within this window (e.g. 30d, 720h)")
+
+	rootCmd.AddCommand(scanCmd)
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"certwiz/internal/testutil"
+)
+
+func TestScanCommand(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "hosts.txt")
+	if err := os.WriteFile(hostsFile, []byte("# no hosts yet\n"), 0644); err != nil {
+		t.Fatalf("Failed to write hosts file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "Scan a valid certificate with no threshold",
+			args:    []string{"scan", testutil.TestdataPath("valid.pem"), "--expires-in", "0d"},
+			wantErr: false,
+		},
+		{
+			name:    "Scan an expired certificate",
+			args:    []string{"scan", testutil.TestdataPath("expired.pem"), "--expires-in", "0d"},
+			wantErr: true,
+		},
+		{
+			name:    "Scan testdata directory as JSON",
+			args:    []string{"scan", testutil.TestdataPath(""), "--json", "--expires-in", "0d"},
+			wantErr: true, // expired.pem and invalid.pem are in testdata
+		},
+		{
+			name:    "Scan with empty hosts file",
+			args:    []string{"scan", "--hosts-file", hostsFile, "--expires-in", "30d"},
+			wantErr: true,
+		},
+		{
+			name:    "Scan with invalid threshold",
+			args:    []string{"scan", testutil.TestdataPath("valid.pem"), "--expires-in", "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "Scan with invalid worker count",
+			args:    []string{"scan", testutil.TestdataPath("valid.pem"), "--workers", "0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset flags that persist between Execute calls
+			scanHostsFile = ""
+			scanWorkers = 10
+			jsonOutput = false
+			defer func() { jsonOutput = false }()
+
+			cmd := rootCmd
+			cmd.SetArgs(tt.args)
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			if tt.wantErr && err == nil {
+				t.Error("Expected error but got none")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"certwiz/internal/config"
 	"certwiz/pkg/cert"
 	"certwiz/pkg/ui"
 
@@ -12,12 +13,19 @@ import (
 )
 
 var (
-	signCSR    string
-	signCA     string
-	signCAKey  string
-	signDays   int
-	signOutput string
-	signSANs   []string
+	signCSR      string
+	signCA       string
+	signCAKey    string
+	signCAKeyURI string
+	signDays     int
+	signOutput   string
+	signSANs     []string
+
+	signCAKeyPasswordFile string
+	signCAKeyPasswordEnv  string
+
+	signProfile      string
+	signListProfiles bool
 )
 
 var signCmd = &cobra.Command{
@@ -28,6 +36,33 @@ var signCmd = &cobra.Command{
 This command takes a CSR file and signs it with the specified CA certificate and key,
 producing a signed certificate that can be used for TLS/SSL or other purposes.
 
+Each issued certificate is recorded in the CA's issuance index next to the CA
+certificate (ca.crt -> ca.index.json), with a copy in ca.certs/. List them
+with 'cert ca list'.
+
+--profile sets the key usage and extended key usage for what the certificate
+is for: server, client, peer (server and client, the default), code-signing,
+email, or ocsp-signing. More profiles can be defined under 'profiles:' in the
+config file (~/.config/certwiz/config.yaml); list them all with
+--list-profiles.
+
+An encrypted CA key is unlocked with a password that is prompted for, or
+read from --ca-key-password-file or --ca-key-password-env.
+
+--ca-key-uri signs with a key that isn't a file on disk:
+
+  pkcs11:token=CA;object=root?module-path=/usr/lib/softhsm/libsofthsm2.so
+      a key on an HSM or smart card (PKCS#11 URI, RFC 7512); the PIN comes
+      from pin-value or pin-source in the URI, or the password flags
+  exec:/usr/local/bin/ca-signer --key root
+      an external command, sent one JSON request on stdin per operation
+  unix:/run/ca-signer.sock
+      an external signer listening on a Unix socket
+  file:/secure/ca.key
+      a key file, like --ca-key
+
+PKCS#11 support needs a build with -tags pkcs11.
+
 Examples:
   # Sign a CSR with a CA
   cert sign --csr server.csr --ca ca.crt --ca-key ca.key
@@ -39,8 +74,44 @@ Examples:
   cert sign --csr server.csr --ca ca.crt --ca-key ca.key --output /etc/ssl/certs/
   
   # Sign with additional SANs (overrides CSR SANs)
-  cert sign --csr server.csr --ca ca.crt --ca-key ca.key --san server.local --san *.server.local`,
+  cert sign --csr server.csr --ca ca.crt --ca-key ca.key --san server.local --san *.server.local
+
+  # Sign with an encrypted CA key, non-interactively
+  cert sign --csr server.csr --ca ca.crt --ca-key ca.key --ca-key-password-env CA_KEY_PASS
+
+  # Sign with a CA key held in an HSM
+  cert sign --csr server.csr --ca ca.crt --ca-key-uri "pkcs11:token=CA;object=root" --ca-key-password-env HSM_PIN
+
+  # Issue an mTLS client certificate
+  cert sign --csr alice.csr --ca ca.crt --ca-key ca.key --profile client
+
+  # Issue an S/MIME certificate
+  cert sign --csr alice.csr --ca ca.crt --ca-key ca.key --profile email --san email:alice@example.com
+
+  # Show the available profiles
+  cert sign --list-profiles`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		customProfiles, err := configProfiles()
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+		if signListProfiles {
+			profiles := append(cert.BuiltinProfiles(), customProfiles...)
+			if jsonOutput {
+				list := cert.JSONProfileList{Default: cert.DefaultProfile, Profiles: []cert.JSONProfile{}}
+				for _, p := range profiles {
+					list.Profiles = append(list.Profiles, p.ToJSON())
+				}
+				printJSON(list)
+				return nil
+			}
+			ui.DisplayProfiles(profiles, cert.DefaultProfile)
+			return nil
+		}
+
 		// Validate required arguments
 		var validationErr error
 		switch {
@@ -48,8 +119,10 @@ Examples:
 			validationErr = fmt.Errorf("CSR file (--csr) is required")
 		case signCA == "":
 			validationErr = fmt.Errorf("CA certificate (--ca) is required")
-		case signCAKey == "":
-			validationErr = fmt.Errorf("CA private key (--ca-key) is required")
+		case signCAKey == "" && signCAKeyURI == "":
+			validationErr = fmt.Errorf("CA private key (--ca-key or --ca-key-uri) is required")
+		case signCAKey != "" && signCAKeyURI != "":
+			validationErr = fmt.Errorf("--ca-key and --ca-key-uri can't be used together")
 		}
 		if validationErr != nil {
 			if jsonOutput {
@@ -58,13 +131,37 @@ Examples:
 			return validationErr
 		}
 
+		profile, err := cert.FindProfile(signProfile, customProfiles)
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+		// The profile's validity applies unless --days is given
+		days := signDays
+		if profile.Days > 0 && !cmd.Flags().Changed("days") {
+			days = profile.Days
+		}
+
+		password, err := keyPassword(signCAKey, "ca-key", signCAKeyPasswordFile, signCAKeyPasswordEnv)
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			}
+			return err
+		}
+
 		// Prepare options
 		options := cert.SignOptions{
-			CSRPath: signCSR,
-			CACert:  signCA,
-			CAKey:   signCAKey,
-			Days:    signDays,
-			SANs:    processSANs(signSANs),
+			CSRPath:       signCSR,
+			CACert:        signCA,
+			CAKey:         signCAKey,
+			CAKeyURI:      signCAKeyURI,
+			CAKeyPassword: password,
+			Days:          days,
+			SANs:          processSANs(signSANs),
+			Profile:       profile,
 		}
 
 		// Set output path
@@ -84,7 +181,7 @@ Examples:
 			fmt.Printf("%s Signing Certificate Signing Request...\n", getEmoji("🖊️", "[SIGN]"))
 		}
 
-		err := cert.SignCSR(options, certPath)
+		err = cert.SignCSR(options, certPath)
 		if err != nil {
 			err = fmt.Errorf("failed to sign CSR: %w", err)
 			if jsonOutput {
@@ -107,6 +204,8 @@ Examples:
 		fmt.Println()
 		fmt.Printf("%s Certificate created:\n", getEmoji("📁", "[FILES]"))
 		fmt.Printf("  %s Certificate: %s\n", getEmoji("📜", "[CERT]"), certPath)
+		fmt.Printf("  %s Recorded in: %s\n", getEmoji("🗂️", "[INDEX]"), cert.CAIndexPath(signCA))
+		fmt.Printf("  %s Profile:     %s (%s)\n", getEmoji("🏷️", "[PROFILE]"), profile.Name, profile.Description)
 		fmt.Println()
 		fmt.Printf("%s Next steps:\n", getEmoji("📋", "[NEXT]"))
 		fmt.Println("  1. Deliver the signed certificate to the requester")
@@ -130,10 +229,31 @@ Examples:
 func init() {
 	signCmd.Flags().StringVar(&signCSR, "csr", "", "Path to the CSR file to sign (required)")
 	signCmd.Flags().StringVar(&signCA, "ca", "", "Path to the CA certificate (required)")
-	signCmd.Flags().StringVar(&signCAKey, "ca-key", "", "Path to the CA private key (required)")
+	signCmd.Flags().StringVar(&signCAKey, "ca-key", "", "Path to the CA private key (required unless --ca-key-uri is set)")
+	signCmd.Flags().StringVar(&signCAKeyURI, "ca-key-uri", "", "CA signing key URI instead of --ca-key: pkcs11:, exec:, unix:, or file:")
+	signCmd.Flags().StringVar(&signCAKeyPasswordFile, "ca-key-password-file", "", "Read the CA key's password (or PKCS#11 PIN) from a file")
+	signCmd.Flags().StringVar(&signCAKeyPasswordEnv, "ca-key-password-env", "", "Read the CA key's password (or PKCS#11 PIN) from an environment variable")
 	signCmd.Flags().IntVarP(&signDays, "days", "d", 365, "Validity period in days")
 	signCmd.Flags().StringVarP(&signOutput, "output", "o", "", "Output directory for signed certificate")
 	signCmd.Flags().StringSliceVar(&signSANs, "san", []string{}, "Subject Alternative Name (overrides CSR SANs if specified)")
+	signCmd.Flags().StringVar(&signProfile, "profile", cert.DefaultProfile, "Issuance profile: server, client, peer, code-signing, email, ocsp-signing, or one from the config file")
+	signCmd.Flags().BoolVar(&signListProfiles, "list-profiles", false, "List the available issuance profiles and exit")
 
 	rootCmd.AddCommand(signCmd)
 }
+
+// configProfiles builds the issuance profiles defined in the config file
+func configProfiles() ([]*cert.Profile, error) {
+	var profiles []*cert.Profile
+	for name, pc := range config.Load().Profiles {
+		p, err := cert.NewProfile(name, pc.Description, pc.KeyUsage, pc.ExtKeyUsage, pc.Days)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile in config file: %w", err)
+		}
+		profiles = append(profiles, p)
+	}
+	if err := cert.SortProfiles(profiles); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"certwiz/internal/config"
 	"certwiz/pkg/cert"
+	"crypto/x509"
 	"os"
 	"path/filepath"
 	"testing"
@@ -142,6 +144,135 @@ func TestSignCommand(t *testing.T) {
 		}
 	})
 
+	// Test issuance profiles, built-in and from the config file
+	t.Run("SignWithProfile", func(t *testing.T) {
+		configDir := t.TempDir()
+		configContent := `profiles:
+  vpn:
+    description: VPN client
+    ext_key_usage: [clientAuth, 1.3.6.1.5.5.7.3.17]
+    days: 30
+`
+		if err := os.WriteFile(filepath.Join(configDir, ".certwiz.yaml"), []byte(configContent), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		t.Setenv("HOME", configDir)
+		t.Setenv("USERPROFILE", configDir)
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(configDir, "xdg"))
+		config.Reset()
+		defer config.Reset()
+
+		signCSR = csrPath
+		signCA = caCertPath
+		signCAKey = caKeyPath
+		signDays = 365
+		signSANs = []string{}
+		defer func() { signProfile = cert.DefaultProfile }()
+
+		tests := []struct {
+			profile  string
+			wantEKU  []x509.ExtKeyUsage
+			wantDays int
+		}{
+			{"client", []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, 365},
+			{"server", []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, 365},
+			{"vpn", []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, 30},
+		}
+		for _, tt := range tests {
+			signProfile = tt.profile
+			signOutput = filepath.Join(tmpDir, tt.profile)
+			if err := os.MkdirAll(signOutput, 0755); err != nil {
+				t.Fatalf("Failed to create output dir: %v", err)
+			}
+			if err := signCmd.RunE(signCmd, []string{}); err != nil {
+				t.Fatalf("Signing with profile %s failed: %v", tt.profile, err)
+			}
+			signedCert, err := cert.InspectFile(filepath.Join(signOutput, "test.crt"))
+			if err != nil {
+				t.Fatalf("Failed to inspect signed certificate: %v", err)
+			}
+			if len(signedCert.ExtKeyUsage) != len(tt.wantEKU) || signedCert.ExtKeyUsage[0] != tt.wantEKU[0] {
+				t.Errorf("Profile %s: EKU = %v, want %v", tt.profile, signedCert.ExtKeyUsage, tt.wantEKU)
+			}
+			if days := int(signedCert.NotAfter.Sub(signedCert.NotBefore).Hours() / 24); days != tt.wantDays {
+				t.Errorf("Profile %s: validity = %d days, want %d", tt.profile, days, tt.wantDays)
+			}
+		}
+
+		// The email profile needs an email SAN
+		signProfile = "email"
+		if err := signCmd.RunE(signCmd, []string{}); err == nil {
+			t.Error("Expected error for the email profile without an email SAN, but got none")
+		}
+
+		signProfile = "nonexistent"
+		if err := signCmd.RunE(signCmd, []string{}); err == nil {
+			t.Error("Expected error for an unknown profile, but got none")
+		}
+
+		signListProfiles = true
+		defer func() { signListProfiles = false }()
+		if err := signCmd.RunE(signCmd, []string{}); err != nil {
+			t.Errorf("Listing profiles failed: %v", err)
+		}
+	})
+
+	t.Run("SignWithEncryptedCAKey", func(t *testing.T) {
+		encCert := filepath.Join(tmpDir, "enc-ca.crt")
+		encKey := filepath.Join(tmpDir, "enc-ca.key")
+		if err := cert.GenerateCA(cert.CAOptions{CommonName: "Encrypted CA", Days: 30, KeyAlgorithm: cert.KeyAlgorithmECDSAP256, KeyPassword: "ca-pass"}, encCert, encKey); err != nil {
+			t.Fatalf("Failed to generate CA: %v", err)
+		}
+
+		signCSR = csrPath
+		signCA = encCert
+		signCAKey = encKey
+		signOutput = tmpDir
+		defer func() { signCAKeyPasswordEnv = "" }()
+
+		// Tests don't run on a terminal, so there is no prompt to fall back to
+		signCAKeyPasswordEnv = ""
+		if err := signCmd.RunE(signCmd, []string{}); err == nil {
+			t.Error("Expected error for an encrypted CA key without a password")
+		}
+
+		t.Setenv("CERTWIZ_TEST_CA_PASS", "wrong")
+		signCAKeyPasswordEnv = "CERTWIZ_TEST_CA_PASS"
+		if err := signCmd.RunE(signCmd, []string{}); err == nil {
+			t.Error("Expected error for a wrong CA key password")
+		}
+
+		t.Setenv("CERTWIZ_TEST_CA_PASS", "ca-pass")
+		if err := signCmd.RunE(signCmd, []string{}); err != nil {
+			t.Fatalf("Signing with an encrypted CA key failed: %v", err)
+		}
+	})
+
+	t.Run("SignWithKeyURI", func(t *testing.T) {
+		signCSR = csrPath
+		signCA = caCertPath
+		signCAKey = ""
+		signOutput = tmpDir
+		defer func() { signCAKeyURI = "" }()
+
+		signCAKeyURI = "file:" + caKeyPath
+		if err := signCmd.RunE(signCmd, []string{}); err != nil {
+			t.Fatalf("Signing with --ca-key-uri failed: %v", err)
+		}
+
+		signCAKey = caKeyPath
+		if err := signCmd.RunE(signCmd, []string{}); err == nil {
+			t.Error("Expected error for --ca-key with --ca-key-uri")
+		}
+
+		signCAKey = ""
+		signCAKeyURI = "pkcs11:object=root"
+		t.Setenv("PKCS11_MODULE", "")
+		if err := signCmd.RunE(signCmd, []string{}); err == nil {
+			t.Error("Expected error for a PKCS#11 URI without a module")
+		}
+	})
+
 	// Test missing required arguments
 	t.Run("MissingArguments", func(t *testing.T) {
 		// Test missing CSR
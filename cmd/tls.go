@@ -14,8 +14,16 @@ import (
 )
 
 var (
-	tlsPort    int
-	tlsTimeout string
+	tlsPort       int
+	tlsTimeout    string
+	tlsCiphers    bool
+	tlsStartTLS   string
+	tlsClientCert string
+	tlsClientKey  string
+	tlsProxy      string
+
+	tlsClientKeyPasswordFile string
+	tlsClientKeyPasswordEnv  string
 )
 
 var tlsCmd = &cobra.Command{
@@ -27,17 +35,49 @@ This command attempts to connect to the specified hostname using each
 TLS version (1.0, 1.1, 1.2, and 1.3) and reports which versions are
 supported by the server.
 
+With --ciphers, every cipher suite the server accepts is enumerated for
+each TLS 1.0-1.2 version, in the server's preference order, and graded as
+secure, weak (CBC mode, no forward secrecy), or insecure (RC4, 3DES).
+This takes one handshake per accepted suite.
+
+With --starttls, each connection starts in plaintext and is upgraded with
+the protocol's STARTTLS exchange (smtp, imap, pop3, ldap, ftp, or postgres).
+The port defaults to the protocol's standard port.
+
+For servers that require mutual TLS, pass a client certificate with
+--client-cert and --client-key. When the server asks for a client
+certificate, the CA names and signature schemes it accepts are shown.
+
+Connections go through the proxy in HTTPS_PROXY unless NO_PROXY exempts the
+host; --proxy sets one explicitly (http://, https://, socks5://, or socks5h://).
+
 Examples:
   cert tls google.com
   cert tls example.com:443
   cert tls 192.168.1.1 --port 443
-  cert tls localhost --timeout 2s`,
+  cert tls localhost --timeout 2s
+  cert tls example.com --ciphers
+  cert tls mail.example.com --starttls smtp
+  cert tls internal.svc --client-cert client.crt --client-key client.key
+  cert tls example.com --proxy http://proxy.corp:3128`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		target := args[0]
 
+		if err := cert.ValidateStartTLS(tlsStartTLS); err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
 		// Strip URL scheme and path if present (e.g. https://example.com/path)
 		port := tlsPort
+		if tlsStartTLS != "" && !cmd.Flags().Changed("port") {
+			port = cert.StartTLSDefaultPort(tlsStartTLS)
+		}
 		host := target
 		if i := strings.Index(host, "://"); i != -1 {
 			host = host[i+3:]
@@ -64,8 +104,37 @@ Examples:
 			timeout = d
 		}
 
-		// Test TLS versions
-		result, err := cert.CheckTLSVersions(host, port, timeout)
+		clientCert, err := loadClientCertificate(tlsClientCert, tlsClientKey, tlsClientKeyPasswordFile, tlsClientKeyPasswordEnv)
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+		proxy, err := parseProxyFlag(tlsProxy)
+		if err != nil {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		// Test TLS versions, and cipher suites if asked
+		check := cert.CheckTLSVersionsWithOptions
+		if tlsCiphers {
+			check = cert.CheckTLSCiphers
+		}
+		result, err := check(host, port, cert.ConnectOptions{
+			Timeout:              timeout,
+			StartTLS:             tlsStartTLS,
+			ClientCertificate:    clientCert,
+			Proxy:                proxy,
+			ProxyFromEnvironment: true,
+		})
 		if err != nil {
 			if jsonOutput {
 				printJSONError(err)
@@ -88,6 +157,13 @@ Examples:
 func init() {
 	tlsCmd.Flags().IntVar(&tlsPort, "port", 443, "Port for TLS testing")
 	tlsCmd.Flags().StringVar(&tlsTimeout, "timeout", "5s", "Network timeout (e.g., 5s, 2s)")
+	tlsCmd.Flags().BoolVar(&tlsCiphers, "ciphers", false, "Enumerate and grade the accepted cipher suites for each version")
+	tlsCmd.Flags().StringVar(&tlsClientCert, "client-cert", "", "Client certificate for servers that require mutual TLS")
+	tlsCmd.Flags().StringVar(&tlsClientKey, "client-key", "", "Private key for --client-cert (defaults to a key in the certificate file)")
+	tlsCmd.Flags().StringVar(&tlsClientKeyPasswordFile, "client-key-password-file", "", "Read an encrypted client key's password from a file")
+	tlsCmd.Flags().StringVar(&tlsClientKeyPasswordEnv, "client-key-password-env", "", "Read an encrypted client key's password from an environment variable")
+	tlsCmd.Flags().StringVar(&tlsProxy, "proxy", "", "Tunnel through a proxy: http://host:port or socks5://host:port (default from HTTPS_PROXY)")
+	tlsCmd.Flags().StringVar(&tlsStartTLS, "starttls", "", "Upgrade a plaintext connection with STARTTLS first: smtp, imap, pop3, ldap, ftp, or postgres")
 
 	rootCmd.AddCommand(tlsCmd)
 }
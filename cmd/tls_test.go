@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -37,6 +38,22 @@ func TestTLSCmdFlags(t *testing.T) {
 	if timeoutFlag.DefValue != "5s" {
 		t.Errorf("timeout flag default = %q, want %q", timeoutFlag.DefValue, "5s")
 	}
+
+	// Check starttls, client certificate, and proxy flags
+	for _, name := range []string{"starttls", "client-cert", "client-key", "proxy"} {
+		if tlsCmd.Flags().Lookup(name) == nil {
+			t.Fatalf("%s flag not found", name)
+		}
+	}
+}
+
+func TestTLSCmdInvalidStartTLS(t *testing.T) {
+	defer func() { tlsStartTLS = "" }()
+	rootCmd.SetArgs([]string{"tls", "127.0.0.1", "--starttls", "gopher"})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "unsupported STARTTLS protocol") {
+		t.Errorf("Execute() error = %v, want an unsupported protocol error", err)
+	}
 }
 
 func TestTLSCmdArgs(t *testing.T) {
@@ -7,6 +7,7 @@ import (
     "strings"
     "time"
 
+    "certwiz/internal/config"
     "certwiz/pkg/cert"
     "certwiz/pkg/ui"
 
@@ -18,21 +19,40 @@ var (
 	verifyHost      string
 	verifyKey       string
 	verifyExpiresIn string
+	verifyOCSP      bool
+	verifyCRL       string
+
+	verifyKeyPasswordFile string
+	verifyKeyPasswordEnv  string
 )
 
 var verifyCmd = &cobra.Command{
     Use:   "verify [certificate]",
     Short: "Verify a certificate",
 	Long: `Verify a certificate's validity, expiration, and optionally check
-hostname matching, CA chain validation, private key matching, and
-upcoming expiry.
+hostname matching, CA chain validation, private key matching,
+upcoming expiry, and revocation status via OCSP or a CRL file.
+
+With --ocsp, the responder URL is read from the certificate's Authority
+Information Access extension. The issuer is taken from the rest of the
+certificate file, the --ca bundle, or the AIA CA Issuers URL. With --crl,
+the certificate's serial number is looked up in the CRL, whose signature is
+checked against the issuer from the certificate file or --ca bundle.
+
+An encrypted --key is unlocked with a password that is prompted for, or read
+from --key-password-file or --key-password-env.
+
+The cert lint rules selected in the config file also run; their findings
+are reported as warnings and don't fail verification.
 
 Examples:
   cert verify cert.pem
   cert verify server.crt --host example.com
   cert verify cert.pem --ca ca.pem --host myserver.local
   cert verify server.crt --key server.key
-  cert verify cert.pem --expires-in 30d`,
+  cert verify cert.pem --expires-in 30d
+  cert verify fullchain.pem --ocsp
+  cert verify server.crt --ca ca.crt --crl ca.crl`,
 	Args: cobra.ExactArgs(1),
     RunE: func(cmd *cobra.Command, args []string) error {
         certPath := args[0]
@@ -58,16 +78,33 @@ Examples:
             return err
         }
 
+		var keyPass string
+		if verifyKey != "" {
+			if keyPass, err = keyPassword(verifyKey, "key", verifyKeyPasswordFile, verifyKeyPasswordEnv); err != nil {
+				if jsonOutput {
+					printJSONError(err)
+				} else {
+					ui.ShowError(err.Error())
+				}
+				return err
+			}
+		}
+
 		if !jsonOutput {
 			ui.ShowInfo("Verifying certificate...")
 		}
 
+        lintCfg := config.Load().Lint
         result, err := cert.VerifyWithOptions(cert.VerifyOptions{
-            CertPath:  certPath,
-            CAPath:    verifyCA,
-            Hostname:  verifyHost,
-            KeyPath:   verifyKey,
-            ExpiresIn: expiresIn,
+            CertPath:    certPath,
+            CAPath:      verifyCA,
+            Hostname:    verifyHost,
+            KeyPath:     verifyKey,
+            KeyPassword: keyPass,
+            ExpiresIn:   expiresIn,
+            OCSP:        verifyOCSP,
+            CRLPath:     verifyCRL,
+            Lint:        &cert.LintOptions{Select: lintCfg.Select, Suppress: lintCfg.Suppress},
         })
         if err != nil {
             if jsonOutput {
@@ -126,5 +163,9 @@ func init() {
 	verifyCmd.Flags().StringVar(&verifyCA, "ca", "", "CA certificate file for chain verification")
 	verifyCmd.Flags().StringVar(&verifyHost, "host", "", "Hostname to verify against the certificate")
 	verifyCmd.Flags().StringVar(&verifyKey, "key", "", "Private key file to check against the certificate")
+	verifyCmd.Flags().StringVar(&verifyKeyPasswordFile, "key-password-file", "", "Read the private key's password from a file")
+	verifyCmd.Flags().StringVar(&verifyKeyPasswordEnv, "key-password-env", "", "Read the private key's password from an environment variable")
 	verifyCmd.Flags().StringVar(&verifyExpiresIn, "expires-in", "", "Fail if the certificate expires within this window (e.g. 30d, 720h)")
+	verifyCmd.Flags().BoolVar(&verifyOCSP, "ocsp", false, "Fail if the OCSP responder reports the certificate as revoked")
+	verifyCmd.Flags().StringVar(&verifyCRL, "crl", "", "Fail if the certificate is listed in this CRL file (PEM or DER)")
 }
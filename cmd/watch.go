@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"certwiz/pkg/cert"
+	"certwiz/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval string
+	watchCount    int
+	watchPort     int
+	watchTimeout  string
+	watchProxy    string
+	watchOnChange string
+)
+
+// watchHookTimeout bounds an --on-change command, so a hung hook can't
+// stall later polls
+const watchHookTimeout = time.Minute
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [host...]",
+	Short: "Watch endpoints and report when their certificate changes",
+	Long: `Inspect one or more endpoints every --interval and report when the served
+certificate changes. Changes to the fingerprint, issuer, expiry, subject, or
+serial number are highlighted as they happen; polls that can't connect are
+reported and the watch carries on. Press Ctrl-C to stop.
+
+With --on-change, a command is run through the shell whenever a target's
+certificate changes. It receives the event as JSON on stdin and in these
+environment variables: CERTWIZ_TARGET, CERTWIZ_FINGERPRINT,
+CERTWIZ_PREVIOUS_FINGERPRINT, CERTWIZ_SUBJECT, CERTWIZ_ISSUER, and
+CERTWIZ_NOT_AFTER. A hook still running after a minute is stopped.
+
+With --json, every poll is written as one JSON object per line (NDJSON).
+
+Examples:
+  cert watch example.com
+  cert watch example.com api.example.com:8443 --interval 10s
+  cert watch example.com --on-change 'notify-send "cert changed on $CERTWIZ_TARGET"'
+  cert watch example.com --json | jq 'select(.event == "changed")'
+  cert watch example.com --count 1 --json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fail := func(err error) error {
+			if jsonOutput {
+				printJSONError(err)
+			} else {
+				ui.ShowError(err.Error())
+			}
+			return err
+		}
+
+		interval, err := time.ParseDuration(watchInterval)
+		if err != nil || interval <= 0 {
+			return fail(fmt.Errorf("invalid --interval value %q: must be a positive duration such as 30s or 5m", watchInterval))
+		}
+		timeout, err := time.ParseDuration(watchTimeout)
+		if err != nil {
+			return fail(fmt.Errorf("invalid --timeout value %q: %w", watchTimeout, err))
+		}
+		if watchCount < 0 {
+			return fail(fmt.Errorf("--count must not be negative"))
+		}
+		proxy, err := parseProxyFlag(watchProxy)
+		if err != nil {
+			return fail(err)
+		}
+
+		watcher := cert.NewWatcher(cert.WatchOptions{
+			Targets:  args,
+			Port:     watchPort,
+			Interval: interval,
+			Count:    watchCount,
+			Connect: cert.ConnectOptions{
+				Timeout:              timeout,
+				Proxy:                proxy,
+				ProxyFromEnvironment: true,
+			},
+		})
+
+		if !jsonOutput {
+			ui.ShowInfo(fmt.Sprintf("Watching %d target(s) every %s (Ctrl-C to stop)", len(args), interval))
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		encoder := json.NewEncoder(os.Stdout)
+		watcher.Run(ctx, func(e cert.WatchEvent) {
+			if jsonOutput {
+				_ = encoder.Encode(e.ToJSON())
+			} else {
+				ui.DisplayWatchEvent(&e)
+			}
+			if e.Type == cert.WatchEventChanged && watchOnChange != "" {
+				if err := runWatchHook(ctx, watchOnChange, &e); err != nil {
+					fmt.Fprintf(os.Stderr, "--on-change for %s failed: %v\n", e.Target, err)
+				}
+			}
+		})
+		return nil
+	},
+}
+
+// runWatchHook runs the --on-change command through the shell with the
+// event as JSON on stdin and its main fields in the environment. The
+// command's output goes to stderr in --json mode so NDJSON stays clean.
+// It's killed when ctx is done or after watchHookTimeout.
+func runWatchHook(ctx context.Context, command string, e *cert.WatchEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, watchHookTimeout)
+	defer cancel()
+
+	var hook *exec.Cmd
+	if runtime.GOOS == "windows" {
+		hook = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		hook = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	event, err := json.Marshal(e.ToJSON())
+	if err != nil {
+		return err
+	}
+	hook.Stdin = bytes.NewReader(event)
+	hook.Stdout = os.Stdout
+	if jsonOutput {
+		hook.Stdout = os.Stderr
+	}
+	hook.Stderr = os.Stderr
+
+	hook.Env = append(os.Environ(),
+		"CERTWIZ_TARGET="+e.Target,
+		"CERTWIZ_FINGERPRINT="+e.Certificate.FingerprintSHA256(),
+		"CERTWIZ_SUBJECT="+e.Certificate.Subject.String(),
+		"CERTWIZ_ISSUER="+e.Certificate.Issuer.String(),
+		"CERTWIZ_NOT_AFTER="+e.Certificate.NotAfter.UTC().Format(time.RFC3339),
+	)
+	if e.Previous != nil {
+		hook.Env = append(hook.Env, "CERTWIZ_PREVIOUS_FINGERPRINT="+e.Previous.FingerprintSHA256())
+	}
+	return hook.Run()
+}
+
+func init() {
+	watchCmd.Flags().StringVar(&watchInterval, "interval", cert.DefaultWatchInterval.String(), "Time between polls (e.g., 30s, 5m)")
+	watchCmd.Flags().IntVar(&watchCount, "count", 0, "Stop after this many polls (0 = until interrupted)")
+	watchCmd.Flags().IntVar(&watchPort, "port", 443, "Default port for hosts without one")
+	watchCmd.Flags().StringVar(&watchTimeout, "timeout", "5s", "Network timeout per poll (e.g., 5s, 2s)")
+	watchCmd.Flags().StringVar(&watchProxy, "proxy", "", "Tunnel through a proxy: http://host:port or socks5://host:port (default from HTTPS_PROXY)")
+	watchCmd.Flags().StringVar(&watchOnChange, "on-change", "", "Shell command to run when a target's certificate changes")
+
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"certwiz/pkg/cert"
+)
+
+func TestWatchCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "Watch an unreachable host once",
+			args:    []string{"watch", "127.0.0.1:1", "--count", "1", "--timeout", "1s"},
+			wantErr: false,
+		},
+		{
+			name:    "Watch an unreachable host once as NDJSON",
+			args:    []string{"watch", "127.0.0.1:1", "--count", "1", "--timeout", "1s", "--json"},
+			wantErr: false,
+		},
+		{
+			name:    "Watch with invalid interval",
+			args:    []string{"watch", "example.com", "--interval", "0s"},
+			wantErr: true,
+		},
+		{
+			name:    "Watch with negative count",
+			args:    []string{"watch", "example.com", "--count", "-1"},
+			wantErr: true,
+		},
+		{
+			name:    "Watch with unsupported proxy scheme",
+			args:    []string{"watch", "example.com", "--proxy", "ftp://proxy.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "Watch with no hosts",
+			args:    []string{"watch"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset flags that persist between Execute calls
+			watchInterval = "30s"
+			watchCount = 0
+			watchTimeout = "5s"
+			watchProxy = ""
+			jsonOutput = false
+			defer func() { jsonOutput = false }()
+
+			cmd := rootCmd
+			cmd.SetArgs(tt.args)
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			if tt.wantErr && err == nil {
+				t.Error("Expected error but got none")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunWatchHookCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook uses sh")
+	}
+	tmpDir := t.TempDir()
+	if err := cert.Generate(cert.GenerateOptions{CommonName: "watch.example.com", Days: 30, KeyAlgorithm: cert.KeyAlgorithmECDSAP256, OutputDir: tmpDir}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	c, err := cert.InspectFile(filepath.Join(tmpDir, "watch.example.com.crt"))
+	if err != nil {
+		t.Fatalf("InspectFile failed: %v", err)
+	}
+
+	// A hung hook is killed when the watch stops
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = runWatchHook(ctx, "exec sleep 30", &cert.WatchEvent{Type: cert.WatchEventChanged, Target: "watch.example.com", Certificate: c})
+	if err == nil {
+		t.Error("Expected the hook to be killed")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Hook ran for %s after the watch stopped", elapsed)
+	}
+}
@@ -4,8 +4,12 @@ go 1.20
 
 require (
 	github.com/charmbracelet/lipgloss v0.9.1
+	github.com/miekg/pkcs11 v1.1.1
 	github.com/spf13/cobra v1.7.0
+	golang.org/x/crypto v0.14.0
 	golang.org/x/term v0.13.0
+	gopkg.in/yaml.v3 v3.0.1
+	software.sslmate.com/src/go-pkcs12 v0.4.0
 )
 
 require (
@@ -19,5 +23,4 @@ require (
 	github.com/rivo/uniseg v0.2.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.13.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -16,9 +16,37 @@ type OutputConfig struct {
 	Emojis  bool `yaml:"emojis"`  // Show emojis (checkmarks, etc.)
 }
 
+// ProfileConfig is a user-defined issuance profile for cert sign
+type ProfileConfig struct {
+	Description string   `yaml:"description"`
+	KeyUsage    []string `yaml:"key_usage"`     // e.g. digitalSignature, keyEncipherment
+	ExtKeyUsage []string `yaml:"ext_key_usage"` // e.g. serverAuth, or a dotted OID
+	Days        int      `yaml:"days"`          // default validity, overridden by --days
+}
+
+// ExporterConfig lists what cert exporter monitors and how often
+type ExporterConfig struct {
+	Listen      string   `yaml:"listen"`       // e.g. :9793
+	Files       []string `yaml:"files"`        // certificate files
+	Targets     []string `yaml:"targets"`      // hosts, host:port pairs, or URLs
+	Interval    string   `yaml:"interval"`     // certificate inspection interval, e.g. 5m
+	TLSInterval string   `yaml:"tls_interval"` // TLS version check interval, e.g. 1h
+	Timeout     string   `yaml:"timeout"`      // network timeout per probe, e.g. 5s
+	CA          string   `yaml:"ca"`           // CA bundle for chain validation
+}
+
+// LintConfig selects the lint rules run by cert lint and cert verify
+type LintConfig struct {
+	Select   []string `yaml:"select"`   // rule IDs to run; every rule when empty
+	Suppress []string `yaml:"suppress"` // rule IDs to skip
+}
+
 // Config holds all certwiz configuration
 type Config struct {
-	Output OutputConfig `yaml:"output"`
+	Output   OutputConfig             `yaml:"output"`
+	Profiles map[string]ProfileConfig `yaml:"profiles"`
+	Exporter ExporterConfig           `yaml:"exporter"`
+	Lint     LintConfig               `yaml:"lint"`
 }
 
 var (
@@ -38,6 +66,20 @@ func DefaultConfig() *Config {
 	}
 }
 
+// Dir returns certwiz's configuration directory: $XDG_CONFIG_HOME/certwiz,
+// or ~/.config/certwiz. Other state, such as ACME accounts, lives under it.
+func Dir() (string, error) {
+	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfig == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		xdgConfig = filepath.Join(home, ".config")
+	}
+	return filepath.Join(xdgConfig, "certwiz"), nil
+}
+
 // configPaths returns the list of config file paths to check, in order of priority
 func configPaths() []string {
 	var paths []string
@@ -48,11 +90,9 @@ func configPaths() []string {
 	}
 
 	// XDG standard location (highest priority)
-	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
-	if xdgConfig == "" {
-		xdgConfig = filepath.Join(home, ".config")
+	if dir, err := Dir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "config.yaml"))
 	}
-	paths = append(paths, filepath.Join(xdgConfig, "certwiz", "config.yaml"))
 
 	// Simple dotfile fallback
 	paths = append(paths, filepath.Join(home, ".certwiz.yaml"))
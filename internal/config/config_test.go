@@ -285,3 +285,94 @@ func TestReset(t *testing.T) {
 		t.Error("Config should not be nil after reset and reload")
 	}
 }
+
+func TestLoadProfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".certwiz.yaml")
+	configContent := `profiles:
+  vpn:
+    description: OpenVPN client
+    key_usage: [digitalSignature, keyAgreement]
+    ext_key_usage: [clientAuth, 1.3.6.1.5.5.7.3.17]
+    days: 90
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	origHome := os.Getenv("HOME")
+	origUserProfile := os.Getenv("USERPROFILE")
+	origXDG := os.Getenv("XDG_CONFIG_HOME")
+	defer func() {
+		os.Setenv("HOME", origHome)
+		os.Setenv("USERPROFILE", origUserProfile)
+		os.Setenv("XDG_CONFIG_HOME", origXDG)
+		Reset()
+	}()
+
+	os.Setenv("HOME", tmpDir)
+	os.Setenv("USERPROFILE", tmpDir) // For Windows compatibility
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "xdg"))
+	Reset()
+
+	cfg := Load()
+
+	vpn, ok := cfg.Profiles["vpn"]
+	if !ok {
+		t.Fatalf("Profile not loaded: %+v", cfg.Profiles)
+	}
+	if vpn.Description != "OpenVPN client" || len(vpn.KeyUsage) != 2 || len(vpn.ExtKeyUsage) != 2 || vpn.Days != 90 {
+		t.Errorf("Profile = %+v", vpn)
+	}
+	// Output settings keep their defaults
+	if !cfg.Output.Borders {
+		t.Error("Borders should keep its default")
+	}
+}
+
+func TestLoadExporter(t *testing.T) {
+	tmpDir := t.TempDir()
+	configContent := `exporter:
+  listen: ":9100"
+  files: [/etc/ssl/server.pem]
+  targets: [example.com, api.example.com:8443]
+  interval: 10m
+  tls_interval: 6h
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, ".certwiz.yaml"), []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("USERPROFILE", tmpDir) // For Windows compatibility
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "xdg"))
+	Reset()
+	defer Reset()
+
+	e := Load().Exporter
+	if e.Listen != ":9100" || len(e.Files) != 1 || len(e.Targets) != 2 || e.Interval != "10m" || e.TLSInterval != "6h" {
+		t.Errorf("Exporter = %+v", e)
+	}
+}
+
+func TestLoadLint(t *testing.T) {
+	tmpDir := t.TempDir()
+	configContent := `lint:
+  select: [weak-key, sha1-signature]
+  suppress: [ski-missing]
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, ".certwiz.yaml"), []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("USERPROFILE", tmpDir) // For Windows compatibility
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "xdg"))
+	Reset()
+	defer Reset()
+
+	l := Load().Lint
+	if len(l.Select) != 2 || l.Select[1] != "sha1-signature" || len(l.Suppress) != 1 || l.Suppress[0] != "ski-missing" {
+		t.Errorf("Lint = %+v", l)
+	}
+}
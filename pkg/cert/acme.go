@@ -0,0 +1,539 @@
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// ACME directories of the Let's Encrypt production and staging CAs
+const (
+	LetsEncryptDirectoryURL        = "https://acme-v02.api.letsencrypt.org/directory"
+	LetsEncryptStagingDirectoryURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+)
+
+// ACME challenge types that ObtainACMECertificate can complete
+const (
+	ACMEChallengeHTTP01 = "http-01"
+	ACMEChallengeDNS01  = "dns-01"
+)
+
+// DefaultACMEHTTPListen is where the built-in HTTP-01 responder listens;
+// the CA always validates on port 80 (Pebble uses 5002)
+const DefaultACMEHTTPListen = ":80"
+
+// acmeAccountKeyFile and acmeAccountFile hold an account in its directory
+const (
+	acmeAccountKeyFile = "account.key"
+	acmeAccountFile    = "account.json"
+)
+
+// ACMEOptions selects an ACME server and the account used with it
+type ACMEOptions struct {
+	DirectoryURL string // ACME directory URL; Let's Encrypt when empty
+	AccountDir   string // parent directory of per-server account directories
+	CABundle     string // roots to trust for the server's TLS certificate, e.g. Pebble's test CA
+	Email        string // contact address for a new account
+	AgreeTOS     bool   // accept the CA's terms of service when registering
+
+	// Log reports progress, such as each challenge being completed; may be nil
+	Log func(format string, args ...interface{})
+}
+
+// ACMEOrderOptions configures a certificate order
+type ACMEOrderOptions struct {
+	ACMEOptions
+	CSRPath   string // CSR to finalize the order with; its names are the order's identifiers
+	Challenge string // http-01 (default) or dns-01
+
+	// HTTPListen is the address of the built-in HTTP-01 responder
+	HTTPListen string
+	// DNSHook is a command run to publish and remove DNS-01 TXT records
+	DNSHook string
+
+	CertPath      string // leaf certificate
+	ChainPath     string // optional: the issuer chain, without the leaf
+	FullChainPath string // optional: leaf followed by the issuer chain
+}
+
+// ACMEAccount is a registered ACME account, as kept in the account directory
+type ACMEAccount struct {
+	URI          string    `json:"uri"`
+	DirectoryURL string    `json:"directory"`
+	Contact      []string  `json:"contact,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	KeyPath string `json:"-"`
+	Created bool   `json:"-"` // registered by this call rather than loaded
+}
+
+// ACMEResult describes an issued certificate and where it was written
+type ACMEResult struct {
+	OrderURI    string
+	CertURI     string
+	Identifiers []string
+	Certificate *Certificate
+	ChainLength int // issuer certificates returned with the leaf
+	Files       []string
+}
+
+// ACMEAccountPath returns the directory holding the account for
+// directoryURL under baseDir, one per server host
+func ACMEAccountPath(baseDir, directoryURL string) string {
+	host := directoryURL
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	host, _, _ = strings.Cut(host, "/")
+	return filepath.Join(baseDir, strings.NewReplacer(":", "_", "[", "", "]", "").Replace(host))
+}
+
+// RegisterACMEAccount creates an account with the ACME server, or loads the
+// one already registered for this server, creating its key if needed
+func RegisterACMEAccount(ctx context.Context, opts ACMEOptions) (*ACMEAccount, error) {
+	_, account, err := newACMEClient(ctx, opts)
+	return account, err
+}
+
+// ObtainACMECertificate orders a certificate for the names in a CSR,
+// completes each authorization with the chosen challenge, and writes the
+// issued certificate and its chain. An account is registered first if
+// this server has none yet.
+func ObtainACMECertificate(ctx context.Context, opts ACMEOrderOptions) (*ACMEResult, error) {
+	challenge := opts.Challenge
+	if challenge == "" {
+		challenge = ACMEChallengeHTTP01
+	}
+	switch challenge {
+	case ACMEChallengeHTTP01:
+	case ACMEChallengeDNS01:
+		if strings.TrimSpace(opts.DNSHook) == "" {
+			return nil, fmt.Errorf("the dns-01 challenge needs a DNS hook command")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported ACME challenge %q (use http-01 or dns-01)", challenge)
+	}
+
+	csr, err := readCSRFile(opts.CSRPath)
+	if err != nil {
+		return nil, err
+	}
+	ids, names := acmeIdentifiers(csr)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("CSR %s has no DNS names or IP addresses to order", opts.CSRPath)
+	}
+
+	client, _, err := newACMEClient(ctx, opts.ACMEOptions)
+	if err != nil {
+		return nil, err
+	}
+	logf := opts.logf
+
+	logf("Ordering a certificate for %s", strings.Join(names, ", "))
+	order, err := client.AuthorizeOrder(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	var responder *acmeHTTPResponder
+	defer func() {
+		if responder != nil {
+			responder.Close()
+		}
+	}()
+
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch authorization: %w", err)
+		}
+		name := authz.Identifier.Value
+		if authz.Status == acme.StatusValid {
+			logf("%s is already authorized", name)
+			continue
+		}
+		if authz.Wildcard && challenge != ACMEChallengeDNS01 {
+			return nil, fmt.Errorf("wildcard name *.%s can only be validated with dns-01", name)
+		}
+
+		var chal *acme.Challenge
+		var offered []string
+		for _, c := range authz.Challenges {
+			offered = append(offered, c.Type)
+			if c.Type == challenge {
+				chal = c
+			}
+		}
+		if chal == nil {
+			return nil, fmt.Errorf("the CA offers no %s challenge for %s (offered: %s)", challenge, name, strings.Join(offered, ", "))
+		}
+
+		var cleanup func()
+		switch challenge {
+		case ACMEChallengeHTTP01:
+			if responder == nil {
+				listen := opts.HTTPListen
+				if listen == "" {
+					listen = DefaultACMEHTTPListen
+				}
+				if responder, err = startACMEHTTPResponder(listen); err != nil {
+					return nil, err
+				}
+				logf("Answering HTTP-01 challenges on %s", responder.Addr())
+			}
+			keyAuth, err := client.HTTP01ChallengeResponse(chal.Token)
+			if err != nil {
+				return nil, err
+			}
+			path := client.HTTP01ChallengePath(chal.Token)
+			responder.Set(path, keyAuth)
+			cleanup = func() { responder.Remove(path) }
+		case ACMEChallengeDNS01:
+			value, err := client.DNS01ChallengeRecord(chal.Token)
+			if err != nil {
+				return nil, err
+			}
+			record := "_acme-challenge." + name
+			logf("Publishing TXT record %s", record)
+			if err := runDNSHook(ctx, opts.DNSHook, "present", name, record, value); err != nil {
+				return nil, err
+			}
+			cleanup = func() {
+				if err := runDNSHook(context.Background(), opts.DNSHook, "cleanup", name, record, value); err != nil {
+					logf("Warning: %v", err)
+				}
+			}
+		}
+
+		logf("Validating %s with %s", name, challenge)
+		_, err = client.Accept(ctx, chal)
+		if err == nil {
+			_, err = client.WaitAuthorization(ctx, authz.URI)
+		}
+		cleanup()
+		if err != nil {
+			return nil, fmt.Errorf("authorization for %s failed: %w", name, err)
+		}
+	}
+
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, fmt.Errorf("order did not become ready: %w", err)
+	}
+	logf("Finalizing the order")
+	ders, certURL, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr.Raw, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize order: %w", err)
+	}
+	if len(ders) == 0 {
+		return nil, fmt.Errorf("the CA returned no certificate")
+	}
+	leaf, err := x509.ParseCertificate(ders[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse issued certificate: %w", err)
+	}
+
+	result := &ACMEResult{
+		OrderURI:    order.URI,
+		CertURI:     certURL,
+		Identifiers: names,
+		Certificate: &Certificate{
+			Certificate:     leaf,
+			Source:          certURL,
+			Format:          FormatPEM,
+			IsExpired:       leaf.NotAfter.Before(time.Now()),
+			DaysUntilExpiry: int(time.Until(leaf.NotAfter).Hours() / 24),
+		},
+		ChainLength: len(ders) - 1,
+	}
+	write := func(path string, certs [][]byte) error {
+		if path == "" {
+			return nil
+		}
+		var buf bytes.Buffer
+		for _, der := range certs {
+			_ = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write certificate: %w", err)
+		}
+		result.Files = append(result.Files, path)
+		return nil
+	}
+	if err := write(opts.CertPath, ders[:1]); err != nil {
+		return nil, err
+	}
+	if len(ders) > 1 {
+		if err := write(opts.ChainPath, ders[1:]); err != nil {
+			return nil, err
+		}
+	}
+	if err := write(opts.FullChainPath, ders); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (o ACMEOptions) logf(format string, args ...interface{}) {
+	if o.Log != nil {
+		o.Log(format, args...)
+	}
+}
+
+// newACMEClient returns a client for the server with its account loaded,
+// registering one first if this server has none on disk
+func newACMEClient(ctx context.Context, opts ACMEOptions) (*acme.Client, *ACMEAccount, error) {
+	directoryURL := opts.DirectoryURL
+	if directoryURL == "" {
+		directoryURL = LetsEncryptDirectoryURL
+	}
+	if opts.AccountDir == "" {
+		return nil, nil, fmt.Errorf("no ACME account directory given")
+	}
+	dir := ACMEAccountPath(opts.AccountDir, directoryURL)
+	keyPath := filepath.Join(dir, acmeAccountKeyFile)
+
+	key, err := loadOrCreateACMEKey(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := &acme.Client{Key: key, DirectoryURL: directoryURL, UserAgent: "certwiz"}
+	if opts.CABundle != "" {
+		roots, err := LoadCertPool(opts.CABundle)
+		if err != nil {
+			return nil, nil, err
+		}
+		client.HTTPClient = &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: roots},
+		}}
+	}
+
+	accountPath := filepath.Join(dir, acmeAccountFile)
+	if data, err := os.ReadFile(accountPath); err == nil {
+		account := &ACMEAccount{}
+		if err := json.Unmarshal(data, account); err != nil {
+			return nil, nil, fmt.Errorf("failed to read ACME account %s: %w", accountPath, err)
+		}
+		account.KeyPath = keyPath
+		client.KID = acme.KeyID(account.URI)
+		return client, account, nil
+	} else if !os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("failed to read ACME account: %w", err)
+	}
+
+	directory, err := client.Discover(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read ACME directory %s: %w", directoryURL, err)
+	}
+	if directory.Terms != "" && !opts.AgreeTOS {
+		return nil, nil, fmt.Errorf("registering requires accepting the CA's terms of service at %s", directory.Terms)
+	}
+	var contact []string
+	if opts.Email != "" {
+		contact = []string{"mailto:" + opts.Email}
+	}
+
+	created := true
+	registered, err := client.Register(ctx, &acme.Account{Contact: contact}, func(string) bool { return opts.AgreeTOS })
+	if errors.Is(err, acme.ErrAccountAlreadyExists) {
+		// The key was registered before but the account file is gone
+		created = false
+		registered, err = client.GetReg(ctx, "")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to register ACME account: %w", err)
+	}
+
+	account := &ACMEAccount{
+		URI:          registered.URI,
+		DirectoryURL: directoryURL,
+		Contact:      registered.Contact,
+		Status:       registered.Status,
+		CreatedAt:    time.Now().UTC(),
+		KeyPath:      keyPath,
+		Created:      created,
+	}
+	data, err := json.MarshalIndent(account, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(accountPath, append(data, '\n'), 0600); err != nil {
+		return nil, nil, fmt.Errorf("failed to save ACME account: %w", err)
+	}
+	client.KID = acme.KeyID(account.URI)
+	return client, account, nil
+}
+
+// loadOrCreateACMEKey loads an account key, generating a P-256 key the
+// first time
+func loadOrCreateACMEKey(path string) (crypto.Signer, error) {
+	if _, err := os.Stat(path); err == nil {
+		return LoadPrivateKey(path, "")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create ACME account directory: %w", err)
+	}
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := writePrivateKey(path, key, ""); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// readCSRFile reads a PEM or DER certificate request
+func readCSRFile(path string) (*x509.CertificateRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSR: %w", err)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	csr, err := x509.ParseCertificateRequest(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSR: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("CSR signature is invalid: %w", err)
+	}
+	return csr, nil
+}
+
+// acmeIdentifiers lists the names a CSR asks for as ACME identifiers:
+// its DNS names and IP addresses, plus the common name if it is not
+// among them
+func acmeIdentifiers(csr *x509.CertificateRequest) ([]acme.AuthzID, []string) {
+	var ids []acme.AuthzID
+	var names []string
+	seen := map[string]bool{}
+	add := func(typ, value string) {
+		if value == "" || seen[value] {
+			return
+		}
+		seen[value] = true
+		ids = append(ids, acme.AuthzID{Type: typ, Value: value})
+		names = append(names, value)
+	}
+
+	for _, name := range csr.DNSNames {
+		add("dns", strings.ToLower(name))
+	}
+	for _, ip := range csr.IPAddresses {
+		add("ip", ip.String())
+	}
+	if cn := csr.Subject.CommonName; cn != "" {
+		if ip := net.ParseIP(cn); ip != nil {
+			add("ip", ip.String())
+		} else {
+			add("dns", strings.ToLower(cn))
+		}
+	}
+	return ids, names
+}
+
+// acmeHTTPResponder serves HTTP-01 key authorizations
+type acmeHTTPResponder struct {
+	server   *http.Server
+	listener net.Listener
+
+	mu        sync.RWMutex
+	responses map[string]string
+}
+
+// startACMEHTTPResponder starts serving on listen; the CA connects to port 80
+// of each name, so listen must be reachable there
+func startACMEHTTPResponder(listen string) (*acmeHTTPResponder, error) {
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the HTTP-01 responder on %s: %w", listen, err)
+	}
+	r := &acmeHTTPResponder{listener: ln, responses: map[string]string{}}
+	r.server = &http.Server{Handler: r, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = r.server.Serve(ln) }()
+	return r, nil
+}
+
+func (r *acmeHTTPResponder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	keyAuth, ok := r.responses[req.URL.Path]
+	r.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(keyAuth))
+}
+
+// Addr is the address the responder is listening on
+func (r *acmeHTTPResponder) Addr() string {
+	return r.listener.Addr().String()
+}
+
+// Set serves keyAuth at path
+func (r *acmeHTTPResponder) Set(path, keyAuth string) {
+	r.mu.Lock()
+	r.responses[path] = keyAuth
+	r.mu.Unlock()
+}
+
+// Remove stops serving path
+func (r *acmeHTTPResponder) Remove(path string) {
+	r.mu.Lock()
+	delete(r.responses, path)
+	r.mu.Unlock()
+}
+
+// Close stops the responder
+func (r *acmeHTTPResponder) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return r.server.Shutdown(ctx)
+}
+
+// runDNSHook runs the DNS-01 hook as `hook <action> <domain> <record> <value>`,
+// where action is present or cleanup. The same values are in the environment
+// as CERTWIZ_ACME_ACTION, CERTWIZ_ACME_DOMAIN, CERTWIZ_ACME_RECORD, and
+// CERTWIZ_ACME_VALUE. present should return once the TXT record is visible.
+func runDNSHook(ctx context.Context, hook, action, domain, record, value string) error {
+	args := strings.Fields(hook)
+	if len(args) == 0 {
+		return fmt.Errorf("no DNS hook command given")
+	}
+	args = append(args, action, domain, record, value)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"CERTWIZ_ACME_ACTION="+action,
+		"CERTWIZ_ACME_DOMAIN="+domain,
+		"CERTWIZ_ACME_RECORD="+record,
+		"CERTWIZ_ACME_VALUE="+value,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("DNS hook %s for %s failed: %w: %s", action, record, err, msg)
+		}
+		return fmt.Errorf("DNS hook %s for %s failed: %w", action, record, err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package cert
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestACMEAccountPath(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{LetsEncryptDirectoryURL, "acme-v02.api.letsencrypt.org"},
+		{"https://localhost:14000/dir", "localhost_14000"},
+		{"https://[::1]:14000/dir", "__1_14000"},
+	}
+	for _, tt := range tests {
+		if got := ACMEAccountPath("/base", tt.url); got != filepath.Join("/base", tt.want) {
+			t.Errorf("ACMEAccountPath(%q) = %q, want %q", tt.url, got, filepath.Join("/base", tt.want))
+		}
+	}
+}
+
+func TestACMEIdentifiers(t *testing.T) {
+	dir := t.TempDir()
+	csrPath := filepath.Join(dir, "multi.csr")
+	err := GenerateCSR(CSROptions{
+		CommonName:   "Example.com",
+		SANs:         []string{"example.com", "www.example.com", "ip:192.0.2.10"},
+		KeyAlgorithm: KeyAlgorithmECDSAP256,
+	}, csrPath, filepath.Join(dir, "multi.key"))
+	if err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+	csr, err := readCSRFile(csrPath)
+	if err != nil {
+		t.Fatalf("readCSRFile failed: %v", err)
+	}
+
+	ids, names := acmeIdentifiers(csr)
+	want := []string{"example.com", "www.example.com", "192.0.2.10"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	if len(ids) != 3 || ids[0].Type != "dns" || ids[2].Type != "ip" {
+		t.Errorf("Unexpected identifiers: %+v", ids)
+	}
+}
+
+func TestACMEHTTPResponder(t *testing.T) {
+	r, err := startACMEHTTPResponder("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("startACMEHTTPResponder failed: %v", err)
+	}
+	defer r.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get("http://" + r.Addr() + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	path := "/.well-known/acme-challenge/token1"
+	if code, _ := get(path); code != http.StatusNotFound {
+		t.Errorf("Unknown token: status %d, want 404", code)
+	}
+	r.Set(path, "token1.thumbprint")
+	if code, body := get(path); code != http.StatusOK || body != "token1.thumbprint" {
+		t.Errorf("GET %s = %d %q, want the key authorization", path, code, body)
+	}
+	r.Remove(path)
+	if code, _ := get(path); code != http.StatusNotFound {
+		t.Errorf("Removed token: status %d, want 404", code)
+	}
+}
+
+func TestRunDNSHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook script needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "calls")
+	hook := filepath.Join(dir, "hook.sh")
+	script := "#!/bin/sh\necho \"$@ $CERTWIZ_ACME_ACTION $CERTWIZ_ACME_VALUE\" >> " + out + "\n[ \"$2\" != fail.example.com ] || { echo 'zone not found' >&2; exit 1; }\n"
+	if err := os.WriteFile(hook, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runDNSHook(context.Background(), hook, "present", "example.com", "_acme-challenge.example.com", "v4lue"); err != nil {
+		t.Fatalf("runDNSHook failed: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Hook did not run: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "present example.com _acme-challenge.example.com v4lue present v4lue" {
+		t.Errorf("Hook saw %q", got)
+	}
+
+	err = runDNSHook(context.Background(), hook, "present", "fail.example.com", "_acme-challenge.fail.example.com", "x")
+	if err == nil || !strings.Contains(err.Error(), "zone not found") {
+		t.Errorf("Expected the hook's output in the error, got %v", err)
+	}
+}
+
+func TestObtainACMECertificateErrors(t *testing.T) {
+	dir := t.TempDir()
+	base := ACMEOptions{DirectoryURL: "https://127.0.0.1:1/dir", AccountDir: dir}
+	tests := []struct {
+		name    string
+		opts    ACMEOrderOptions
+		wantErr string
+	}{
+		{"dns-01 without a hook", ACMEOrderOptions{ACMEOptions: base, Challenge: ACMEChallengeDNS01}, "DNS hook"},
+		{"unsupported challenge", ACMEOrderOptions{ACMEOptions: base, Challenge: "tls-alpn-01"}, "unsupported ACME challenge"},
+		{"missing CSR", ACMEOrderOptions{ACMEOptions: base, CSRPath: filepath.Join(dir, "missing.csr")}, "failed to read CSR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ObtainACMECertificate(context.Background(), tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestACMEPebble issues a certificate from a local Pebble test CA:
+//
+//	PEBBLE_VA_ALWAYS_VALID=1 pebble -config test/config/pebble-config.json
+//	CERTWIZ_TEST_ACME_DIRECTORY=https://localhost:14000/dir \
+//	CERTWIZ_TEST_ACME_CA=test/certs/pebble.minica.pem \
+//	go test -run ACMEPebble ./pkg/cert
+//
+// Without PEBBLE_VA_ALWAYS_VALID, Pebble validates HTTP-01 on port 5002
+// (or CERTWIZ_TEST_ACME_HTTP_LISTEN) of test.example, which must resolve
+// to this host.
+func TestACMEPebble(t *testing.T) {
+	directory := os.Getenv("CERTWIZ_TEST_ACME_DIRECTORY")
+	if directory == "" {
+		t.Skip("set CERTWIZ_TEST_ACME_DIRECTORY (and CERTWIZ_TEST_ACME_CA) to test against a Pebble ACME server")
+	}
+	listen := os.Getenv("CERTWIZ_TEST_ACME_HTTP_LISTEN")
+	if listen == "" {
+		listen = ":5002"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	dir := t.TempDir()
+	opts := ACMEOptions{
+		DirectoryURL: directory,
+		AccountDir:   filepath.Join(dir, "accounts"),
+		CABundle:     os.Getenv("CERTWIZ_TEST_ACME_CA"),
+		Email:        "admin@test.example",
+		AgreeTOS:     true,
+	}
+	account, err := RegisterACMEAccount(ctx, opts)
+	if err != nil {
+		t.Fatalf("RegisterACMEAccount failed: %v", err)
+	}
+	if !account.Created || account.URI == "" {
+		t.Errorf("Expected a new account, got %+v", account)
+	}
+	again, err := RegisterACMEAccount(ctx, opts)
+	if err != nil || again.URI != account.URI || again.Created {
+		t.Errorf("Second registration should load the saved account: %+v, %v", again, err)
+	}
+
+	csrPath := filepath.Join(dir, "test.example.csr")
+	if err := GenerateCSR(CSROptions{CommonName: "test.example", SANs: []string{"test.example", "www.test.example"}, KeyAlgorithm: KeyAlgorithmECDSAP256}, csrPath, filepath.Join(dir, "test.example.key")); err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+	result, err := ObtainACMECertificate(ctx, ACMEOrderOptions{
+		ACMEOptions:   opts,
+		CSRPath:       csrPath,
+		HTTPListen:    listen,
+		CertPath:      filepath.Join(dir, "test.example.crt"),
+		FullChainPath: filepath.Join(dir, "test.example-fullchain.crt"),
+	})
+	if err != nil {
+		t.Fatalf("ObtainACMECertificate failed: %v", err)
+	}
+	if len(result.Certificate.DNSNames) != 2 || result.ChainLength == 0 {
+		t.Errorf("Unexpected certificate: names %v, %d issuer(s)", result.Certificate.DNSNames, result.ChainLength)
+	}
+	chain, err := InspectFileAll(filepath.Join(dir, "test.example-fullchain.crt"))
+	if err != nil || len(chain) != result.ChainLength+1 {
+		t.Errorf("Full chain has %d certificate(s), want %d (%v)", len(chain), result.ChainLength+1, err)
+	}
+}
@@ -0,0 +1,143 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// jwsMessage is a flattened JWS (RFC 7515) as ACME clients send it
+type jwsMessage struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// jwsHeader is the protected header of an ACME request (RFC 8555, 6.2)
+type jwsHeader struct {
+	Alg   string          `json:"alg"`
+	Nonce string          `json:"nonce"`
+	URL   string          `json:"url"`
+	JWK   json.RawMessage `json:"jwk,omitempty"`
+	KID   string          `json:"kid,omitempty"`
+}
+
+// jsonWebKey holds the public members of an RSA, EC, or OKP JWK
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// parseJWK returns the public key of a JWK and its RFC 7638 thumbprint
+func parseJWK(raw []byte) (crypto.PublicKey, string, error) {
+	var k jsonWebKey
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return nil, "", fmt.Errorf("invalid JWK: %w", err)
+	}
+	b64 := base64.RawURLEncoding
+	var pub crypto.PublicKey
+	var canonical string
+	switch k.Kty {
+	case "RSA":
+		n, err1 := b64.DecodeString(k.N)
+		e, err2 := b64.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, "", fmt.Errorf("invalid RSA JWK")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, "", fmt.Errorf("RSA account keys must be at least 2048 bits")
+		}
+		pub = key
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, "", fmt.Errorf("unsupported EC curve %q", k.Crv)
+		}
+		x, err1 := b64.DecodeString(k.X)
+		y, err2 := b64.DecodeString(k.Y)
+		if err1 != nil || err2 != nil {
+			return nil, "", fmt.Errorf("invalid EC JWK")
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, "", fmt.Errorf("EC JWK point is not on the curve")
+		}
+		pub = key
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Crv, k.X, k.Y)
+	case "OKP":
+		x, err := b64.DecodeString(k.X)
+		if k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, "", fmt.Errorf("unsupported OKP JWK (only Ed25519)")
+		}
+		pub = ed25519.PublicKey(x)
+		canonical = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":%q}`, k.X)
+	default:
+		return nil, "", fmt.Errorf("unsupported JWK key type %q", k.Kty)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return pub, b64.EncodeToString(sum[:]), nil
+}
+
+// verifyJWS checks a JWS signature made with alg by pub
+func verifyJWS(pub crypto.PublicKey, alg string, signingInput, sig []byte) error {
+	hashFor := map[string]crypto.Hash{
+		"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+		"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+	}
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		hash, ok := hashFor[alg]
+		if !ok || alg[0] != 'R' {
+			return fmt.Errorf("algorithm %s does not match an RSA key", alg)
+		}
+		h := hash.New()
+		h.Write(signingInput)
+		return rsa.VerifyPKCS1v15(key, hash, h.Sum(nil), sig)
+	case *ecdsa.PublicKey:
+		want := map[string]string{"P-256": "ES256", "P-384": "ES384", "P-521": "ES512"}[key.Curve.Params().Name]
+		if alg != want {
+			return fmt.Errorf("algorithm %s does not match a %s key", alg, key.Curve.Params().Name)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return fmt.Errorf("ECDSA signature has the wrong length")
+		}
+		h := hashFor[alg].New()
+		h.Write(signingInput)
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(key, h.Sum(nil), r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if alg != "EdDSA" {
+			return fmt.Errorf("algorithm %s does not match an Ed25519 key", alg)
+		}
+		if !ed25519.Verify(key, signingInput, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type %T", pub)
+	}
+}
@@ -0,0 +1,1280 @@
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for cert ca serve-acme
+const (
+	DefaultACMEServerListen = ":8443"
+	DefaultACMEServerDays   = 90
+)
+
+const (
+	acmeErrorPrefix    = "urn:ietf:params:acme:error:"
+	acmeNonceLifetime  = time.Hour
+	acmeOrderLifetime  = 7 * 24 * time.Hour
+	acmeHTTP01Timeout  = 10 * time.Second
+	acmeMaxRequestSize = 1 << 20
+)
+
+// ACME object statuses (RFC 8555, 7.1.6)
+const (
+	acmeStatusPending     = "pending"
+	acmeStatusProcessing  = "processing"
+	acmeStatusReady       = "ready"
+	acmeStatusValid       = "valid"
+	acmeStatusInvalid     = "invalid"
+	acmeStatusDeactivated = "deactivated"
+)
+
+// ACMEServerOptions configures an ACME server for a local CA
+type ACMEServerOptions struct {
+	CACert        string
+	CAKey         string
+	CAKeyURI      string   // instead of CAKey: a key URI (see OpenSigner)
+	CAKeyPassword string   // for an encrypted CA key, or the PKCS#11 PIN
+	StateDir      string   // accounts, orders, and certificates; defaults to DefaultACMEStatePath(CACert)
+	Days          int      // validity of issued certificates; defaults to DefaultACMEServerDays
+	Profile       *Profile // issuance profile; the server profile if nil
+
+	// AutoApprove marks every authorization valid without a challenge. It
+	// issues for any name to anyone who can reach the server, so it is only
+	// for development.
+	AutoApprove bool
+	// HTTP01Port is the port HTTP-01 challenges are fetched from; 80 unless
+	// set (for tests and port-forwarded setups)
+	HTTP01Port int
+	// ExternalURL is the base URL clients reach the server at, such as
+	// https://ca.internal:8443, when a proxy sits in front of it; otherwise
+	// it is taken from each request
+	ExternalURL string
+
+	// Log reports requests that change state; may be nil
+	Log func(format string, args ...interface{})
+}
+
+// DefaultACMEStatePath returns the directory where an ACME server for a CA
+// keeps its state: <name>-ca.acme/ next to the CA certificate
+func DefaultACMEStatePath(caCertPath string) string {
+	return strings.TrimSuffix(caCertPath, filepath.Ext(caCertPath)) + ".acme"
+}
+
+// ACMEServer is an RFC 8555 ACME server that issues certificates from a
+// local CA with SignCSR. Accounts, orders, authorizations, and issued
+// certificates are kept as files in the state directory, so they survive
+// restarts; every issued certificate is also recorded in the CA's index.
+type ACMEServer struct {
+	opts    ACMEServerOptions
+	caCert  *x509.Certificate
+	chain   [][]byte // issuer certificates sent after the leaf
+	profile *Profile
+
+	mu       sync.Mutex
+	accounts map[string]*acmeServerAccount
+	byKey    map[string]string // account key thumbprint -> account ID
+	orders   map[string]*acmeServerOrder
+	authzs   map[string]*acmeServerAuthz
+	nonces   map[string]time.Time
+
+	// issueMu serializes writes to the CA index and CRL (SignCSR in issue,
+	// Revoke in handleRevoke); it's never held with mu
+	issueMu sync.Mutex
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	validate sync.WaitGroup
+}
+
+// acmeIdentifier is an ACME identifier (RFC 8555, 9.7.7)
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// acmeProblem is an RFC 7807 problem document, as ACME errors are reported
+type acmeProblem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status,omitempty"`
+}
+
+func (p *acmeProblem) Error() string {
+	return p.Detail
+}
+
+func newACMEProblem(status int, typ, format string, args ...interface{}) *acmeProblem {
+	return &acmeProblem{Type: acmeErrorPrefix + typ, Detail: fmt.Sprintf(format, args...), Status: status}
+}
+
+// acmeServerAccount is an account as stored in accounts/<id>.json
+type acmeServerAccount struct {
+	ID         string          `json:"id"`
+	Status     string          `json:"status"`
+	Contact    []string        `json:"contact,omitempty"`
+	JWK        json.RawMessage `json:"jwk"`
+	Thumbprint string          `json:"thumbprint"`
+	CreatedAt  time.Time       `json:"created_at"`
+
+	key crypto.PublicKey
+}
+
+// acmeServerOrder is an order as stored in orders/<id>.json
+type acmeServerOrder struct {
+	ID             string           `json:"id"`
+	Account        string           `json:"account"`
+	Status         string           `json:"status"`
+	Expires        time.Time        `json:"expires"`
+	Identifiers    []acmeIdentifier `json:"identifiers"`
+	Authorizations []string         `json:"authorizations"`
+	Error          *acmeProblem     `json:"error,omitempty"`
+	Serial         string           `json:"serial,omitempty"` // of the issued certificate, in hex
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// acmeServerAuthz is an authorization as stored in authz/<id>.json, with
+// its challenges
+type acmeServerAuthz struct {
+	ID         string                 `json:"id"`
+	Account    string                 `json:"account"`
+	Order      string                 `json:"order"`
+	Identifier acmeIdentifier         `json:"identifier"`
+	Wildcard   bool                   `json:"wildcard,omitempty"`
+	Status     string                 `json:"status"`
+	Expires    time.Time              `json:"expires"`
+	Challenges []*acmeServerChallenge `json:"challenges"`
+}
+
+// acmeServerChallenge is one way to complete an authorization
+type acmeServerChallenge struct {
+	Type      string       `json:"type"`
+	Token     string       `json:"token"`
+	Status    string       `json:"status"`
+	Validated *time.Time   `json:"validated,omitempty"`
+	Error     *acmeProblem `json:"error,omitempty"`
+}
+
+// acmeRequest is a verified JWS request
+type acmeRequest struct {
+	payload    []byte
+	account    *acmeServerAccount // signer, for kid requests
+	jwk        json.RawMessage    // signer, for jwk requests
+	key        crypto.PublicKey
+	thumbprint string
+}
+
+// NewACMEServer loads the CA and the server's state. The CA key is opened
+// once to check it, and again for each issuance.
+func NewACMEServer(opts ACMEServerOptions) (*ACMEServer, error) {
+	caCert, caKey, err := loadCA(opts.CACert, opts.CAKey, opts.CAKeyURI, opts.CAKeyPassword)
+	if err != nil {
+		return nil, err
+	}
+	caKey.Close()
+	if !caCert.IsCA {
+		return nil, fmt.Errorf("%s is not a CA certificate", opts.CACert)
+	}
+
+	if opts.StateDir == "" {
+		opts.StateDir = DefaultACMEStatePath(opts.CACert)
+	}
+	if opts.Days <= 0 {
+		opts.Days = DefaultACMEServerDays
+	}
+	if opts.HTTP01Port == 0 {
+		opts.HTTP01Port = 80
+	}
+	opts.ExternalURL = strings.TrimSuffix(opts.ExternalURL, "/")
+	profile := opts.Profile
+	if profile == nil {
+		if profile, err = FindProfile("server", nil); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &ACMEServer{
+		opts:     opts,
+		caCert:   caCert,
+		profile:  profile,
+		accounts: map[string]*acmeServerAccount{},
+		byKey:    map[string]string{},
+		orders:   map[string]*acmeServerOrder{},
+		authzs:   map[string]*acmeServerAuthz{},
+		nonces:   map[string]time.Time{},
+	}
+	// A root is left out of the chain; clients already trust it
+	if !bytes.Equal(caCert.RawIssuer, caCert.RawSubject) || caCert.CheckSignatureFrom(caCert) != nil {
+		s.chain = [][]byte{caCert.Raw}
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s, nil
+}
+
+// Close stops challenge validations in progress
+func (s *ACMEServer) Close() {
+	s.cancel()
+	s.validate.Wait()
+}
+
+// StateDir is where the server keeps its accounts, orders, and certificates
+func (s *ACMEServer) StateDir() string {
+	return s.opts.StateDir
+}
+
+// ServingCertificate issues the server's own TLS certificate from the CA
+// for hosts, so clients that trust the CA trust the server. It is kept in
+// memory only and not recorded in the CA index.
+func (s *ACMEServer) ServingCertificate(hosts []string) (tls.Certificate, error) {
+	caCert, caKey, err := loadCA(s.opts.CACert, s.opts.CAKey, s.opts.CAKeyURI, s.opts.CAKeyPassword)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	defer caKey.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	notAfter := time.Now().AddDate(1, 0, 0)
+	if caCert.NotAfter.Before(notAfter) {
+		notAfter = caCert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	template.Subject.CommonName = "certwiz ACME server"
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to issue the server's certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: append([][]byte{der}, s.chain...), PrivateKey: key}, nil
+}
+
+func (s *ACMEServer) logf(format string, args ...interface{}) {
+	if s.opts.Log != nil {
+		s.opts.Log(format, args...)
+	}
+}
+
+// ServeHTTP serves the ACME API: the directory at /directory and the
+// resources it links to
+func (s *ACMEServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	base := s.opts.ExternalURL
+	if base == "" {
+		scheme := "https"
+		if r.TLS == nil {
+			scheme = "http"
+		}
+		base = scheme + "://" + r.Host
+	}
+	w.Header().Set("Link", fmt.Sprintf("<%s/directory>;rel=\"index\"", base))
+
+	switch r.URL.Path {
+	case "/directory":
+		if r.Method != http.MethodGet {
+			s.fail(w, newACMEProblem(http.StatusMethodNotAllowed, "malformed", "use GET for the directory"))
+			return
+		}
+		s.reply(w, http.StatusOK, map[string]interface{}{
+			"newNonce":   base + "/new-nonce",
+			"newAccount": base + "/new-account",
+			"newOrder":   base + "/new-order",
+			"revokeCert": base + "/revoke-cert",
+			"meta":       map[string]interface{}{"externalAccountRequired": false},
+		})
+		return
+	case "/new-nonce":
+		w.Header().Set("Replay-Nonce", s.newNonce())
+		w.Header().Set("Cache-Control", "no-store")
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+	if r.Method != http.MethodPost {
+		s.fail(w, newACMEProblem(http.StatusMethodNotAllowed, "malformed", "ACME resources take POST requests"))
+		return
+	}
+	w.Header().Set("Replay-Nonce", s.newNonce())
+
+	req, prob := s.verifyRequest(r, base)
+	if prob != nil {
+		s.fail(w, prob)
+		return
+	}
+
+	resource, id, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if resource != "new-account" && resource != "revoke-cert" && req.account == nil {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "this request must be signed with the account key (kid)"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Checked under the lock, since handleAccount may deactivate it
+	if req.account != nil && req.account.Status != acmeStatusValid {
+		s.fail(w, newACMEProblem(http.StatusUnauthorized, "unauthorized", "account is %s", req.account.Status))
+		return
+	}
+	switch resource {
+	case "new-account":
+		s.handleNewAccount(w, req, base)
+	case "account":
+		s.handleAccount(w, req, base, id)
+	case "orders":
+		s.handleOrderList(w, req, base, id)
+	case "new-order":
+		s.handleNewOrder(w, req, base)
+	case "order":
+		s.handleOrder(w, req, base, id)
+	case "authz":
+		s.handleAuthz(w, req, base, id)
+	case "chall":
+		s.handleChallenge(w, req, base, id)
+	case "finalize":
+		s.handleFinalize(w, req, base, id)
+	case "cert":
+		s.handleCertificate(w, req, id)
+	case "revoke-cert":
+		s.handleRevoke(w, req)
+	default:
+		s.fail(w, newACMEProblem(http.StatusNotFound, "malformed", "no such resource %s", r.URL.Path))
+	}
+}
+
+// verifyRequest checks a POST's JWS: its nonce, URL, and signature by the
+// account key (kid) or an embedded key (jwk)
+func (s *ACMEServer) verifyRequest(r *http.Request, base string) (*acmeRequest, *acmeProblem) {
+	if ct := r.Header.Get("Content-Type"); ct != "application/jose+json" {
+		return nil, newACMEProblem(http.StatusUnsupportedMediaType, "malformed", "Content-Type must be application/jose+json, not %q", ct)
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, acmeMaxRequestSize))
+	if err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "failed to read request: %v", err)
+	}
+	var msg jwsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "request is not a flattened JWS: %v", err)
+	}
+	protected, err := base64.RawURLEncoding.DecodeString(msg.Protected)
+	if err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "invalid JWS protected header encoding")
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(protected, &header); err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "invalid JWS protected header: %v", err)
+	}
+	if !s.consumeNonce(header.Nonce) {
+		return nil, newACMEProblem(http.StatusBadRequest, "badNonce", "JWS has an invalid or reused nonce")
+	}
+	if url := base + r.URL.Path; header.URL != url {
+		return nil, newACMEProblem(http.StatusUnauthorized, "unauthorized", "JWS url %q does not match the request URL %q", header.URL, url)
+	}
+
+	req := &acmeRequest{}
+	switch {
+	case len(header.JWK) > 0 && header.KID != "":
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "JWS must have either jwk or kid, not both")
+	case len(header.JWK) > 0:
+		if req.key, req.thumbprint, err = parseJWK(header.JWK); err != nil {
+			return nil, newACMEProblem(http.StatusBadRequest, "badPublicKey", "%v", err)
+		}
+		req.jwk = header.JWK
+	case header.KID != "":
+		id, ok := strings.CutPrefix(header.KID, base+"/account/")
+		s.mu.Lock()
+		account := s.accounts[id]
+		s.mu.Unlock()
+		if !ok || account == nil {
+			return nil, newACMEProblem(http.StatusBadRequest, "accountDoesNotExist", "no account %s", header.KID)
+		}
+		req.account, req.key = account, account.key
+	default:
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "JWS has neither jwk nor kid")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "invalid JWS signature encoding")
+	}
+	if err := verifyJWS(req.key, header.Alg, []byte(msg.Protected+"."+msg.Payload), sig); err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "badSignatureAlgorithm", "JWS signature does not verify: %v", err)
+	}
+	if req.payload, err = base64.RawURLEncoding.DecodeString(msg.Payload); err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "invalid JWS payload encoding")
+	}
+	return req, nil
+}
+
+func (s *ACMEServer) handleNewAccount(w http.ResponseWriter, req *acmeRequest, base string) {
+	if req.jwk == nil {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "newAccount must be signed with a jwk"))
+		return
+	}
+	var payload struct {
+		Contact            []string `json:"contact"`
+		OnlyReturnExisting bool     `json:"onlyReturnExisting"`
+	}
+	if err := json.Unmarshal(req.payload, &payload); err != nil {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "invalid newAccount request: %v", err))
+		return
+	}
+
+	if id, ok := s.byKey[req.thumbprint]; ok {
+		account := s.accounts[id]
+		if account.Status != acmeStatusValid {
+			s.fail(w, newACMEProblem(http.StatusUnauthorized, "unauthorized", "account is %s", account.Status))
+			return
+		}
+		w.Header().Set("Location", base+"/account/"+id)
+		s.reply(w, http.StatusOK, s.accountView(account, base))
+		return
+	}
+	if payload.OnlyReturnExisting {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "accountDoesNotExist", "no account exists for this key"))
+		return
+	}
+	if prob := checkACMEContacts(payload.Contact); prob != nil {
+		s.fail(w, prob)
+		return
+	}
+
+	account := &acmeServerAccount{
+		ID:         newACMEID(),
+		Status:     acmeStatusValid,
+		Contact:    payload.Contact,
+		JWK:        req.jwk,
+		Thumbprint: req.thumbprint,
+		CreatedAt:  time.Now().UTC(),
+		key:        req.key,
+	}
+	if prob := s.save("accounts", account.ID, account); prob != nil {
+		s.fail(w, prob)
+		return
+	}
+	s.accounts[account.ID] = account
+	s.byKey[account.Thumbprint] = account.ID
+	s.logf("Account %s registered (%s)", account.ID, strings.Join(account.Contact, ", "))
+
+	w.Header().Set("Location", base+"/account/"+account.ID)
+	s.reply(w, http.StatusCreated, s.accountView(account, base))
+}
+
+func (s *ACMEServer) handleAccount(w http.ResponseWriter, req *acmeRequest, base, id string) {
+	if req.account.ID != id {
+		s.fail(w, newACMEProblem(http.StatusForbidden, "unauthorized", "account %s belongs to another key", id))
+		return
+	}
+	account := req.account
+	if len(req.payload) > 0 {
+		var payload struct {
+			Status  string   `json:"status"`
+			Contact []string `json:"contact"`
+		}
+		if err := json.Unmarshal(req.payload, &payload); err != nil {
+			s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "invalid account update: %v", err))
+			return
+		}
+		if payload.Status != "" && payload.Status != acmeStatusDeactivated {
+			s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "account status can only be changed to deactivated"))
+			return
+		}
+		if payload.Contact != nil {
+			if prob := checkACMEContacts(payload.Contact); prob != nil {
+				s.fail(w, prob)
+				return
+			}
+		}
+
+		// Apply the update only once it's saved
+		updated := *account
+		if payload.Status != "" {
+			updated.Status = payload.Status
+		}
+		if payload.Contact != nil {
+			updated.Contact = payload.Contact
+		}
+		if prob := s.save("accounts", account.ID, &updated); prob != nil {
+			s.fail(w, prob)
+			return
+		}
+		account.Status, account.Contact = updated.Status, updated.Contact
+		if payload.Status != "" {
+			s.logf("Account %s deactivated", account.ID)
+		}
+	}
+	s.reply(w, http.StatusOK, s.accountView(account, base))
+}
+
+func (s *ACMEServer) handleOrderList(w http.ResponseWriter, req *acmeRequest, base, id string) {
+	if req.account.ID != id {
+		s.fail(w, newACMEProblem(http.StatusForbidden, "unauthorized", "account %s belongs to another key", id))
+		return
+	}
+	urls := []string{}
+	for _, o := range s.sortedOrders() {
+		if o.Account == id {
+			urls = append(urls, base+"/order/"+o.ID)
+		}
+	}
+	s.reply(w, http.StatusOK, map[string]interface{}{"orders": urls})
+}
+
+func (s *ACMEServer) handleNewOrder(w http.ResponseWriter, req *acmeRequest, base string) {
+	var payload struct {
+		Identifiers []acmeIdentifier `json:"identifiers"`
+	}
+	if err := json.Unmarshal(req.payload, &payload); err != nil {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "invalid newOrder request: %v", err))
+		return
+	}
+	if len(payload.Identifiers) == 0 {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "order has no identifiers"))
+		return
+	}
+
+	now := time.Now().UTC()
+	order := &acmeServerOrder{
+		ID:        newACMEID(),
+		Account:   req.account.ID,
+		Status:    acmeStatusPending,
+		Expires:   now.Add(acmeOrderLifetime),
+		CreatedAt: now,
+	}
+	var authzs []*acmeServerAuthz
+	seen := map[string]bool{}
+	for _, ident := range payload.Identifiers {
+		ident, prob := s.checkIdentifier(ident)
+		if prob != nil {
+			s.fail(w, prob)
+			return
+		}
+		if seen[ident.Type+":"+ident.Value] {
+			continue
+		}
+		seen[ident.Type+":"+ident.Value] = true
+		order.Identifiers = append(order.Identifiers, ident)
+
+		authz := &acmeServerAuthz{
+			ID:         newACMEID(),
+			Account:    req.account.ID,
+			Order:      order.ID,
+			Identifier: ident,
+			Status:     acmeStatusPending,
+			Expires:    order.Expires,
+			Challenges: []*acmeServerChallenge{},
+		}
+		if strings.HasPrefix(ident.Value, "*.") {
+			authz.Identifier.Value = ident.Value[2:]
+			authz.Wildcard = true
+		}
+		if s.opts.AutoApprove {
+			authz.Status = acmeStatusValid
+		} else {
+			authz.Challenges = append(authz.Challenges, &acmeServerChallenge{Type: "http-01", Token: newACMEToken(), Status: acmeStatusPending})
+		}
+		authzs = append(authzs, authz)
+		order.Authorizations = append(order.Authorizations, authz.ID)
+	}
+	s.refreshOrder(order)
+
+	for _, authz := range authzs {
+		if prob := s.save("authz", authz.ID, authz); prob != nil {
+			s.fail(w, prob)
+			return
+		}
+		s.authzs[authz.ID] = authz
+	}
+	if prob := s.save("orders", order.ID, order); prob != nil {
+		s.fail(w, prob)
+		return
+	}
+	s.orders[order.ID] = order
+	names := make([]string, 0, len(order.Identifiers))
+	for _, ident := range order.Identifiers {
+		names = append(names, ident.Value)
+	}
+	s.logf("Order %s for %s created by account %s", order.ID, strings.Join(names, ", "), order.Account)
+
+	w.Header().Set("Location", base+"/order/"+order.ID)
+	s.reply(w, http.StatusCreated, s.orderView(order, base))
+}
+
+// checkIdentifier normalizes an identifier and rejects what this server
+// can't issue for
+func (s *ACMEServer) checkIdentifier(ident acmeIdentifier) (acmeIdentifier, *acmeProblem) {
+	switch ident.Type {
+	case "dns":
+		name := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(ident.Value)), ".")
+		bare := strings.TrimPrefix(name, "*.")
+		if bare == "" || strings.ContainsAny(bare, "*/:@ ") || net.ParseIP(bare) != nil {
+			return ident, newACMEProblem(http.StatusBadRequest, "rejectedIdentifier", "invalid DNS name %q", ident.Value)
+		}
+		if bare != name && !s.opts.AutoApprove {
+			return ident, newACMEProblem(http.StatusBadRequest, "rejectedIdentifier", "wildcard %s needs dns-01, which this server does not offer; run it with auto-approve", ident.Value)
+		}
+		return acmeIdentifier{Type: "dns", Value: name}, nil
+	case "ip":
+		ip := net.ParseIP(ident.Value)
+		if ip == nil {
+			return ident, newACMEProblem(http.StatusBadRequest, "rejectedIdentifier", "invalid IP address %q", ident.Value)
+		}
+		return acmeIdentifier{Type: "ip", Value: ip.String()}, nil
+	default:
+		return ident, newACMEProblem(http.StatusBadRequest, "unsupportedIdentifier", "identifier type %q is not supported (use dns or ip)", ident.Type)
+	}
+}
+
+func (s *ACMEServer) handleOrder(w http.ResponseWriter, req *acmeRequest, base, id string) {
+	order := s.orders[id]
+	if order == nil || order.Account != req.account.ID {
+		s.fail(w, newACMEProblem(http.StatusNotFound, "malformed", "no order %s for this account", id))
+		return
+	}
+	s.refreshOrder(order)
+	if order.Status == acmeStatusProcessing {
+		w.Header().Set("Retry-After", "1")
+	}
+	s.reply(w, http.StatusOK, s.orderView(order, base))
+}
+
+func (s *ACMEServer) handleAuthz(w http.ResponseWriter, req *acmeRequest, base, id string) {
+	authz := s.authzs[id]
+	if authz == nil || authz.Account != req.account.ID {
+		s.fail(w, newACMEProblem(http.StatusNotFound, "malformed", "no authorization %s for this account", id))
+		return
+	}
+	if len(req.payload) > 0 {
+		var payload struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(req.payload, &payload); err != nil || payload.Status != acmeStatusDeactivated {
+			s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "an authorization can only be deactivated"))
+			return
+		}
+		authz.Status = acmeStatusDeactivated
+		if prob := s.save("authz", authz.ID, authz); prob != nil {
+			s.fail(w, prob)
+			return
+		}
+	}
+	if authz.Status == acmeStatusPending && time.Now().After(authz.Expires) {
+		authz.Status = "expired"
+	}
+	s.reply(w, http.StatusOK, s.authzView(authz, base))
+}
+
+func (s *ACMEServer) handleChallenge(w http.ResponseWriter, req *acmeRequest, base, id string) {
+	authzID, typ, _ := strings.Cut(id, "/")
+	authz := s.authzs[authzID]
+	if authz == nil || authz.Account != req.account.ID {
+		s.fail(w, newACMEProblem(http.StatusNotFound, "malformed", "no challenge %s for this account", id))
+		return
+	}
+	var chal *acmeServerChallenge
+	for _, c := range authz.Challenges {
+		if c.Type == typ {
+			chal = c
+		}
+	}
+	if chal == nil {
+		s.fail(w, newACMEProblem(http.StatusNotFound, "malformed", "no challenge %s", id))
+		return
+	}
+
+	// A POST of {} asks the server to validate; POST-as-GET only reads
+	if len(req.payload) > 0 && chal.Status == acmeStatusPending && authz.Status == acmeStatusPending {
+		chal.Status = acmeStatusProcessing
+		keyAuth := chal.Token + "." + req.account.Thumbprint
+		s.validate.Add(1)
+		go s.validateHTTP01(authz, chal, keyAuth)
+	}
+	w.Header().Add("Link", fmt.Sprintf("<%s/authz/%s>;rel=\"up\"", base, authz.ID))
+	s.reply(w, http.StatusOK, s.challengeView(authz, chal, base))
+}
+
+// validateHTTP01 fetches the key authorization from the identifier's HTTP
+// server and records the outcome in the challenge, authorization, and order
+func (s *ACMEServer) validateHTTP01(authz *acmeServerAuthz, chal *acmeServerChallenge, keyAuth string) {
+	defer s.validate.Done()
+
+	s.mu.Lock()
+	host, token := authz.Identifier.Value, chal.Token
+	s.mu.Unlock()
+	err := fetchHTTP01(s.ctx, host, s.opts.HTTP01Port, token, keyAuth)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		chal.Status, chal.Error = acmeStatusInvalid, err
+		authz.Status = acmeStatusInvalid
+		s.logf("HTTP-01 validation of %s failed: %s", host, err.Detail)
+	} else {
+		now := time.Now().UTC()
+		chal.Status, chal.Validated = acmeStatusValid, &now
+		authz.Status = acmeStatusValid
+		s.logf("HTTP-01 validation of %s succeeded", host)
+	}
+	_ = s.save("authz", authz.ID, authz)
+	if order := s.orders[authz.Order]; order != nil {
+		s.refreshOrder(order)
+		_ = s.save("orders", order.ID, order)
+	}
+}
+
+// fetchHTTP01 checks that http://host:port/.well-known/acme-challenge/token
+// serves keyAuth
+func fetchHTTP01(ctx context.Context, host string, port int, token, keyAuth string) *acmeProblem {
+	url := "http://" + net.JoinHostPort(host, strconv.Itoa(port)) + "/.well-known/acme-challenge/" + token
+	ctx, cancel := context.WithTimeout(ctx, acmeHTTP01Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return newACMEProblem(http.StatusBadRequest, "connection", "%v", err)
+	}
+	client := &http.Client{Transport: &http.Transport{}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return newACMEProblem(http.StatusBadRequest, "connection", "fetching %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return newACMEProblem(http.StatusForbidden, "unauthorized", "fetching %s: %s", url, resp.Status)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if got := strings.TrimSpace(string(body)); got != keyAuth {
+		return newACMEProblem(http.StatusForbidden, "incorrectResponse", "%s served %q, not the expected key authorization", url, got)
+	}
+	return nil
+}
+
+func (s *ACMEServer) handleFinalize(w http.ResponseWriter, req *acmeRequest, base, id string) {
+	order := s.orders[id]
+	if order == nil || order.Account != req.account.ID {
+		s.fail(w, newACMEProblem(http.StatusNotFound, "malformed", "no order %s for this account", id))
+		return
+	}
+	s.refreshOrder(order)
+	if order.Status != acmeStatusReady {
+		s.fail(w, newACMEProblem(http.StatusForbidden, "orderNotReady", "order is %s, not ready", order.Status))
+		return
+	}
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	if err := json.Unmarshal(req.payload, &payload); err != nil {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "invalid finalize request: %v", err))
+		return
+	}
+	der, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "badCSR", "invalid CSR encoding"))
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err == nil {
+		err = csr.CheckSignature()
+	}
+	if err != nil {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "badCSR", "invalid CSR: %v", err))
+		return
+	}
+	if prob := checkCSRIdentifiers(csr, order.Identifiers); prob != nil {
+		s.fail(w, prob)
+		return
+	}
+
+	// Sign without holding the lock; the CA key may be slow (an HSM, or
+	// an external signer)
+	order.Status = acmeStatusProcessing
+	s.mu.Unlock()
+	serial, err := s.issue(order, der)
+	s.mu.Lock()
+	if err != nil {
+		order.Status = acmeStatusInvalid
+		order.Error = newACMEProblem(http.StatusInternalServerError, "serverInternal", "issuance failed: %v", err)
+		_ = s.save("orders", order.ID, order)
+		s.logf("Order %s failed: %v", order.ID, err)
+		s.fail(w, order.Error)
+		return
+	}
+	order.Status, order.Serial = acmeStatusValid, serial
+	if prob := s.save("orders", order.ID, order); prob != nil {
+		s.fail(w, prob)
+		return
+	}
+	s.logf("Order %s issued certificate %s", order.ID, serial)
+
+	w.Header().Set("Location", base+"/order/"+order.ID)
+	s.reply(w, http.StatusOK, s.orderView(order, base))
+}
+
+// issue signs an order's CSR with SignCSR, keeping the CSR and certificate
+// in the state directory, and returns the serial number in hex
+func (s *ACMEServer) issue(order *acmeServerOrder, csrDER []byte) (string, error) {
+	s.issueMu.Lock()
+	defer s.issueMu.Unlock()
+
+	csrPath := filepath.Join(s.opts.StateDir, "certs", order.ID+".csr")
+	if err := os.WriteFile(csrPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}), 0600); err != nil {
+		return "", fmt.Errorf("failed to save CSR: %w", err)
+	}
+	// The certificate names exactly what was authorized, even if the CSR
+	// put one of them only in the common name
+	var sans []string
+	for _, ident := range order.Identifiers {
+		if ident.Type == "ip" {
+			sans = append(sans, "ip:"+ident.Value)
+		} else {
+			sans = append(sans, ident.Value)
+		}
+	}
+	certPath := filepath.Join(s.opts.StateDir, "certs", order.ID+".crt")
+	err := SignCSR(SignOptions{
+		CSRPath:       csrPath,
+		CACert:        s.opts.CACert,
+		CAKey:         s.opts.CAKey,
+		CAKeyURI:      s.opts.CAKeyURI,
+		CAKeyPassword: s.opts.CAKeyPassword,
+		Days:          s.opts.Days,
+		SANs:          sans,
+		Profile:       s.profile,
+	}, certPath)
+	if err != nil {
+		return "", err
+	}
+	issued, err := InspectFile(certPath)
+	if err != nil {
+		return "", err
+	}
+	return issued.SerialNumber.Text(16), nil
+}
+
+func (s *ACMEServer) handleCertificate(w http.ResponseWriter, req *acmeRequest, id string) {
+	order := s.orders[id]
+	if order == nil || order.Account != req.account.ID || order.Status != acmeStatusValid {
+		s.fail(w, newACMEProblem(http.StatusNotFound, "malformed", "no certificate %s for this account", id))
+		return
+	}
+	leaf, err := os.ReadFile(filepath.Join(s.opts.StateDir, "certs", order.ID+".crt"))
+	if err != nil {
+		s.fail(w, newACMEProblem(http.StatusInternalServerError, "serverInternal", "failed to read certificate: %v", err))
+		return
+	}
+	for _, der := range s.chain {
+		leaf = append(leaf, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(leaf)
+}
+
+func (s *ACMEServer) handleRevoke(w http.ResponseWriter, req *acmeRequest) {
+	var payload struct {
+		Certificate string `json:"certificate"`
+		Reason      *int   `json:"reason"`
+	}
+	if err := json.Unmarshal(req.payload, &payload); err != nil {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "invalid revokeCert request: %v", err))
+		return
+	}
+	der, err := base64.RawURLEncoding.DecodeString(payload.Certificate)
+	if err != nil {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "invalid certificate encoding"))
+		return
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "malformed", "invalid certificate: %v", err))
+		return
+	}
+	reason := 0
+	if payload.Reason != nil {
+		reason = *payload.Reason
+	}
+	if reason < 0 || reason > 10 || reason == 7 {
+		s.fail(w, newACMEProblem(http.StatusBadRequest, "badRevocationReason", "invalid revocation reason %d", reason))
+		return
+	}
+
+	var order *acmeServerOrder
+	for _, o := range s.orders {
+		if o.Serial != "" && o.Serial == c.SerialNumber.Text(16) {
+			order = o
+		}
+	}
+	if order == nil || c.CheckSignatureFrom(s.caCert) != nil {
+		s.fail(w, newACMEProblem(http.StatusNotFound, "malformed", "certificate was not issued by this server"))
+		return
+	}
+	// The account that ordered the certificate, or its key, may revoke it
+	if !(req.account != nil && req.account.ID == order.Account) && !(req.key != nil && req.account == nil && publicKeysEqual(req.key, c.PublicKey)) {
+		s.fail(w, newACMEProblem(http.StatusForbidden, "unauthorized", "only the account that ordered the certificate, or its key, can revoke it"))
+		return
+	}
+
+	// Revoke without holding the lock, like issuance in handleFinalize
+	certPath, serial := filepath.Join(s.opts.StateDir, "certs", order.ID+".crt"), order.Serial
+	s.mu.Unlock()
+	s.issueMu.Lock()
+	_, _, err = Revoke(RevokeOptions{
+		CACert:        s.opts.CACert,
+		CAKey:         s.opts.CAKey,
+		CAKeyURI:      s.opts.CAKeyURI,
+		CAKeyPassword: s.opts.CAKeyPassword,
+		CertPath:      certPath,
+		Reason:        reason,
+	}, DefaultCRLPath(s.opts.CACert))
+	s.issueMu.Unlock()
+	s.mu.Lock()
+	if err != nil {
+		if strings.Contains(err.Error(), "already revoked") {
+			s.fail(w, newACMEProblem(http.StatusBadRequest, "alreadyRevoked", "%v", err))
+		} else {
+			s.fail(w, newACMEProblem(http.StatusInternalServerError, "serverInternal", "revocation failed: %v", err))
+		}
+		return
+	}
+	s.logf("Certificate %s revoked", serial)
+	w.WriteHeader(http.StatusOK)
+}
+
+// refreshOrder moves a pending order to ready once all its authorizations
+// are valid, or to invalid when one fails or the order expires
+func (s *ACMEServer) refreshOrder(order *acmeServerOrder) {
+	if order.Status != acmeStatusPending {
+		return
+	}
+	if time.Now().After(order.Expires) {
+		order.Status = acmeStatusInvalid
+		order.Error = newACMEProblem(http.StatusForbidden, "unauthorized", "order expired")
+		return
+	}
+	ready := true
+	for _, id := range order.Authorizations {
+		authz := s.authzs[id]
+		switch {
+		case authz == nil:
+			ready = false
+		case authz.Status == acmeStatusValid:
+		case authz.Status == acmeStatusPending:
+			ready = false
+		default:
+			order.Status = acmeStatusInvalid
+			order.Error = newACMEProblem(http.StatusForbidden, "unauthorized", "authorization for %s is %s", authz.Identifier.Value, authz.Status)
+			return
+		}
+	}
+	if ready {
+		order.Status = acmeStatusReady
+	}
+}
+
+func (s *ACMEServer) accountView(a *acmeServerAccount, base string) interface{} {
+	return map[string]interface{}{
+		"status":  a.Status,
+		"contact": a.Contact,
+		"orders":  base + "/orders/" + a.ID,
+	}
+}
+
+func (s *ACMEServer) orderView(o *acmeServerOrder, base string) interface{} {
+	authzURLs := make([]string, 0, len(o.Authorizations))
+	for _, id := range o.Authorizations {
+		authzURLs = append(authzURLs, base+"/authz/"+id)
+	}
+	view := map[string]interface{}{
+		"status":         o.Status,
+		"expires":        o.Expires.Format(time.RFC3339),
+		"identifiers":    o.Identifiers,
+		"authorizations": authzURLs,
+		"finalize":       base + "/finalize/" + o.ID,
+	}
+	if o.Error != nil {
+		view["error"] = o.Error
+	}
+	if o.Status == acmeStatusValid {
+		view["certificate"] = base + "/cert/" + o.ID
+	}
+	return view
+}
+
+func (s *ACMEServer) authzView(a *acmeServerAuthz, base string) interface{} {
+	challenges := make([]interface{}, 0, len(a.Challenges))
+	for _, c := range a.Challenges {
+		challenges = append(challenges, s.challengeView(a, c, base))
+	}
+	view := map[string]interface{}{
+		"identifier": a.Identifier,
+		"status":     a.Status,
+		"expires":    a.Expires.Format(time.RFC3339),
+		"challenges": challenges,
+	}
+	if a.Wildcard {
+		view["wildcard"] = true
+	}
+	return view
+}
+
+func (s *ACMEServer) challengeView(a *acmeServerAuthz, c *acmeServerChallenge, base string) interface{} {
+	view := map[string]interface{}{
+		"type":   c.Type,
+		"url":    base + "/chall/" + a.ID + "/" + c.Type,
+		"status": c.Status,
+		"token":  c.Token,
+	}
+	if c.Validated != nil {
+		view["validated"] = c.Validated.Format(time.RFC3339)
+	}
+	if c.Error != nil {
+		view["error"] = c.Error
+	}
+	return view
+}
+
+// reply writes a JSON response
+func (s *ACMEServer) reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// fail writes a problem document
+func (s *ACMEServer) fail(w http.ResponseWriter, p *acmeProblem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// newNonce issues a nonce for the next request, forgetting expired ones
+func (s *ACMEServer) newNonce() string {
+	nonce := newACMEToken()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if len(s.nonces) > 1000 {
+		for n, issued := range s.nonces {
+			if now.Sub(issued) > acmeNonceLifetime {
+				delete(s.nonces, n)
+			}
+		}
+	}
+	s.nonces[nonce] = now
+	return nonce
+}
+
+// consumeNonce reports whether a nonce was issued and not yet used
+func (s *ACMEServer) consumeNonce(nonce string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	issued, ok := s.nonces[nonce]
+	delete(s.nonces, nonce)
+	return ok && time.Since(issued) <= acmeNonceLifetime
+}
+
+// save writes an object to <state>/<kind>/<id>.json
+func (s *ACMEServer) save(kind, id string, v interface{}) *acmeProblem {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(s.opts.StateDir, kind, id+".json"), append(data, '\n'), 0600)
+	}
+	if err != nil {
+		s.logf("Failed to save %s %s: %v", kind, id, err)
+		return newACMEProblem(http.StatusInternalServerError, "serverInternal", "failed to save %s", kind)
+	}
+	return nil
+}
+
+// load reads the accounts, orders, and authorizations in the state
+// directory, creating it on first use
+func (s *ACMEServer) load() error {
+	for _, kind := range []string{"accounts", "orders", "authz", "certs"} {
+		if err := os.MkdirAll(filepath.Join(s.opts.StateDir, kind), 0700); err != nil {
+			return fmt.Errorf("failed to create ACME state directory: %w", err)
+		}
+	}
+	each := func(kind string, fn func(data []byte) error) error {
+		paths, err := filepath.Glob(filepath.Join(s.opts.StateDir, kind, "*.json"))
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err == nil {
+				err = fn(data)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to load %s: %w", path, err)
+			}
+		}
+		return nil
+	}
+
+	err := each("accounts", func(data []byte) error {
+		a := &acmeServerAccount{}
+		if err := json.Unmarshal(data, a); err != nil {
+			return err
+		}
+		key, thumbprint, err := parseJWK(a.JWK)
+		if err != nil {
+			return err
+		}
+		a.key, a.Thumbprint = key, thumbprint
+		s.accounts[a.ID] = a
+		s.byKey[thumbprint] = a.ID
+		return nil
+	})
+	if err == nil {
+		err = each("orders", func(data []byte) error {
+			o := &acmeServerOrder{}
+			if err := json.Unmarshal(data, o); err != nil {
+				return err
+			}
+			// An issuance interrupted by a restart never completed
+			if o.Status == acmeStatusProcessing {
+				o.Status = acmeStatusReady
+			}
+			s.orders[o.ID] = o
+			return nil
+		})
+	}
+	if err == nil {
+		err = each("authz", func(data []byte) error {
+			a := &acmeServerAuthz{}
+			if err := json.Unmarshal(data, a); err != nil {
+				return err
+			}
+			// Validations don't survive a restart; the client can retry
+			for _, c := range a.Challenges {
+				if c.Status == acmeStatusProcessing {
+					c.Status = acmeStatusPending
+				}
+			}
+			s.authzs[a.ID] = a
+			return nil
+		})
+	}
+	return err
+}
+
+// sortedOrders returns the orders, oldest first
+func (s *ACMEServer) sortedOrders() []*acmeServerOrder {
+	orders := make([]*acmeServerOrder, 0, len(s.orders))
+	for _, o := range s.orders {
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.Before(orders[j].CreatedAt) })
+	return orders
+}
+
+// checkACMEContacts accepts mailto: contacts only
+func checkACMEContacts(contacts []string) *acmeProblem {
+	for _, c := range contacts {
+		addr, ok := strings.CutPrefix(c, "mailto:")
+		if !ok {
+			return newACMEProblem(http.StatusBadRequest, "unsupportedContact", "contact %q must be a mailto: URL", c)
+		}
+		if !strings.Contains(addr, "@") || strings.ContainsAny(addr, ",?") {
+			return newACMEProblem(http.StatusBadRequest, "invalidContact", "invalid email address %q", addr)
+		}
+	}
+	return nil
+}
+
+// checkCSRIdentifiers requires a CSR to ask for exactly the order's names
+func checkCSRIdentifiers(csr *x509.CertificateRequest, identifiers []acmeIdentifier) *acmeProblem {
+	want := map[string]bool{}
+	for _, ident := range identifiers {
+		want[ident.Value] = true
+	}
+	got := map[string]bool{}
+	for _, name := range csr.DNSNames {
+		got[strings.ToLower(name)] = true
+	}
+	for _, ip := range csr.IPAddresses {
+		got[ip.String()] = true
+	}
+	if cn := strings.ToLower(csr.Subject.CommonName); cn != "" {
+		if ip := net.ParseIP(cn); ip != nil {
+			cn = ip.String()
+		}
+		if !want[cn] {
+			return newACMEProblem(http.StatusBadRequest, "badCSR", "CSR common name %q is not in the order", csr.Subject.CommonName)
+		}
+		got[cn] = true
+	}
+	for name := range got {
+		if !want[name] {
+			return newACMEProblem(http.StatusBadRequest, "badCSR", "CSR asks for %s, which is not in the order", name)
+		}
+	}
+	for name := range want {
+		if !got[name] {
+			return newACMEProblem(http.StatusBadRequest, "badCSR", "CSR does not ask for %s", name)
+		}
+	}
+	if len(csr.EmailAddresses) > 0 || len(csr.URIs) > 0 {
+		return newACMEProblem(http.StatusBadRequest, "badCSR", "CSR may only ask for DNS names and IP addresses")
+	}
+	return nil
+}
+
+// newACMEID returns a random identifier for a stored object
+func newACMEID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(errors.New("crypto/rand failed: " + err.Error()))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// newACMEToken returns a random nonce or challenge token
+func newACMEToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(errors.New("crypto/rand failed: " + err.Error()))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,368 @@
+package cert
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// startTestACMEServer runs an ACME server for a new CA over TLS, with a
+// serving certificate from that CA
+func startTestACMEServer(t *testing.T, opts ACMEServerOptions) (*ACMEServer, *httptest.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	opts.CACert = filepath.Join(dir, "dev-ca.crt")
+	opts.CAKey = filepath.Join(dir, "dev-ca.key")
+	if err := GenerateCA(CAOptions{CommonName: "Dev CA", Days: 365, KeyAlgorithm: KeyAlgorithmECDSAP256}, opts.CACert, opts.CAKey); err != nil {
+		t.Fatalf("GenerateCA failed: %v", err)
+	}
+	srv, err := NewACMEServer(opts)
+	if err != nil {
+		t.Fatalf("NewACMEServer failed: %v", err)
+	}
+	t.Cleanup(srv.Close)
+
+	serving, err := srv.ServingCertificate([]string{"127.0.0.1", "localhost"})
+	if err != nil {
+		t.Fatalf("ServingCertificate failed: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(srv)
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{serving}}
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+// freePort returns a TCP port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestACMEServerHTTP01(t *testing.T) {
+	port := freePort(t)
+	srv, ts := startTestACMEServer(t, ACMEServerOptions{HTTP01Port: port, Days: 30})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	dir := t.TempDir()
+	opts := ACMEOptions{
+		DirectoryURL: ts.URL + "/directory",
+		AccountDir:   filepath.Join(dir, "accounts"),
+		CABundle:     srv.opts.CACert,
+		Email:        "dev@example.com",
+	}
+	account, err := RegisterACMEAccount(ctx, opts)
+	if err != nil {
+		t.Fatalf("RegisterACMEAccount failed: %v", err)
+	}
+	if !account.Created || !strings.HasPrefix(account.URI, ts.URL+"/account/") || account.Status != acmeStatusValid {
+		t.Errorf("Unexpected account: %+v", account)
+	}
+
+	csrPath := filepath.Join(dir, "localhost.csr")
+	if err := GenerateCSR(CSROptions{CommonName: "localhost", SANs: []string{"localhost"}, KeyAlgorithm: KeyAlgorithmECDSAP256}, csrPath, filepath.Join(dir, "localhost.key")); err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+	certPath := filepath.Join(dir, "localhost.crt")
+	result, err := ObtainACMECertificate(ctx, ACMEOrderOptions{
+		ACMEOptions: opts,
+		CSRPath:     csrPath,
+		HTTPListen:  ":" + strconv.Itoa(port),
+		CertPath:    certPath,
+	})
+	if err != nil {
+		t.Fatalf("ObtainACMECertificate failed: %v", err)
+	}
+
+	issued := result.Certificate
+	if len(issued.DNSNames) != 1 || issued.DNSNames[0] != "localhost" {
+		t.Errorf("DNSNames = %v, want [localhost]", issued.DNSNames)
+	}
+	if days := issued.NotAfter.Sub(issued.NotBefore).Hours() / 24; days < 29 || days > 31 {
+		t.Errorf("Validity is %.0f days, want 30", days)
+	}
+	// The CA is a root, so only the leaf is served
+	if result.ChainLength != 0 {
+		t.Errorf("ChainLength = %d, want 0", result.ChainLength)
+	}
+	if v, err := Verify(certPath, srv.opts.CACert, "localhost"); err != nil || !v.IsValid {
+		t.Errorf("Issued certificate does not verify against the CA: %+v, %v", v, err)
+	}
+	idx, err := LoadCAIndex(CAIndexPath(srv.opts.CACert))
+	if err != nil || idx.Find(issued.SerialNumber) == nil {
+		t.Errorf("Issued certificate is not in the CA index (%v)", err)
+	}
+
+	// State survives a restart
+	reloaded, err := NewACMEServer(srv.opts)
+	if err != nil {
+		t.Fatalf("Reloading the server failed: %v", err)
+	}
+	defer reloaded.Close()
+	if len(reloaded.accounts) != 1 || len(reloaded.orders) != 1 || len(reloaded.authzs) != 1 {
+		t.Fatalf("Reloaded %d account(s), %d order(s), %d authorization(s), want 1 each", len(reloaded.accounts), len(reloaded.orders), len(reloaded.authzs))
+	}
+	for _, o := range reloaded.orders {
+		if o.Status != acmeStatusValid || o.Serial != issued.SerialNumber.Text(16) {
+			t.Errorf("Reloaded order: %+v", o)
+		}
+	}
+
+	// Only the ordering account (or the certificate's key) can revoke
+	client, _, err := newACMEClient(ctx, opts)
+	if err != nil {
+		t.Fatalf("newACMEClient failed: %v", err)
+	}
+	stranger, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var acmeErr *acme.Error
+	err = client.RevokeCert(ctx, stranger, issued.Raw, acme.CRLReasonKeyCompromise)
+	if !errors.As(err, &acmeErr) || acmeErr.ProblemType != acmeErrorPrefix+"unauthorized" {
+		t.Errorf("Revocation by another key: got %v, want unauthorized", err)
+	}
+	if err := client.RevokeCert(ctx, nil, issued.Raw, acme.CRLReasonKeyCompromise); err != nil {
+		t.Fatalf("RevokeCert failed: %v", err)
+	}
+	// x/crypto/acme treats alreadyRevoked as success
+	if err := client.RevokeCert(ctx, nil, issued.Raw, acme.CRLReasonKeyCompromise); err != nil {
+		t.Errorf("Second revocation: %v", err)
+	}
+	db, err := LoadRevocationDB(DefaultRevocationDBPath(srv.opts.CACert))
+	if err != nil || len(db.Entries) != 1 || db.Find(issued.SerialNumber) == nil {
+		t.Errorf("Revocation database should hold the certificate once (%v)", err)
+	}
+}
+
+func TestACMEServerAutoApprove(t *testing.T) {
+	srv, ts := startTestACMEServer(t, ACMEServerOptions{AutoApprove: true})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	dir := t.TempDir()
+	csrPath := filepath.Join(dir, "dev.csr")
+	if err := GenerateCSR(CSROptions{CommonName: "dev.test", SANs: []string{"dev.test", "*.dev.test", "ip:192.0.2.10"}, KeyAlgorithm: KeyAlgorithmECDSAP256}, csrPath, filepath.Join(dir, "dev.key")); err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+	result, err := ObtainACMECertificate(ctx, ACMEOrderOptions{
+		ACMEOptions: ACMEOptions{DirectoryURL: ts.URL + "/directory", AccountDir: filepath.Join(dir, "accounts"), CABundle: srv.opts.CACert},
+		CSRPath:     csrPath,
+		CertPath:    filepath.Join(dir, "dev.crt"),
+	})
+	if err != nil {
+		t.Fatalf("ObtainACMECertificate failed: %v", err)
+	}
+	if got := strings.Join(result.Certificate.DNSNames, ","); got != "dev.test,*.dev.test" {
+		t.Errorf("DNSNames = %s", got)
+	}
+	if len(result.Certificate.IPAddresses) != 1 || result.Certificate.IPAddresses[0].String() != "192.0.2.10" {
+		t.Errorf("IPAddresses = %v", result.Certificate.IPAddresses)
+	}
+}
+
+func TestACMEServerRejects(t *testing.T) {
+	srv, ts := startTestACMEServer(t, ACMEServerOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &acme.Client{Key: key, DirectoryURL: ts.URL + "/directory", HTTPClient: ts.Client()}
+	if _, err := client.Register(ctx, &acme.Account{Contact: []string{"dev@example.com"}}, acme.AcceptTOS); err == nil || !strings.Contains(err.Error(), "mailto:") {
+		t.Errorf("Register with a bare email: got %v, want a mailto: error", err)
+	}
+	if _, err := client.Register(ctx, &acme.Account{}, acme.AcceptTOS); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	// Wildcards need dns-01, which is only skipped with auto-approve
+	if _, err := client.AuthorizeOrder(ctx, acme.DomainIDs("*.example.com")); err == nil || !strings.Contains(err.Error(), "wildcard") {
+		t.Errorf("Wildcard order: got %v, want a rejection", err)
+	}
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs("example.com"))
+	if err != nil {
+		t.Fatalf("AuthorizeOrder failed: %v", err)
+	}
+	if order.Status != acme.StatusPending || len(order.AuthzURLs) != 1 {
+		t.Errorf("Unexpected order: %+v", order)
+	}
+	if _, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, []byte("not a CSR"), false); err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Errorf("Finalizing a pending order: got %v, want orderNotReady", err)
+	}
+
+	// Requests that aren't signed JWS never get past the checks
+	for _, tt := range []struct {
+		method, path, contentType string
+		status                    int
+	}{
+		{http.MethodGet, "/new-order", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/new-order", "application/json", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "/new-order", "application/jose+json", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"protected":"e30","payload":"","signature":""}`))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		var problem acmeProblem
+		if rec.Code != tt.status || json.Unmarshal(rec.Body.Bytes(), &problem) != nil || !strings.HasPrefix(problem.Type, acmeErrorPrefix) {
+			t.Errorf("%s %s (%s): %d %s", tt.method, tt.path, tt.contentType, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestACMEServerDeactivate(t *testing.T) {
+	srv, ts := startTestACMEServer(t, ACMEServerOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &acme.Client{Key: key, DirectoryURL: ts.URL + "/directory", HTTPClient: ts.Client()}
+	account, err := client.Register(ctx, &acme.Account{Contact: []string{"mailto:dev@example.com"}}, acme.AcceptTOS)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	id := strings.TrimPrefix(account.URI, ts.URL+"/account/")
+
+	// A bad contact rejects the whole update, deactivation included
+	rec := httptest.NewRecorder()
+	srv.mu.Lock()
+	srv.handleAccount(rec, &acmeRequest{account: srv.accounts[id], payload: []byte(`{"status":"deactivated","contact":["dev@example.com"]}`)}, ts.URL, id)
+	status := srv.accounts[id].Status
+	srv.mu.Unlock()
+	if rec.Code != http.StatusBadRequest || status != acmeStatusValid {
+		t.Errorf("Update with a bad contact: %d, account %s", rec.Code, status)
+	}
+
+	// Requests in flight while the account is deactivated (run with -race)
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs("example.com"))
+	if err != nil {
+		t.Fatalf("AuthorizeOrder failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	started := make(chan struct{}, 4)
+	for i := 0; i < cap(started); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if _, err := client.GetOrder(ctx, order.URI); err != nil {
+					return
+				}
+				select {
+				case started <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+	for i := 0; i < cap(started); i++ {
+		<-started
+	}
+	if err := client.DeactivateReg(ctx); err != nil {
+		t.Fatalf("DeactivateReg failed: %v", err)
+	}
+	wg.Wait()
+
+	if _, err := client.GetOrder(ctx, order.URI); err == nil || !strings.Contains(err.Error(), "deactivated") {
+		t.Errorf("GetOrder after deactivation: got %v, want unauthorized", err)
+	}
+	var acmeErr *acme.Error
+	if _, err := client.Register(ctx, &acme.Account{}, acme.AcceptTOS); !errors.As(err, &acmeErr) || acmeErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Register with a deactivated key: got %v, want 401", err)
+	}
+}
+
+func TestParseJWK(t *testing.T) {
+	b64 := base64.RawURLEncoding
+	pad := func(n *big.Int, size int) string {
+		return b64.EncodeToString(n.FillBytes(make([]byte, size)))
+	}
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		key  crypto.PublicKey
+		jwk  string
+	}{
+		{"P-256", &p256.PublicKey, fmt.Sprintf(`{"kty":"EC","crv":"P-256","x":%q,"y":%q}`, pad(p256.X, 32), pad(p256.Y, 32))},
+		{"P-384", &p384.PublicKey, fmt.Sprintf(`{"kty":"EC","crv":"P-384","x":%q,"y":%q}`, pad(p384.X, 48), pad(p384.Y, 48))},
+		{"RSA", &rsaKey.PublicKey, fmt.Sprintf(`{"kty":"RSA","n":%q,"e":"AQAB"}`, b64.EncodeToString(rsaKey.N.Bytes()))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Thumbprints must match what clients compute for key authorizations
+			want, err := acme.JWKThumbprint(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pub, thumbprint, err := parseJWK([]byte(tt.jwk))
+			if err != nil || thumbprint != want {
+				t.Fatalf("parseJWK = %q, %v; want %q", thumbprint, err, want)
+			}
+			if !publicKeysEqual(pub, tt.key) {
+				t.Error("parseJWK returned a different key")
+			}
+		})
+	}
+
+	digest := sha256.Sum256([]byte("protected.payload"))
+	r, sv, err := ecdsa.Sign(rand.Reader, p256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := append(r.FillBytes(make([]byte, 32)), sv.FillBytes(make([]byte, 32))...)
+	if err := verifyJWS(&p256.PublicKey, "ES256", []byte("protected.payload"), sig); err != nil {
+		t.Errorf("verifyJWS failed: %v", err)
+	}
+	if err := verifyJWS(&p256.PublicKey, "ES256", []byte("protected.tampered"), sig); err == nil {
+		t.Error("Expected a signature over other data to fail")
+	}
+	if err := verifyJWS(&p256.PublicKey, "RS256", []byte("protected.payload"), sig); err == nil {
+		t.Error("Expected an algorithm that does not match the key to fail")
+	}
+
+	if _, _, err := parseJWK([]byte(`{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}`)); err == nil {
+		t.Error("Expected a point off the curve to be rejected")
+	}
+	if _, _, err := parseJWK([]byte(`{"kty":"oct","k":"c2VjcmV0"}`)); err == nil {
+		t.Error("Expected a symmetric JWK to be rejected")
+	}
+}
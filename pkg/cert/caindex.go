@@ -0,0 +1,276 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Status of a certificate in a CA index
+const (
+	CertStatusValid   = "valid"
+	CertStatusExpired = "expired"
+	CertStatusRevoked = "revoked"
+)
+
+// CertStatuses lists the statuses accepted by CAIndex.List
+var CertStatuses = []string{CertStatusValid, CertStatusExpired, CertStatusRevoked}
+
+// IndexEntry records one certificate issued by a CA
+type IndexEntry struct {
+	SerialNumber     *big.Int
+	Subject          string
+	SANs             []string
+	NotBefore        time.Time
+	NotAfter         time.Time
+	IssuedAt         time.Time
+	File             string // copy of the certificate, relative to the index
+	Revoked          bool
+	RevokedAt        time.Time
+	RevocationReason int
+}
+
+// Status returns the entry's status at the given time; revocation takes
+// precedence over expiry
+func (e *IndexEntry) Status(now time.Time) string {
+	switch {
+	case e.Revoked:
+		return CertStatusRevoked
+	case e.NotAfter.Before(now):
+		return CertStatusExpired
+	default:
+		return CertStatusValid
+	}
+}
+
+// CAIndex is the issuance index of a CA directory: every certificate the
+// CA signed, like openssl's index.txt. It lives next to the CA certificate
+// (ca.crt -> ca.index.json), with a copy of each issued certificate in
+// ca.certs/<serial>.pem.
+type CAIndex struct {
+	CASubject     string
+	CAFingerprint string // SHA-256 fingerprint of the CA certificate
+	Entries       []IndexEntry
+}
+
+// jsonCAIndex is the on-disk format of a CAIndex
+type jsonCAIndex struct {
+	CASubject     string             `json:"ca_subject"`
+	CAFingerprint string             `json:"ca_fingerprint_sha256"`
+	Certificates  []JSONCAIndexEntry `json:"certificates"`
+}
+
+// IndexListOptions filters the entries returned by CAIndex.List
+type IndexListOptions struct {
+	Status        string        // valid, expired, or revoked; empty for all
+	ExpiresWithin time.Duration // only valid certificates expiring within this window
+	Subject       string        // case-insensitive substring of the subject or a SAN
+}
+
+// CAIndexPath returns the path of the issuance index for a CA certificate
+func CAIndexPath(caCertPath string) string {
+	return strings.TrimSuffix(caCertPath, filepath.Ext(caCertPath)) + ".index.json"
+}
+
+// caIssuedDir returns the directory holding copies of issued certificates
+func caIssuedDir(caCertPath string) string {
+	return strings.TrimSuffix(caCertPath, filepath.Ext(caCertPath)) + ".certs"
+}
+
+// InitCAIndex creates an empty issuance index and certificate directory
+// for a newly generated CA
+func InitCAIndex(caCertPath string, caCert *x509.Certificate) error {
+	if err := os.MkdirAll(caIssuedDir(caCertPath), 0755); err != nil {
+		return fmt.Errorf("failed to create CA certificate directory: %w", err)
+	}
+	idx := &CAIndex{
+		CASubject:     caCert.Subject.String(),
+		CAFingerprint: (&Certificate{Certificate: caCert}).FingerprintSHA256(),
+	}
+	return idx.Save(CAIndexPath(caCertPath))
+}
+
+// LoadCAIndex reads the issuance index at path
+func LoadCAIndex(path string) (*CAIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("CA index %s not found; it is created by 'cert ca' and updated by 'cert sign'", path)
+		}
+		return nil, fmt.Errorf("failed to read CA index: %w", err)
+	}
+
+	var stored jsonCAIndex
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse CA index %s: %w", path, err)
+	}
+	idx := &CAIndex{CASubject: stored.CASubject, CAFingerprint: stored.CAFingerprint}
+	for _, je := range stored.Certificates {
+		serial, err := parseSerialNumber(je.SerialNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA index %s: %w", path, err)
+		}
+		e := IndexEntry{
+			SerialNumber: serial,
+			Subject:      je.Subject,
+			SANs:         je.SANs,
+			NotBefore:    je.NotBefore,
+			NotAfter:     je.NotAfter,
+			IssuedAt:     je.IssuedAt,
+			File:         je.File,
+		}
+		if je.RevokedAt != nil {
+			e.Revoked = true
+			e.RevokedAt = *je.RevokedAt
+			if e.RevocationReason, err = ParseRevocationReason(je.RevocationReason); err != nil {
+				return nil, fmt.Errorf("failed to parse CA index %s: %w", path, err)
+			}
+		}
+		idx.Entries = append(idx.Entries, e)
+	}
+	return idx, nil
+}
+
+// Save writes the index to path
+func (idx *CAIndex) Save(path string) error {
+	stored := jsonCAIndex{
+		CASubject:     idx.CASubject,
+		CAFingerprint: idx.CAFingerprint,
+		Certificates:  []JSONCAIndexEntry{},
+	}
+	for i := range idx.Entries {
+		// Status is computed when listing, so it isn't stored
+		stored.Certificates = append(stored.Certificates, idx.Entries[i].ToJSON(time.Time{}))
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode CA index: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write CA index: %w", err)
+	}
+	return nil
+}
+
+// Find returns the entry for a serial number, or nil
+func (idx *CAIndex) Find(serial *big.Int) *IndexEntry {
+	for i := range idx.Entries {
+		if idx.Entries[i].SerialNumber.Cmp(serial) == 0 {
+			return &idx.Entries[i]
+		}
+	}
+	return nil
+}
+
+// List returns the entries matching opts, soonest expiry first
+func (idx *CAIndex) List(opts IndexListOptions) []IndexEntry {
+	now := time.Now()
+	subject := strings.ToLower(opts.Subject)
+	entries := []IndexEntry{}
+	for _, e := range idx.Entries {
+		status := e.Status(now)
+		if opts.Status != "" && status != opts.Status {
+			continue
+		}
+		if opts.ExpiresWithin > 0 && (status != CertStatusValid || e.NotAfter.After(now.Add(opts.ExpiresWithin))) {
+			continue
+		}
+		if subject != "" && !entryMatches(e, subject) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].NotAfter.Before(entries[j].NotAfter)
+	})
+	return entries
+}
+
+// entryMatches reports whether the subject or a SAN contains the lowercase substring
+func entryMatches(e IndexEntry, substr string) bool {
+	if strings.Contains(strings.ToLower(e.Subject), substr) {
+		return true
+	}
+	for _, san := range e.SANs {
+		if strings.Contains(strings.ToLower(san), substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateCertStatus checks a --status filter value
+func ValidateCertStatus(status string) error {
+	if status == "" || status == "all" {
+		return nil
+	}
+	for _, s := range CertStatuses {
+		if s == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid status %q (use one of: all, %s)", status, strings.Join(CertStatuses, ", "))
+}
+
+// recordIssued adds a certificate signed by the CA at caCertPath to its
+// index and stores a copy. A CA without an index (created before the
+// index existed, or elsewhere) gets one.
+func recordIssued(caCertPath string, caCert, issued *x509.Certificate) error {
+	indexPath := CAIndexPath(caCertPath)
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+		if err := InitCAIndex(caCertPath, caCert); err != nil {
+			return err
+		}
+	}
+	idx, err := LoadCAIndex(indexPath)
+	if err != nil {
+		return err
+	}
+
+	dir := caIssuedDir(caCertPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create CA certificate directory: %w", err)
+	}
+	name := issued.SerialNumber.Text(16) + ".pem"
+	if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issued.Raw}), 0644); err != nil {
+		return fmt.Errorf("failed to store issued certificate: %w", err)
+	}
+
+	idx.Entries = append(idx.Entries, IndexEntry{
+		SerialNumber: issued.SerialNumber,
+		Subject:      issued.Subject.String(),
+		SANs:         joinSANs(issued.DNSNames, issued.IPAddresses, issued.EmailAddresses, issued.URIs),
+		NotBefore:    issued.NotBefore,
+		NotAfter:     issued.NotAfter,
+		IssuedAt:     time.Now().UTC().Truncate(time.Second),
+		File:         filepath.ToSlash(filepath.Join(filepath.Base(dir), name)),
+	})
+	return idx.Save(indexPath)
+}
+
+// markRevokedInIndex records a revocation in the CA's index, if it has one
+func markRevokedInIndex(caCertPath string, entry RevokedEntry) error {
+	indexPath := CAIndexPath(caCertPath)
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+		return nil
+	}
+	idx, err := LoadCAIndex(indexPath)
+	if err != nil {
+		return err
+	}
+	e := idx.Find(entry.SerialNumber)
+	if e == nil {
+		return nil
+	}
+	e.Revoked = true
+	e.RevokedAt = entry.RevokedAt
+	e.RevocationReason = entry.Reason
+	return idx.Save(indexPath)
+}
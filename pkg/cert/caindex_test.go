@@ -0,0 +1,172 @@
+package cert
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// signTestCSR issues a certificate for cn from the CA at caCert
+func signTestCSR(t *testing.T, dir, caCert, caKey, cn string, days int) string {
+	t.Helper()
+	return signTestCSRWith(t, dir, SignOptions{CACert: caCert, CAKey: caKey, Days: days}, cn)
+}
+
+// signTestCSRWith issues a certificate for cn with opts, which name the CA
+func signTestCSRWith(t *testing.T, dir string, opts SignOptions, cn string) string {
+	t.Helper()
+	opts.CSRPath = filepath.Join(dir, cn+".csr")
+	if err := GenerateCSR(CSROptions{CommonName: cn, SANs: []string{cn}, KeyAlgorithm: KeyAlgorithmECDSAP256}, opts.CSRPath, filepath.Join(dir, cn+".key")); err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+	certPath := filepath.Join(dir, cn+".crt")
+	if err := SignCSR(opts, certPath); err != nil {
+		t.Fatalf("SignCSR failed: %v", err)
+	}
+	return certPath
+}
+
+func TestCAIndex(t *testing.T) {
+	dir := t.TempDir()
+	caCert := filepath.Join(dir, "ca.crt")
+	caKey := filepath.Join(dir, "ca.key")
+	if err := GenerateCA(CAOptions{CommonName: "Index Test CA", Days: 365, KeyAlgorithm: KeyAlgorithmECDSAP256}, caCert, caKey); err != nil {
+		t.Fatalf("GenerateCA failed: %v", err)
+	}
+
+	indexPath := CAIndexPath(caCert)
+	if indexPath != filepath.Join(dir, "ca.index.json") {
+		t.Fatalf("CAIndexPath = %s", indexPath)
+	}
+	idx, err := LoadCAIndex(indexPath)
+	if err != nil {
+		t.Fatalf("LoadCAIndex after GenerateCA failed: %v", err)
+	}
+	if idx.CASubject != "CN=Index Test CA" || len(idx.Entries) != 0 || idx.CAFingerprint == "" {
+		t.Errorf("New index = %+v", idx)
+	}
+
+	soon := signTestCSR(t, dir, caCert, caKey, "soon.example.com", 10)
+	signTestCSR(t, dir, caCert, caKey, "later.example.com", 200)
+	revoked := signTestCSR(t, dir, caCert, caKey, "revoked.example.com", 100)
+	if _, _, err := Revoke(RevokeOptions{CACert: caCert, CAKey: caKey, CertPath: revoked, Reason: ocsp.Superseded}, DefaultCRLPath(caCert)); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+
+	idx, err = LoadCAIndex(indexPath)
+	if err != nil {
+		t.Fatalf("LoadCAIndex failed: %v", err)
+	}
+	// An expired entry, added by hand
+	idx.Entries = append(idx.Entries, IndexEntry{
+		SerialNumber: big.NewInt(0xdead),
+		Subject:      "CN=old.example.com",
+		NotBefore:    time.Now().AddDate(-1, 0, 0).UTC().Truncate(time.Second),
+		NotAfter:     time.Now().AddDate(0, 0, -1).UTC().Truncate(time.Second),
+	})
+	if err := idx.Save(indexPath); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if idx, err = LoadCAIndex(indexPath); err != nil {
+		t.Fatalf("LoadCAIndex failed: %v", err)
+	}
+
+	// Issued certificates are copied into the CA directory
+	soonCert, err := InspectFile(soon)
+	if err != nil {
+		t.Fatalf("InspectFile failed: %v", err)
+	}
+	entry := idx.Find(soonCert.SerialNumber)
+	if entry == nil {
+		t.Fatal("Signed certificate is not in the index")
+	}
+	if entry.Subject != "CN=soon.example.com" || len(entry.SANs) != 1 || entry.IssuedAt.IsZero() {
+		t.Errorf("Index entry = %+v", entry)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(entry.File))); err != nil {
+		t.Errorf("Issued certificate copy missing: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts IndexListOptions
+		want []string
+	}{
+		{"All, soonest expiry first", IndexListOptions{}, []string{"CN=old.example.com", "CN=soon.example.com", "CN=revoked.example.com", "CN=later.example.com"}},
+		{"Valid", IndexListOptions{Status: CertStatusValid}, []string{"CN=soon.example.com", "CN=later.example.com"}},
+		{"Expired", IndexListOptions{Status: CertStatusExpired}, []string{"CN=old.example.com"}},
+		{"Revoked", IndexListOptions{Status: CertStatusRevoked}, []string{"CN=revoked.example.com"}},
+		{"Expiring within 30 days", IndexListOptions{ExpiresWithin: 30 * 24 * time.Hour}, []string{"CN=soon.example.com"}},
+		{"Subject", IndexListOptions{Subject: "LATER"}, []string{"CN=later.example.com"}},
+		{"No match", IndexListOptions{Subject: "nothing"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := idx.List(tt.opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("List returned %d entries, want %d", len(got), len(tt.want))
+			}
+			for i, e := range got {
+				if e.Subject != tt.want[i] {
+					t.Errorf("Entry %d = %s, want %s", i, e.Subject, tt.want[i])
+				}
+			}
+		})
+	}
+
+	revokedEntry := idx.List(IndexListOptions{Status: CertStatusRevoked})[0]
+	je := revokedEntry.ToJSON(time.Now())
+	if je.Status != CertStatusRevoked || je.RevokedAt == nil || je.RevocationReason != "superseded" || je.DaysUntilExpiry == nil {
+		t.Errorf("JSON entry = %+v", je)
+	}
+}
+
+func TestCAIndexErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := LoadCAIndex(filepath.Join(dir, "missing.index.json")); err == nil {
+		t.Error("Expected error for missing index")
+	}
+	broken := filepath.Join(dir, "broken.index.json")
+	if err := os.WriteFile(broken, []byte("[]"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := LoadCAIndex(broken); err == nil {
+		t.Error("Expected error for malformed index")
+	}
+
+	if err := ValidateCertStatus("pending"); err == nil {
+		t.Error("Expected error for unknown status")
+	}
+	for _, s := range []string{"", "all", CertStatusValid, CertStatusExpired, CertStatusRevoked} {
+		if err := ValidateCertStatus(s); err != nil {
+			t.Errorf("ValidateCertStatus(%q) failed: %v", s, err)
+		}
+	}
+}
+
+func TestSignCreatesMissingIndex(t *testing.T) {
+	dir := t.TempDir()
+	caCert := filepath.Join(dir, "ca.crt")
+	caKey := filepath.Join(dir, "ca.key")
+	if err := GenerateCA(CAOptions{CommonName: "Legacy CA", Days: 365, KeyAlgorithm: KeyAlgorithmECDSAP256}, caCert, caKey); err != nil {
+		t.Fatalf("GenerateCA failed: %v", err)
+	}
+	// A CA created before the index existed
+	if err := os.Remove(CAIndexPath(caCert)); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	signTestCSR(t, dir, caCert, caKey, "legacy.example.com", 30)
+	idx, err := LoadCAIndex(CAIndexPath(caCert))
+	if err != nil {
+		t.Fatalf("LoadCAIndex failed: %v", err)
+	}
+	if len(idx.Entries) != 1 || idx.CASubject != "CN=Legacy CA" {
+		t.Errorf("Index = %+v", idx)
+	}
+}
@@ -3,6 +3,7 @@ package cert
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1" //nolint:gosec // used for fingerprint display only
@@ -11,13 +12,13 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"math/big"
 	"net"
 	"net/url"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -40,6 +41,13 @@ type Certificate struct {
 	DaysUntilExpiry int
 	TLSVersion      uint16 // Negotiated TLS version (0 for file inspection)
 	CipherSuite     uint16 // Negotiated cipher suite (0 for file inspection)
+
+	ClientCertRequest *ClientCertRequest // Server asked for a client certificate (URL inspection only)
+
+	SCTs     []*SCT          // Signed Certificate Timestamps: embedded, and from the TLS handshake and OCSP staple
+	CTPolicy *CTPolicyResult // Browser CT policy result, set by VerifyCT
+
+	OCSPStaple *OCSPStaple // Stapled OCSP response (URL inspection only; nil if the server sent none)
 }
 
 // formatFingerprint renders a digest as colon-separated uppercase hex,
@@ -81,6 +89,7 @@ func InspectData(data []byte, source string) ([]*Certificate, error) {
 			Format:          format,
 			IsExpired:       c.NotAfter.Before(time.Now()),
 			DaysUntilExpiry: int(time.Until(c.NotAfter).Hours() / 24),
+			SCTs:            extractSCTs(c, nil, nil),
 		})
 	}
 	return result, nil
@@ -131,9 +140,25 @@ func InspectURLWithConnectTimeout(targetURL string, port int, connectHost string
 // InspectURLWithOptions connects with a specific timeout and signature algorithm preference.
 // sigAlg can be "auto", "ecdsa", or "rsa" to control cipher suite selection.
 func InspectURLWithOptions(targetURL string, port int, connectHost string, timeout time.Duration, sigAlg string) (*Certificate, []*Certificate, error) {
+	return InspectURLWithConnectOptions(targetURL, port, ConnectOptions{
+		ConnectHost: connectHost,
+		Timeout:     timeout,
+		SigAlg:      sigAlg,
+	})
+}
+
+// InspectURLWithConnectOptions connects as described by opts, upgrading the
+// connection with STARTTLS first if requested, and retrieves the certificate and chain
+func InspectURLWithConnectOptions(targetURL string, port int, opts ConnectOptions) (*Certificate, []*Certificate, error) {
+	connectHost := opts.ConnectHost
+
     // Parse and normalize URL
     if !strings.Contains(targetURL, "://") {
-        targetURL = "https://" + targetURL
+        scheme := "https"
+        if opts.StartTLS != "" {
+            scheme = opts.StartTLS
+        }
+        targetURL = scheme + "://" + targetURL
     }
 
 	u, err := url.Parse(targetURL)
@@ -164,7 +189,7 @@ func InspectURLWithOptions(targetURL string, port int, connectHost string, timeo
     }
     
     // Set cipher suites based on signature algorithm preference
-    switch strings.ToLower(sigAlg) {
+    switch strings.ToLower(opts.SigAlg) {
     case "ecdsa":
         // Only ECDSA cipher suites - server will be forced to use ECDSA cert if available
         tlsConfig.CipherSuites = []uint16{
@@ -197,8 +222,7 @@ func InspectURLWithOptions(targetURL string, port int, connectHost string, timeo
     }
     
     // Connect with TLS using a timeout to avoid hanging
-    dialer := &net.Dialer{Timeout: timeout}
-    conn, err := tls.DialWithDialer(dialer, "tcp", dialHost, tlsConfig)
+    conn, clientCertRequest, err := dialTLS(dialHost, tlsConfig, opts)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to connect: %w", err)
 	}
@@ -220,6 +244,14 @@ func InspectURLWithOptions(targetURL string, port int, connectHost string, timeo
 		DaysUntilExpiry: int(time.Until(certs[0].NotAfter).Hours() / 24),
 		TLSVersion:      state.Version,
 		CipherSuite:     state.CipherSuite,
+
+		ClientCertRequest: clientCertRequest,
+
+		SCTs: extractSCTs(certs[0], state.SignedCertificateTimestamps, state.OCSPResponse),
+	}
+
+	if len(state.OCSPResponse) > 0 {
+		serverCert.OCSPStaple = parseOCSPStaple(state.OCSPResponse, certs[0], certs[1:])
 	}
 
 	// Build chain from remaining certificates
@@ -252,9 +284,9 @@ func newSerialNumber() (*big.Int, error) {
 // Generate creates a new self-signed certificate
 func Generate(opts GenerateOptions) error {
 	// Generate private key
-	privateKey, err := rsa.GenerateKey(rand.Reader, opts.KeySize)
+	privateKey, err := generatePrivateKey(opts.KeyAlgorithm, opts.KeySize)
 	if err != nil {
-		return fmt.Errorf("failed to generate private key: %w", err)
+		return err
 	}
 
 	serialNumber, err := newSerialNumber()
@@ -270,9 +302,10 @@ func Generate(opts GenerateOptions) error {
 		},
 		NotBefore:             time.Now(),
 		NotAfter:              time.Now().AddDate(0, 0, opts.Days),
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:              leafKeyUsage(privateKey.Public()),
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
+		SignatureAlgorithm:    signatureAlgorithmFor(privateKey.Public()),
 	}
 
     // Add Subject Alternative Names (DNS, IP)
@@ -283,7 +316,7 @@ func Generate(opts GenerateOptions) error {
     }
 
 	// Create certificate
-	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, privateKey.Public(), privateKey)
 	if err != nil {
 		return fmt.Errorf("failed to create certificate: %w", err)
 	}
@@ -308,76 +341,152 @@ func Generate(opts GenerateOptions) error {
 		return fmt.Errorf("failed to write certificate: %w", err)
 	}
 
-	// Write private key file
+	// Write private key file, encrypted when a password is set
 	keyPath := filepath.Join(opts.OutputDir, opts.CommonName+".key")
-	keyFile, err := os.Create(keyPath)
-	if err != nil {
-		return fmt.Errorf("failed to create key file: %w", err)
-	}
-	defer func() { _ = keyFile.Close() }()
+	return writePrivateKey(keyPath, privateKey, opts.KeyPassword)
+}
 
-	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
-	if err != nil {
-		return fmt.Errorf("failed to marshal private key: %w", err)
-	}
+// ConvertOptions contains options for certificate format conversion
+type ConvertOptions struct {
+	InputPath  string
+	OutputPath string
+	Format     string // pem, der, or p12 (aliases: pkcs12, pfx)
+	KeyPath    string // optional: private key to include in PKCS#12 output
+	KeyFormat  string // private key encoding: pkcs8 (default), pkcs1, sec1, or encrypted-pkcs8
+	Password   string // password for PKCS#12 and encrypted private keys
+	Legacy     bool   // use legacy 3DES/SHA-1 PKCS#12 encryption for older clients
+}
 
-	if err := pem.Encode(keyFile, &pem.Block{
-		Type:  "PRIVATE KEY",
-		Bytes: privateKeyBytes,
-	}); err != nil {
-		return fmt.Errorf("failed to write private key: %w", err)
+// Convert changes certificate format
+func Convert(inputPath, outputPath, format string) error {
+	return ConvertWithOptions(ConvertOptions{InputPath: inputPath, OutputPath: outputPath, Format: format})
+}
+
+// ConvertWithOptions converts between PEM, DER, and PKCS#12. Every
+// certificate in a bundle is preserved. PKCS#12 input is unpacked into its
+// certificate, chain, and key; PKCS#12 output bundles the input certificates
+// with a private key from KeyPath or the input PEM. Private key files are
+// re-encoded in KeyFormat.
+func ConvertWithOptions(opts ConvertOptions) error {
+	data, err := os.ReadFile(opts.InputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
 	}
 
-	// Set restrictive permissions on the private key (Unix-like systems only)
-	if runtime.GOOS != "windows" {
-		if err := os.Chmod(keyPath, 0600); err != nil {
-			return fmt.Errorf("failed to set key permissions: %w", err)
+	if !IsPKCS12Path(opts.InputPath) && isPrivateKeyData(data) {
+		output, err := convertPrivateKey(data, opts)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(opts.OutputPath, output, 0600); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
 		}
+		return nil
 	}
 
-	return nil
-}
+	var (
+		certs []*x509.Certificate
+		key   crypto.Signer
+	)
 
-// Convert changes certificate format
-func Convert(inputPath, outputPath, format string) error {
-	data, err := os.ReadFile(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to read input file: %w", err)
+	if IsPKCS12Path(opts.InputPath) {
+		bundle, err := DecodePKCS12(data, opts.Password)
+		if err != nil {
+			return err
+		}
+		certs = bundle.Certificates()
+		key = bundle.PrivateKey
+		if len(certs) == 0 {
+			return fmt.Errorf("no certificates found in PKCS#12 file")
+		}
+	} else {
+		certs, _, err = parseCertificates(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		// An embedded key only matters when bundling it into PKCS#12
+		if isPKCS12Format(opts.Format) {
+			if key, err = findPEMPrivateKey(data, opts.Password); err != nil {
+				return err
+			}
+		}
 	}
 
-	cert, _, err := parseCertificate(data)
-	if err != nil {
-		return fmt.Errorf("failed to parse certificate: %w", err)
+	if opts.KeyPath != "" {
+		if key, err = LoadPrivateKey(opts.KeyPath, opts.Password); err != nil {
+			return err
+		}
 	}
 
 	var output []byte
-
-	switch strings.ToLower(format) {
-	case "pem":
-		output = pem.EncodeToMemory(&pem.Block{
-			Type:  "CERTIFICATE",
-			Bytes: cert.Raw,
-		})
-	case "der":
-		output = cert.Raw
+	perm := os.FileMode(0644)
+
+	switch {
+	case strings.ToLower(opts.Format) == "pem":
+		for _, c := range certs {
+			output = append(output, pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: c.Raw,
+			})...)
+		}
+		// Keys are only written when unpacking a PKCS#12 archive
+		if key != nil && IsPKCS12Path(opts.InputPath) {
+			blockType, keyBytes, err := marshalPrivateKey(key, opts.KeyFormat, opts.Password)
+			if err != nil {
+				return err
+			}
+			output = append(output, pem.EncodeToMemory(&pem.Block{
+				Type:  blockType,
+				Bytes: keyBytes,
+			})...)
+			perm = 0600
+		}
+	case strings.ToLower(opts.Format) == "der":
+		// Bundles become concatenated DER certificates, which
+		// parseCertificates (and x509.ParseCertificates) read back
+		for _, c := range certs {
+			output = append(output, c.Raw...)
+		}
+	case isPKCS12Format(opts.Format):
+		output, err = encodePKCS12(key, certs[0], certs[1:], opts.Password, opts.Legacy)
+		if err != nil {
+			return err
+		}
+		if key != nil {
+			perm = 0600
+		}
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 
-	if err := os.WriteFile(outputPath, output, 0644); err != nil {
+	if err := os.WriteFile(opts.OutputPath, output, perm); err != nil {
 		return fmt.Errorf("failed to write output file: %w", err)
 	}
 
 	return nil
 }
 
+// findPEMPrivateKey returns the first private key found in PEM data, or nil
+// if there is none. Encrypted keys are decrypted with password.
+func findPEMPrivateKey(data []byte, password string) (crypto.Signer, error) {
+	if findPrivateKeyBlock(data) == nil {
+		return nil, nil
+	}
+	return ParsePrivateKey(data, password)
+}
+
 // VerifyOptions contains options for certificate verification
 type VerifyOptions struct {
-	CertPath  string
-	CAPath    string        // optional: CA bundle for chain verification
-	Hostname  string        // optional: hostname to verify against the certificate
-	KeyPath   string        // optional: private key to check against the certificate
-	ExpiresIn time.Duration // optional: fail if the certificate expires within this window
+	CertPath    string
+	CAPath      string        // optional: CA bundle for chain verification
+	Hostname    string        // optional: hostname to verify against the certificate
+	KeyPath     string        // optional: private key to check against the certificate
+	KeyPassword string        // for an encrypted private key
+	ExpiresIn   time.Duration // optional: fail if the certificate expires within this window
+	OCSP        bool          // optional: check revocation status with the OCSP responder
+	CRLPath     string        // optional: check revocation status against a CRL file
+	Timeout     time.Duration // network timeout for OCSP and issuer downloads
+	Lint        *LintOptions  // optional: lint rules whose findings are reported as warnings
 }
 
 // Verify checks certificate validity and hostname matching
@@ -388,10 +497,11 @@ func Verify(certPath, caPath, hostname string) (*VerificationResult, error) {
 // VerifyWithOptions checks certificate validity, hostname matching, chain
 // trust, key matching, and expiry thresholds depending on the options set.
 func VerifyWithOptions(opts VerifyOptions) (*VerificationResult, error) {
-    cert, err := InspectFile(opts.CertPath)
+    certs, err := InspectFileAll(opts.CertPath)
     if err != nil {
         return nil, err
     }
+    cert := certs[0]
 
 	result := &VerificationResult{
 		Certificate: cert,
@@ -425,11 +535,7 @@ func VerifyWithOptions(opts VerifyOptions) (*VerificationResult, error) {
 
 	// Check that the private key matches the certificate if provided
 	if opts.KeyPath != "" {
-		keyData, err := os.ReadFile(opts.KeyPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read key file: %w", err)
-		}
-		key, err := parsePrivateKey(keyData)
+		key, err := LoadPrivateKey(opts.KeyPath, opts.KeyPassword)
 		if err != nil {
 			return nil, err
 		}
@@ -443,20 +549,9 @@ func VerifyWithOptions(opts VerifyOptions) (*VerificationResult, error) {
 
     // CA chain verification when a CA bundle/path is provided
     if opts.CAPath != "" {
-        caData, err := os.ReadFile(opts.CAPath)
+        roots, err := LoadCertPool(opts.CAPath)
         if err != nil {
-            return nil, fmt.Errorf("failed to read CA file: %w", err)
-        }
-
-        roots := x509.NewCertPool()
-        // Try PEM first
-        if ok := roots.AppendCertsFromPEM(caData); !ok {
-            // Fallback: try single DER certificate
-            if caCert, err := x509.ParseCertificate(caData); err == nil {
-                roots.AddCert(caCert)
-            } else {
-                return nil, fmt.Errorf("failed to parse CA certificate(s)")
-            }
+            return nil, err
         }
 
         verifyOpts := x509.VerifyOptions{Roots: roots}
@@ -470,30 +565,92 @@ func VerifyWithOptions(opts VerifyOptions) (*VerificationResult, error) {
         }
     }
 
-    return result, nil
-}
+	// Lint findings are warnings: they don't make the certificate invalid
+	if opts.Lint != nil {
+		lint, err := Lint(cert, *opts.Lint)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range lint.Findings {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s (%s)", f.Message, f.Rule.ID))
+		}
+	}
 
-// parsePrivateKey parses a PEM- or DER-encoded private key in PKCS#8,
-// PKCS#1 (RSA), or SEC1 (EC) format.
-func parsePrivateKey(data []byte) (crypto.Signer, error) {
-	der := data
-	if block, _ := pem.Decode(data); block != nil {
-		der = block.Bytes
+	// Revocation checks need the issuer, which comes from the rest of the
+	// certificate file or the CA bundle
+	var candidates []*x509.Certificate
+	if opts.OCSP || opts.CRLPath != "" {
+		for _, c := range certs[1:] {
+			candidates = append(candidates, c.Certificate)
+		}
+		if opts.CAPath != "" {
+			if caData, err := os.ReadFile(opts.CAPath); err == nil {
+				if caCerts, _, err := parseCertificates(caData); err == nil {
+					candidates = append(candidates, caCerts...)
+				}
+			}
+		}
 	}
 
-	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
-		if signer, ok := key.(crypto.Signer); ok {
-			return signer, nil
+	// Revocation check against a CRL file
+	if opts.CRLPath != "" {
+		check, err := CheckCRL(cert.Certificate, opts.CRLPath, candidates)
+		if err != nil {
+			result.IsValid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("CRL check failed: %v", err))
+		} else {
+			result.CRL = check
+			if check.Revoked != nil {
+				result.IsValid = false
+				result.Errors = append(result.Errors, revokedMessage(check.Revoked.RevokedAt, check.Revoked.Reason))
+			}
+			if !check.CRL.SignatureChecked {
+				result.Warnings = append(result.Warnings, "CRL signature not verified: issuer certificate not found in the certificate file or CA bundle")
+			}
+			if check.CRL.IsStale() {
+				result.Warnings = append(result.Warnings, "CRL is stale (next update has passed)")
+			}
 		}
-		return nil, fmt.Errorf("unsupported private key type %T", key)
 	}
-	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
-		return key, nil
+
+	// Revocation check against the OCSP responder; the issuer may also be
+	// downloaded from the AIA CA Issuers URL
+	if opts.OCSP {
+		applyOCSPResult(result, candidates, OCSPOptions{Timeout: opts.Timeout})
+	}
+
+    return result, nil
+}
+
+// applyOCSPResult checks the result's certificate with OCSP and records the
+// status: revoked certificates and failed checks are errors, unknown or
+// stale responses are warnings.
+func applyOCSPResult(result *VerificationResult, candidates []*x509.Certificate, opts OCSPOptions) {
+	issuer, err := FindIssuer(result.Certificate.Certificate, candidates, opts.Timeout)
+	if err == nil {
+		result.OCSP, err = CheckOCSP(result.Certificate.Certificate, issuer, opts)
+	}
+	if err != nil {
+		result.IsValid = false
+		result.Errors = append(result.Errors, fmt.Sprintf("OCSP check failed: %v", err))
+		return
+	}
+
+	switch result.OCSP.Status {
+	case OCSPStatusRevoked:
+		result.IsValid = false
+		result.Errors = append(result.Errors, revokedMessage(result.OCSP.RevokedAt, result.OCSP.RevocationReason))
+	case OCSPStatusUnknown:
+		result.Warnings = append(result.Warnings, "OCSP responder does not know this certificate")
 	}
-	if key, err := x509.ParseECPrivateKey(der); err == nil {
-		return key, nil
+	if result.OCSP.IsStale() {
+		result.Warnings = append(result.Warnings, "OCSP response is stale (next update has passed)")
 	}
-	return nil, fmt.Errorf("failed to parse private key (tried PKCS#8, PKCS#1, and EC formats)")
+}
+
+// revokedMessage describes a revocation for verification errors
+func revokedMessage(at time.Time, reason int) string {
+	return fmt.Sprintf("Certificate was revoked on %s (%s)", at.UTC().Format("2006-01-02 15:04:05 UTC"), RevocationReasonName(reason))
 }
 
 // publicKeysEqual reports whether two public keys are the same key
@@ -544,13 +701,16 @@ func parseCertificates(data []byte) ([]*x509.Certificate, string, error) {
 		return nil, FormatPEM, fmt.Errorf("no CERTIFICATE blocks found in PEM data")
 	}
 
-	// Try DER
-	cert, err := x509.ParseCertificate(data)
+	// Try DER (one certificate, or several concatenated)
+	certs, err := x509.ParseCertificates(data)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to parse as PEM or DER: %w", err)
 	}
+	if len(certs) == 0 {
+		return nil, "", fmt.Errorf("failed to parse as PEM or DER: no certificates found")
+	}
 
-	return []*x509.Certificate{cert}, FormatDER, nil
+	return certs, FormatDER, nil
 }
 
 // getPublicKeyAlgorithm returns the algorithm name for a public key
@@ -560,6 +720,8 @@ func getPublicKeyAlgorithm(pubKey interface{}) string {
 		return "RSA"
 	case *ecdsa.PublicKey:
 		return "ECDSA"
+	case ed25519.PublicKey:
+		return "Ed25519"
 	default:
 		return "Unknown"
 	}
@@ -572,6 +734,8 @@ func getPublicKeySize(pubKey interface{}) int {
 		return key.N.BitLen()
 	case *ecdsa.PublicKey:
 		return key.Params().BitSize
+	case ed25519.PublicKey:
+		return ed25519.PublicKeySize * 8
 	default:
 		return 0
 	}
@@ -580,9 +744,9 @@ func getPublicKeySize(pubKey interface{}) int {
 // GenerateCSR generates a Certificate Signing Request
 func GenerateCSR(options CSROptions, csrPath, keyPath string) error {
 	// Generate private key
-	privateKey, err := rsa.GenerateKey(rand.Reader, options.KeySize)
+	privateKey, err := generatePrivateKey(options.KeyAlgorithm, options.KeySize)
 	if err != nil {
-		return fmt.Errorf("failed to generate private key: %w", err)
+		return err
 	}
 
 	// Prepare subject
@@ -608,7 +772,8 @@ func GenerateCSR(options CSROptions, csrPath, keyPath string) error {
 
 	// Prepare CSR template
 	template := x509.CertificateRequest{
-		Subject: subject,
+		Subject:            subject,
+		SignatureAlgorithm: signatureAlgorithmFor(privateKey.Public()),
 	}
 
 	// Add email if provided
@@ -647,26 +812,8 @@ func GenerateCSR(options CSROptions, csrPath, keyPath string) error {
 		return fmt.Errorf("failed to write CSR: %w", err)
 	}
 
-	// Write private key to file
-	keyFile, err := os.Create(keyPath)
-	if err != nil {
-		return fmt.Errorf("failed to create private key file: %w", err)
-	}
-	defer keyFile.Close()
-
-	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
-	if err != nil {
-		return fmt.Errorf("failed to marshal private key: %w", err)
-	}
-
-	if err := pem.Encode(keyFile, &pem.Block{
-		Type:  "PRIVATE KEY",
-		Bytes: privateKeyBytes,
-	}); err != nil {
-		return fmt.Errorf("failed to write private key: %w", err)
-	}
-
-	return nil
+	// Write private key to file, encrypted when a password is set
+	return writePrivateKey(keyPath, privateKey, options.KeyPassword)
 }
 
 // ParseCSR parses a CSR from PEM-encoded data
@@ -687,35 +834,63 @@ func ParseCSR(data []byte) (*CSRInfo, error) {
 	}
 
 	// Collect SANs
-	info.SANs = append(info.SANs, csr.DNSNames...)
-	for _, ip := range csr.IPAddresses {
-		info.SANs = append(info.SANs, "IP:"+ip.String())
-	}
-	for _, email := range csr.EmailAddresses {
-		info.SANs = append(info.SANs, "email:"+email)
-	}
-	for _, uri := range csr.URIs {
-		info.SANs = append(info.SANs, "uri:"+uri.String())
-	}
+	info.SANs = joinSANs(csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, csr.URIs)
 
 	// Determine public key info
-	switch pub := csr.PublicKey.(type) {
-	case *rsa.PublicKey:
-		info.PublicKeyAlgorithm = "RSA"
-		info.KeySize = pub.N.BitLen()
-	default:
-		info.PublicKeyAlgorithm = "Unknown"
+	info.PublicKeyAlgorithm = getPublicKeyAlgorithm(csr.PublicKey)
+	info.KeySize = getPublicKeySize(csr.PublicKey)
+	if pub, ok := csr.PublicKey.(*ecdsa.PublicKey); ok {
+		info.Curve = pub.Curve.Params().Name
 	}
 
 	return info, nil
 }
 
-// GenerateCA generates a self-signed Certificate Authority certificate
+// GenerateCA generates a Certificate Authority certificate: a self-signed
+// root, or an intermediate signed by options.ParentCert/ParentKey
 func GenerateCA(options CAOptions, certPath, keyPath string) error {
-	// Generate private key
-	privateKey, err := rsa.GenerateKey(rand.Reader, options.KeySize)
-	if err != nil {
-		return fmt.Errorf("failed to generate private key: %w", err)
+	// Load and check the parent CA before generating anything
+	var parentCert *x509.Certificate
+	var parentKey *Signer
+	var parentChain []*x509.Certificate
+	hasParentKey := options.ParentKey != "" || options.ParentKeyURI != ""
+	if options.ParentCert != "" || hasParentKey {
+		if options.ParentCert == "" || !hasParentKey {
+			return fmt.Errorf("an intermediate CA needs both the parent certificate and the parent key")
+		}
+		var err error
+		parentCert, parentKey, err = loadCA(options.ParentCert, options.ParentKey, options.ParentKeyURI, options.ParentKeyPassword)
+		if err != nil {
+			return fmt.Errorf("failed to load parent CA: %w", err)
+		}
+		defer parentKey.Close()
+		if err := checkParentCA(parentCert, &options); err != nil {
+			return err
+		}
+		parentData, err := os.ReadFile(options.ParentCert)
+		if err != nil {
+			return fmt.Errorf("failed to read parent CA certificate: %w", err)
+		}
+		if parentChain, _, err = parseCertificates(parentData); err != nil {
+			return fmt.Errorf("failed to parse parent CA certificate: %w", err)
+		}
+	}
+
+	// Generate the CA key, or use an existing one held elsewhere
+	var privateKey crypto.Signer
+	if options.KeyURI != "" {
+		signer, err := OpenSigner(options.KeyURI, options.KeyPassword)
+		if err != nil {
+			return fmt.Errorf("failed to open CA key: %w", err)
+		}
+		defer signer.Close()
+		privateKey = signer
+	} else {
+		key, err := generatePrivateKey(options.KeyAlgorithm, options.KeySize)
+		if err != nil {
+			return err
+		}
+		privateKey = key
 	}
 
 	// Prepare subject
@@ -742,10 +917,13 @@ func GenerateCA(options CAOptions, certPath, keyPath string) error {
 		NotBefore:    time.Now(),
 		NotAfter:     time.Now().AddDate(0, 0, options.Days),
 
+		SignatureAlgorithm: signatureAlgorithmFor(privateKey.Public()),
+
 		// CA specific settings
 		IsCA:                  true,
 		BasicConstraintsValid: true,
-		MaxPathLen:            -1, // No path length constraint
+		MaxPathLen:            options.MaxPathLen,
+		MaxPathLenZero:        options.MaxPathLenZero,
 
 		// Key usage for CA
 		KeyUsage: x509.KeyUsageCertSign |
@@ -762,13 +940,27 @@ func GenerateCA(options CAOptions, certPath, keyPath string) error {
 		},
 	}
 
+	if err := applyNameConstraints(&template, options); err != nil {
+		return err
+	}
+
+	// Self-signed roots are their own parent; intermediates can't outlive theirs
+	parent, signer := &template, crypto.Signer(privateKey)
+	if parentCert != nil {
+		parent, signer = parentCert, parentKey
+		template.SignatureAlgorithm = signatureAlgorithmFor(parentKey.Public())
+		if template.NotAfter.After(parentCert.NotAfter) {
+			template.NotAfter = parentCert.NotAfter
+		}
+	}
+
 	// Generate certificate
 	certBytes, err := x509.CreateCertificate(
 		rand.Reader,
 		&template,
-		&template, // Self-signed, so parent is itself
-		&privateKey.PublicKey,
-		privateKey,
+		parent,
+		privateKey.Public(),
+		signer,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create certificate: %w", err)
@@ -788,33 +980,98 @@ func GenerateCA(options CAOptions, certPath, keyPath string) error {
 		return fmt.Errorf("failed to write certificate: %w", err)
 	}
 
-	// Write private key to file
-	keyFile, err := os.Create(keyPath)
-	if err != nil {
-		return fmt.Errorf("failed to create private key file: %w", err)
+	// Write private key to file, encrypted when a password is set; a key
+	// opened from a URI stays where it is
+	if options.KeyURI == "" {
+		if err := writePrivateKey(keyPath, privateKey, options.KeyPassword); err != nil {
+			return err
+		}
 	}
-	defer keyFile.Close()
 
-	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	// Start the issuance index that SignCSR adds to
+	caCert, err := x509.ParseCertificate(certBytes)
 	if err != nil {
-		return fmt.Errorf("failed to marshal private key: %w", err)
+		return fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+	if err := InitCAIndex(certPath, caCert); err != nil {
+		return err
+	}
+	if parentCert == nil {
+		return nil
 	}
 
-	if err := pem.Encode(keyFile, &pem.Block{
-		Type:  "PRIVATE KEY",
-		Bytes: privateKeyBytes,
-	}); err != nil {
-		return fmt.Errorf("failed to write private key: %w", err)
+	// The parent records the intermediate it issued
+	if err := recordIssued(options.ParentCert, parentCert, caCert); err != nil {
+		return fmt.Errorf("intermediate CA written but the parent's CA index was not updated: %w", err)
 	}
 
-	// Set restrictive permissions on the private key (Unix-like systems only)
-	// Windows has different permission semantics
-	if runtime.GOOS != "windows" {
-		if err := os.Chmod(keyPath, 0600); err != nil {
-			return fmt.Errorf("failed to set key permissions: %w", err)
+	// Full chain: the intermediate followed by the parent's certificates
+	if options.BundlePath != "" {
+		bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes})
+		for _, c := range parentChain {
+			bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
 		}
+		if err := os.WriteFile(options.BundlePath, bundle, 0644); err != nil {
+			return fmt.Errorf("failed to write chain bundle: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkParentCA checks that parent may issue an intermediate CA with the
+// requested path length. An unconstrained request under a constrained
+// parent gets the longest path length the parent allows.
+func checkParentCA(parent *x509.Certificate, options *CAOptions) error {
+	if !parent.BasicConstraintsValid || !parent.IsCA {
+		return fmt.Errorf("parent certificate %s is not a CA", parent.Subject.CommonName)
+	}
+	if parent.KeyUsage != 0 && parent.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("parent CA %s is not allowed to sign certificates (missing keyCertSign key usage)", parent.Subject.CommonName)
+	}
+
+	parentLimited := parent.MaxPathLen > 0 || (parent.MaxPathLen == 0 && parent.MaxPathLenZero)
+	if !parentLimited {
+		return nil
+	}
+	if parent.MaxPathLen == 0 {
+		return fmt.Errorf("parent CA %s has a path length of 0 and cannot issue intermediate CAs", parent.Subject.CommonName)
 	}
+	requestedLimited := options.MaxPathLen > 0 || (options.MaxPathLen == 0 && options.MaxPathLenZero)
+	if !requestedLimited {
+		options.MaxPathLen = parent.MaxPathLen - 1
+		options.MaxPathLenZero = options.MaxPathLen == 0
+		return nil
+	}
+	if options.MaxPathLen >= parent.MaxPathLen {
+		return fmt.Errorf("path length %d must be less than the parent CA's path length %d", options.MaxPathLen, parent.MaxPathLen)
+	}
+	return nil
+}
 
+// applyNameConstraints sets the CA's name constraints from the options.
+// The extension is marked critical, as RFC 5280 requires.
+func applyNameConstraints(template *x509.Certificate, options CAOptions) error {
+	template.PermittedDNSDomains = options.PermittedDNSDomains
+	template.ExcludedDNSDomains = options.ExcludedDNSDomains
+	template.PermittedEmailAddresses = options.PermittedEmailAddresses
+	template.ExcludedEmailAddresses = options.ExcludedEmailAddresses
+	for _, cidr := range options.PermittedIPRanges {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid permitted IP range %q (use CIDR notation, e.g. 10.0.0.0/8)", cidr)
+		}
+		template.PermittedIPRanges = append(template.PermittedIPRanges, ipNet)
+	}
+	for _, cidr := range options.ExcludedIPRanges {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid excluded IP range %q (use CIDR notation, e.g. 10.0.0.0/8)", cidr)
+		}
+		template.ExcludedIPRanges = append(template.ExcludedIPRanges, ipNet)
+	}
+	template.PermittedDNSDomainsCritical = len(template.PermittedDNSDomains)+len(template.ExcludedDNSDomains)+
+		len(template.PermittedEmailAddresses)+len(template.ExcludedEmailAddresses)+
+		len(template.PermittedIPRanges)+len(template.ExcludedIPRanges) > 0
 	return nil
 }
 
@@ -841,42 +1098,11 @@ func SignCSR(options SignOptions, certPath string) error {
 		return fmt.Errorf("CSR signature verification failed: %w", err)
 	}
 
-	// Read CA certificate
-	caCertData, err := os.ReadFile(options.CACert)
+	caCert, caKey, err := loadCA(options.CACert, options.CAKey, options.CAKeyURI, options.CAKeyPassword)
 	if err != nil {
-		return fmt.Errorf("failed to read CA certificate: %w", err)
-	}
-
-	caBlock, _ := pem.Decode(caCertData)
-	if caBlock == nil {
-		return fmt.Errorf("failed to parse CA certificate PEM block")
-	}
-
-	caCert, err := x509.ParseCertificate(caBlock.Bytes)
-	if err != nil {
-		return fmt.Errorf("failed to parse CA certificate: %w", err)
-	}
-
-	// Read CA private key
-	caKeyData, err := os.ReadFile(options.CAKey)
-	if err != nil {
-		return fmt.Errorf("failed to read CA private key: %w", err)
-	}
-
-	keyBlock, _ := pem.Decode(caKeyData)
-	if keyBlock == nil {
-		return fmt.Errorf("failed to parse CA private key PEM block")
-	}
-
-	caKey, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
-	if err != nil {
-		// Try PKCS1 format
-		if rsaKey, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes); err == nil {
-			caKey = rsaKey
-		} else {
-			return fmt.Errorf("failed to parse CA private key: %w", err)
-		}
+		return err
 	}
+	defer caKey.Close()
 
 	// Generate a random serial number
 	serialNumber, err := newSerialNumber()
@@ -890,16 +1116,16 @@ func SignCSR(options SignOptions, certPath string) error {
 		Subject:      csr.Subject,
 		NotBefore:    time.Now(),
 		NotAfter:     time.Now().AddDate(0, 0, options.Days),
+	}
 
-		// Standard certificate settings
-		KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage: []x509.ExtKeyUsage{
-			x509.ExtKeyUsageServerAuth,
-			x509.ExtKeyUsageClientAuth,
-		},
-		BasicConstraintsValid: true,
-		IsCA:                  false,
+	// Key usage, extended key usage, and basic constraints come from the profile
+	profile := options.Profile
+	if profile == nil {
+		if profile, err = FindProfile(DefaultProfile, nil); err != nil {
+			return err
+		}
 	}
+	profile.apply(&template, csr.PublicKey)
 
 	// Handle SANs - use provided SANs or fall back to CSR SANs
     if len(options.SANs) > 0 {
@@ -916,6 +1142,9 @@ func SignCSR(options SignOptions, certPath string) error {
 		template.EmailAddresses = csr.EmailAddresses
 		template.URIs = csr.URIs
 	}
+	if err := profile.checkSANs(&template); err != nil {
+		return err
+	}
 
 	// Create certificate
 	certBytes, err := x509.CreateCertificate(
@@ -943,16 +1172,27 @@ func SignCSR(options SignOptions, certPath string) error {
 		return fmt.Errorf("failed to write certificate: %w", err)
 	}
 
+	// Record the issuance in the CA's index
+	issued, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse signed certificate: %w", err)
+	}
+	if err := recordIssued(options.CACert, caCert, issued); err != nil {
+		return fmt.Errorf("certificate written but the CA index was not updated: %w", err)
+	}
+
 	return nil
 }
 
 // GenerateOptions contains options for certificate generation
 type GenerateOptions struct {
-	CommonName string
-	Days       int
-	KeySize    int
-	SANs       []string
-	OutputDir  string
+	CommonName   string
+	Days         int
+	KeyAlgorithm string // rsa (default), ecdsa-p256, ecdsa-p384, ecdsa-p521, or ed25519
+	KeySize      int    // RSA key size in bits; ignored for other algorithms
+	SANs         []string
+	OutputDir    string
+	KeyPassword  string // optional: encrypt the private key (PBES2, AES-256-CBC)
 }
 
 // VerificationResult contains the results of certificate verification
@@ -961,8 +1201,10 @@ type VerificationResult struct {
 	IsValid     bool
 	Errors      []string
 	Warnings    []string
-	KeyChecked  bool // whether a private key was checked against the certificate
-	KeyMatches  bool // whether the checked private key matches the certificate
+	KeyChecked  bool        // whether a private key was checked against the certificate
+	KeyMatches  bool        // whether the checked private key matches the certificate
+	OCSP        *OCSPResult // revocation status, when checked with OCSP
+	CRL         *CRLCheck   // revocation status, when checked against a CRL
 }
 
 // CSROptions contains options for CSR generation
@@ -975,7 +1217,9 @@ type CSROptions struct {
 	Locality           string
 	EmailAddress       string
 	SANs               []string
-	KeySize            int
+	KeyAlgorithm       string // rsa (default), ecdsa-p256, ecdsa-p384, ecdsa-p521, or ed25519
+	KeySize            int    // RSA key size in bits; ignored for other algorithms
+	KeyPassword        string // optional: encrypt the private key (PBES2, AES-256-CBC)
 }
 
 // CSRInfo contains parsed CSR information for display
@@ -985,6 +1229,7 @@ type CSRInfo struct {
 	SignatureAlgorithm string
 	PublicKeyAlgorithm string
 	KeySize            int
+	Curve              string // Named curve for ECDSA keys (e.g. P-256)
 }
 
 // CAOptions contains options for CA certificate generation
@@ -993,16 +1238,43 @@ type CAOptions struct {
 	Organization string
 	Country      string
 	Days         int
-	KeySize      int
+	KeyAlgorithm string // rsa (default), ecdsa-p256, ecdsa-p384, ecdsa-p521, or ed25519
+	KeySize      int    // RSA key size in bits; ignored for other algorithms
+	KeyPassword  string // optional: encrypt the private key (PBES2, AES-256-CBC)
+	KeyURI       string // optional: use this existing key (see OpenSigner), opened with KeyPassword, instead of generating one
+
+	// Path length constraint, as in x509.Certificate: -1 (or 0 without
+	// MaxPathLenZero) is unconstrained
+	MaxPathLen     int
+	MaxPathLenZero bool
+
+	// Name constraints on the certificates this CA may issue
+	PermittedDNSDomains     []string
+	ExcludedDNSDomains      []string
+	PermittedIPRanges       []string // CIDR notation
+	ExcludedIPRanges        []string // CIDR notation
+	PermittedEmailAddresses []string
+	ExcludedEmailAddresses  []string
+
+	// Optional: issue an intermediate signed by this parent CA instead of a
+	// self-signed root, and write the full chain to BundlePath
+	ParentCert        string
+	ParentKey         string
+	ParentKeyURI      string // instead of ParentKey: a key URI (see OpenSigner)
+	ParentKeyPassword string // for an encrypted parent key, or the PKCS#11 PIN
+	BundlePath        string
 }
 
 // SignOptions contains options for signing a CSR
 type SignOptions struct {
-	CSRPath string
-	CACert  string
-	CAKey   string
-	Days    int
-	SANs    []string // Optional: override CSR SANs
+	CSRPath       string
+	CACert        string
+	CAKey         string
+	CAKeyURI      string // instead of CAKey: a key URI (see OpenSigner)
+	CAKeyPassword string // for an encrypted CA key, or the PKCS#11 PIN
+	Days          int
+	SANs          []string // Optional: override CSR SANs
+	Profile       *Profile // Optional: issuance profile; DefaultProfile if nil
 }
 
 // TLSVersion represents a TLS version constant
@@ -1022,15 +1294,23 @@ type TLSVersionInfo struct {
 	Supported   bool
 	Error       string
 	CipherSuite uint16 // Negotiated cipher suite for this version (0 if not supported)
+
+	// Set by CheckTLSCiphers
+	Ciphers           []CipherSuiteInfo // Accepted suites, in the order the server picked them
+	CipherOrder       string            // CipherOrderServer, CipherOrderClient, or "" if undetermined
+	CiphersEnumerated bool              // False for TLS 1.3, where only the negotiated suite is known
 }
 
 // TLSResult contains the results of TLS version testing
 type TLSResult struct {
-	Host         string
-	Port         int
-	Versions     []TLSVersionInfo
-	MinSupported TLSVersion
-	MaxSupported TLSVersion
+	Host           string
+	Port           int
+	Versions       []TLSVersionInfo
+	MinSupported   TLSVersion
+	MaxSupported   TLSVersion
+	CiphersChecked bool // Cipher suites were enumerated (CheckTLSCiphers)
+
+	ClientCertRequest *ClientCertRequest // Server asked for a client certificate (from the newest version that did)
 }
 
 // tlsVersionNames maps TLS versions to their human-readable names
@@ -1052,14 +1332,20 @@ func TLSVersionName(version uint16) string {
 
 // CheckTLSVersions tests which TLS versions are supported by a server
 func CheckTLSVersions(host string, port int, timeout time.Duration) (*TLSResult, error) {
+	return CheckTLSVersionsWithOptions(host, port, ConnectOptions{Timeout: timeout})
+}
+
+// CheckTLSVersionsWithOptions tests which TLS versions are supported by a
+// server reached as described by opts. A failed proxy tunnel or STARTTLS
+// upgrade is returned as an error rather than reported per version.
+func CheckTLSVersionsWithOptions(host string, port int, opts ConnectOptions) (*TLSResult, error) {
 	result := &TLSResult{
 		Host:     host,
 		Port:     port,
 		Versions: make([]TLSVersionInfo, 0, 4),
 	}
 
-	dialHost := net.JoinHostPort(host, strconv.Itoa(port))
-	dialer := &net.Dialer{Timeout: timeout}
+	dialHost := opts.dialAddress(host, port)
 
 	// Test each TLS version
 	versions := []TLSVersion{
@@ -1085,7 +1371,14 @@ func CheckTLSVersions(host string, port int, timeout time.Duration) (*TLSResult,
 			MaxVersion:         uint16(version),
 		}
 
-		conn, err := tls.DialWithDialer(dialer, "tcp", dialHost, tlsConfig)
+		conn, clientCertRequest, err := dialTLS(dialHost, tlsConfig, opts)
+		if clientCertRequest != nil {
+			result.ClientCertRequest = clientCertRequest
+		}
+		var setupErr *setupError
+		if errors.As(err, &setupErr) {
+			return nil, err
+		}
 		if err != nil {
 			// Check if it's a version-specific error
 			info.Error = err.Error()
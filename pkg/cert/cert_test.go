@@ -580,3 +580,145 @@ func BenchmarkGenerate(b *testing.B) {
 		}
 	}
 }
+
+func TestGenerateIntermediateCA(t *testing.T) {
+	dir := t.TempDir()
+	rootCert := filepath.Join(dir, "root.crt")
+	rootKey := filepath.Join(dir, "root.key")
+	if err := GenerateCA(CAOptions{CommonName: "Test Root CA", Days: 365, KeyAlgorithm: KeyAlgorithmECDSAP384, MaxPathLen: 1}, rootCert, rootKey); err != nil {
+		t.Fatalf("GenerateCA (root) failed: %v", err)
+	}
+
+	intCert := filepath.Join(dir, "issuing.crt")
+	intKey := filepath.Join(dir, "issuing.key")
+	bundle := filepath.Join(dir, "issuing-chain.crt")
+	err := GenerateCA(CAOptions{
+		CommonName:          "Test Issuing CA",
+		Days:                3650, // longer than the root
+		KeyAlgorithm:        KeyAlgorithmECDSAP256,
+		PermittedDNSDomains: []string{"example.com"},
+		ExcludedIPRanges:    []string{"192.168.0.0/16"},
+		ParentCert:          rootCert,
+		ParentKey:           rootKey,
+		BundlePath:          bundle,
+	}, intCert, intKey)
+	if err != nil {
+		t.Fatalf("GenerateCA (intermediate) failed: %v", err)
+	}
+
+	root, err := InspectFile(rootCert)
+	if err != nil {
+		t.Fatalf("InspectFile (root) failed: %v", err)
+	}
+	inter, err := InspectFile(intCert)
+	if err != nil {
+		t.Fatalf("InspectFile (intermediate) failed: %v", err)
+	}
+	if err := inter.CheckSignatureFrom(root.Certificate); err != nil {
+		t.Errorf("Intermediate is not signed by the root: %v", err)
+	}
+	if !inter.IsCA || inter.MaxPathLen != 0 || !inter.MaxPathLenZero {
+		t.Errorf("Intermediate path length = %d (zero: %v), want 0 from the root's limit", inter.MaxPathLen, inter.MaxPathLenZero)
+	}
+	if inter.NotAfter.After(root.NotAfter) {
+		t.Errorf("Intermediate outlives the root: %v > %v", inter.NotAfter, root.NotAfter)
+	}
+	if !inter.PermittedDNSDomainsCritical || len(inter.PermittedDNSDomains) != 1 || len(inter.ExcludedIPRanges) != 1 {
+		t.Errorf("Name constraints = %v / %v (critical: %v)", inter.PermittedDNSDomains, inter.ExcludedIPRanges, inter.PermittedDNSDomainsCritical)
+	}
+	jc := inter.ToJSON()
+	if jc.MaxPathLen == nil || *jc.MaxPathLen != 0 || jc.NameConstraints == nil || jc.NameConstraints.ExcludedIPRanges[0] != "192.168.0.0/16" {
+		t.Errorf("JSON constraints = %v / %+v", jc.MaxPathLen, jc.NameConstraints)
+	}
+
+	// The bundle is the intermediate followed by the root
+	data, err := os.ReadFile(bundle)
+	if err != nil {
+		t.Fatalf("ReadFile (bundle) failed: %v", err)
+	}
+	chain, _, err := parseCertificates(data)
+	if err != nil || len(chain) != 2 || chain[0].Subject.CommonName != "Test Issuing CA" || chain[1].Subject.CommonName != "Test Root CA" {
+		t.Errorf("Bundle = %d certificates (%v)", len(chain), err)
+	}
+
+	// The root's index records the intermediate; the intermediate has its own
+	idx, err := LoadCAIndex(CAIndexPath(rootCert))
+	if err != nil {
+		t.Fatalf("LoadCAIndex (root) failed: %v", err)
+	}
+	if idx.Find(inter.SerialNumber) == nil {
+		t.Error("Intermediate is not in the root's index")
+	}
+	if _, err := LoadCAIndex(CAIndexPath(intCert)); err != nil {
+		t.Errorf("Intermediate index was not created: %v", err)
+	}
+
+	// Leaves chain to the root, within the name constraints only
+	roots := x509.NewCertPool()
+	roots.AddCert(root.Certificate)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(inter.Certificate)
+	for _, tt := range []struct {
+		cn      string
+		wantErr bool
+	}{
+		{"www.example.com", false},
+		{"www.example.org", true},
+	} {
+		leafPath := signTestCSR(t, dir, intCert, intKey, tt.cn, 30)
+		leaf, err := InspectFile(leafPath)
+		if err != nil {
+			t.Fatalf("InspectFile (%s) failed: %v", tt.cn, err)
+		}
+		_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Verify(%s) error = %v, wantErr %v", tt.cn, err, tt.wantErr)
+		}
+	}
+
+	// A path length of 0 stops further intermediates
+	err = GenerateCA(CAOptions{CommonName: "Too Deep CA", Days: 30, KeyAlgorithm: KeyAlgorithmECDSAP256, ParentCert: intCert, ParentKey: intKey},
+		filepath.Join(dir, "deep.crt"), filepath.Join(dir, "deep.key"))
+	if err == nil {
+		t.Error("Expected error for an intermediate under a path length 0 CA")
+	}
+}
+
+func TestGenerateIntermediateCAErrors(t *testing.T) {
+	dir := t.TempDir()
+	rootCert := filepath.Join(dir, "root.crt")
+	rootKey := filepath.Join(dir, "root.key")
+	if err := GenerateCA(CAOptions{CommonName: "Test Root CA", Days: 365, KeyAlgorithm: KeyAlgorithmECDSAP256, MaxPathLen: 1}, rootCert, rootKey); err != nil {
+		t.Fatalf("GenerateCA (root) failed: %v", err)
+	}
+	otherDir := filepath.Join(dir, "other")
+	if err := os.Mkdir(otherDir, 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	_, otherKey, leafCert := newTestCAFiles(t, otherDir, "Other CA")
+
+	tests := []struct {
+		name    string
+		options CAOptions
+	}{
+		{"Parent key missing", CAOptions{ParentCert: rootCert}},
+		{"Parent key mismatch", CAOptions{ParentCert: rootCert, ParentKey: otherKey}},
+		{"Parent is not a CA", CAOptions{ParentCert: leafCert, ParentKey: filepath.Join(otherDir, "leaf.key")}},
+		{"Path length too long", CAOptions{ParentCert: rootCert, ParentKey: rootKey, MaxPathLen: 1}},
+		{"Invalid IP range", CAOptions{ParentCert: rootCert, ParentKey: rootKey, PermittedIPRanges: []string{"10.0.0.1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.CommonName = "Bad Intermediate"
+			tt.options.Days = 30
+			tt.options.KeyAlgorithm = KeyAlgorithmECDSAP256
+			certPath := filepath.Join(dir, "bad.crt")
+			if err := GenerateCA(tt.options, certPath, filepath.Join(dir, "bad.key")); err == nil {
+				t.Error("Expected error")
+			}
+			if _, err := os.Stat(certPath); err == nil {
+				t.Error("Certificate written despite the error")
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ChainOptions configures chain analysis
+type ChainOptions struct {
+	Roots       *x509.CertPool // trust anchors; nil uses the system roots
+	RootsSource string         // description of Roots for display, e.g. the CA bundle path
+}
+
+// ChainLink describes how one presented certificate fits into the chain
+type ChainLink struct {
+	Index          int
+	IssuerIndex    int  // presented certificate that issued this one, or -1
+	SelfSigned     bool // issuer equals subject and the signature verifies with its own key
+	SignatureValid bool // signature verifies against IssuerIndex (or itself when self-signed)
+	Duplicate      bool // identical to an earlier certificate
+	Unused         bool // not on the path from the leaf
+}
+
+// ChainAnalysis reports on the order, completeness, and trust of a
+// presented certificate chain (leaf first, as sent by a server or stored
+// in a bundle file).
+type ChainAnalysis struct {
+	Certificates []*Certificate
+	Links        []ChainLink
+	Ordered      bool // each certificate is followed by its issuer
+	Complete     bool // no intermediates are missing between the leaf and a root
+	Trusted      bool // the leaf verifies to a trust anchor
+	TrustSource  string
+	Path         []*x509.Certificate // validated path from leaf to anchor, when trusted
+	PathSent     []bool              // whether each Path certificate was presented
+	Errors       []string
+	Warnings     []string
+}
+
+// LoadCertPool reads a PEM bundle (or a single DER certificate) into a pool
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	// Try PEM first
+	if ok := pool.AppendCertsFromPEM(data); !ok {
+		// Fallback: try single DER certificate
+		caCert, err := x509.ParseCertificate(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA certificate(s)")
+		}
+		pool.AddCert(caCert)
+	}
+	return pool, nil
+}
+
+// AnalyzeChain checks each issuer/subject link and signature in a presented
+// chain, flags wrong order, missing intermediates, duplicated or unused
+// certificates, and roots that did not need to be sent, then validates the
+// path to the trust anchors in opts.
+func AnalyzeChain(certs []*Certificate, opts ChainOptions) *ChainAnalysis {
+	a := &ChainAnalysis{
+		Certificates: certs,
+		Links:        make([]ChainLink, len(certs)),
+		Ordered:      true,
+		TrustSource:  opts.RootsSource,
+		Errors:       []string{},
+		Warnings:     []string{},
+	}
+	if a.TrustSource == "" {
+		a.TrustSource = "system roots"
+	}
+	if len(certs) == 0 {
+		return a
+	}
+
+	// Duplicates and self-signed certificates
+	for i, c := range certs {
+		link := &a.Links[i]
+		link.Index = i
+		link.IssuerIndex = -1
+		for j := 0; j < i; j++ {
+			if bytes.Equal(c.Raw, certs[j].Raw) {
+				link.Duplicate = true
+				a.Warnings = append(a.Warnings, fmt.Sprintf("%s is a duplicate of %s", chainLabel(certs, i), chainLabel(certs, j)))
+				break
+			}
+		}
+		if bytes.Equal(c.RawIssuer, c.RawSubject) && c.CheckSignature(c.SignatureAlgorithm, c.RawTBSCertificate, c.Signature) == nil {
+			link.SelfSigned = true
+			link.SignatureValid = true
+		}
+	}
+
+	// Issuer links within the presented certificates
+	for i, c := range certs {
+		link := &a.Links[i]
+		if link.Duplicate || link.SelfSigned {
+			continue
+		}
+		failed, failErr := -1, error(nil)
+		for j, candidate := range certs {
+			if j == i || a.Links[j].Duplicate || !isIssuerCandidate(c.Certificate, candidate.Certificate) {
+				continue
+			}
+			err := c.CheckSignatureFrom(candidate.Certificate)
+			if err == nil {
+				link.IssuerIndex = j
+				link.SignatureValid = true
+				break
+			}
+			if failed == -1 {
+				failed, failErr = j, err
+			}
+		}
+		if link.IssuerIndex == -1 && failed != -1 {
+			// Keep the name match so the broken link is reported
+			link.IssuerIndex = failed
+			a.Errors = append(a.Errors, fmt.Sprintf("Signature on %s does not verify with %s: %v", chainLabel(certs, i), chainLabel(certs, failed), failErr))
+		}
+	}
+
+	// Walk from the leaf through presented issuers (matched by name, so a
+	// bad signature is reported once rather than as a missing issuer)
+	onPath := map[int]bool{0: true}
+	top := 0
+	for {
+		next := a.Links[top].IssuerIndex
+		if next == -1 || onPath[next] {
+			break
+		}
+		if next != top+1 {
+			a.Ordered = false
+			a.Errors = append(a.Errors, fmt.Sprintf("Chain is out of order: %s is issued by %s, which should come directly after it", chainLabel(certs, top), chainLabel(certs, next)))
+		}
+		onPath[next] = true
+		top = next
+	}
+
+	for i := range certs {
+		if !onPath[i] && !a.Links[i].Duplicate {
+			a.Links[i].Unused = true
+			a.Warnings = append(a.Warnings, fmt.Sprintf("%s is not part of the chain from the leaf", chainLabel(certs, i)))
+		}
+	}
+
+	if top != 0 && a.Links[top].SelfSigned {
+		a.Warnings = append(a.Warnings, fmt.Sprintf("Root certificate %s is included; clients already have trust anchors, so servers don't need to send it", chainLabel(certs, top)))
+	}
+
+	// Validate the path to the trust anchors, offering every presented
+	// certificate as a possible intermediate
+	intermediates := x509.NewCertPool()
+	for i, c := range certs[1:] {
+		if !a.Links[i+1].Duplicate {
+			intermediates.AddCert(c.Certificate)
+		}
+	}
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         opts.Roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err == nil && len(chains) > 0 {
+		a.Trusted = true
+		a.Complete = true
+		a.Path = chains[0]
+		a.PathSent = make([]bool, len(a.Path))
+		for i, p := range a.Path {
+			a.PathSent[i] = isPresented(certs, p)
+		}
+		return a
+	}
+
+	// The presented chain is complete if it ends at a root, or at a
+	// certificate issued by a trust anchor (Verify may have failed for
+	// another reason, such as expiry)
+	a.Complete = a.Links[top].SelfSigned
+	if !a.Complete {
+		_, topErr := certs[top].Verify(x509.VerifyOptions{Roots: opts.Roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+		var unknown x509.UnknownAuthorityError
+		a.Complete = !errors.As(topErr, &unknown)
+	}
+	if !a.Complete {
+		a.Errors = append(a.Errors, fmt.Sprintf("Incomplete chain: the issuer of %s (%s) was not provided and is not a trusted root", chainLabel(certs, top), certs[top].Issuer.String()))
+	}
+	a.Errors = append(a.Errors, fmt.Sprintf("Not trusted by %s: %v", a.TrustSource, err))
+	return a
+}
+
+// isIssuerCandidate reports whether parent's subject matches child's issuer,
+// using the key identifiers to tell apart CAs with the same name.
+func isIssuerCandidate(child, parent *x509.Certificate) bool {
+	if !bytes.Equal(child.RawIssuer, parent.RawSubject) {
+		return false
+	}
+	if len(child.AuthorityKeyId) > 0 && len(parent.SubjectKeyId) > 0 {
+		return bytes.Equal(child.AuthorityKeyId, parent.SubjectKeyId)
+	}
+	return true
+}
+
+// isPresented reports whether c is one of the presented certificates
+func isPresented(certs []*Certificate, c *x509.Certificate) bool {
+	for _, p := range certs {
+		if bytes.Equal(p.Raw, c.Raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// chainLabel names a presented certificate by position and common name
+func chainLabel(certs []*Certificate, i int) string {
+	name := certs[i].Subject.CommonName
+	if name == "" {
+		name = certs[i].Subject.String()
+	}
+	if i == 0 {
+		return fmt.Sprintf("the leaf (%s)", name)
+	}
+	return fmt.Sprintf("Chain[%d] (%s)", i, name)
+}
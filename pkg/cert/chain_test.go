@@ -0,0 +1,267 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"strings"
+	"testing"
+	"time"
+
+	"certwiz/internal/testutil"
+)
+
+// testCA is a certificate with its key, for building chains in tests
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// newTestCert issues a certificate signed by parent (self-signed when parent is nil)
+func newTestCert(t *testing.T, cn string, isCA bool, parent *testCA, skid []byte) *testCA {
+	t.Helper()
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		t.Fatalf("newSerialNumber failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		SubjectKeyId:          skid,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.ExtKeyUsage = nil
+	} else {
+		template.DNSNames = []string{cn}
+	}
+
+	return issueTestCert(t, template, key, parent)
+}
+
+// issueTestCert signs template for key with parent (self-signed when parent is nil)
+func issueTestCert(t *testing.T, template *x509.Certificate, key crypto.Signer, parent *testCA) *testCA {
+	t.Helper()
+	issuer, signer := template, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(nil, template, issuer, key.Public(), signer)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed: %v", err)
+	}
+	return &testCA{cert: c, key: key}
+}
+
+func toCertificates(certs ...*x509.Certificate) []*Certificate {
+	result := make([]*Certificate, 0, len(certs))
+	for _, c := range certs {
+		result = append(result, &Certificate{Certificate: c})
+	}
+	return result
+}
+
+func containsMessage(messages []string, substr string) bool {
+	for _, m := range messages {
+		if strings.Contains(m, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAnalyzeChain(t *testing.T) {
+	root := newTestCert(t, "Chain Test Root", true, nil, nil)
+	inter := newTestCert(t, "Chain Test Intermediate", true, root, []byte{1, 2, 3})
+	leaf := newTestCert(t, "leaf.example.com", false, inter, nil)
+	other := newTestCert(t, "Unrelated Root", true, nil, nil)
+
+	// Same name and key identifier as the intermediate, but a different key
+	impostor := newTestCert(t, "Chain Test Intermediate", true, root, []byte{1, 2, 3})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+	trusted := ChainOptions{Roots: roots, RootsSource: "test roots"}
+
+	tests := []struct {
+		name         string
+		certs        []*x509.Certificate
+		opts         ChainOptions
+		wantOrdered  bool
+		wantComplete bool
+		wantTrusted  bool
+		wantErrors   []string
+		wantWarnings []string
+	}{
+		{
+			name:         "Correct chain",
+			certs:        []*x509.Certificate{leaf.cert, inter.cert},
+			opts:         trusted,
+			wantOrdered:  true,
+			wantComplete: true,
+			wantTrusted:  true,
+		},
+		{
+			name:         "Root sent",
+			certs:        []*x509.Certificate{leaf.cert, inter.cert, root.cert},
+			opts:         trusted,
+			wantOrdered:  true,
+			wantComplete: true,
+			wantTrusted:  true,
+			wantWarnings: []string{"Root certificate Chain[2]"},
+		},
+		{
+			name:         "Wrong order",
+			certs:        []*x509.Certificate{leaf.cert, root.cert, inter.cert},
+			opts:         trusted,
+			wantOrdered:  false,
+			wantComplete: true,
+			wantTrusted:  true,
+			wantErrors:   []string{"out of order"},
+			wantWarnings: []string{"Root certificate Chain[1]"},
+		},
+		{
+			name:         "Missing intermediate",
+			certs:        []*x509.Certificate{leaf.cert},
+			opts:         trusted,
+			wantOrdered:  true,
+			wantComplete: false,
+			wantTrusted:  false,
+			wantErrors:   []string{"Incomplete chain", "Not trusted by test roots"},
+		},
+		{
+			name:         "Duplicate certificate",
+			certs:        []*x509.Certificate{leaf.cert, inter.cert, inter.cert},
+			opts:         trusted,
+			wantOrdered:  true,
+			wantComplete: true,
+			wantTrusted:  true,
+			wantWarnings: []string{"duplicate of Chain[1]"},
+		},
+		{
+			name:         "Extra certificate",
+			certs:        []*x509.Certificate{leaf.cert, inter.cert, other.cert},
+			opts:         trusted,
+			wantOrdered:  true,
+			wantComplete: true,
+			wantTrusted:  true,
+			wantWarnings: []string{"Chain[2] (Unrelated Root) is not part of the chain"},
+		},
+		{
+			name:         "Untrusted root",
+			certs:        []*x509.Certificate{leaf.cert, inter.cert, root.cert},
+			opts:         ChainOptions{Roots: x509.NewCertPool()},
+			wantOrdered:  true,
+			wantComplete: true,
+			wantTrusted:  false,
+			wantErrors:   []string{"Not trusted by system roots"},
+			wantWarnings: []string{"Root certificate Chain[2]"},
+		},
+		{
+			name:         "Bad signature",
+			certs:        []*x509.Certificate{leaf.cert, impostor.cert},
+			opts:         trusted,
+			wantOrdered:  true,
+			wantComplete: true, // nothing is missing; the link is broken
+			wantTrusted:  false,
+			wantErrors:   []string{"Signature on the leaf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := AnalyzeChain(toCertificates(tt.certs...), tt.opts)
+			if a.Ordered != tt.wantOrdered {
+				t.Errorf("Ordered = %v, want %v", a.Ordered, tt.wantOrdered)
+			}
+			if a.Complete != tt.wantComplete {
+				t.Errorf("Complete = %v, want %v", a.Complete, tt.wantComplete)
+			}
+			if a.Trusted != tt.wantTrusted {
+				t.Errorf("Trusted = %v, want %v (errors: %v)", a.Trusted, tt.wantTrusted, a.Errors)
+			}
+			for _, e := range tt.wantErrors {
+				if !containsMessage(a.Errors, e) {
+					t.Errorf("Expected error containing %q, got %v", e, a.Errors)
+				}
+			}
+			if len(tt.wantErrors) == 0 && len(a.Errors) > 0 {
+				t.Errorf("Unexpected errors: %v", a.Errors)
+			}
+			for _, w := range tt.wantWarnings {
+				if !containsMessage(a.Warnings, w) {
+					t.Errorf("Expected warning containing %q, got %v", w, a.Warnings)
+				}
+			}
+			if len(tt.wantWarnings) == 0 && len(a.Warnings) > 0 {
+				t.Errorf("Unexpected warnings: %v", a.Warnings)
+			}
+		})
+	}
+}
+
+func TestAnalyzeChainPath(t *testing.T) {
+	root := newTestCert(t, "Path Root", true, nil, nil)
+	inter := newTestCert(t, "Path Intermediate", true, root, nil)
+	leaf := newTestCert(t, "path.example.com", false, inter, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+	a := AnalyzeChain(toCertificates(leaf.cert, inter.cert), ChainOptions{Roots: roots})
+
+	if len(a.Path) != 3 {
+		t.Fatalf("Path length = %d, want 3", len(a.Path))
+	}
+	wantSent := []bool{true, true, false}
+	for i, want := range wantSent {
+		if a.PathSent[i] != want {
+			t.Errorf("PathSent[%d] = %v, want %v", i, a.PathSent[i], want)
+		}
+	}
+	if a.Links[0].IssuerIndex != 1 || !a.Links[0].SignatureValid {
+		t.Errorf("Leaf link = %+v, want issuer 1 with a valid signature", a.Links[0])
+	}
+	if a.Links[1].IssuerIndex != -1 {
+		t.Errorf("Intermediate issuer index = %d, want -1 (root not sent)", a.Links[1].IssuerIndex)
+	}
+
+	ja := a.ToJSON()
+	if !ja.Trusted || len(ja.Path) != 3 || ja.Path[2].Sent {
+		t.Errorf("JSON analysis = %+v", ja)
+	}
+	if ja.Certificates[0].IssuerIndex == nil || *ja.Certificates[0].IssuerIndex != 1 {
+		t.Error("JSON should include the leaf's issuer index")
+	}
+	if ja.Certificates[1].IssuerIndex != nil {
+		t.Error("JSON should omit the issuer index when the issuer was not sent")
+	}
+}
+
+func TestLoadCertPool(t *testing.T) {
+	if _, err := LoadCertPool(testutil.TestdataPath("ca.pem")); err != nil {
+		t.Errorf("LoadCertPool(ca.pem) failed: %v", err)
+	}
+	if _, err := LoadCertPool(testutil.TestdataPath("valid.der")); err != nil {
+		t.Errorf("LoadCertPool(valid.der) failed: %v", err)
+	}
+	if _, err := LoadCertPool(testutil.TestdataPath("invalid.pem")); err == nil {
+		t.Error("Expected error for invalid CA file")
+	}
+	if _, err := LoadCertPool("/nonexistent/ca.pem"); err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+}
@@ -0,0 +1,213 @@
+package cert
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Cipher suite grades
+const (
+	CipherGradeSecure   = "secure"
+	CipherGradeWeak     = "weak"
+	CipherGradeInsecure = "insecure"
+)
+
+// Whose preference decides the negotiated cipher suite
+const (
+	CipherOrderServer = "server"
+	CipherOrderClient = "client"
+)
+
+// CipherSuiteInfo is a cipher suite accepted by a server, with its grade
+type CipherSuiteInfo struct {
+	ID     uint16
+	Name   string
+	Grade  string
+	Reason string // why the suite is weak or insecure
+}
+
+// errStopHandshake aborts the in-memory handshake used to read the client's cipher order
+var errStopHandshake = errors.New("stop handshake")
+
+// CheckTLSCiphers tests which TLS versions a server supports, like
+// CheckTLSVersionsWithOptions, and then enumerates the cipher suites it
+// accepts for each version by offering fewer and fewer suites until the
+// handshake fails.
+//
+// TLS 1.3 suites can't be restricted by crypto/tls, so only the negotiated
+// TLS 1.3 suite is reported (all TLS 1.3 suites are secure).
+func CheckTLSCiphers(host string, port int, opts ConnectOptions) (*TLSResult, error) {
+	result, err := CheckTLSVersionsWithOptions(host, port, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result.Versions {
+		v := &result.Versions[i]
+		if !v.Supported {
+			continue
+		}
+		if v.Version == TLSVersionTLS13 {
+			v.Ciphers = []CipherSuiteInfo{newCipherSuiteInfo(v.CipherSuite)}
+			continue
+		}
+		v.Ciphers, v.CipherOrder = enumerateCipherSuites(host, port, v.Version, opts)
+		v.CiphersEnumerated = true
+	}
+	result.CiphersChecked = true
+	return result, nil
+}
+
+// enumerateCipherSuites finds the suites a server accepts for a TLS 1.0-1.2
+// version, in the order it picks them, and whose preference that order is
+func enumerateCipherSuites(host string, port int, version TLSVersion, opts ConnectOptions) ([]CipherSuiteInfo, string) {
+	dialHost := opts.dialAddress(host, port)
+	remaining := cipherSuitesFor(version)
+	var accepted []uint16
+	for len(remaining) > 0 {
+		conn, _, err := dialTLS(dialHost, &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         host,
+			MinVersion:         uint16(version),
+			MaxVersion:         uint16(version),
+			CipherSuites:       remaining,
+		}, opts)
+		if err != nil {
+			break
+		}
+		suite := conn.ConnectionState().CipherSuite
+		_ = conn.Close()
+
+		next := removeCipherSuite(remaining, suite)
+		if len(next) == len(remaining) {
+			break // not one we offered; stop rather than loop
+		}
+		remaining = next
+		accepted = append(accepted, suite)
+	}
+
+	ciphers := make([]CipherSuiteInfo, 0, len(accepted))
+	for _, id := range accepted {
+		ciphers = append(ciphers, newCipherSuiteInfo(id))
+	}
+	return ciphers, cipherOrder(version, accepted)
+}
+
+// cipherOrder tells whether a server picked suites by its own preference or
+// the client's. The client's order is fixed by crypto/tls, not by
+// tls.Config.CipherSuites, so it is read from an in-memory handshake. A
+// server whose preference matches the client's is reported as following
+// the client.
+func cipherOrder(version TLSVersion, accepted []uint16) string {
+	if len(accepted) < 2 {
+		return ""
+	}
+	offered := clientCipherOrder(version, accepted)
+	if len(offered) != len(accepted) {
+		return ""
+	}
+	for i := range accepted {
+		if offered[i] != accepted[i] {
+			return CipherOrderServer
+		}
+	}
+	return CipherOrderClient
+}
+
+// clientCipherOrder returns the order in which crypto/tls offers suites
+func clientCipherOrder(version TLSVersion, suites []uint16) []uint16 {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	offered := make(chan []uint16, 1)
+	go func() {
+		defer serverConn.Close()
+		server := tls.Server(serverConn, &tls.Config{
+			GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				offered <- hello.CipherSuites
+				return nil, errStopHandshake
+			},
+		})
+		_ = server.Handshake()
+	}()
+
+	client := tls.Client(clientConn, &tls.Config{
+		InsecureSkipVerify: true,
+		MinVersion:         uint16(version),
+		MaxVersion:         uint16(version),
+		CipherSuites:       suites,
+	})
+	_ = client.Handshake()
+
+	select {
+	case order := <-offered:
+		return order
+	default:
+		return nil
+	}
+}
+
+// cipherSuitesFor returns every suite crypto/tls can offer for a version,
+// including the insecure ones
+func cipherSuitesFor(version TLSVersion) []uint16 {
+	var suites []uint16
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		for _, v := range s.SupportedVersions {
+			if v == uint16(version) {
+				suites = append(suites, s.ID)
+				break
+			}
+		}
+	}
+	return suites
+}
+
+// removeCipherSuite returns suites without id
+func removeCipherSuite(suites []uint16, id uint16) []uint16 {
+	out := make([]uint16, 0, len(suites))
+	for _, s := range suites {
+		if s != id {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// newCipherSuiteInfo describes and grades a cipher suite
+func newCipherSuiteInfo(id uint16) CipherSuiteInfo {
+	info := CipherSuiteInfo{ID: id, Name: tls.CipherSuiteName(id)}
+	info.Grade, info.Reason = gradeCipherSuite(info.Name)
+	return info
+}
+
+// gradeCipherSuite grades a suite by name. Insecure suites have practical
+// attacks; weak ones lack forward secrecy or use CBC mode.
+func gradeCipherSuite(name string) (grade, reason string) {
+	switch {
+	case strings.Contains(name, "_NULL_") || strings.Contains(name, "_anon_") || strings.Contains(name, "_EXPORT_"):
+		return CipherGradeInsecure, "no encryption or authentication"
+	case strings.Contains(name, "_RC4_"):
+		return CipherGradeInsecure, "RC4 is broken"
+	case strings.Contains(name, "_3DES_"):
+		return CipherGradeInsecure, "3DES is vulnerable to Sweet32"
+	case strings.HasPrefix(name, "TLS_RSA_"):
+		return CipherGradeWeak, "RSA key exchange has no forward secrecy"
+	case strings.Contains(name, "_CBC_"):
+		return CipherGradeWeak, "CBC mode is prone to padding oracle attacks"
+	default:
+		return CipherGradeSecure, ""
+	}
+}
+
+// CipherGradeCounts counts the accepted suites of each grade across all versions
+func (tr *TLSResult) CipherGradeCounts() map[string]int {
+	counts := map[string]int{}
+	for _, v := range tr.Versions {
+		for _, c := range v.Ciphers {
+			counts[c.Grade]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,86 @@
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"os"
+)
+
+// ClientCertRequest is a server's CertificateRequest: which client
+// certificates it will accept
+type ClientCertRequest struct {
+	AcceptableCAs    []string              // Distinguished names of accepted issuers; empty means any
+	SignatureSchemes []tls.SignatureScheme // Signature schemes the server accepts, in its order
+	CertificateSent  bool                  // A client certificate was sent in reply
+}
+
+// LoadClientCertificate reads a client certificate (with any intermediates
+// after it) and its private key for mutual TLS. If keyPath is empty, the
+// key is read from the certificate file. password decrypts an encrypted key.
+func LoadClientCertificate(certPath, keyPath, password string) (*tls.Certificate, error) {
+	certData, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	certs, _, err := parseCertificates(certData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+
+	keyData := certData
+	if keyPath != "" {
+		if keyData, err = os.ReadFile(keyPath); err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+	}
+	key, err := findPEMPrivateKey(keyData, password)
+	if err == nil && key == nil {
+		if keyPath == "" {
+			return nil, fmt.Errorf("no private key in %s (use a separate key file)", certPath)
+		}
+		key, err = ParsePrivateKey(keyData, password)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client key: %w", err)
+	}
+	if !publicKeysEqual(certs[0].PublicKey, key.Public()) {
+		return nil, fmt.Errorf("client key does not match the client certificate")
+	}
+
+	clientCert := &tls.Certificate{PrivateKey: key, Leaf: certs[0]}
+	for _, c := range certs {
+		clientCert.Certificate = append(clientCert.Certificate, c.Raw)
+	}
+	return clientCert, nil
+}
+
+// newClientCertRequest records a server's CertificateRequest
+func newClientCertRequest(info *tls.CertificateRequestInfo, sent bool) *ClientCertRequest {
+	req := &ClientCertRequest{SignatureSchemes: info.SignatureSchemes, CertificateSent: sent}
+	for _, der := range info.AcceptableCAs {
+		req.AcceptableCAs = append(req.AcceptableCAs, distinguishedName(der))
+	}
+	return req
+}
+
+// distinguishedName renders a DER-encoded name, falling back to hex
+func distinguishedName(der []byte) string {
+	var rdns pkix.RDNSequence
+	if rest, err := asn1.Unmarshal(der, &rdns); err != nil || len(rest) > 0 {
+		return fmt.Sprintf("%x", der)
+	}
+	var name pkix.Name
+	name.FillFromRDNSequence(&rdns)
+	return name.String()
+}
+
+// SignatureSchemeNames returns the names of the schemes, e.g. ECDSAWithP256AndSHA256
+func (r *ClientCertRequest) SignatureSchemeNames() []string {
+	names := make([]string, 0, len(r.SignatureSchemes))
+	for _, s := range r.SignatureSchemes {
+		names = append(names, s.String())
+	}
+	return names
+}
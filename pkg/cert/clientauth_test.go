@@ -0,0 +1,172 @@
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newMTLSServer starts a server that requires a client certificate issued
+// by the CA in caPath
+func newMTLSServer(t *testing.T, caPath string, maxVersion uint16) (string, int) {
+	t.Helper()
+	clientCAs, err := LoadCertPool(caPath)
+	if err != nil {
+		t.Fatalf("LoadCertPool failed: %v", err)
+	}
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	leaf := issueTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "internal.svc"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"internal.svc"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, key, nil)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{leaf.cert.Raw}, PrivateKey: key}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MaxVersion:   maxVersion,
+	})
+	if err != nil {
+		t.Fatalf("tls.Listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	return "127.0.0.1", ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestInspectURLClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	caCert, _, leafCert := newTestCAFiles(t, dir, "mTLS Client CA")
+	clientCert, err := LoadClientCertificate(leafCert, filepath.Join(dir, "leaf.key"), "")
+	if err != nil {
+		t.Fatalf("LoadClientCertificate failed: %v", err)
+	}
+
+	for _, version := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+		t.Run(TLSVersionName(version), func(t *testing.T) {
+			host, port := newMTLSServer(t, caCert, version)
+			opts := ConnectOptions{ConnectHost: host, Timeout: 2 * time.Second, ClientCertificate: clientCert}
+			c, _, err := InspectURLWithConnectOptions("internal.svc", port, opts)
+			if err != nil {
+				t.Fatalf("InspectURLWithConnectOptions failed: %v", err)
+			}
+			if c.Subject.CommonName != "internal.svc" {
+				t.Errorf("CommonName = %q", c.Subject.CommonName)
+			}
+
+			req := c.ClientCertRequest
+			if req == nil {
+				t.Fatal("ClientCertRequest not recorded")
+			}
+			if !req.CertificateSent {
+				t.Error("CertificateSent = false, want true")
+			}
+			if len(req.AcceptableCAs) != 1 || !strings.Contains(req.AcceptableCAs[0], "CN=mTLS Client CA") {
+				t.Errorf("AcceptableCAs = %v", req.AcceptableCAs)
+			}
+			if len(req.SignatureSchemes) == 0 || len(req.SignatureSchemeNames()) != len(req.SignatureSchemes) {
+				t.Errorf("SignatureSchemes = %v", req.SignatureSchemeNames())
+			}
+		})
+	}
+
+	// Without a client certificate, a TLS 1.2 handshake fails and says why
+	host, port := newMTLSServer(t, caCert, tls.VersionTLS12)
+	_, _, err = InspectURLWithConnectOptions("internal.svc", port, ConnectOptions{ConnectHost: host, Timeout: 2 * time.Second})
+	if err == nil || !strings.Contains(err.Error(), "requires a client certificate") {
+		t.Errorf("error = %v, want a client certificate error", err)
+	}
+}
+
+func TestCheckTLSVersionsClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	caCert, _, leafCert := newTestCAFiles(t, dir, "mTLS Client CA")
+	clientCert, err := LoadClientCertificate(leafCert, filepath.Join(dir, "leaf.key"), "")
+	if err != nil {
+		t.Fatalf("LoadClientCertificate failed: %v", err)
+	}
+	host, port := newMTLSServer(t, caCert, tls.VersionTLS12)
+
+	result, err := CheckTLSVersionsWithOptions(host, port, ConnectOptions{Timeout: 2 * time.Second, ClientCertificate: clientCert})
+	if err != nil {
+		t.Fatalf("CheckTLSVersionsWithOptions failed: %v", err)
+	}
+	if result.MaxSupported != TLSVersionTLS12 {
+		t.Errorf("MaxSupported = %v, want TLS 1.2", result.MaxSupported)
+	}
+	if result.ClientCertRequest == nil || !result.ClientCertRequest.CertificateSent {
+		t.Errorf("ClientCertRequest = %+v", result.ClientCertRequest)
+	}
+	if jr := result.ToJSON().ClientCertRequest; jr == nil || len(jr.AcceptableCAs) != 1 {
+		t.Errorf("JSON ClientCertRequest = %+v", jr)
+	}
+}
+
+func TestLoadClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	_, caKey, leafCert := newTestCAFiles(t, dir, "Client Load CA")
+	leafKey := filepath.Join(dir, "leaf.key")
+
+	// Certificate and key in one file
+	certData, _ := os.ReadFile(leafCert)
+	keyData, _ := os.ReadFile(leafKey)
+	combined := filepath.Join(dir, "combined.pem")
+	if err := os.WriteFile(combined, append(certData, keyData...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := LoadClientCertificate(combined, "", ""); err != nil || c.Leaf == nil || len(c.Certificate) != 1 {
+		t.Errorf("LoadClientCertificate(combined) = %v, %v", c, err)
+	}
+
+	if _, err := LoadClientCertificate(leafCert, "", ""); err == nil || !strings.Contains(err.Error(), "no private key") {
+		t.Errorf("Expected a missing key error, got %v", err)
+	}
+	if _, err := LoadClientCertificate(leafCert, caKey, ""); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected a key mismatch error, got %v", err)
+	}
+
+	// Encrypted keys take a password
+	key, err := LoadPrivateKey(leafKey, "")
+	if err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+	encryptedKey := filepath.Join(dir, "leaf-encrypted.key")
+	if err := writePrivateKey(encryptedKey, key, "secret"); err != nil {
+		t.Fatalf("writePrivateKey failed: %v", err)
+	}
+	if c, err := LoadClientCertificate(leafCert, encryptedKey, "secret"); err != nil || c.Leaf == nil {
+		t.Errorf("LoadClientCertificate(encrypted) = %v, %v", c, err)
+	}
+	if _, err := LoadClientCertificate(leafCert, encryptedKey, "wrong"); err == nil {
+		t.Error("Expected error for a wrong key password")
+	}
+	if _, err := LoadClientCertificate(filepath.Join(dir, "missing.crt"), leafKey, ""); err == nil {
+		t.Error("Expected error for a missing certificate file")
+	}
+}
@@ -0,0 +1,119 @@
+package cert
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ConnectOptions controls how remote TLS servers are reached
+type ConnectOptions struct {
+	ConnectHost string        // Dial this host instead of the target; SNI still uses the target
+	Timeout     time.Duration // Dial and handshake timeout; defaultDialTimeout if zero
+	SigAlg      string        // Preferred certificate signature algorithm: auto, ecdsa, or rsa
+	StartTLS    string        // Upgrade a plaintext connection first: smtp, imap, pop3, ldap, ftp, or postgres
+
+	ClientCertificate *tls.Certificate // Sent when the server requests a client certificate (mutual TLS)
+
+	Proxy                *url.URL // Tunnel through this proxy (see ParseProxyURL); nil connects directly
+	ProxyFromEnvironment bool     // Without Proxy, use HTTPS_PROXY unless NO_PROXY exempts the host
+}
+
+// timeout returns the dial timeout, defaulting when unset
+func (o ConnectOptions) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return defaultDialTimeout
+	}
+	return o.Timeout
+}
+
+// dialAddress returns the address to dial for host: ConnectHost if set
+func (o ConnectOptions) dialAddress(host string, port int) string {
+	if o.ConnectHost != "" {
+		host = o.ConnectHost
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// setupError is returned by dialTLS when the proxy tunnel or the STARTTLS
+// upgrade fails, as opposed to the TLS handshake that follows them
+type setupError struct {
+	err error
+}
+
+func (e *setupError) Error() string {
+	return e.err.Error()
+}
+
+func (e *setupError) Unwrap() error {
+	return e.err
+}
+
+// dialTLS connects to addr and completes a TLS handshake, upgrading the
+// plaintext connection with STARTTLS first when opts.StartTLS is set. The
+// timeout covers the dial, the upgrade, and the handshake.
+//
+// If the server requests a client certificate, opts.ClientCertificate is
+// sent and the request is returned; it is nil if the server didn't ask.
+func dialTLS(addr string, config *tls.Config, opts ConnectOptions) (*tls.Conn, *ClientCertRequest, error) {
+	var req *ClientCertRequest
+	config = config.Clone()
+	config.GetClientCertificate = func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		req = newClientCertRequest(info, opts.ClientCertificate != nil)
+		if opts.ClientCertificate == nil {
+			return &tls.Certificate{}, nil
+		}
+		return opts.ClientCertificate, nil
+	}
+
+	conn, err := dialTLSConn(addr, config, opts)
+	if err != nil && req != nil && !req.CertificateSent {
+		err = fmt.Errorf("server requires a client certificate: %w", err)
+	}
+	return conn, req, err
+}
+
+// dialTLSConn dials (through a proxy if configured) and runs the STARTTLS
+// upgrade and handshake for dialTLS
+func dialTLSConn(addr string, config *tls.Config, opts ConnectOptions) (*tls.Conn, error) {
+	proxy, err := opts.proxyFor(addr)
+	if err != nil {
+		return nil, &setupError{err: err}
+	}
+	dialer := &net.Dialer{Timeout: opts.timeout()}
+	if opts.StartTLS == "" && proxy == nil {
+		return tls.DialWithDialer(dialer, "tcp", addr, config)
+	}
+
+	deadline := time.Now().Add(opts.timeout())
+	dialer.Deadline = deadline
+	var conn net.Conn
+	if proxy != nil {
+		if conn, err = dialProxy(dialer, proxy, addr, deadline); err != nil {
+			return nil, &setupError{err: err}
+		}
+	} else if conn, err = dialer.Dial("tcp", addr); err == nil {
+		if err = conn.SetDeadline(deadline); err != nil {
+			_ = conn.Close()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.StartTLS != "" {
+		if err := startTLS(conn, opts.StartTLS); err != nil {
+			_ = conn.Close()
+			return nil, &setupError{err: fmt.Errorf("STARTTLS (%s) failed: %w", opts.StartTLS, err)}
+		}
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
@@ -0,0 +1,463 @@
+package cert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCRLDays is the default time until a CRL's next update
+const DefaultCRLDays = 7
+
+// oidExtensionReasonCode is the CRL entry reason code extension (RFC 5280 5.3.1)
+var oidExtensionReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// RevokedEntry is one revoked certificate in a revocation database or CRL
+type RevokedEntry struct {
+	SerialNumber *big.Int
+	RevokedAt    time.Time
+	Reason       int    // RFC 5280 CRLReason code
+	Subject      string // subject of the revoked certificate, when known
+}
+
+// RevocationDB tracks the certificates a CA has revoked and the number of
+// the last CRL it issued. It is stored as JSON next to the CA certificate.
+type RevocationDB struct {
+	CRLNumber int64
+	Entries   []RevokedEntry
+}
+
+// jsonRevocationDB is the on-disk format of a RevocationDB
+type jsonRevocationDB struct {
+	CRLNumber int64              `json:"crl_number"`
+	Revoked   []JSONRevokedEntry `json:"revoked"`
+}
+
+// CRLOptions contains options for issuing a CRL
+type CRLOptions struct {
+	CACert        string
+	CAKey         string
+	CAKeyPassword string // for an encrypted CA key
+	DBPath        string // revocation database; defaults to DefaultRevocationDBPath(CACert)
+	Days          int    // days until the next update; defaults to DefaultCRLDays
+}
+
+// RevokeOptions contains options for revoking a certificate
+type RevokeOptions struct {
+	CACert        string
+	CAKey         string
+	CAKeyURI      string // instead of CAKey: a key URI (see OpenSigner)
+	CAKeyPassword string // for an encrypted CA key
+	DBPath        string // revocation database; defaults to DefaultRevocationDBPath(CACert)
+	Days          int    // days until the next update of the reissued CRL
+	CertPath      string // certificate to revoke
+	Serial        string // or its serial number in hex, when the certificate is not at hand
+	Reason        int    // RFC 5280 CRLReason code
+}
+
+// CRLInfo is a parsed certificate revocation list
+type CRLInfo struct {
+	RevocationList     *x509.RevocationList
+	Source             string
+	Issuer             pkix.Name
+	Number             *big.Int
+	ThisUpdate         time.Time
+	NextUpdate         time.Time // zero if the CRL does not set one
+	SignatureAlgorithm string
+	Entries            []RevokedEntry
+	SignatureChecked   bool // whether the signature was checked against an issuer
+	SignatureValid     bool
+}
+
+// IsStale reports whether the CRL's next update time has passed
+func (c *CRLInfo) IsStale() bool {
+	return !c.NextUpdate.IsZero() && c.NextUpdate.Before(time.Now())
+}
+
+// Find returns the entry for a serial number, or nil if it is not revoked
+func (c *CRLInfo) Find(serial *big.Int) *RevokedEntry {
+	return findRevokedEntry(c.Entries, serial)
+}
+
+// CheckSignature verifies the CRL's signature with issuer and records the result
+func (c *CRLInfo) CheckSignature(issuer *x509.Certificate) error {
+	c.SignatureChecked = true
+	err := c.RevocationList.CheckSignatureFrom(issuer)
+	c.SignatureValid = err == nil
+	return err
+}
+
+// CRLCheck is the result of checking a certificate against a CRL file
+type CRLCheck struct {
+	Path    string
+	CRL     *CRLInfo
+	Revoked *RevokedEntry // nil if the certificate is not on the list
+}
+
+// DefaultRevocationDBPath returns where the revocation database for a CA
+// certificate is kept: next to it, with a .revoked.json extension.
+func DefaultRevocationDBPath(caCertPath string) string {
+	return strings.TrimSuffix(caCertPath, filepath.Ext(caCertPath)) + ".revoked.json"
+}
+
+// DefaultCRLPath returns the default CRL output path for a CA certificate
+func DefaultCRLPath(caCertPath string) string {
+	return strings.TrimSuffix(caCertPath, filepath.Ext(caCertPath)) + ".crl"
+}
+
+// ParseRevocationReason parses an RFC 5280 reason name (case-insensitive,
+// e.g. keyCompromise) or numeric code. An empty string is unspecified.
+func ParseRevocationReason(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	reason := -1
+	if n, err := strconv.Atoi(s); err == nil {
+		reason = n
+	} else {
+		for code, name := range revocationReasons {
+			if strings.EqualFold(name, s) {
+				reason = code
+				break
+			}
+		}
+	}
+	// removeFromCRL only appears in delta CRLs
+	if _, ok := revocationReasons[reason]; !ok || reason == 8 {
+		return 0, fmt.Errorf("invalid revocation reason %q (use one of: %s)", s, strings.Join(RevocationReasonNames(), ", "))
+	}
+	return reason, nil
+}
+
+// RevocationReasonNames lists the reasons accepted when revoking a certificate
+func RevocationReasonNames() []string {
+	names := []string{}
+	for code := 0; code <= 10; code++ {
+		if name, ok := revocationReasons[code]; ok && code != 8 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// LoadRevocationDB reads a revocation database. A missing file is an empty database.
+func LoadRevocationDB(path string) (*RevocationDB, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &RevocationDB{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revocation database: %w", err)
+	}
+
+	var stored jsonRevocationDB
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse revocation database %s: %w", path, err)
+	}
+	db := &RevocationDB{CRLNumber: stored.CRLNumber}
+	for _, e := range stored.Revoked {
+		serial, err := parseSerialNumber(e.SerialNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse revocation database %s: %w", path, err)
+		}
+		reason, err := ParseRevocationReason(e.Reason)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse revocation database %s: %w", path, err)
+		}
+		db.Entries = append(db.Entries, RevokedEntry{
+			SerialNumber: serial,
+			RevokedAt:    e.RevokedAt,
+			Reason:       reason,
+			Subject:      e.Subject,
+		})
+	}
+	return db, nil
+}
+
+// Save writes the revocation database to path
+func (db *RevocationDB) Save(path string) error {
+	stored := jsonRevocationDB{CRLNumber: db.CRLNumber, Revoked: []JSONRevokedEntry{}}
+	for _, e := range db.Entries {
+		stored.Revoked = append(stored.Revoked, e.ToJSON())
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode revocation database: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write revocation database: %w", err)
+	}
+	return nil
+}
+
+// Find returns the entry for a serial number, or nil if it is not revoked
+func (db *RevocationDB) Find(serial *big.Int) *RevokedEntry {
+	return findRevokedEntry(db.Entries, serial)
+}
+
+// Revoke adds an entry, refusing serial numbers that are already revoked
+func (db *RevocationDB) Revoke(entry RevokedEntry) error {
+	if existing := db.Find(entry.SerialNumber); existing != nil {
+		return fmt.Errorf("certificate %s was already revoked on %s", entry.SerialNumber.Text(16),
+			existing.RevokedAt.UTC().Format("2006-01-02 15:04:05 UTC"))
+	}
+	db.Entries = append(db.Entries, entry)
+	return nil
+}
+
+func findRevokedEntry(entries []RevokedEntry, serial *big.Int) *RevokedEntry {
+	for i := range entries {
+		if entries[i].SerialNumber.Cmp(serial) == 0 {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// GenerateCRL issues a new CRL listing every certificate in the CA's
+// revocation database, signed with the CA key, and writes it to crlPath
+func GenerateCRL(opts CRLOptions, crlPath string) (*CRLInfo, error) {
+	caCert, caKey, err := loadCA(opts.CACert, opts.CAKey, "", opts.CAKeyPassword)
+	if err != nil {
+		return nil, err
+	}
+	defer caKey.Close()
+	dbPath := opts.DBPath
+	if dbPath == "" {
+		dbPath = DefaultRevocationDBPath(opts.CACert)
+	}
+	db, err := LoadRevocationDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return issueCRL(caCert, caKey, db, dbPath, opts.Days, crlPath)
+}
+
+// Revoke records a certificate as revoked in the CA's revocation database
+// and reissues the CRL at crlPath
+func Revoke(opts RevokeOptions, crlPath string) (*RevokedEntry, *CRLInfo, error) {
+	caCert, caKey, err := loadCA(opts.CACert, opts.CAKey, opts.CAKeyURI, opts.CAKeyPassword)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer caKey.Close()
+
+	entry := RevokedEntry{RevokedAt: time.Now().UTC().Truncate(time.Second), Reason: opts.Reason}
+	switch {
+	case opts.CertPath != "":
+		c, err := InspectFile(opts.CertPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := c.CheckSignatureFrom(caCert); err != nil {
+			return nil, nil, fmt.Errorf("certificate was not issued by this CA: %w", err)
+		}
+		entry.SerialNumber = c.SerialNumber
+		entry.Subject = c.Subject.String()
+	case opts.Serial != "":
+		entry.SerialNumber, err = parseSerialNumber(opts.Serial)
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("a certificate or serial number to revoke is required")
+	}
+
+	dbPath := opts.DBPath
+	if dbPath == "" {
+		dbPath = DefaultRevocationDBPath(opts.CACert)
+	}
+	db, err := LoadRevocationDB(dbPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.Revoke(entry); err != nil {
+		return nil, nil, err
+	}
+
+	info, err := issueCRL(caCert, caKey, db, dbPath, opts.Days, crlPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := markRevokedInIndex(opts.CACert, entry); err != nil {
+		return nil, nil, err
+	}
+	return &entry, info, nil
+}
+
+// issueCRL signs a CRL for the entries in db, bumping its CRL number, and
+// saves both the CRL and the database
+func issueCRL(caCert *x509.Certificate, caKey crypto.Signer, db *RevocationDB, dbPath string, days int, crlPath string) (*CRLInfo, error) {
+	if caCert.KeyUsage != 0 && caCert.KeyUsage&x509.KeyUsageCRLSign == 0 {
+		return nil, fmt.Errorf("CA certificate does not allow CRL signing (missing cRLSign key usage)")
+	}
+	if days <= 0 {
+		days = DefaultCRLDays
+	}
+
+	// Entries use the pkix form so the CRL can be built with Go 1.20
+	revoked := make([]pkix.RevokedCertificate, 0, len(db.Entries))
+	for _, e := range db.Entries {
+		rc := pkix.RevokedCertificate{SerialNumber: e.SerialNumber, RevocationTime: e.RevokedAt}
+		// RFC 5280: the reason code should be absent rather than unspecified
+		if e.Reason != 0 {
+			value, err := asn1.Marshal(asn1.Enumerated(e.Reason))
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode revocation reason: %w", err)
+			}
+			rc.Extensions = []pkix.Extension{{Id: oidExtensionReasonCode, Value: value}}
+		}
+		revoked = append(revoked, rc)
+	}
+
+	now := time.Now()
+	template := &x509.RevocationList{
+		Number:              big.NewInt(db.CRLNumber + 1),
+		ThisUpdate:          now,
+		NextUpdate:          now.AddDate(0, 0, days),
+		RevokedCertificates: revoked,
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, caCert, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CRL: %w", err)
+	}
+
+	db.CRLNumber++
+	if err := db.Save(dbPath); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(crlPath, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write CRL: %w", err)
+	}
+
+	info, err := ParseCRL(der, crlPath)
+	if err != nil {
+		return nil, err
+	}
+	_ = info.CheckSignature(caCert)
+	return info, nil
+}
+
+// InspectCRLFile reads and parses a PEM or DER CRL file
+func InspectCRLFile(path string) (*CRLInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CRL: %w", err)
+	}
+	return ParseCRL(data, path)
+}
+
+// ParseCRL parses a PEM or DER certificate revocation list
+func ParseCRL(data []byte, source string) (*CRLInfo, error) {
+	der := data
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("expected an X509 CRL PEM block, found %s", block.Type)
+		}
+		der = block.Bytes
+	}
+	rl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL: %w", err)
+	}
+
+	info := &CRLInfo{
+		RevocationList:     rl,
+		Source:             source,
+		Issuer:             rl.Issuer,
+		Number:             rl.Number,
+		ThisUpdate:         rl.ThisUpdate,
+		NextUpdate:         rl.NextUpdate,
+		SignatureAlgorithm: rl.SignatureAlgorithm.String(),
+		Entries:            []RevokedEntry{},
+	}
+	for _, rc := range rl.RevokedCertificates {
+		entry := RevokedEntry{SerialNumber: rc.SerialNumber, RevokedAt: rc.RevocationTime}
+		for _, ext := range rc.Extensions {
+			if !ext.Id.Equal(oidExtensionReasonCode) {
+				continue
+			}
+			var reason asn1.Enumerated
+			if _, err := asn1.Unmarshal(ext.Value, &reason); err != nil {
+				return nil, fmt.Errorf("failed to parse CRL entry reason: %w", err)
+			}
+			entry.Reason = int(reason)
+		}
+		info.Entries = append(info.Entries, entry)
+	}
+	return info, nil
+}
+
+// CheckCRL checks c against the CRL at path. The CRL's signature is
+// verified with the first matching issuer among candidates; SignatureChecked
+// is false when none is available.
+func CheckCRL(c *x509.Certificate, path string, candidates []*x509.Certificate) (*CRLCheck, error) {
+	info, err := InspectCRLFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(info.RevocationList.RawIssuer, c.RawIssuer) {
+		return nil, fmt.Errorf("CRL was issued by %s, not by the certificate's issuer %s", info.Issuer.String(), c.Issuer.String())
+	}
+	for _, candidate := range candidates {
+		if bytes.Equal(candidate.RawSubject, info.RevocationList.RawIssuer) && c.CheckSignatureFrom(candidate) == nil {
+			if err := info.CheckSignature(candidate); err != nil {
+				return nil, fmt.Errorf("CRL signature is invalid: %w", err)
+			}
+			break
+		}
+	}
+	return &CRLCheck{Path: path, CRL: info, Revoked: info.Find(c.SerialNumber)}, nil
+}
+
+// loadCA reads a CA certificate and opens its signing key, from keyURI if
+// set or else the key file, and checks that they match. password decrypts
+// an encrypted key file or is the PKCS#11 PIN. Callers Close the signer.
+func loadCA(certPath, keyPath, keyURI, password string) (*x509.Certificate, *Signer, error) {
+	caCertData, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	caCert, _, err := parseCertificate(caCertData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	if keyURI == "" {
+		keyURI = "file:" + keyPath
+	}
+	caKey, err := OpenSigner(keyURI, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load CA private key: %w", err)
+	}
+	if !publicKeysEqual(caCert.PublicKey, caKey.Public()) {
+		caKey.Close()
+		return nil, nil, fmt.Errorf("CA private key does not match the CA certificate")
+	}
+	return caCert, caKey, nil
+}
+
+// parseSerialNumber parses a hex serial number, optionally colon-separated
+// or prefixed with 0x
+func parseSerialNumber(s string) (*big.Int, error) {
+	hex := strings.ReplaceAll(strings.TrimSpace(s), ":", "")
+	hex = strings.TrimPrefix(strings.TrimPrefix(hex, "0x"), "0X")
+	serial, ok := new(big.Int).SetString(hex, 16)
+	if !ok || serial.Sign() < 0 {
+		return nil, fmt.Errorf("invalid serial number %q (expected hex, e.g. 1a:2b:3c)", s)
+	}
+	return serial, nil
+}
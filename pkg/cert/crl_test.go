@@ -0,0 +1,243 @@
+package cert
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// newTestCAFiles creates a CA and a certificate it signed in dir
+func newTestCAFiles(t *testing.T, dir, cn string) (caCert, caKey, leafCert string) {
+	t.Helper()
+	caCert = filepath.Join(dir, "ca.crt")
+	caKey = filepath.Join(dir, "ca.key")
+	if err := GenerateCA(CAOptions{CommonName: cn, Days: 30, KeyAlgorithm: KeyAlgorithmECDSAP256}, caCert, caKey); err != nil {
+		t.Fatalf("GenerateCA failed: %v", err)
+	}
+
+	csrPath := filepath.Join(dir, "leaf.csr")
+	if err := GenerateCSR(CSROptions{CommonName: "revoked.example.com", KeyAlgorithm: KeyAlgorithmECDSAP256}, csrPath, filepath.Join(dir, "leaf.key")); err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+	leafCert = filepath.Join(dir, "leaf.crt")
+	if err := SignCSR(SignOptions{CSRPath: csrPath, CACert: caCert, CAKey: caKey, Days: 30}, leafCert); err != nil {
+		t.Fatalf("SignCSR failed: %v", err)
+	}
+	return caCert, caKey, leafCert
+}
+
+func TestParseRevocationReason(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"", ocsp.Unspecified, false},
+		{"keyCompromise", ocsp.KeyCompromise, false},
+		{"KEYCOMPROMISE", ocsp.KeyCompromise, false},
+		{"superseded", ocsp.Superseded, false},
+		{"5", ocsp.CessationOfOperation, false},
+		{"removeFromCRL", 0, true},
+		{"7", 0, true},
+		{"stolen", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRevocationReason(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRevocationReason(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRevocationReason(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseSerialNumber(t *testing.T) {
+	for _, s := range []string{"3fa2910c", "3f:a2:91:0c", "0x3FA2910C"} {
+		serial, err := parseSerialNumber(s)
+		if err != nil || serial.Cmp(big.NewInt(0x3fa2910c)) != 0 {
+			t.Errorf("parseSerialNumber(%q) = %v, %v", s, serial, err)
+		}
+	}
+	if _, err := parseSerialNumber("not-hex"); err == nil {
+		t.Error("Expected error for invalid serial number")
+	}
+}
+
+func TestRevokeAndGenerateCRL(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey, leafCert := newTestCAFiles(t, dir, "CRL Test CA")
+	crlPath := DefaultCRLPath(caCert)
+	dbPath := DefaultRevocationDBPath(caCert)
+	if crlPath != filepath.Join(dir, "ca.crl") || dbPath != filepath.Join(dir, "ca.revoked.json") {
+		t.Fatalf("Default paths = %s, %s", crlPath, dbPath)
+	}
+
+	// An empty CRL before anything is revoked
+	info, err := GenerateCRL(CRLOptions{CACert: caCert, CAKey: caKey}, crlPath)
+	if err != nil {
+		t.Fatalf("GenerateCRL failed: %v", err)
+	}
+	if info.Number.Int64() != 1 || len(info.Entries) != 0 || !info.SignatureValid {
+		t.Errorf("Empty CRL: number=%v entries=%d signature=%v", info.Number, len(info.Entries), info.SignatureValid)
+	}
+	if days := info.NextUpdate.Sub(info.ThisUpdate); days != DefaultCRLDays*24*time.Hour {
+		t.Errorf("Next update is %v after this update, want %d days", days, DefaultCRLDays)
+	}
+
+	entry, info, err := Revoke(RevokeOptions{CACert: caCert, CAKey: caKey, CertPath: leafCert, Reason: ocsp.KeyCompromise, Days: 30}, crlPath)
+	if err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if !strings.Contains(entry.Subject, "revoked.example.com") {
+		t.Errorf("Entry subject = %q", entry.Subject)
+	}
+	if info.Number.Int64() != 2 || len(info.Entries) != 1 {
+		t.Fatalf("CRL after revoke: number=%v entries=%d", info.Number, len(info.Entries))
+	}
+
+	// Re-read the CRL from disk and check the entry round-trips
+	parsed, err := InspectCRLFile(crlPath)
+	if err != nil {
+		t.Fatalf("InspectCRLFile failed: %v", err)
+	}
+	got := parsed.Find(entry.SerialNumber)
+	if got == nil {
+		t.Fatal("Revoked serial is not in the CRL")
+	}
+	if got.Reason != ocsp.KeyCompromise || !got.RevokedAt.Equal(entry.RevokedAt) {
+		t.Errorf("CRL entry = %+v, want reason keyCompromise at %v", got, entry.RevokedAt)
+	}
+	if parsed.SignatureChecked {
+		t.Error("Signature should not be checked until an issuer is given")
+	}
+	ca, err := InspectFile(caCert)
+	if err != nil {
+		t.Fatalf("InspectFile failed: %v", err)
+	}
+	if err := parsed.CheckSignature(ca.Certificate); err != nil || !parsed.SignatureValid {
+		t.Errorf("CheckSignature failed: %v", err)
+	}
+
+	// Revoking the same certificate twice is refused
+	if _, _, err := Revoke(RevokeOptions{CACert: caCert, CAKey: caKey, CertPath: leafCert}, crlPath); err == nil || !strings.Contains(err.Error(), "already revoked") {
+		t.Errorf("Expected already revoked error, got %v", err)
+	}
+
+	// Revoking by serial number
+	if _, info, err = Revoke(RevokeOptions{CACert: caCert, CAKey: caKey, Serial: "0a:0b"}, crlPath); err != nil {
+		t.Fatalf("Revoke by serial failed: %v", err)
+	}
+	if len(info.Entries) != 2 || info.Find(big.NewInt(0x0a0b)) == nil {
+		t.Errorf("CRL entries = %+v", info.Entries)
+	}
+
+	// The database keeps the CRL number and entries
+	db, err := LoadRevocationDB(dbPath)
+	if err != nil {
+		t.Fatalf("LoadRevocationDB failed: %v", err)
+	}
+	if db.CRLNumber != 3 || len(db.Entries) != 2 || db.Find(entry.SerialNumber).Subject != entry.Subject {
+		t.Errorf("Database = %+v", db)
+	}
+
+	// JSON output
+	jc := info.ToJSON()
+	if jc.Number != "3" || len(jc.Revoked) != 2 || jc.Revoked[0].Reason != "keyCompromise" || jc.SignatureValid == nil {
+		t.Errorf("JSON CRL = %+v", jc)
+	}
+}
+
+func TestRevokeErrors(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey, _ := newTestCAFiles(t, dir, "CRL Test CA")
+	otherDir := t.TempDir()
+	otherCA, otherKey, otherLeaf := newTestCAFiles(t, otherDir, "Other CRL CA")
+	crlPath := filepath.Join(dir, "ca.crl")
+
+	// A certificate from another CA
+	if _, _, err := Revoke(RevokeOptions{CACert: caCert, CAKey: caKey, CertPath: otherLeaf}, crlPath); err == nil || !strings.Contains(err.Error(), "not issued by this CA") {
+		t.Errorf("Expected issuer mismatch error, got %v", err)
+	}
+	// Mismatched CA key
+	if _, _, err := Revoke(RevokeOptions{CACert: caCert, CAKey: otherKey, Serial: "01"}, crlPath); err == nil {
+		t.Error("Expected error for mismatched CA key")
+	}
+	// Nothing to revoke
+	if _, _, err := Revoke(RevokeOptions{CACert: otherCA, CAKey: otherKey}, crlPath); err == nil {
+		t.Error("Expected error without a certificate or serial")
+	}
+	// Corrupt database
+	dbPath := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(dbPath, []byte("{"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := GenerateCRL(CRLOptions{CACert: caCert, CAKey: caKey, DBPath: dbPath}, crlPath); err == nil {
+		t.Error("Expected error for corrupt revocation database")
+	}
+	// Not a CRL
+	if _, err := InspectCRLFile(caCert); err == nil {
+		t.Error("Expected error when inspecting a certificate as a CRL")
+	}
+}
+
+func TestVerifyWithCRL(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey, leafCert := newTestCAFiles(t, dir, "CRL Test CA")
+	crlPath := filepath.Join(dir, "ca.crl")
+
+	if _, err := GenerateCRL(CRLOptions{CACert: caCert, CAKey: caKey}, crlPath); err != nil {
+		t.Fatalf("GenerateCRL failed: %v", err)
+	}
+	result, err := VerifyWithOptions(VerifyOptions{CertPath: leafCert, CAPath: caCert, CRLPath: crlPath})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if !result.IsValid || result.CRL == nil || result.CRL.Revoked != nil || len(result.Warnings) != 0 {
+		t.Errorf("Unrevoked certificate: valid=%v errors=%v warnings=%v", result.IsValid, result.Errors, result.Warnings)
+	}
+
+	// Without the issuer the signature can't be checked
+	result, err = VerifyWithOptions(VerifyOptions{CertPath: leafCert, CRLPath: crlPath})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if !result.IsValid || !containsMessage(result.Warnings, "CRL signature not verified") {
+		t.Errorf("Expected unverified signature warning, got %v", result.Warnings)
+	}
+
+	if _, _, err := Revoke(RevokeOptions{CACert: caCert, CAKey: caKey, CertPath: leafCert, Reason: ocsp.Superseded}, crlPath); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	result, err = VerifyWithOptions(VerifyOptions{CertPath: leafCert, CAPath: caCert, CRLPath: crlPath})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if result.IsValid || !containsMessage(result.Errors, "revoked") || !containsMessage(result.Errors, "superseded") {
+		t.Errorf("Revoked certificate: valid=%v errors=%v", result.IsValid, result.Errors)
+	}
+	jr := result.ToJSON()
+	if jr.CRL == nil || !jr.CRL.Revoked || jr.CRL.Entry == nil || jr.CRL.Entry.Reason != "superseded" {
+		t.Errorf("JSON CRL check = %+v", jr.CRL)
+	}
+
+	// A CRL from a different CA is an error
+	otherCA, otherKey, _ := newTestCAFiles(t, t.TempDir(), "Other CRL CA")
+	otherCRL := filepath.Join(dir, "other.crl")
+	if _, err := GenerateCRL(CRLOptions{CACert: otherCA, CAKey: otherKey}, otherCRL); err != nil {
+		t.Fatalf("GenerateCRL failed: %v", err)
+	}
+	result, err = VerifyWithOptions(VerifyOptions{CertPath: leafCert, CRLPath: otherCRL})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if result.IsValid || !containsMessage(result.Errors, "not by the certificate's issuer") {
+		t.Errorf("Expected issuer mismatch, got %v", result.Errors)
+	}
+}
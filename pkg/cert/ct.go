@@ -0,0 +1,458 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	casn1 "golang.org/x/crypto/cryptobyte/asn1"
+	"golang.org/x/crypto/ocsp"
+)
+
+// Where an SCT was delivered
+const (
+	SCTSourceEmbedded = "embedded" // X.509 extension in the certificate
+	SCTSourceTLS      = "tls"      // signed_certificate_timestamp TLS extension
+	SCTSourceOCSP     = "ocsp"     // extension in the stapled OCSP response
+)
+
+// SCT verification statuses
+const (
+	SCTStatusUnverified = "unverified"  // no log list, or the issuer needed for an embedded SCT is unknown
+	SCTStatusValid      = "valid"       // signature verified with the log's key
+	SCTStatusInvalid    = "invalid"     // bad signature or timestamp
+	SCTStatusUnknownLog = "unknown_log" // log is not in the log list
+)
+
+// CT log states from the log list (the state object's key)
+const (
+	CTLogStatePending   = "pending"
+	CTLogStateQualified = "qualified"
+	CTLogStateUsable    = "usable"
+	CTLogStateReadOnly  = "readonly"
+	CTLogStateRetired   = "retired"
+	CTLogStateRejected  = "rejected"
+)
+
+// ctPolicyLongLifetime is the certificate lifetime above which browsers
+// require an extra embedded SCT
+const ctPolicyLongLifetime = 180 * 24 * time.Hour
+
+var (
+	oidSCTList     = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	oidOCSPSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 5}
+)
+
+// SCT is a Signed Certificate Timestamp (RFC 6962): a CT log's promise to
+// include the certificate
+type SCT struct {
+	Source             string // SCTSourceEmbedded, SCTSourceTLS, or SCTSourceOCSP
+	Version            uint8  // 0 for v1
+	LogID              [sha256.Size]byte
+	Timestamp          time.Time
+	Extensions         []byte
+	HashAlgorithm      uint8 // TLS HashAlgorithm; 4 is SHA-256
+	SignatureAlgorithm uint8 // TLS SignatureAlgorithm; 1 is RSA, 3 is ECDSA
+	Signature          []byte
+
+	// Set by VerifyCT
+	Log    *CTLog // nil if the log is not in the log list
+	Status string
+	Error  string // why the SCT is invalid or unverified
+}
+
+// LogIDBase64 returns the log ID in the base64 form used by log lists
+func (s *SCT) LogIDBase64() string {
+	return base64.StdEncoding.EncodeToString(s.LogID[:])
+}
+
+// SignatureAlgorithmName returns the SCT's signature algorithm, e.g. ECDSA-SHA256
+func (s *SCT) SignatureAlgorithmName() string {
+	sig := map[uint8]string{1: "RSA", 3: "ECDSA"}[s.SignatureAlgorithm]
+	hash := map[uint8]string{4: "SHA256", 5: "SHA384", 6: "SHA512"}[s.HashAlgorithm]
+	if sig == "" || hash == "" {
+		return fmt.Sprintf("unknown (%d/%d)", s.HashAlgorithm, s.SignatureAlgorithm)
+	}
+	return sig + "-" + hash
+}
+
+// CTLog is a log from a CT log list
+type CTLog struct {
+	Description string
+	Operator    string
+	URL         string
+	LogID       [sha256.Size]byte
+	Key         crypto.PublicKey
+	State       string    // one of the CTLogState* constants
+	StateSince  time.Time // when the log entered State
+}
+
+// CTLogList is a list of known CT logs, such as Chrome's log_list.json
+type CTLogList struct {
+	Logs []*CTLog
+	byID map[[sha256.Size]byte]*CTLog
+}
+
+// CTPolicyResult is the outcome of checking a certificate's SCTs against
+// the browser CT policies (Chrome and Apple): 2 SCTs for certificates valid
+// for up to 180 days and 3 beyond that when embedded, or 2 delivered over
+// TLS or OCSP, from logs run by at least 2 different operators
+type CTPolicyResult struct {
+	Compliant bool
+	Source    string // SCT delivery the result is based on: embedded, or tls/ocsp
+	Required  int    // SCTs required from distinct logs
+	Valid     int    // qualifying SCTs from distinct logs
+	Operators int    // distinct operators of those logs
+	Problems  []string
+}
+
+// ctLogListJSON is the v3 log list schema (https://www.gstatic.com/ct/log_list/v3/log_list_schema.json)
+type ctLogListJSON struct {
+	Operators []struct {
+		Name      string      `json:"name"`
+		Logs      []ctLogJSON `json:"logs"`
+		TiledLogs []ctLogJSON `json:"tiled_logs"`
+	} `json:"operators"`
+}
+
+type ctLogJSON struct {
+	Description   string `json:"description"`
+	LogID         string `json:"log_id"`
+	Key           string `json:"key"`
+	URL           string `json:"url"`
+	SubmissionURL string `json:"submission_url"`
+	State         map[string]struct {
+		Timestamp time.Time `json:"timestamp"`
+	} `json:"state"`
+}
+
+// LoadCTLogList reads a CT log list in the v3 JSON format used by Chrome
+// (log_list.json)
+func LoadCTLogList(path string) (*CTLogList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CT log list: %w", err)
+	}
+	return ParseCTLogList(data)
+}
+
+// ParseCTLogList parses a CT log list in the v3 JSON format
+func ParseCTLogList(data []byte) (*CTLogList, error) {
+	var raw ctLogListJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid CT log list: %w", err)
+	}
+
+	list := &CTLogList{byID: make(map[[sha256.Size]byte]*CTLog)}
+	for _, op := range raw.Operators {
+		for _, l := range append(op.Logs, op.TiledLogs...) {
+			der, err := base64.StdEncoding.DecodeString(l.Key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key for CT log %q: %w", l.Description, err)
+			}
+			key, err := x509.ParsePKIXPublicKey(der)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key for CT log %q: %w", l.Description, err)
+			}
+			log := &CTLog{
+				Description: l.Description,
+				Operator:    op.Name,
+				URL:         l.URL,
+				LogID:       sha256.Sum256(der),
+				Key:         key,
+			}
+			if log.URL == "" {
+				log.URL = l.SubmissionURL
+			}
+			if l.LogID != "" && l.LogID != base64.StdEncoding.EncodeToString(log.LogID[:]) {
+				return nil, fmt.Errorf("CT log %q: log_id does not match its key", l.Description)
+			}
+			for state, info := range l.State {
+				log.State, log.StateSince = state, info.Timestamp
+			}
+			list.Logs = append(list.Logs, log)
+			list.byID[log.LogID] = log
+		}
+	}
+	if len(list.Logs) == 0 {
+		return nil, fmt.Errorf("CT log list contains no logs")
+	}
+	return list, nil
+}
+
+// Find returns the log with the given ID, or nil
+func (l *CTLogList) Find(id [sha256.Size]byte) *CTLog {
+	return l.byID[id]
+}
+
+// extractSCTs decodes the SCTs embedded in c and those delivered in the
+// TLS handshake and stapled OCSP response. Malformed SCTs are skipped.
+func extractSCTs(c *x509.Certificate, tlsSCTs [][]byte, ocspResponse []byte) []*SCT {
+	var scts []*SCT
+	for _, ext := range c.Extensions {
+		if ext.Id.Equal(oidSCTList) {
+			scts = append(scts, parseSCTExtension(ext.Value, SCTSourceEmbedded)...)
+		}
+	}
+	for _, raw := range tlsSCTs {
+		if sct, err := parseSCT(raw, SCTSourceTLS); err == nil {
+			scts = append(scts, sct)
+		}
+	}
+	if len(ocspResponse) > 0 {
+		if resp, err := ocsp.ParseResponse(ocspResponse, nil); err == nil {
+			for _, ext := range resp.Extensions {
+				if ext.Id.Equal(oidOCSPSCTList) {
+					scts = append(scts, parseSCTExtension(ext.Value, SCTSourceOCSP)...)
+				}
+			}
+		}
+	}
+	return scts
+}
+
+// parseSCTExtension decodes an SCT list extension value: an OCTET STRING
+// holding a TLS-encoded SignedCertificateTimestampList
+func parseSCTExtension(value []byte, source string) []*SCT {
+	var list []byte
+	if rest, err := asn1.Unmarshal(value, &list); err != nil || len(rest) > 0 {
+		return nil
+	}
+	input := cryptobyte.String(list)
+	var entries cryptobyte.String
+	if !input.ReadUint16LengthPrefixed(&entries) {
+		return nil
+	}
+	var scts []*SCT
+	for !entries.Empty() {
+		var raw cryptobyte.String
+		if !entries.ReadUint16LengthPrefixed(&raw) {
+			break
+		}
+		if sct, err := parseSCT(raw, source); err == nil {
+			scts = append(scts, sct)
+		}
+	}
+	return scts
+}
+
+// parseSCT decodes one TLS-encoded SignedCertificateTimestamp
+func parseSCT(raw []byte, source string) (*SCT, error) {
+	input := cryptobyte.String(raw)
+	sct := &SCT{Source: source, Status: SCTStatusUnverified}
+	var logID []byte
+	var timestamp uint64
+	var extensions, signature cryptobyte.String
+	if !input.ReadUint8(&sct.Version) ||
+		!input.ReadBytes(&logID, sha256.Size) ||
+		!input.ReadUint64(&timestamp) ||
+		!input.ReadUint16LengthPrefixed(&extensions) ||
+		!input.ReadUint8(&sct.HashAlgorithm) ||
+		!input.ReadUint8(&sct.SignatureAlgorithm) ||
+		!input.ReadUint16LengthPrefixed(&signature) ||
+		!input.Empty() {
+		return nil, fmt.Errorf("malformed SCT")
+	}
+	if sct.Version != 0 {
+		return nil, fmt.Errorf("unsupported SCT version %d", sct.Version+1)
+	}
+	copy(sct.LogID[:], logID)
+	sct.Timestamp = time.UnixMilli(int64(timestamp)).UTC()
+	sct.Extensions = append([]byte{}, extensions...)
+	sct.Signature = append([]byte{}, signature...)
+	return sct, nil
+}
+
+// VerifyCT verifies c's SCTs against logs and checks the browser CT
+// policies, setting each SCT's status and c.CTPolicy. issuer is needed for
+// embedded SCTs, which are signed over the precertificate; without it they
+// stay unverified.
+func VerifyCT(c *Certificate, issuer *x509.Certificate, logs *CTLogList, now time.Time) {
+	for _, sct := range c.SCTs {
+		verifySCT(sct, c.Certificate, issuer, logs, now)
+	}
+	c.CTPolicy = checkCTPolicy(c, now)
+}
+
+// verifySCT checks one SCT's log and signature
+func verifySCT(sct *SCT, c, issuer *x509.Certificate, logs *CTLogList, now time.Time) {
+	sct.Log = logs.Find(sct.LogID)
+	if sct.Log == nil {
+		sct.Status = SCTStatusUnknownLog
+		return
+	}
+
+	// digitally-signed struct (RFC 6962, section 3.2)
+	var b cryptobyte.Builder
+	b.AddUint8(sct.Version)
+	b.AddUint8(0) // signature_type: certificate_timestamp
+	b.AddUint64(uint64(sct.Timestamp.UnixMilli()))
+	if sct.Source == SCTSourceEmbedded {
+		if issuer == nil {
+			sct.Status = SCTStatusUnverified
+			sct.Error = "issuer certificate needed to verify an embedded SCT"
+			return
+		}
+		tbs, err := precertTBS(c.RawTBSCertificate)
+		if err != nil {
+			sct.Status, sct.Error = SCTStatusInvalid, err.Error()
+			return
+		}
+		issuerKeyHash := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+		b.AddUint16(1) // entry_type: precert_entry
+		b.AddBytes(issuerKeyHash[:])
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(tbs) })
+	} else {
+		b.AddUint16(0) // entry_type: x509_entry
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(c.Raw) })
+	}
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sct.Extensions) })
+	signed, err := b.Bytes()
+	if err != nil {
+		sct.Status, sct.Error = SCTStatusInvalid, err.Error()
+		return
+	}
+
+	if sct.HashAlgorithm != 4 {
+		sct.Status, sct.Error = SCTStatusInvalid, "unsupported hash algorithm"
+		return
+	}
+	digest := sha256.Sum256(signed)
+	valid := false
+	switch key := sct.Log.Key.(type) {
+	case *ecdsa.PublicKey:
+		valid = sct.SignatureAlgorithm == 3 && ecdsa.VerifyASN1(key, digest[:], sct.Signature)
+	case *rsa.PublicKey:
+		valid = sct.SignatureAlgorithm == 1 && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sct.Signature) == nil
+	}
+	switch {
+	case !valid:
+		sct.Status, sct.Error = SCTStatusInvalid, "signature does not verify with the log's key"
+	case sct.Timestamp.After(now):
+		sct.Status, sct.Error = SCTStatusInvalid, "timestamp is in the future"
+	default:
+		sct.Status = SCTStatusValid
+	}
+}
+
+// precertTBS rebuilds the precertificate TBSCertificate that embedded SCTs
+// sign: the certificate's TBSCertificate without the SCT list extension
+func precertTBS(tbs []byte) ([]byte, error) {
+	input := cryptobyte.String(tbs)
+	var body cryptobyte.String
+	if !input.ReadASN1(&body, casn1.SEQUENCE) {
+		return nil, fmt.Errorf("malformed TBSCertificate")
+	}
+	extensionsTag := casn1.Tag(3).Constructed().ContextSpecific()
+
+	var b cryptobyte.Builder
+	b.AddASN1(casn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		for !body.Empty() {
+			var element cryptobyte.String
+			var tag casn1.Tag
+			if !body.ReadAnyASN1Element(&element, &tag) {
+				b.SetError(fmt.Errorf("malformed TBSCertificate"))
+				return
+			}
+			if tag != extensionsTag {
+				b.AddBytes(element)
+				continue
+			}
+
+			var wrapper, extensions cryptobyte.String
+			if !element.ReadASN1(&wrapper, extensionsTag) || !wrapper.ReadASN1(&extensions, casn1.SEQUENCE) {
+				b.SetError(fmt.Errorf("malformed extensions"))
+				return
+			}
+			b.AddASN1(extensionsTag, func(b *cryptobyte.Builder) {
+				b.AddASN1(casn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					for !extensions.Empty() {
+						var ext, extBody cryptobyte.String
+						var oid asn1.ObjectIdentifier
+						if !extensions.ReadASN1Element(&ext, casn1.SEQUENCE) {
+							b.SetError(fmt.Errorf("malformed extension"))
+							return
+						}
+						parse := ext
+						if !parse.ReadASN1(&extBody, casn1.SEQUENCE) || !extBody.ReadASN1ObjectIdentifier(&oid) {
+							b.SetError(fmt.Errorf("malformed extension"))
+							return
+						}
+						if !oid.Equal(oidSCTList) {
+							b.AddBytes(ext)
+						}
+					}
+				})
+			})
+		}
+	})
+	return b.Bytes()
+}
+
+// qualifyingSCT reports whether a verified SCT counts toward the CT
+// policy. Embedded SCTs may come from logs that have since been retired
+// (if issued before retirement); SCTs delivered over TLS or OCSP must come
+// from logs that are still qualified, usable, or read-only.
+func qualifyingSCT(sct *SCT) bool {
+	if sct.Status != SCTStatusValid {
+		return false
+	}
+	switch sct.Log.State {
+	case CTLogStateQualified, CTLogStateUsable, CTLogStateReadOnly:
+		return true
+	case CTLogStateRetired:
+		return sct.Source == SCTSourceEmbedded && sct.Timestamp.Before(sct.Log.StateSince)
+	}
+	return false
+}
+
+// checkCTPolicy evaluates the browser CT policies using embedded SCTs, or
+// SCTs delivered over TLS and OCSP, whichever satisfies them
+func checkCTPolicy(c *Certificate, now time.Time) *CTPolicyResult {
+	evaluate := func(source string, required int, include func(*SCT) bool) *CTPolicyResult {
+		r := &CTPolicyResult{Source: source, Required: required}
+		logs := map[[sha256.Size]byte]bool{}
+		operators := map[string]bool{}
+		for _, sct := range c.SCTs {
+			if include(sct) && qualifyingSCT(sct) && !logs[sct.LogID] {
+				logs[sct.LogID] = true
+				operators[sct.Log.Operator] = true
+			}
+		}
+		r.Valid, r.Operators = len(logs), len(operators)
+		if r.Valid < required {
+			r.Problems = append(r.Problems, fmt.Sprintf("%d qualifying SCT(s) from distinct logs; %d required", r.Valid, required))
+		}
+		if r.Operators < 2 {
+			r.Problems = append(r.Problems, fmt.Sprintf("SCTs from %d log operator(s); 2 required", r.Operators))
+		}
+		r.Compliant = len(r.Problems) == 0
+		return r
+	}
+
+	required := 2
+	if c.NotAfter.Sub(c.NotBefore) > ctPolicyLongLifetime {
+		required = 3
+	}
+	embedded := evaluate(SCTSourceEmbedded, required, func(s *SCT) bool { return s.Source == SCTSourceEmbedded })
+	if embedded.Compliant {
+		return embedded
+	}
+	delivered := evaluate("tls/ocsp", 2, func(s *SCT) bool { return s.Source != SCTSourceEmbedded })
+	if delivered.Compliant || (delivered.Valid > 0 && embedded.Valid == 0) {
+		return delivered
+	}
+	if len(c.SCTs) == 0 {
+		embedded.Problems = []string{"no SCTs: the certificate was not logged, or the server sent none"}
+	}
+	return embedded
+}
@@ -0,0 +1,394 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ocsp"
+)
+
+// testCTLog is a fake CT log that issues SCTs with its own key
+type testCTLog struct {
+	description string
+	operator    string
+	state       string
+	stateSince  time.Time
+	key         *ecdsa.PrivateKey
+}
+
+func newTestCTLog(t *testing.T, description, operator, state string) *testCTLog {
+	t.Helper()
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	return &testCTLog{
+		description: description,
+		operator:    operator,
+		state:       state,
+		stateSince:  time.Now().Add(-365 * 24 * time.Hour),
+		key:         key.(*ecdsa.PrivateKey),
+	}
+}
+
+// issue returns a TLS-encoded SCT over a precertificate TBS (embedded) or
+// a certificate (TLS and OCSP delivery)
+func (l *testCTLog) issue(t *testing.T, timestamp time.Time, embedded bool, issuer *x509.Certificate, entry []byte) []byte {
+	t.Helper()
+	keyDER, err := x509.MarshalPKIXPublicKey(l.key.Public())
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey failed: %v", err)
+	}
+	logID := sha256.Sum256(keyDER)
+	ms := uint64(timestamp.UnixMilli())
+
+	var signed cryptobyte.Builder
+	signed.AddUint8(0)
+	signed.AddUint8(0)
+	signed.AddUint64(ms)
+	if embedded {
+		keyHash := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+		signed.AddUint16(1)
+		signed.AddBytes(keyHash[:])
+	} else {
+		signed.AddUint16(0)
+	}
+	signed.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(entry) })
+	signed.AddUint16(0)
+	digest := sha256.Sum256(signed.BytesOrPanic())
+	signature, err := l.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	var sct cryptobyte.Builder
+	sct.AddUint8(0)
+	sct.AddBytes(logID[:])
+	sct.AddUint64(ms)
+	sct.AddUint16(0)
+	sct.AddUint8(4) // SHA-256
+	sct.AddUint8(3) // ECDSA
+	sct.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(signature) })
+	return sct.BytesOrPanic()
+}
+
+// sctListExtension encodes SCTs as an SCT list extension value
+func sctListExtension(t *testing.T, scts ...[]byte) []byte {
+	t.Helper()
+	var list cryptobyte.Builder
+	list.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, sct := range scts {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sct) })
+		}
+	})
+	value, err := asn1.Marshal(list.BytesOrPanic())
+	if err != nil {
+		t.Fatalf("asn1.Marshal failed: %v", err)
+	}
+	return value
+}
+
+// testCTLogList builds a v3 log list containing logs
+func testCTLogList(t *testing.T, logs ...*testCTLog) *CTLogList {
+	t.Helper()
+	type logJSON struct {
+		Description string                            `json:"description"`
+		LogID       string                            `json:"log_id"`
+		Key         string                            `json:"key"`
+		URL         string                            `json:"url"`
+		State       map[string]map[string]interface{} `json:"state"`
+	}
+	type operatorJSON struct {
+		Name string    `json:"name"`
+		Logs []logJSON `json:"logs"`
+	}
+	var operators []operatorJSON
+	for _, l := range logs {
+		keyDER, err := x509.MarshalPKIXPublicKey(l.key.Public())
+		if err != nil {
+			t.Fatalf("MarshalPKIXPublicKey failed: %v", err)
+		}
+		id := sha256.Sum256(keyDER)
+		operators = append(operators, operatorJSON{Name: l.operator, Logs: []logJSON{{
+			Description: l.description,
+			LogID:       base64.StdEncoding.EncodeToString(id[:]),
+			Key:         base64.StdEncoding.EncodeToString(keyDER),
+			URL:         "https://" + l.description + ".example/",
+			State:       map[string]map[string]interface{}{l.state: {"timestamp": l.stateSince}},
+		}}})
+	}
+	data, err := json.Marshal(map[string]interface{}{"version": "1.0", "operators": operators})
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	list, err := ParseCTLogList(data)
+	if err != nil {
+		t.Fatalf("ParseCTLogList failed: %v", err)
+	}
+	return list
+}
+
+// newCTTestCert issues a leaf under issuer with SCTs from logs embedded,
+// signed over the precertificate
+func newCTTestCert(t *testing.T, issuer *testCA, lifetime time.Duration, logs ...*testCTLog) *x509.Certificate {
+	t.Helper()
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "ct.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(lifetime),
+		DNSNames:     []string{"ct.example.com"},
+	}
+	precert := issueTestCert(t, template, key, issuer)
+
+	var scts [][]byte
+	for _, l := range logs {
+		scts = append(scts, l.issue(t, time.Now().Add(-time.Minute), true, issuer.cert, precert.cert.RawTBSCertificate))
+	}
+	template.ExtraExtensions = []pkix.Extension{{Id: oidSCTList, Value: sctListExtension(t, scts...)}}
+	return issueTestCert(t, template, key, issuer).cert
+}
+
+func TestEmbeddedSCTs(t *testing.T) {
+	root := newTestCert(t, "CT Test Root", true, nil, nil)
+	logA := newTestCTLog(t, "log-a", "Operator A", CTLogStateUsable)
+	logB := newTestCTLog(t, "log-b", "Operator B", CTLogStateQualified)
+	logC := newTestCTLog(t, "log-c", "Operator A", CTLogStateUsable)
+	leaf := newCTTestCert(t, root, 24*time.Hour, logA, logB)
+
+	certs, err := InspectData(leaf.Raw, "test")
+	if err != nil {
+		t.Fatalf("InspectData failed: %v", err)
+	}
+	c := certs[0]
+	if len(c.SCTs) != 2 {
+		t.Fatalf("got %d SCTs, want 2", len(c.SCTs))
+	}
+	for _, sct := range c.SCTs {
+		if sct.Source != SCTSourceEmbedded || sct.Status != SCTStatusUnverified {
+			t.Errorf("SCT source/status = %s/%s, want embedded/unverified", sct.Source, sct.Status)
+		}
+		if sct.SignatureAlgorithmName() != "ECDSA-SHA256" {
+			t.Errorf("SignatureAlgorithmName() = %q", sct.SignatureAlgorithmName())
+		}
+	}
+
+	t.Run("compliant", func(t *testing.T) {
+		VerifyCT(c, root.cert, testCTLogList(t, logA, logB), time.Now())
+		for _, sct := range c.SCTs {
+			if sct.Status != SCTStatusValid {
+				t.Errorf("SCT from %s: status %s (%s), want valid", sct.LogIDBase64(), sct.Status, sct.Error)
+			}
+		}
+		if !c.CTPolicy.Compliant || c.CTPolicy.Required != 2 || c.CTPolicy.Operators != 2 {
+			t.Errorf("CTPolicy = %+v, want compliant with 2 required from 2 operators", c.CTPolicy)
+		}
+	})
+
+	t.Run("without issuer", func(t *testing.T) {
+		VerifyCT(c, nil, testCTLogList(t, logA, logB), time.Now())
+		if c.SCTs[0].Status != SCTStatusUnverified || c.CTPolicy.Compliant {
+			t.Errorf("status %s, compliant %v; want unverified and not compliant", c.SCTs[0].Status, c.CTPolicy.Compliant)
+		}
+	})
+
+	t.Run("wrong issuer", func(t *testing.T) {
+		other := newTestCert(t, "Other Root", true, nil, nil)
+		VerifyCT(c, other.cert, testCTLogList(t, logA, logB), time.Now())
+		if c.SCTs[0].Status != SCTStatusInvalid {
+			t.Errorf("status %s, want invalid", c.SCTs[0].Status)
+		}
+	})
+
+	t.Run("unknown log", func(t *testing.T) {
+		VerifyCT(c, root.cert, testCTLogList(t, logA, logC), time.Now())
+		if c.SCTs[1].Status != SCTStatusUnknownLog {
+			t.Errorf("status %s, want unknown_log", c.SCTs[1].Status)
+		}
+		if c.CTPolicy.Compliant || c.CTPolicy.Valid != 1 {
+			t.Errorf("CTPolicy = %+v, want 1 valid and not compliant", c.CTPolicy)
+		}
+	})
+
+	t.Run("retired log", func(t *testing.T) {
+		retired := *logB
+		retired.state = CTLogStateRetired
+		retired.stateSince = time.Now()
+		VerifyCT(c, root.cert, testCTLogList(t, logA, &retired), time.Now())
+		if !c.CTPolicy.Compliant {
+			t.Errorf("SCT issued before retirement should count: %+v", c.CTPolicy)
+		}
+
+		retired.stateSince = time.Now().Add(-time.Hour)
+		VerifyCT(c, root.cert, testCTLogList(t, logA, &retired), time.Now())
+		if c.CTPolicy.Compliant {
+			t.Errorf("SCT issued after retirement should not count: %+v", c.CTPolicy)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		VerifyCT(c, root.cert, testCTLogList(t, logA, logB), time.Now())
+		jc := c.ToJSON()
+		if len(jc.SCTs) != 2 || jc.SCTs[0].Log != "log-a" || jc.SCTs[0].Status != SCTStatusValid || jc.SCTs[0].Version != 1 {
+			t.Errorf("JSON SCTs = %+v", jc.SCTs)
+		}
+		if jc.CTPolicy == nil || !jc.CTPolicy.Compliant {
+			t.Errorf("JSON CT policy = %+v", jc.CTPolicy)
+		}
+	})
+}
+
+func TestCTPolicyLongLivedCertificate(t *testing.T) {
+	root := newTestCert(t, "CT Test Root", true, nil, nil)
+	logA := newTestCTLog(t, "log-a", "Operator A", CTLogStateUsable)
+	logB := newTestCTLog(t, "log-b", "Operator B", CTLogStateUsable)
+	leaf := newCTTestCert(t, root, 365*24*time.Hour, logA, logB)
+
+	certs, err := InspectData(leaf.Raw, "test")
+	if err != nil {
+		t.Fatalf("InspectData failed: %v", err)
+	}
+	VerifyCT(certs[0], root.cert, testCTLogList(t, logA, logB), time.Now())
+	policy := certs[0].CTPolicy
+	if policy.Compliant || policy.Required != 3 || policy.Valid != 2 {
+		t.Errorf("CTPolicy = %+v, want 3 required, 2 valid, not compliant", policy)
+	}
+}
+
+func TestPrecertTBS(t *testing.T) {
+	root := newTestCert(t, "CT Test Root", true, nil, nil)
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(7),
+		Subject:      pkix.Name{CommonName: "precert.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"precert.example.com"},
+	}
+	precert := issueTestCert(t, template, key, root)
+	template.ExtraExtensions = []pkix.Extension{{Id: oidSCTList, Value: sctListExtension(t)}}
+	final := issueTestCert(t, template, key, root)
+
+	tbs, err := precertTBS(final.cert.RawTBSCertificate)
+	if err != nil {
+		t.Fatalf("precertTBS failed: %v", err)
+	}
+	if string(tbs) != string(precert.cert.RawTBSCertificate) {
+		t.Error("precertTBS did not reproduce the TBSCertificate without the SCT extension")
+	}
+}
+
+func TestTLSAndOCSPSCTs(t *testing.T) {
+	logA := newTestCTLog(t, "log-a", "Operator A", CTLogStateUsable)
+	logB := newTestCTLog(t, "log-b", "Operator B", CTLogStateUsable)
+
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	leaf := issueTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ct.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"ct.example.com"},
+	}, key, nil)
+
+	tlsSCT := logA.issue(t, time.Now().Add(-time.Minute), false, nil, leaf.cert.Raw)
+	ocspSCT := logB.issue(t, time.Now().Add(-time.Minute), false, nil, leaf.cert.Raw)
+	staple, err := ocsp.CreateResponse(leaf.cert, leaf.cert, ocsp.Response{
+		Status:          ocsp.Good,
+		SerialNumber:    leaf.cert.SerialNumber,
+		ThisUpdate:      time.Now().Add(-time.Minute),
+		NextUpdate:      time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: oidOCSPSCTList, Value: sctListExtension(t, ocspSCT)}},
+	}, key)
+	if err != nil {
+		t.Fatalf("ocsp.CreateResponse failed: %v", err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate:                 [][]byte{leaf.cert.Raw},
+			PrivateKey:                  key,
+			SignedCertificateTimestamps: [][]byte{tlsSCT},
+			OCSPStaple:                  staple,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("tls.Listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	c, _, err := InspectURLWithConnectOptions(host, port, ConnectOptions{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("InspectURLWithConnectOptions failed: %v", err)
+	}
+	if len(c.SCTs) != 2 || c.SCTs[0].Source != SCTSourceTLS || c.SCTs[1].Source != SCTSourceOCSP {
+		t.Fatalf("got SCTs %+v, want one from TLS and one from OCSP", c.SCTs)
+	}
+
+	VerifyCT(c, nil, testCTLogList(t, logA, logB), time.Now())
+	for _, sct := range c.SCTs {
+		if sct.Status != SCTStatusValid {
+			t.Errorf("%s SCT: status %s (%s), want valid", sct.Source, sct.Status, sct.Error)
+		}
+	}
+	if !c.CTPolicy.Compliant || c.CTPolicy.Source != "tls/ocsp" {
+		t.Errorf("CTPolicy = %+v, want compliant via tls/ocsp", c.CTPolicy)
+	}
+}
+
+func TestLoadCTLogList(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log_list.json")
+	if err := os.WriteFile(path, []byte(`{"operators":[]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCTLogList(path); err == nil {
+		t.Error("expected an error for a log list without logs")
+	}
+	if _, err := LoadCTLogList(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing log list")
+	}
+	if _, err := ParseCTLogList([]byte(`{"operators":[{"name":"x","logs":[{"key":"bm90IGEga2V5"}]}]}`)); err == nil {
+		t.Error("expected an error for an invalid log key")
+	}
+}
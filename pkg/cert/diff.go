@@ -0,0 +1,147 @@
+package cert
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldDiff compares one field of two certificates. Single-valued fields
+// have at most one entry in Old and New; list fields (SANs, key usages,
+// extensions) also report which entries were added and removed.
+type FieldDiff struct {
+	Key     string   // Stable identifier, e.g. "sans" (used in JSON)
+	Name    string   // Display name, e.g. "SANs"
+	List    bool     // Multi-valued field
+	Old     []string // Values in the first certificate
+	New     []string // Values in the second certificate
+	Added   []string // List fields: values only in New
+	Removed []string // List fields: values only in Old
+}
+
+// Changed reports whether the field differs between the certificates
+func (f FieldDiff) Changed() bool {
+	if f.List {
+		return len(f.Added) > 0 || len(f.Removed) > 0
+	}
+	return strings.Join(f.Old, "\n") != strings.Join(f.New, "\n")
+}
+
+// CertificateDiff is a field-by-field comparison of two certificates
+type CertificateDiff struct {
+	Old    *Certificate
+	New    *Certificate
+	Fields []FieldDiff // Every compared field, in display order
+}
+
+// Changes returns the fields that differ
+func (d *CertificateDiff) Changes() []FieldDiff {
+	var changed []FieldDiff
+	for _, f := range d.Fields {
+		if f.Changed() {
+			changed = append(changed, f)
+		}
+	}
+	return changed
+}
+
+// Identical reports whether the certificates are byte-for-byte the same
+func (d *CertificateDiff) Identical() bool {
+	return string(d.Old.Raw) == string(d.New.Raw)
+}
+
+// extensionNames names the extensions listed in a diff
+var extensionNames = map[string]string{
+	"2.5.29.14":               "Subject Key Identifier",
+	"2.5.29.15":               "Key Usage",
+	"2.5.29.17":               "Subject Alternative Name",
+	"2.5.29.19":               "Basic Constraints",
+	"2.5.29.30":               "Name Constraints",
+	"2.5.29.31":               "CRL Distribution Points",
+	"2.5.29.32":               "Certificate Policies",
+	"2.5.29.35":               "Authority Key Identifier",
+	"2.5.29.37":               "Extended Key Usage",
+	"1.3.6.1.5.5.7.1.1":       "Authority Info Access",
+	"1.3.6.1.5.5.7.1.24":      "TLS Feature",
+	"1.3.6.1.4.1.11129.2.4.2": "Certificate Transparency SCT",
+	"1.3.6.1.4.1.11129.2.4.3": "CT Precertificate Poison",
+}
+
+// DiffCertificates compares the subject, issuer, validity, key, SANs,
+// key usages, and extensions of two certificates
+func DiffCertificates(oldCert, newCert *Certificate) *CertificateDiff {
+	d := &CertificateDiff{Old: oldCert, New: newCert}
+	scalar := func(key, name string, value func(c *Certificate) string) {
+		d.Fields = append(d.Fields, FieldDiff{
+			Key:  key,
+			Name: name,
+			Old:  []string{value(oldCert)},
+			New:  []string{value(newCert)},
+		})
+	}
+	list := func(key, name string, values func(c *Certificate) []string) {
+		f := FieldDiff{Key: key, Name: name, List: true, Old: values(oldCert), New: values(newCert)}
+		f.Added = difference(f.New, f.Old)
+		f.Removed = difference(f.Old, f.New)
+		d.Fields = append(d.Fields, f)
+	}
+	const dateFormat = "2006-01-02 15:04:05 UTC"
+
+	scalar("subject", "Subject", func(c *Certificate) string { return c.Subject.String() })
+	scalar("issuer", "Issuer", func(c *Certificate) string { return c.Issuer.String() })
+	scalar("serial_number", "Serial Number", func(c *Certificate) string { return c.SerialNumber.Text(16) })
+	scalar("not_before", "Valid From", func(c *Certificate) string { return c.NotBefore.UTC().Format(dateFormat) })
+	scalar("not_after", "Valid To", func(c *Certificate) string { return c.NotAfter.UTC().Format(dateFormat) })
+	scalar("validity_days", "Validity Period", func(c *Certificate) string {
+		return fmt.Sprintf("%d days", int(c.NotAfter.Sub(c.NotBefore).Hours()/24))
+	})
+	scalar("public_key_algorithm", "Key Algorithm", func(c *Certificate) string { return getPublicKeyAlgorithm(c.PublicKey) })
+	scalar("public_key_size", "Key Size", func(c *Certificate) string {
+		return strconv.Itoa(getPublicKeySize(c.PublicKey)) + " bits"
+	})
+	scalar("signature_algorithm", "Signature Algorithm", func(c *Certificate) string { return c.SignatureAlgorithm.String() })
+	scalar("is_ca", "CA", func(c *Certificate) string { return strconv.FormatBool(c.IsCA) })
+	list("sans", "SANs", func(c *Certificate) []string {
+		return joinSANs(c.DNSNames, c.IPAddresses, c.EmailAddresses, c.URIs)
+	})
+	list("key_usage", "Key Usage", func(c *Certificate) []string { return getKeyUsageStrings(c.KeyUsage) })
+	list("ext_key_usage", "Extended Key Usage", func(c *Certificate) []string { return getExtKeyUsageStrings(c.ExtKeyUsage) })
+	list("extensions", "Extensions", certificateExtensions)
+	scalar("fingerprint_sha256", "SHA-256 Fingerprint", func(c *Certificate) string { return c.FingerprintSHA256() })
+
+	return d
+}
+
+// certificateExtensions lists a certificate's extensions by name (or OID),
+// marking critical ones
+func certificateExtensions(c *Certificate) []string {
+	exts := make([]string, 0, len(c.Extensions))
+	for _, ext := range c.Extensions {
+		name := ext.Id.String()
+		if n, ok := extensionNames[name]; ok {
+			name = n
+		}
+		if ext.Critical {
+			name += " (critical)"
+		}
+		exts = append(exts, name)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// difference returns the values of a that are not in b, in a's order
+func difference(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, v := range b {
+		inB[v] = true
+	}
+	var out []string
+	for _, v := range a {
+		if !inB[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package cert
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestDiffCertificates(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	template := func(cn string, sans []string, days int) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber: big.NewInt(int64(days)),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    now,
+			NotAfter:     now.AddDate(0, 0, days),
+			DNSNames:     sans,
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+	}
+	ecKey, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	rsaKey, err := generatePrivateKey(KeyAlgorithmRSA, 2048)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+
+	oldCert := issueTestCert(t, template("example.com", []string{"example.com", "old.example.com"}, 90), rsaKey, nil)
+	renewed := template("example.com", []string{"example.com", "new.example.com"}, 365)
+	renewed.ExtKeyUsage = append(renewed.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+	newCert := issueTestCert(t, renewed, ecKey, nil)
+
+	d := DiffCertificates(&Certificate{Certificate: oldCert.cert, Source: "old.pem"}, &Certificate{Certificate: newCert.cert, Source: "new.pem"})
+	if d.Identical() {
+		t.Fatal("Identical() = true for different certificates")
+	}
+
+	changed := map[string]FieldDiff{}
+	for _, f := range d.Changes() {
+		changed[f.Key] = f
+	}
+	for _, key := range []string{"serial_number", "not_after", "validity_days", "public_key_algorithm", "public_key_size", "signature_algorithm", "sans", "ext_key_usage", "fingerprint_sha256"} {
+		if _, ok := changed[key]; !ok {
+			t.Errorf("expected %s to change", key)
+		}
+	}
+	for _, key := range []string{"subject", "not_before", "is_ca", "key_usage", "extensions"} {
+		if f, ok := changed[key]; ok {
+			t.Errorf("expected %s to be unchanged, got %v -> %v", key, f.Old, f.New)
+		}
+	}
+
+	sans := changed["sans"]
+	if len(sans.Added) != 1 || sans.Added[0] != "new.example.com" || len(sans.Removed) != 1 || sans.Removed[0] != "old.example.com" {
+		t.Errorf("SANs added %v, removed %v", sans.Added, sans.Removed)
+	}
+	if eku := changed["ext_key_usage"]; len(eku.Added) != 1 || eku.Added[0] != "Client Authentication" || len(eku.Removed) != 0 {
+		t.Errorf("EKU added %v, removed %v", eku.Added, eku.Removed)
+	}
+	if f := changed["public_key_size"]; f.Old[0] != "2048 bits" || f.New[0] != "256 bits" {
+		t.Errorf("key size %v -> %v", f.Old, f.New)
+	}
+
+	jd := d.ToJSON()
+	if jd.Identical || len(jd.Changes) != len(changed) || len(jd.Unchanged)+len(jd.Changes) != len(d.Fields) {
+		t.Errorf("JSON diff = %+v", jd)
+	}
+	for _, c := range jd.Changes {
+		switch c.Field {
+		case "sans":
+			if _, ok := c.Old.([]string); !ok {
+				t.Errorf("sans old = %T, want []string", c.Old)
+			}
+		case "subject", "public_key_algorithm":
+			if _, ok := c.Old.(string); !ok {
+				t.Errorf("%s old = %T, want string", c.Field, c.Old)
+			}
+		}
+	}
+	if jd.Old.Source != "old.pem" || jd.New.Source != "new.pem" {
+		t.Errorf("sources = %q, %q", jd.Old.Source, jd.New.Source)
+	}
+
+	same := DiffCertificates(&Certificate{Certificate: oldCert.cert}, &Certificate{Certificate: oldCert.cert})
+	if !same.Identical() || len(same.Changes()) != 0 {
+		t.Errorf("self diff: identical %v, changes %v", same.Identical(), same.Changes())
+	}
+}
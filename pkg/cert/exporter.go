@@ -0,0 +1,289 @@
+package cert
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default exporter probe intervals. TLS version checks make one handshake
+// per version, so they run less often than certificate inspections.
+const (
+	DefaultExporterInterval    = 5 * time.Minute
+	DefaultExporterTLSInterval = time.Hour
+)
+
+// Exporter probe kinds, used as the probe label
+const (
+	probeInspect = "inspect"
+	probeTLS     = "tls"
+)
+
+// ExporterOptions configures a Prometheus exporter for certificate expiry
+type ExporterOptions struct {
+	Files       []string       // certificate files; every certificate in a bundle is exported
+	Targets     []string       // hostnames, host:port pairs, or URLs
+	Port        int            // default port for targets without one
+	Interval    time.Duration  // how often certificates are re-inspected
+	TLSInterval time.Duration  // how often targets' TLS versions are re-checked
+	Chain       ChainOptions   // trust anchors for the chain_valid metric
+	Connect     ConnectOptions // timeout, proxy, and other connection settings
+}
+
+// probeResult is the cached outcome of one probe of one source
+type probeResult struct {
+	kind     string // ScanKindFile or ScanKindHost
+	time     time.Time
+	duration time.Duration
+	err      error
+
+	certs []*Certificate // inspect probes
+	chain *ChainAnalysis // inspect probes
+	tls   *TLSResult     // tls probes
+}
+
+// Exporter probes certificates in the background and serves the cached
+// results as Prometheus metrics
+type Exporter struct {
+	opts ExporterOptions
+
+	mu          sync.RWMutex
+	inspections map[string]*probeResult // by source
+	tlsChecks   map[string]*probeResult // by target
+	lastInspect time.Time
+	lastTLS     time.Time
+
+	inspectFile   func(path string) ([]*Certificate, error)
+	inspectTarget func(host string, port int) ([]*Certificate, error)
+	checkTLS      func(host string, port int) (*TLSResult, error)
+}
+
+// NewExporter returns an Exporter for opts. Nothing is probed until
+// Refresh or Run is called.
+func NewExporter(opts ExporterOptions) *Exporter {
+	if opts.Port == 0 {
+		opts.Port = 443
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultExporterInterval
+	}
+	if opts.TLSInterval <= 0 {
+		opts.TLSInterval = DefaultExporterTLSInterval
+	}
+	e := &Exporter{
+		opts:        opts,
+		inspections: make(map[string]*probeResult),
+		tlsChecks:   make(map[string]*probeResult),
+		inspectFile: InspectFileAll,
+	}
+	e.inspectTarget = func(host string, port int) ([]*Certificate, error) {
+		c, chain, err := InspectURLWithConnectOptions(host, port, opts.Connect)
+		if err != nil {
+			return nil, err
+		}
+		return append([]*Certificate{c}, chain...), nil
+	}
+	e.checkTLS = func(host string, port int) (*TLSResult, error) {
+		return CheckTLSVersionsWithOptions(host, port, opts.Connect)
+	}
+	return e
+}
+
+// Refresh runs the probes that are due at now: certificate inspections
+// every Interval and TLS version checks every TLSInterval. Sources are
+// probed concurrently; the cache is updated when all of them are done.
+func (e *Exporter) Refresh(now time.Time) {
+	e.mu.RLock()
+	inspectDue := now.Sub(e.lastInspect) >= e.opts.Interval
+	tlsDue := len(e.opts.Targets) > 0 && now.Sub(e.lastTLS) >= e.opts.TLSInterval
+	e.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	inspections := make(map[string]*probeResult)
+	tlsChecks := make(map[string]*probeResult)
+	probe := func(results map[string]*probeResult, source, kind string, run func(r *probeResult)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := &probeResult{kind: kind, time: time.Now()}
+			run(r)
+			r.duration = time.Since(r.time)
+			mu.Lock()
+			results[source] = r
+			mu.Unlock()
+		}()
+	}
+
+	if inspectDue {
+		for _, path := range e.opts.Files {
+			path := path
+			probe(inspections, path, ScanKindFile, func(r *probeResult) {
+				r.certs, r.err = e.inspectFile(path)
+				if r.err == nil {
+					r.chain = AnalyzeChain(r.certs, e.opts.Chain)
+				}
+			})
+		}
+		for _, target := range e.opts.Targets {
+			host, port := SplitHostTarget(target, e.opts.Port)
+			probe(inspections, target, ScanKindHost, func(r *probeResult) {
+				r.certs, r.err = e.inspectTarget(host, port)
+				if r.err == nil {
+					r.chain = AnalyzeChain(r.certs, e.opts.Chain)
+				}
+			})
+		}
+	}
+	if tlsDue {
+		for _, target := range e.opts.Targets {
+			host, port := SplitHostTarget(target, e.opts.Port)
+			probe(tlsChecks, target, ScanKindHost, func(r *probeResult) {
+				r.tls, r.err = e.checkTLS(host, port)
+				if r.err == nil && r.tls.MaxSupported == 0 {
+					r.err = fmt.Errorf("no TLS version could be negotiated")
+				}
+			})
+		}
+	}
+	wg.Wait()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if inspectDue {
+		e.inspections = inspections
+		e.lastInspect = now
+	}
+	if tlsDue {
+		e.tlsChecks = tlsChecks
+		e.lastTLS = now
+	}
+}
+
+// Run refreshes the cache until ctx is done, checking which probes are
+// due at the shorter of the two intervals
+func (e *Exporter) Run(ctx context.Context) {
+	tick := e.opts.Interval
+	if len(e.opts.Targets) > 0 && e.opts.TLSInterval < tick {
+		tick = e.opts.TLSInterval
+	}
+	for {
+		e.Refresh(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(tick):
+		}
+	}
+}
+
+// ServeHTTP serves the cached metrics in the Prometheus text format
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e.WriteMetrics(w)
+}
+
+// metricFamily collects the samples of one metric
+type metricFamily struct {
+	name, help string
+	samples    []string
+}
+
+func (f *metricFamily) add(labels []string, value float64) {
+	f.samples = append(f.samples, fmt.Sprintf("%s{%s} %s", f.name, strings.Join(labels, ","), strconv.FormatFloat(value, 'f', -1, 64)))
+}
+
+// label renders a Prometheus label pair, escaping the value
+func label(name, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return fmt.Sprintf(`%s="%s"`, name, value)
+}
+
+// boolValue converts a bool to a 0/1 gauge value
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// WriteMetrics writes the cached probe results as Prometheus metrics
+func (e *Exporter) WriteMetrics(w io.Writer) {
+	notAfter := &metricFamily{name: "certwiz_cert_not_after_timestamp_seconds", help: "Certificate expiry time (NotAfter) in seconds since the Unix epoch."}
+	notBefore := &metricFamily{name: "certwiz_cert_not_before_timestamp_seconds", help: "Certificate start time (NotBefore) in seconds since the Unix epoch."}
+	days := &metricFamily{name: "certwiz_cert_days_until_expiry", help: "Days until the certificate expires; negative once expired."}
+	chainValid := &metricFamily{name: "certwiz_chain_valid", help: "Whether the certificate chain is complete and verifies to a trusted root (1) or not (0)."}
+	tlsSupported := &metricFamily{name: "certwiz_tls_version_supported", help: "Whether the target accepts a TLS version (1) or not (0)."}
+	success := &metricFamily{name: "certwiz_probe_success", help: "Whether the last probe of a source succeeded (1) or failed (0)."}
+	duration := &metricFamily{name: "certwiz_probe_duration_seconds", help: "How long the last probe of a source took."}
+	timestamp := &metricFamily{name: "certwiz_probe_timestamp_seconds", help: "When the last probe of a source ran, in seconds since the Unix epoch."}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	probeMetrics := func(source, probe string, r *probeResult) {
+		labels := []string{label("source", source), label("kind", r.kind), label("probe", probe)}
+		success.add(labels, boolValue(r.err == nil))
+		duration.add(labels, r.duration.Seconds())
+		timestamp.add(labels, float64(r.time.Unix()))
+	}
+
+	for _, source := range sortedKeys(e.inspections) {
+		r := e.inspections[source]
+		probeMetrics(source, probeInspect, r)
+		if r.err != nil {
+			continue
+		}
+		for i, c := range r.certs {
+			labels := []string{
+				label("source", source),
+				label("kind", r.kind),
+				label("index", strconv.Itoa(i)),
+				label("subject", c.Subject.String()),
+				label("issuer", c.Issuer.String()),
+				label("serial", c.SerialNumber.Text(16)),
+			}
+			notAfter.add(labels, float64(c.NotAfter.Unix()))
+			notBefore.add(labels, float64(c.NotBefore.Unix()))
+			days.add(labels, float64(c.DaysUntilExpiry))
+		}
+		chainValid.add([]string{label("source", source), label("kind", r.kind)}, boolValue(r.chain.Trusted && len(r.chain.Errors) == 0))
+	}
+
+	for _, target := range sortedKeys(e.tlsChecks) {
+		r := e.tlsChecks[target]
+		probeMetrics(target, probeTLS, r)
+		if r.err != nil {
+			continue
+		}
+		for _, v := range r.tls.Versions {
+			tlsSupported.add([]string{label("source", target), label("version", v.Name)}, boolValue(v.Supported))
+		}
+	}
+
+	for _, f := range []*metricFamily{notAfter, notBefore, days, chainValid, tlsSupported, success, duration, timestamp} {
+		if len(f.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", f.name, f.help, f.name)
+		for _, s := range f.samples {
+			fmt.Fprintln(w, s)
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]*probeResult) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
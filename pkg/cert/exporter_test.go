@@ -0,0 +1,127 @@
+package cert
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExporterMetrics(t *testing.T) {
+	dir := t.TempDir()
+	caCert, _, leafCert := newTestCAFiles(t, dir, "Exporter CA")
+	roots, err := LoadCertPool(caCert)
+	if err != nil {
+		t.Fatalf("LoadCertPool failed: %v", err)
+	}
+
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	served := issueTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "exporter.example.com"},
+		NotBefore:    time.Unix(1700000000, 0),
+		NotAfter:     time.Unix(1800000000, 0),
+	}, key, nil)
+
+	e := NewExporter(ExporterOptions{
+		Files:       []string{leafCert, filepath.Join(dir, "missing.pem")},
+		Targets:     []string{"example.com:8443", "down.example.com"},
+		Interval:    time.Minute,
+		TLSInterval: time.Hour,
+		Chain:       ChainOptions{Roots: roots, RootsSource: caCert},
+	})
+	// Targets are probed concurrently
+	var mu sync.Mutex
+	inspections, tlsChecks := 0, 0
+	e.inspectTarget = func(host string, port int) ([]*Certificate, error) {
+		mu.Lock()
+		inspections++
+		mu.Unlock()
+		if host == "down.example.com" {
+			return nil, errors.New("connection refused")
+		}
+		return []*Certificate{{Certificate: served.cert, DaysUntilExpiry: 30}}, nil
+	}
+	e.checkTLS = func(host string, port int) (*TLSResult, error) {
+		mu.Lock()
+		tlsChecks++
+		mu.Unlock()
+		result := &TLSResult{Host: host, Port: port, Versions: []TLSVersionInfo{
+			{Version: TLSVersionTLS12, Name: "TLS 1.2", Supported: true},
+			{Version: TLSVersionTLS13, Name: "TLS 1.3", Supported: host == "example.com"},
+		}}
+		if host == "example.com" {
+			result.MaxSupported = TLSVersionTLS13
+		}
+		return result, nil
+	}
+
+	// Nothing is exported before the first refresh
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Body.Len() != 0 {
+		t.Errorf("metrics before refresh:\n%s", rec.Body.String())
+	}
+
+	start := time.Now()
+	e.Refresh(start)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	metrics := rec.Body.String()
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	for _, want := range []string{
+		"# TYPE certwiz_cert_not_after_timestamp_seconds gauge",
+		`certwiz_cert_not_after_timestamp_seconds{source="example.com:8443",kind="host",index="0",subject="CN=exporter.example.com",issuer="CN=exporter.example.com",serial="2a"} 1800000000`,
+		`certwiz_cert_not_before_timestamp_seconds{source="example.com:8443",kind="host",index="0",`,
+		`certwiz_cert_days_until_expiry{source="example.com:8443",kind="host",index="0",subject="CN=exporter.example.com",issuer="CN=exporter.example.com",serial="2a"} 30`,
+		`certwiz_chain_valid{source="` + leafCert + `",kind="file"} 1`,
+		`certwiz_chain_valid{source="example.com:8443",kind="host"} 0`,
+		`certwiz_tls_version_supported{source="example.com:8443",version="TLS 1.3"} 1`,
+		`certwiz_probe_success{source="` + leafCert + `",kind="file",probe="inspect"} 1`,
+		`certwiz_probe_success{source="` + filepath.Join(dir, "missing.pem") + `",kind="file",probe="inspect"} 0`,
+		`certwiz_probe_success{source="down.example.com",kind="host",probe="inspect"} 0`,
+		`certwiz_probe_success{source="down.example.com",kind="host",probe="tls"} 0`,
+		`certwiz_probe_duration_seconds{source="example.com:8443",kind="host",probe="tls"} `,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+	if strings.Contains(metrics, `certwiz_tls_version_supported{source="down.example.com"`) {
+		t.Error("TLS versions exported for a target that negotiated none")
+	}
+
+	// Results are cached until each probe's interval has passed
+	e.Refresh(start.Add(30 * time.Second))
+	if inspections != 2 || tlsChecks != 2 {
+		t.Errorf("after cached refresh: %d inspections, %d TLS checks; want 2, 2", inspections, tlsChecks)
+	}
+	e.Refresh(start.Add(time.Minute))
+	if inspections != 4 || tlsChecks != 2 {
+		t.Errorf("after interval: %d inspections, %d TLS checks; want 4, 2", inspections, tlsChecks)
+	}
+	e.Refresh(start.Add(time.Hour))
+	if inspections != 6 || tlsChecks != 4 {
+		t.Errorf("after TLS interval: %d inspections, %d TLS checks; want 6, 4", inspections, tlsChecks)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	got := label("subject", "a\\b\"c\nd")
+	want := `subject="a\\b\"c\nd"`
+	if got != want {
+		t.Errorf("label() = %s, want %s", got, want)
+	}
+}
@@ -4,38 +4,101 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 )
 
 // JSONCertificate represents certificate data in JSON format
 type JSONCertificate struct {
-	Subject            JSONSubject       `json:"subject"`
-	Issuer             JSONSubject       `json:"issuer"`
-	SerialNumber       string            `json:"serial_number"`
-	NotBefore          time.Time         `json:"not_before"`
-	NotAfter           time.Time         `json:"not_after"`
-	IsCA               bool              `json:"is_ca"`
-	IsExpired          bool              `json:"is_expired"`
-	DaysUntilExpiry    int               `json:"days_until_expiry"`
-	SignatureAlgorithm string            `json:"signature_algorithm"`
-	PublicKeyAlgorithm string            `json:"public_key_algorithm"`
-	PublicKeySize      int               `json:"public_key_size"`
-	FingerprintSHA256  string            `json:"fingerprint_sha256"`
-	FingerprintSHA1    string            `json:"fingerprint_sha1"`
-	DNSNames           []string          `json:"dns_names,omitempty"`
-	IPAddresses        []string          `json:"ip_addresses,omitempty"`
-	EmailAddresses     []string          `json:"email_addresses,omitempty"`
-	URIs               []string          `json:"uris,omitempty"`
-	KeyUsage           []string          `json:"key_usage,omitempty"`
-	ExtKeyUsage        []string          `json:"ext_key_usage,omitempty"`
-	Source             string            `json:"source,omitempty"`
-	Format             string            `json:"format,omitempty"`
-	Chain              []JSONCertSummary `json:"chain,omitempty"`
-	TLSVersion         string            `json:"tls_version,omitempty"`
-	CipherSuite        string            `json:"cipher_suite,omitempty"`
+	Subject            JSONSubject            `json:"subject"`
+	Issuer             JSONSubject            `json:"issuer"`
+	SerialNumber       string                 `json:"serial_number"`
+	NotBefore          time.Time              `json:"not_before"`
+	NotAfter           time.Time              `json:"not_after"`
+	IsCA               bool                   `json:"is_ca"`
+	MaxPathLen         *int                   `json:"max_path_len,omitempty"`
+	NameConstraints    *JSONNameConstraints   `json:"name_constraints,omitempty"`
+	IsExpired          bool                   `json:"is_expired"`
+	DaysUntilExpiry    int                    `json:"days_until_expiry"`
+	SignatureAlgorithm string                 `json:"signature_algorithm"`
+	PublicKeyAlgorithm string                 `json:"public_key_algorithm"`
+	PublicKeySize      int                    `json:"public_key_size"`
+	FingerprintSHA256  string                 `json:"fingerprint_sha256"`
+	FingerprintSHA1    string                 `json:"fingerprint_sha1"`
+	DNSNames           []string               `json:"dns_names,omitempty"`
+	IPAddresses        []string               `json:"ip_addresses,omitempty"`
+	EmailAddresses     []string               `json:"email_addresses,omitempty"`
+	URIs               []string               `json:"uris,omitempty"`
+	KeyUsage           []string               `json:"key_usage,omitempty"`
+	ExtKeyUsage        []string               `json:"ext_key_usage,omitempty"`
+	Source             string                 `json:"source,omitempty"`
+	Format             string                 `json:"format,omitempty"`
+	Chain              []JSONCertSummary      `json:"chain,omitempty"`
+	ChainAnalysis      *JSONChainAnalysis     `json:"chain_analysis,omitempty"`
+	OCSP               *JSONOCSPResult        `json:"ocsp,omitempty"`
+	TLSVersion         string                 `json:"tls_version,omitempty"`
+	CipherSuite        string                 `json:"cipher_suite,omitempty"`
+	ClientCertRequest  *JSONClientCertRequest `json:"client_cert_request,omitempty"`
+	SCTs               []JSONSCT              `json:"scts,omitempty"`
+	CTPolicy           *JSONCTPolicy          `json:"ct_policy,omitempty"`
+	OCSPStaple         *JSONOCSPStaple        `json:"ocsp_staple,omitempty"`
+}
+
+// JSONOCSPStaple represents a stapled OCSP response. The response fields
+// are omitted when it could not be parsed.
+type JSONOCSPStaple struct {
+	*JSONOCSPResult
+	SignatureValid bool   `json:"signature_valid"`
+	Error          string `json:"error,omitempty"`
+}
+
+// JSONSCT represents a Signed Certificate Timestamp
+type JSONSCT struct {
+	Source             string    `json:"source"`
+	Version            int       `json:"version"`
+	LogID              string    `json:"log_id"`
+	Log                string    `json:"log,omitempty"`
+	LogOperator        string    `json:"log_operator,omitempty"`
+	LogState           string    `json:"log_state,omitempty"`
+	Timestamp          time.Time `json:"timestamp"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	Signature          string    `json:"signature"`
+	Status             string    `json:"status"`
+	Error              string    `json:"error,omitempty"`
+}
+
+// JSONCTPolicy represents the browser CT policy result
+type JSONCTPolicy struct {
+	Compliant bool     `json:"compliant"`
+	Source    string   `json:"source"`
+	Required  int      `json:"required"`
+	Valid     int      `json:"valid"`
+	Operators int      `json:"operators"`
+	Problems  []string `json:"problems,omitempty"`
+}
+
+// JSONClientCertRequest represents a server's request for a client certificate
+type JSONClientCertRequest struct {
+	AcceptableCAs    []string `json:"acceptable_cas"`
+	SignatureSchemes []string `json:"signature_schemes"`
+	CertificateSent  bool     `json:"certificate_sent"`
+}
+
+// JSONNameConstraints represents the name constraints of a CA certificate
+type JSONNameConstraints struct {
+	Critical                bool     `json:"critical"`
+	PermittedDNSDomains     []string `json:"permitted_dns_domains,omitempty"`
+	ExcludedDNSDomains      []string `json:"excluded_dns_domains,omitempty"`
+	PermittedIPRanges       []string `json:"permitted_ip_ranges,omitempty"`
+	ExcludedIPRanges        []string `json:"excluded_ip_ranges,omitempty"`
+	PermittedEmailAddresses []string `json:"permitted_email_addresses,omitempty"`
+	ExcludedEmailAddresses  []string `json:"excluded_email_addresses,omitempty"`
+	PermittedURIDomains     []string `json:"permitted_uri_domains,omitempty"`
+	ExcludedURIDomains      []string `json:"excluded_uri_domains,omitempty"`
 }
 
 // JSONSubject represents certificate subject/issuer in JSON format
@@ -60,6 +123,48 @@ type JSONCertSummary struct {
 	SerialNumber string    `json:"serial_number"`
 }
 
+// JSONChainLink describes one presented certificate in a chain analysis
+type JSONChainLink struct {
+	Index          int    `json:"index"`
+	Subject        string `json:"subject"`
+	IssuerIndex    *int   `json:"issuer_index,omitempty"`
+	SelfSigned     bool   `json:"self_signed"`
+	SignatureValid bool   `json:"signature_valid"`
+	Duplicate      bool   `json:"duplicate,omitempty"`
+	Unused         bool   `json:"unused,omitempty"`
+}
+
+// JSONChainPathEntry is one certificate on the validated path
+type JSONChainPathEntry struct {
+	Subject           string `json:"subject"`
+	Issuer            string `json:"issuer"`
+	FingerprintSHA256 string `json:"fingerprint_sha256"`
+	Sent              bool   `json:"sent"`
+}
+
+// JSONChainAnalysis represents chain analysis results in JSON format
+type JSONChainAnalysis struct {
+	Ordered      bool                 `json:"ordered"`
+	Complete     bool                 `json:"complete"`
+	Trusted      bool                 `json:"trusted"`
+	TrustSource  string               `json:"trust_source"`
+	Certificates []JSONChainLink      `json:"certificates"`
+	Path         []JSONChainPathEntry `json:"path,omitempty"`
+	Errors       []string             `json:"errors,omitempty"`
+	Warnings     []string             `json:"warnings,omitempty"`
+}
+
+// JSONPKCS12Bundle represents the contents of a PKCS#12 archive in JSON format
+type JSONPKCS12Bundle struct {
+	Source              string            `json:"source,omitempty"`
+	HasPrivateKey       bool              `json:"has_private_key"`
+	PrivateKeyAlgorithm string            `json:"private_key_algorithm,omitempty"`
+	PrivateKeySize      int               `json:"private_key_size,omitempty"`
+	KeyMatches          *bool             `json:"key_matches,omitempty"`
+	Certificate         *JSONCertificate  `json:"certificate,omitempty"`
+	Chain               []JSONCertSummary `json:"chain,omitempty"`
+}
+
 // JSONCSRInfo represents CSR data in JSON format
 type JSONCSRInfo struct {
 	Subject            JSONSubject `json:"subject"`
@@ -78,9 +183,96 @@ type JSONVerificationResult struct {
 	Errors      []string        `json:"errors,omitempty"`
 	Warnings    []string        `json:"warnings,omitempty"`
 	KeyMatches  *bool           `json:"key_matches,omitempty"`
+	OCSP        *JSONOCSPResult `json:"ocsp,omitempty"`
+	CRL         *JSONCRLCheck   `json:"crl,omitempty"`
 	Certificate JSONCertificate `json:"certificate"`
 }
 
+// JSONRevokedEntry represents a revoked certificate in JSON format
+type JSONRevokedEntry struct {
+	SerialNumber string    `json:"serial_number"`
+	RevokedAt    time.Time `json:"revoked_at"`
+	Reason       string    `json:"reason"`
+	Subject      string    `json:"subject,omitempty"`
+}
+
+// JSONCRL represents a certificate revocation list in JSON format
+type JSONCRL struct {
+	Source             string             `json:"source,omitempty"`
+	Issuer             JSONSubject        `json:"issuer"`
+	Number             string             `json:"crl_number,omitempty"`
+	ThisUpdate         time.Time          `json:"this_update"`
+	NextUpdate         *time.Time         `json:"next_update,omitempty"`
+	SignatureAlgorithm string             `json:"signature_algorithm"`
+	SignatureValid     *bool              `json:"signature_valid,omitempty"`
+	Stale              bool               `json:"stale"`
+	Revoked            []JSONRevokedEntry `json:"revoked"`
+}
+
+// JSONCRLCheck represents a certificate checked against a CRL in JSON format
+type JSONCRLCheck struct {
+	Path    string            `json:"path"`
+	Revoked bool              `json:"revoked"`
+	Entry   *JSONRevokedEntry `json:"entry,omitempty"`
+	CRL     JSONCRL           `json:"crl"`
+}
+
+// JSONCAIndexEntry represents a certificate in a CA index in JSON format
+type JSONCAIndexEntry struct {
+	SerialNumber     string     `json:"serial_number"`
+	Subject          string     `json:"subject"`
+	SANs             []string   `json:"sans,omitempty"`
+	Status           string     `json:"status,omitempty"`
+	NotBefore        time.Time  `json:"not_before"`
+	NotAfter         time.Time  `json:"not_after"`
+	DaysUntilExpiry  *int       `json:"days_until_expiry,omitempty"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	File             string     `json:"file,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+}
+
+// JSONCAList represents a filtered listing of a CA index in JSON format
+type JSONCAList struct {
+	CASubject    string             `json:"ca_subject"`
+	Index        string             `json:"index"`
+	Total        int                `json:"total"`
+	Certificates []JSONCAIndexEntry `json:"certificates"`
+}
+
+// JSONProfile represents an issuance profile in JSON format
+type JSONProfile struct {
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	Builtin         bool     `json:"builtin"`
+	KeyUsage        []string `json:"key_usage"`
+	ExtKeyUsage     []string `json:"ext_key_usage,omitempty"`
+	OCSPNoCheck     bool     `json:"ocsp_no_check,omitempty"`
+	RequireHostSAN  bool     `json:"require_host_san,omitempty"`
+	RequireEmailSAN bool     `json:"require_email_san,omitempty"`
+	Days            int      `json:"days,omitempty"`
+}
+
+// JSONProfileList represents the issuance profiles available to cert sign
+type JSONProfileList struct {
+	Default  string        `json:"default"`
+	Profiles []JSONProfile `json:"profiles"`
+}
+
+// JSONOCSPResult represents an OCSP status check in JSON format
+type JSONOCSPResult struct {
+	Responder        string     `json:"responder"`
+	Status           string     `json:"status"`
+	SerialNumber     string     `json:"serial_number"`
+	ProducedAt       time.Time  `json:"produced_at"`
+	ThisUpdate       time.Time  `json:"this_update"`
+	NextUpdate       *time.Time `json:"next_update,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+	ResponderName    string     `json:"responder_name,omitempty"`
+	Stale            bool       `json:"stale"`
+}
+
 // JSONOperationResult represents the result of certificate operations
 type JSONOperationResult struct {
 	Success bool     `json:"success"`
@@ -96,6 +288,18 @@ type JSONTLSVersionInfo struct {
 	Supported   bool   `json:"supported"`
 	Error       string `json:"error,omitempty"`
 	CipherSuite string `json:"cipher_suite,omitempty"`
+
+	Ciphers           []JSONCipherSuite `json:"ciphers,omitempty"`
+	CipherOrder       string            `json:"cipher_order,omitempty"`
+	CiphersEnumerated *bool             `json:"ciphers_enumerated,omitempty"`
+}
+
+// JSONCipherSuite represents an accepted cipher suite and its grade in JSON format
+type JSONCipherSuite struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Grade  string `json:"grade"`
+	Reason string `json:"reason,omitempty"`
 }
 
 // JSONTLSResult represents TLS version test results in JSON format
@@ -105,6 +309,77 @@ type JSONTLSResult struct {
 	Versions     []JSONTLSVersionInfo `json:"versions"`
 	MinSupported string               `json:"min_supported"`
 	MaxSupported string               `json:"max_supported"`
+	CipherGrades map[string]int       `json:"cipher_grades,omitempty"`
+
+	ClientCertRequest *JSONClientCertRequest `json:"client_cert_request,omitempty"`
+}
+
+// JSONScanResult represents one scanned certificate (or failed target) in JSON format
+type JSONScanResult struct {
+	Target            string     `json:"target"`
+	Kind              string     `json:"kind"`
+	Index             int        `json:"index"`
+	Subject           string     `json:"subject,omitempty"`
+	Issuer            string     `json:"issuer,omitempty"`
+	SerialNumber      string     `json:"serial_number,omitempty"`
+	FingerprintSHA256 string     `json:"fingerprint_sha256,omitempty"`
+	NotAfter          *time.Time `json:"not_after,omitempty"`
+	DaysUntilExpiry   int        `json:"days_until_expiry"`
+	IsExpired         bool       `json:"is_expired"`
+	WithinThreshold   bool       `json:"within_threshold"`
+	Error             string     `json:"error,omitempty"`
+}
+
+// JSONScanReport represents a batch expiry scan in JSON format
+type JSONScanReport struct {
+	ThresholdDays int              `json:"threshold_days"`
+	Total         int              `json:"total"`
+	Expiring      int              `json:"expiring"`
+	Errors        int              `json:"errors"`
+	Results       []JSONScanResult `json:"results"`
+}
+
+// JSONDiffSide identifies one of the certificates in a diff
+type JSONDiffSide struct {
+	Source            string `json:"source"`
+	Subject           string `json:"subject"`
+	FingerprintSHA256 string `json:"fingerprint_sha256"`
+}
+
+// JSONFieldDiff represents one changed field in a certificate diff.
+// Old and New are strings for single-valued fields and arrays for lists.
+type JSONFieldDiff struct {
+	Field   string      `json:"field"`
+	Old     interface{} `json:"old"`
+	New     interface{} `json:"new"`
+	Added   []string    `json:"added,omitempty"`
+	Removed []string    `json:"removed,omitempty"`
+}
+
+// JSONCertificateDiff represents a field-by-field certificate comparison in JSON format
+type JSONCertificateDiff struct {
+	Old       JSONDiffSide    `json:"old"`
+	New       JSONDiffSide    `json:"new"`
+	Identical bool            `json:"identical"`
+	Changes   []JSONFieldDiff `json:"changes"`
+	Unchanged []string        `json:"unchanged"`
+}
+
+// JSONWatchEvent represents one poll of a watched target, written as a
+// line of NDJSON
+type JSONWatchEvent struct {
+	Time                      time.Time       `json:"time"`
+	Target                    string          `json:"target"`
+	Event                     string          `json:"event"`
+	Subject                   string          `json:"subject,omitempty"`
+	Issuer                    string          `json:"issuer,omitempty"`
+	SerialNumber              string          `json:"serial_number,omitempty"`
+	FingerprintSHA256         string          `json:"fingerprint_sha256,omitempty"`
+	NotAfter                  *time.Time      `json:"not_after,omitempty"`
+	DaysUntilExpiry           *int            `json:"days_until_expiry,omitempty"`
+	PreviousFingerprintSHA256 string          `json:"previous_fingerprint_sha256,omitempty"`
+	Changes                   []JSONFieldDiff `json:"changes,omitempty"`
+	Error                     string          `json:"error,omitempty"`
 }
 
 // ToJSON converts a Certificate to JSONCertificate
@@ -135,6 +410,31 @@ func (c *Certificate) ToJSON() JSONCertificate {
 	if c.CipherSuite != 0 {
 		jc.CipherSuite = tls.CipherSuiteName(c.CipherSuite)
 	}
+	if c.ClientCertRequest != nil {
+		jr := c.ClientCertRequest.ToJSON()
+		jc.ClientCertRequest = &jr
+	}
+	for _, sct := range c.SCTs {
+		jc.SCTs = append(jc.SCTs, sct.ToJSON())
+	}
+	if c.OCSPStaple != nil {
+		js := &JSONOCSPStaple{SignatureValid: c.OCSPStaple.SignatureValid, Error: c.OCSPStaple.Error}
+		if c.OCSPStaple.Result != nil {
+			jo := c.OCSPStaple.Result.ToJSON()
+			js.JSONOCSPResult = &jo
+		}
+		jc.OCSPStaple = js
+	}
+	if c.CTPolicy != nil {
+		jc.CTPolicy = &JSONCTPolicy{
+			Compliant: c.CTPolicy.Compliant,
+			Source:    c.CTPolicy.Source,
+			Required:  c.CTPolicy.Required,
+			Valid:     c.CTPolicy.Valid,
+			Operators: c.CTPolicy.Operators,
+			Problems:  c.CTPolicy.Problems,
+		}
+	}
 
 	// Convert IP addresses to strings
 	for _, ip := range c.IPAddresses {
@@ -155,9 +455,56 @@ func (c *Certificate) ToJSON() JSONCertificate {
 	// Add extended key usage
 	jc.ExtKeyUsage = getExtKeyUsageStrings(c.ExtKeyUsage)
 
+	// Add CA constraints
+	if c.IsCA && (c.MaxPathLen > 0 || c.MaxPathLenZero) {
+		pathLen := c.MaxPathLen
+		jc.MaxPathLen = &pathLen
+	}
+	jc.NameConstraints = nameConstraintsToJSON(c.Certificate)
+
 	return jc
 }
 
+// nameConstraintsToJSON returns the certificate's name constraints, or nil
+func nameConstraintsToJSON(c *x509.Certificate) *JSONNameConstraints {
+	ipRanges := func(nets []*net.IPNet) []string {
+		var out []string
+		for _, n := range nets {
+			out = append(out, n.String())
+		}
+		return out
+	}
+	nc := &JSONNameConstraints{
+		Critical:                c.PermittedDNSDomainsCritical,
+		PermittedDNSDomains:     c.PermittedDNSDomains,
+		ExcludedDNSDomains:      c.ExcludedDNSDomains,
+		PermittedIPRanges:       ipRanges(c.PermittedIPRanges),
+		ExcludedIPRanges:        ipRanges(c.ExcludedIPRanges),
+		PermittedEmailAddresses: c.PermittedEmailAddresses,
+		ExcludedEmailAddresses:  c.ExcludedEmailAddresses,
+		PermittedURIDomains:     c.PermittedURIDomains,
+		ExcludedURIDomains:      c.ExcludedURIDomains,
+	}
+	if len(nc.PermittedDNSDomains)+len(nc.ExcludedDNSDomains)+len(nc.PermittedIPRanges)+len(nc.ExcludedIPRanges)+
+		len(nc.PermittedEmailAddresses)+len(nc.ExcludedEmailAddresses)+len(nc.PermittedURIDomains)+len(nc.ExcludedURIDomains) == 0 {
+		return nil
+	}
+	return nc
+}
+
+// ToJSON converts a ClientCertRequest to JSONClientCertRequest
+func (r *ClientCertRequest) ToJSON() JSONClientCertRequest {
+	acceptableCAs := r.AcceptableCAs
+	if acceptableCAs == nil {
+		acceptableCAs = []string{}
+	}
+	return JSONClientCertRequest{
+		AcceptableCAs:    acceptableCAs,
+		SignatureSchemes: r.SignatureSchemeNames(),
+		CertificateSent:  r.CertificateSent,
+	}
+}
+
 // ToJSON converts CSRInfo to JSONCSRInfo
 func (info *CSRInfo) ToJSON() JSONCSRInfo {
 	ji := JSONCSRInfo{
@@ -196,9 +543,166 @@ func (vr *VerificationResult) ToJSON() JSONVerificationResult {
 		matches := vr.KeyMatches
 		result.KeyMatches = &matches
 	}
+	if vr.OCSP != nil {
+		jo := vr.OCSP.ToJSON()
+		result.OCSP = &jo
+	}
+	if vr.CRL != nil {
+		jc := vr.CRL.ToJSON()
+		result.CRL = &jc
+	}
 	return result
 }
 
+// ToJSON converts OCSPResult to JSONOCSPResult
+func (r *OCSPResult) ToJSON() JSONOCSPResult {
+	jo := JSONOCSPResult{
+		Responder:     r.Responder,
+		Status:        r.Status,
+		SerialNumber:  r.SerialNumber.Text(16),
+		ProducedAt:    r.ProducedAt,
+		ThisUpdate:    r.ThisUpdate,
+		ResponderName: r.ResponderName,
+		Stale:         r.IsStale(),
+	}
+	if !r.NextUpdate.IsZero() {
+		next := r.NextUpdate
+		jo.NextUpdate = &next
+	}
+	if r.Status == OCSPStatusRevoked {
+		revoked := r.RevokedAt
+		jo.RevokedAt = &revoked
+		jo.RevocationReason = RevocationReasonName(r.RevocationReason)
+	}
+	return jo
+}
+
+// ToJSON converts RevokedEntry to JSONRevokedEntry
+func (e *RevokedEntry) ToJSON() JSONRevokedEntry {
+	return JSONRevokedEntry{
+		SerialNumber: e.SerialNumber.Text(16),
+		RevokedAt:    e.RevokedAt,
+		Reason:       RevocationReasonName(e.Reason),
+		Subject:      e.Subject,
+	}
+}
+
+// ToJSON converts CRLInfo to JSONCRL
+func (c *CRLInfo) ToJSON() JSONCRL {
+	jc := JSONCRL{
+		Source:             c.Source,
+		Issuer:             subjectToJSON(c.Issuer),
+		ThisUpdate:         c.ThisUpdate,
+		SignatureAlgorithm: c.SignatureAlgorithm,
+		Stale:              c.IsStale(),
+		Revoked:            []JSONRevokedEntry{},
+	}
+	if c.Number != nil {
+		jc.Number = c.Number.String()
+	}
+	if !c.NextUpdate.IsZero() {
+		next := c.NextUpdate
+		jc.NextUpdate = &next
+	}
+	if c.SignatureChecked {
+		valid := c.SignatureValid
+		jc.SignatureValid = &valid
+	}
+	for i := range c.Entries {
+		jc.Revoked = append(jc.Revoked, c.Entries[i].ToJSON())
+	}
+	return jc
+}
+
+// ToJSON converts CRLCheck to JSONCRLCheck
+func (c *CRLCheck) ToJSON() JSONCRLCheck {
+	jc := JSONCRLCheck{
+		Path:    c.Path,
+		Revoked: c.Revoked != nil,
+		CRL:     c.CRL.ToJSON(),
+	}
+	if c.Revoked != nil {
+		entry := c.Revoked.ToJSON()
+		jc.Entry = &entry
+	}
+	return jc
+}
+
+// ToJSON converts IndexEntry to JSONCAIndexEntry. Status and days until
+// expiry are computed at now; a zero now omits them.
+func (e *IndexEntry) ToJSON(now time.Time) JSONCAIndexEntry {
+	je := JSONCAIndexEntry{
+		SerialNumber: e.SerialNumber.Text(16),
+		Subject:      e.Subject,
+		SANs:         e.SANs,
+		NotBefore:    e.NotBefore,
+		NotAfter:     e.NotAfter,
+		IssuedAt:     e.IssuedAt,
+		File:         e.File,
+	}
+	if !now.IsZero() {
+		je.Status = e.Status(now)
+		days := int(e.NotAfter.Sub(now).Hours() / 24)
+		je.DaysUntilExpiry = &days
+	}
+	if e.Revoked {
+		revoked := e.RevokedAt
+		je.RevokedAt = &revoked
+		je.RevocationReason = RevocationReasonName(e.RevocationReason)
+	}
+	return je
+}
+
+// ToJSON converts Profile to JSONProfile
+func (p *Profile) ToJSON() JSONProfile {
+	return JSONProfile{
+		Name:            p.Name,
+		Description:     p.Description,
+		Builtin:         p.Builtin,
+		KeyUsage:        p.KeyUsageNames(),
+		ExtKeyUsage:     p.ExtKeyUsageNames(),
+		OCSPNoCheck:     p.OCSPNoCheck,
+		RequireHostSAN:  p.RequireHostSAN,
+		RequireEmailSAN: p.RequireEmailSAN,
+		Days:            p.Days,
+	}
+}
+
+// ToJSON converts PKCS12Bundle to JSONPKCS12Bundle
+func (b *PKCS12Bundle) ToJSON() JSONPKCS12Bundle {
+	jb := JSONPKCS12Bundle{
+		Source:        b.Source,
+		HasPrivateKey: b.PrivateKey != nil,
+	}
+	if b.PrivateKey != nil {
+		jb.PrivateKeyAlgorithm = getPublicKeyAlgorithm(b.PrivateKey.Public())
+		jb.PrivateKeySize = getPublicKeySize(b.PrivateKey.Public())
+		matches := b.KeyMatches()
+		jb.KeyMatches = &matches
+	}
+	if b.Certificate != nil {
+		leaf := (&Certificate{
+			Certificate:     b.Certificate,
+			Source:          b.Source,
+			Format:          FormatPKCS12,
+			IsExpired:       b.Certificate.NotAfter.Before(time.Now()),
+			DaysUntilExpiry: int(time.Until(b.Certificate.NotAfter).Hours() / 24),
+		}).ToJSON()
+		jb.Certificate = &leaf
+	}
+	for _, c := range b.CACerts {
+		jb.Chain = append(jb.Chain, JSONCertSummary{
+			Subject:      c.Subject.String(),
+			Issuer:       c.Issuer.String(),
+			NotBefore:    c.NotBefore,
+			NotAfter:     c.NotAfter,
+			IsExpired:    c.NotAfter.Before(time.Now()),
+			SerialNumber: c.SerialNumber.Text(16),
+		})
+	}
+	return jb
+}
+
 // ToJSON converts TLSResult to JSONTLSResult
 func (tr *TLSResult) ToJSON() JSONTLSResult {
 	jsonResult := JSONTLSResult{
@@ -219,8 +723,32 @@ func (tr *TLSResult) ToJSON() JSONTLSResult {
 		if v.Supported && v.CipherSuite != 0 {
 			jsonVersion.CipherSuite = tls.CipherSuiteName(v.CipherSuite)
 		}
+		if tr.CiphersChecked && v.Supported {
+			enumerated := v.CiphersEnumerated
+			jsonVersion.CiphersEnumerated = &enumerated
+			jsonVersion.CipherOrder = v.CipherOrder
+			jsonVersion.Ciphers = make([]JSONCipherSuite, 0, len(v.Ciphers))
+			for _, c := range v.Ciphers {
+				jsonVersion.Ciphers = append(jsonVersion.Ciphers, JSONCipherSuite{
+					ID:     fmt.Sprintf("0x%04x", c.ID),
+					Name:   c.Name,
+					Grade:  c.Grade,
+					Reason: c.Reason,
+				})
+			}
+		}
 		jsonResult.Versions = append(jsonResult.Versions, jsonVersion)
 	}
+	if tr.ClientCertRequest != nil {
+		jr := tr.ClientCertRequest.ToJSON()
+		jsonResult.ClientCertRequest = &jr
+	}
+	if tr.CiphersChecked {
+		jsonResult.CipherGrades = map[string]int{CipherGradeSecure: 0, CipherGradeWeak: 0, CipherGradeInsecure: 0}
+		for grade, n := range tr.CipherGradeCounts() {
+			jsonResult.CipherGrades[grade] = n
+		}
+	}
 
 	if tr.MinSupported != 0 {
 		jsonResult.MinSupported = tlsVersionNames[tr.MinSupported]
@@ -232,6 +760,145 @@ func (tr *TLSResult) ToJSON() JSONTLSResult {
 	return jsonResult
 }
 
+// ToJSON converts a ChainAnalysis to JSONChainAnalysis
+func (a *ChainAnalysis) ToJSON() JSONChainAnalysis {
+	ja := JSONChainAnalysis{
+		Ordered:      a.Ordered,
+		Complete:     a.Complete,
+		Trusted:      a.Trusted,
+		TrustSource:  a.TrustSource,
+		Certificates: make([]JSONChainLink, 0, len(a.Links)),
+		Errors:       a.Errors,
+		Warnings:     a.Warnings,
+	}
+
+	for _, l := range a.Links {
+		jl := JSONChainLink{
+			Index:          l.Index,
+			Subject:        a.Certificates[l.Index].Subject.String(),
+			SelfSigned:     l.SelfSigned,
+			SignatureValid: l.SignatureValid,
+			Duplicate:      l.Duplicate,
+			Unused:         l.Unused,
+		}
+		if l.IssuerIndex >= 0 {
+			issuer := l.IssuerIndex
+			jl.IssuerIndex = &issuer
+		}
+		ja.Certificates = append(ja.Certificates, jl)
+	}
+
+	for i, c := range a.Path {
+		ja.Path = append(ja.Path, JSONChainPathEntry{
+			Subject:           c.Subject.String(),
+			Issuer:            c.Issuer.String(),
+			FingerprintSHA256: (&Certificate{Certificate: c}).FingerprintSHA256(),
+			Sent:              a.PathSent[i],
+		})
+	}
+
+	return ja
+}
+
+// ToJSON converts a ScanReport to JSONScanReport
+func (r *ScanReport) ToJSON() JSONScanReport {
+	jr := JSONScanReport{
+		ThresholdDays: int(r.Threshold.Hours() / 24),
+		Total:         len(r.Results),
+		Expiring:      r.Expiring(),
+		Errors:        r.Errors(),
+		Results:       make([]JSONScanResult, 0, len(r.Results)),
+	}
+
+	for _, res := range r.Results {
+		js := JSONScanResult{
+			Target:          res.Target,
+			Kind:            res.Kind,
+			Index:           res.Index,
+			WithinThreshold: res.WithinThreshold,
+		}
+		if res.Error != nil {
+			js.Error = res.Error.Error()
+		}
+		if c := res.Certificate; c != nil {
+			notAfter := c.NotAfter
+			js.Subject = c.Subject.String()
+			js.Issuer = c.Issuer.String()
+			js.SerialNumber = c.SerialNumber.Text(16)
+			js.FingerprintSHA256 = c.FingerprintSHA256()
+			js.NotAfter = &notAfter
+			js.DaysUntilExpiry = c.DaysUntilExpiry
+			js.IsExpired = c.IsExpired
+		}
+		jr.Results = append(jr.Results, js)
+	}
+
+	return jr
+}
+
+// ToJSON converts a CertificateDiff to JSONCertificateDiff
+func (d *CertificateDiff) ToJSON() JSONCertificateDiff {
+	side := func(c *Certificate) JSONDiffSide {
+		return JSONDiffSide{Source: c.Source, Subject: c.Subject.String(), FingerprintSHA256: c.FingerprintSHA256()}
+	}
+	jd := JSONCertificateDiff{
+		Old:       side(d.Old),
+		New:       side(d.New),
+		Identical: d.Identical(),
+		Changes:   []JSONFieldDiff{},
+		Unchanged: []string{},
+	}
+	for _, f := range d.Fields {
+		if !f.Changed() {
+			jd.Unchanged = append(jd.Unchanged, f.Key)
+			continue
+		}
+		jd.Changes = append(jd.Changes, f.ToJSON())
+	}
+	return jd
+}
+
+// ToJSON converts a FieldDiff to JSONFieldDiff
+func (f FieldDiff) ToJSON() JSONFieldDiff {
+	values := func(v []string) interface{} {
+		if f.List {
+			return append([]string{}, v...)
+		}
+		return v[0]
+	}
+	return JSONFieldDiff{
+		Field:   f.Key,
+		Old:     values(f.Old),
+		New:     values(f.New),
+		Added:   f.Added,
+		Removed: f.Removed,
+	}
+}
+
+// ToJSON converts a WatchEvent to JSONWatchEvent
+func (e *WatchEvent) ToJSON() JSONWatchEvent {
+	je := JSONWatchEvent{Time: e.Time, Target: e.Target, Event: e.Type}
+	if e.Error != nil {
+		je.Error = e.Error.Error()
+	}
+	if c := e.Certificate; c != nil {
+		notAfter, days := c.NotAfter, c.DaysUntilExpiry
+		je.Subject = c.Subject.String()
+		je.Issuer = c.Issuer.String()
+		je.SerialNumber = c.SerialNumber.Text(16)
+		je.FingerprintSHA256 = c.FingerprintSHA256()
+		je.NotAfter = &notAfter
+		je.DaysUntilExpiry = &days
+	}
+	if e.Previous != nil {
+		je.PreviousFingerprintSHA256 = e.Previous.FingerprintSHA256()
+	}
+	for _, f := range e.Changes {
+		je.Changes = append(je.Changes, f.ToJSON())
+	}
+	return je
+}
+
 // MarshalJSON implements json.Marshaler for TLSResult
 func (tr *TLSResult) MarshalJSON() ([]byte, error) {
 	return json.Marshal(tr.ToJSON())
@@ -340,3 +1007,127 @@ func getExtKeyUsageStrings(usage []x509.ExtKeyUsage) []string {
 
 	return usages
 }
+
+// ToJSON converts an SCT to its JSON representation
+func (s *SCT) ToJSON() JSONSCT {
+	js := JSONSCT{
+		Source:             s.Source,
+		Version:            int(s.Version) + 1,
+		LogID:              s.LogIDBase64(),
+		Timestamp:          s.Timestamp,
+		SignatureAlgorithm: s.SignatureAlgorithmName(),
+		Signature:          hex.EncodeToString(s.Signature),
+		Status:             s.Status,
+		Error:              s.Error,
+	}
+	if s.Log != nil {
+		js.Log = s.Log.Description
+		js.LogOperator = s.Log.Operator
+		js.LogState = s.Log.State
+	}
+	return js
+}
+
+// JSONLintRule represents a lint rule in JSON format
+type JSONLintRule struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Reference   string `json:"reference"`
+	Description string `json:"description"`
+	AppliesTo   string `json:"applies_to"`
+}
+
+// JSONLintFinding represents a lint finding in JSON format
+type JSONLintFinding struct {
+	Rule      string `json:"rule"`
+	Severity  string `json:"severity"`
+	Reference string `json:"reference"`
+	Message   string `json:"message"`
+}
+
+// JSONLintResult represents the lint findings for one certificate
+type JSONLintResult struct {
+	Source            string            `json:"source,omitempty"`
+	Subject           string            `json:"subject"`
+	FingerprintSHA256 string            `json:"fingerprint_sha256"`
+	Errors            int               `json:"errors"`
+	Warnings          int               `json:"warnings"`
+	Findings          []JSONLintFinding `json:"findings"`
+	RulesChecked      []string          `json:"rules_checked"`
+}
+
+// JSONLintReport represents a lint run over one or more certificates
+type JSONLintReport struct {
+	Passed       bool             `json:"passed"`
+	Errors       int              `json:"errors"`
+	Warnings     int              `json:"warnings"`
+	Certificates []JSONLintResult `json:"certificates"`
+}
+
+// ToJSON converts a LintRule to its JSON representation
+func (r *LintRule) ToJSON() JSONLintRule {
+	return JSONLintRule{
+		ID:          r.ID,
+		Severity:    r.Severity,
+		Reference:   r.Reference,
+		Description: r.Description,
+		AppliesTo:   r.AppliesTo(),
+	}
+}
+
+// ToJSON converts a LintResult to its JSON representation
+func (r *LintResult) ToJSON() JSONLintResult {
+	jr := JSONLintResult{
+		Source:            r.Certificate.Source,
+		Subject:           r.Certificate.Subject.String(),
+		FingerprintSHA256: r.Certificate.FingerprintSHA256(),
+		Errors:            r.Count(LintSeverityError),
+		Warnings:          r.Count(LintSeverityWarning),
+		Findings:          []JSONLintFinding{},
+		RulesChecked:      r.Rules,
+	}
+	for _, f := range r.Findings {
+		jr.Findings = append(jr.Findings, JSONLintFinding{
+			Rule:      f.Rule.ID,
+			Severity:  f.Rule.Severity,
+			Reference: f.Rule.Reference,
+			Message:   f.Message,
+		})
+	}
+	return jr
+}
+
+// LintReportToJSON summarizes the lint results for several certificates
+func LintReportToJSON(results []*LintResult) JSONLintReport {
+	report := JSONLintReport{Certificates: []JSONLintResult{}}
+	for _, r := range results {
+		jr := r.ToJSON()
+		report.Errors += jr.Errors
+		report.Warnings += jr.Warnings
+		report.Certificates = append(report.Certificates, jr)
+	}
+	report.Passed = report.Errors == 0
+	return report
+}
+
+// JSONACMEAccount represents an ACME account in JSON format
+type JSONACMEAccount struct {
+	URI       string   `json:"uri"`
+	Directory string   `json:"directory"`
+	Contact   []string `json:"contact,omitempty"`
+	Status    string   `json:"status,omitempty"`
+	KeyPath   string   `json:"key_path"`
+	Created   bool     `json:"created"`
+}
+
+// ToJSON converts ACMEAccount to JSONACMEAccount
+func (a *ACMEAccount) ToJSON() JSONACMEAccount {
+	return JSONACMEAccount{
+		URI:       a.URI,
+		Directory: a.DirectoryURL,
+		Contact:   a.Contact,
+		Status:    a.Status,
+		KeyPath:   a.KeyPath,
+		Created:   a.Created,
+	}
+}
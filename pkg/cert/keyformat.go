@@ -0,0 +1,159 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Private key encodings supported by convert
+const (
+	KeyFormatPKCS1          = "pkcs1"
+	KeyFormatPKCS8          = "pkcs8"
+	KeyFormatSEC1           = "sec1"
+	KeyFormatEncryptedPKCS8 = "encrypted-pkcs8"
+)
+
+// KeyFormats lists the accepted values for the key format option
+var KeyFormats = []string{KeyFormatPKCS1, KeyFormatPKCS8, KeyFormatSEC1, KeyFormatEncryptedPKCS8}
+
+// normalizeKeyFormat lowercases a key format and maps aliases; the empty
+// string means PKCS#8.
+func normalizeKeyFormat(format string) string {
+	switch f := strings.ToLower(strings.TrimSpace(format)); f {
+	case "":
+		return KeyFormatPKCS8
+	case "pkcs8-encrypted", "encrypted":
+		return KeyFormatEncryptedPKCS8
+	case "ec":
+		return KeyFormatSEC1
+	case "rsa":
+		return KeyFormatPKCS1
+	default:
+		return f
+	}
+}
+
+// marshalPrivateKey encodes a key in the requested format and returns the
+// DER bytes with the matching PEM block type. PKCS#1 only holds RSA keys and
+// SEC1 only holds EC keys; PKCS#8 holds any key type.
+func marshalPrivateKey(key crypto.Signer, format, password string) (string, []byte, error) {
+	switch normalizeKeyFormat(format) {
+	case KeyFormatPKCS1:
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return "", nil, fmt.Errorf("PKCS#1 only supports RSA keys, not %s", getPublicKeyAlgorithm(key.Public()))
+		}
+		return "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil
+	case KeyFormatSEC1:
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return "", nil, fmt.Errorf("SEC1 only supports EC keys, not %s", getPublicKeyAlgorithm(key.Public()))
+		}
+		der, err := x509.MarshalECPrivateKey(ecKey)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to marshal private key: %w", err)
+		}
+		return "EC PRIVATE KEY", der, nil
+	case KeyFormatPKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to marshal private key: %w", err)
+		}
+		return "PRIVATE KEY", der, nil
+	case KeyFormatEncryptedPKCS8:
+		der, err := encryptPKCS8PrivateKey(key, password)
+		if err != nil {
+			return "", nil, err
+		}
+		return "ENCRYPTED PRIVATE KEY", der, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported key format %q (use %s)", format, strings.Join(KeyFormats, ", "))
+	}
+}
+
+// writePrivateKey writes a newly generated key as PEM PKCS#8, encrypted
+// with PBES2 (AES-256-CBC) when password is set, readable only by the owner
+func writePrivateKey(path string, key crypto.Signer, password string) error {
+	format := KeyFormatPKCS8
+	if password != "" {
+		format = KeyFormatEncryptedPKCS8
+	}
+	blockType, der, err := marshalPrivateKey(key, format, password)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+
+	// WriteFile keeps the mode of an existing file; Windows has different
+	// permission semantics
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(path, 0600); err != nil {
+			return fmt.Errorf("failed to set key permissions: %w", err)
+		}
+	}
+	return nil
+}
+
+// isPrivateKeyData reports whether data holds a private key rather than
+// certificates: PEM with a private key block and no certificates, or a
+// DER-encoded key in any supported format.
+func isPrivateKeyData(data []byte) bool {
+	sawKey := false
+	sawPEM := false
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		sawPEM = true
+		switch {
+		case block.Type == "CERTIFICATE":
+			return false
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			sawKey = true
+		}
+	}
+	if sawPEM {
+		return sawKey
+	}
+
+	if isEncryptedPKCS8(data) {
+		return true
+	}
+	_, err := ParsePrivateKey(data, "")
+	return err == nil
+}
+
+// convertPrivateKey re-encodes a private key in the requested key format,
+// as PEM or DER.
+func convertPrivateKey(data []byte, opts ConvertOptions) ([]byte, error) {
+	key, err := ParsePrivateKey(data, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	blockType, der, err := marshalPrivateKey(key, opts.KeyFormat, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(opts.Format) {
+	case "pem":
+		return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), nil
+	case "der":
+		return der, nil
+	default:
+		return nil, fmt.Errorf("unsupported format for private keys: %s (use pem or der)", opts.Format)
+	}
+}
@@ -0,0 +1,422 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"certwiz/internal/testutil"
+)
+
+func TestConvertPrivateKeyFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+	rsaPath := testutil.TestdataPath("valid.key")
+
+	tests := []struct {
+		name      string
+		keyFormat string
+		blockType string
+	}{
+		{"PKCS1", KeyFormatPKCS1, "RSA PRIVATE KEY"},
+		{"PKCS8", KeyFormatPKCS8, "PRIVATE KEY"},
+		{"Default", "", "PRIVATE KEY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outPath := filepath.Join(tmpDir, tt.name+".key")
+			err := ConvertWithOptions(ConvertOptions{
+				InputPath:  rsaPath,
+				OutputPath: outPath,
+				Format:     "pem",
+				KeyFormat:  tt.keyFormat,
+			})
+			if err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+
+			data, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatalf("Failed to read output: %v", err)
+			}
+			block, _ := pem.Decode(data)
+			if block == nil || block.Type != tt.blockType {
+				t.Fatalf("Expected %s block, got %v", tt.blockType, block)
+			}
+			if _, err := ParsePrivateKey(data, ""); err != nil {
+				t.Errorf("Converted key does not parse: %v", err)
+			}
+		})
+	}
+
+	// EC keys round-trip through SEC1 and DER
+	ecKey, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey failed: %v", err)
+	}
+	ecPath := filepath.Join(tmpDir, "ec-pkcs8.der")
+	if err := os.WriteFile(ecPath, der, 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	sec1Path := filepath.Join(tmpDir, "ec-sec1.pem")
+	if err := ConvertWithOptions(ConvertOptions{InputPath: ecPath, OutputPath: sec1Path, Format: "pem", KeyFormat: "sec1"}); err != nil {
+		t.Fatalf("Convert to SEC1 failed: %v", err)
+	}
+	data, err := os.ReadFile(sec1Path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if !strings.Contains(string(data), "BEGIN EC PRIVATE KEY") {
+		t.Error("SEC1 output should be an EC PRIVATE KEY block")
+	}
+	key, err := ParsePrivateKey(data, "")
+	if err != nil {
+		t.Fatalf("SEC1 output does not parse: %v", err)
+	}
+	if _, ok := key.(*ecdsa.PrivateKey); !ok || !publicKeysEqual(key.Public(), ecKey.Public()) {
+		t.Error("SEC1 output does not match the original key")
+	}
+
+	// Format mismatches are rejected
+	if err := ConvertWithOptions(ConvertOptions{InputPath: ecPath, OutputPath: filepath.Join(tmpDir, "bad.pem"), Format: "pem", KeyFormat: "pkcs1"}); err == nil {
+		t.Error("Expected error converting an EC key to PKCS#1")
+	}
+	if err := ConvertWithOptions(ConvertOptions{InputPath: rsaPath, OutputPath: filepath.Join(tmpDir, "bad.pem"), Format: "pem", KeyFormat: "sec1"}); err == nil {
+		t.Error("Expected error converting an RSA key to SEC1")
+	}
+	if err := ConvertWithOptions(ConvertOptions{InputPath: rsaPath, OutputPath: filepath.Join(tmpDir, "bad.pem"), Format: "pem", KeyFormat: "jwk"}); err == nil {
+		t.Error("Expected error for unsupported key format")
+	}
+}
+
+func TestEncryptedPKCS8(t *testing.T) {
+	tmpDir := t.TempDir()
+	encPath := filepath.Join(tmpDir, "encrypted.key")
+
+	err := ConvertWithOptions(ConvertOptions{
+		InputPath:  testutil.TestdataPath("valid.key"),
+		OutputPath: encPath,
+		Format:     "pem",
+		KeyFormat:  KeyFormatEncryptedPKCS8,
+		Password:   "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("Convert to encrypted PKCS#8 failed: %v", err)
+	}
+
+	data, err := os.ReadFile(encPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if !strings.Contains(string(data), "BEGIN ENCRYPTED PRIVATE KEY") {
+		t.Fatal("Expected an ENCRYPTED PRIVATE KEY block")
+	}
+
+	if _, err := ParsePrivateKey(data, ""); err != errPasswordRequired {
+		t.Errorf("Expected errPasswordRequired without a password, got %v", err)
+	}
+	if _, err := ParsePrivateKey(data, "wrong"); err == nil {
+		t.Error("Expected error for wrong password")
+	}
+	key, err := ParsePrivateKey(data, "s3cret")
+	if err != nil {
+		t.Fatalf("Failed to decrypt key: %v", err)
+	}
+	original, err := os.ReadFile(testutil.TestdataPath("valid.key"))
+	if err != nil {
+		t.Fatalf("Failed to read original key: %v", err)
+	}
+	origKey, err := ParsePrivateKey(original, "")
+	if err != nil {
+		t.Fatalf("Failed to parse original key: %v", err)
+	}
+	if !publicKeysEqual(key.Public(), origKey.Public()) {
+		t.Error("Decrypted key does not match the original")
+	}
+
+	// Encrypting without a password is an error
+	err = ConvertWithOptions(ConvertOptions{
+		InputPath:  testutil.TestdataPath("valid.key"),
+		OutputPath: filepath.Join(tmpDir, "nopass.key"),
+		Format:     "pem",
+		KeyFormat:  KeyFormatEncryptedPKCS8,
+	})
+	if err == nil {
+		t.Error("Expected error encrypting without a password")
+	}
+
+	// Ed25519 keys can be encrypted too
+	edKey, err := generatePrivateKey(KeyAlgorithmEd25519, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	edDER, err := encryptPKCS8PrivateKey(edKey, "pw")
+	if err != nil {
+		t.Fatalf("encryptPKCS8PrivateKey failed: %v", err)
+	}
+	decrypted, err := ParsePrivateKey(edDER, "pw")
+	if err != nil {
+		t.Fatalf("Failed to decrypt Ed25519 key: %v", err)
+	}
+	if _, ok := decrypted.(ed25519.PrivateKey); !ok {
+		t.Errorf("Expected ed25519.PrivateKey, got %T", decrypted)
+	}
+}
+
+func TestEncryptedPKCS8OpenSSLFixture(t *testing.T) {
+	// valid-encrypted.key is written by openssl pkcs8 -topk8 -v2 aes-256-cbc
+	data, err := os.ReadFile(testutil.TestdataPath("valid-encrypted.key"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	key, err := ParsePrivateKey(data, "changeit")
+	if err != nil {
+		t.Fatalf("Failed to decrypt OpenSSL key: %v", err)
+	}
+	if _, ok := key.(*rsa.PrivateKey); !ok {
+		t.Errorf("Expected *rsa.PrivateKey, got %T", key)
+	}
+
+	// Decrypting to plain PKCS#1 via convert
+	outPath := filepath.Join(t.TempDir(), "plain.key")
+	err = ConvertWithOptions(ConvertOptions{
+		InputPath:  testutil.TestdataPath("valid-encrypted.key"),
+		OutputPath: outPath,
+		Format:     "pem",
+		KeyFormat:  "pkcs1",
+		Password:   "changeit",
+	})
+	if err != nil {
+		t.Fatalf("Convert of encrypted key failed: %v", err)
+	}
+}
+
+func TestEncryptedPKCS8IterationLimit(t *testing.T) {
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	der, err := encryptPKCS8PrivateKey(key, "pw")
+	if err != nil {
+		t.Fatalf("encryptPKCS8PrivateKey failed: %v", err)
+	}
+
+	// Rewrite the PBKDF2 parameters with a work factor that would take hours
+	var info encryptedPrivateKeyInfo
+	var params pbes2Params
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		t.Fatal(err)
+	}
+	kdf.IterationCount = 1<<31 - 1
+	kdfDER, err := asn1.Marshal(kdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.KeyDerivationFunc.Parameters = asn1.RawValue{FullBytes: kdfDER}
+	paramsDER, err := asn1.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info.Algorithm.Parameters = asn1.RawValue{FullBytes: paramsDER}
+	crafted, err := asn1.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParsePrivateKey(crafted, "pw")
+	if err == nil || !strings.Contains(err.Error(), "iteration count") {
+		t.Errorf("Expected the iteration count to be rejected, got %v", err)
+	}
+}
+
+func TestConvertIgnoresEmbeddedKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	certPEM, err := os.ReadFile(testutil.TestdataPath("valid.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM, err := os.ReadFile(testutil.TestdataPath("valid-encrypted.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(tmpDir, "bundle.pem")
+	if err := os.WriteFile(bundle, append(certPEM, keyPEM...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The encrypted key is only needed for PKCS#12, so no password is asked for
+	for _, format := range []string{"der", "pem"} {
+		out := filepath.Join(tmpDir, "out."+format)
+		if err := ConvertWithOptions(ConvertOptions{InputPath: bundle, OutputPath: out, Format: format}); err != nil {
+			t.Errorf("Convert to %s failed: %v", format, err)
+		}
+	}
+	err = ConvertWithOptions(ConvertOptions{InputPath: bundle, OutputPath: filepath.Join(tmpDir, "out.p12"), Format: "p12"})
+	if err == nil {
+		t.Error("Expected PKCS#12 output to need the key's password")
+	}
+}
+
+func TestConvertPreservesChain(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// PEM bundle -> DER keeps every certificate
+	derPath := filepath.Join(tmpDir, "fullchain.der")
+	if err := Convert(testutil.TestdataPath("fullchain.pem"), derPath, "der"); err != nil {
+		t.Fatalf("Convert to DER failed: %v", err)
+	}
+	derCerts, err := InspectFileAll(derPath)
+	if err != nil {
+		t.Fatalf("InspectFileAll on DER failed: %v", err)
+	}
+	if len(derCerts) != 3 {
+		t.Fatalf("DER output has %d certificates, want 3", len(derCerts))
+	}
+
+	// DER bundle -> PEM keeps every certificate, in order
+	pemPath := filepath.Join(tmpDir, "fullchain.pem")
+	if err := Convert(derPath, pemPath, "pem"); err != nil {
+		t.Fatalf("Convert to PEM failed: %v", err)
+	}
+	pemCerts, err := InspectFileAll(pemPath)
+	if err != nil {
+		t.Fatalf("InspectFileAll on PEM failed: %v", err)
+	}
+	if len(pemCerts) != 3 {
+		t.Fatalf("PEM output has %d certificates, want 3", len(pemCerts))
+	}
+	for i := range pemCerts {
+		if !pemCerts[i].Equal(derCerts[i].Certificate) {
+			t.Errorf("Certificate %d changed order or content", i)
+		}
+	}
+	if pemCerts[0].Subject.CommonName != "chain.example.com" {
+		t.Errorf("Leaf CN = %s, want chain.example.com", pemCerts[0].Subject.CommonName)
+	}
+}
+
+func TestEncryptedKeyGeneration(t *testing.T) {
+	dir := t.TempDir()
+	const password = "correct horse"
+
+	// An encrypted root and an intermediate signed with it
+	rootCert, rootKey := filepath.Join(dir, "root.crt"), filepath.Join(dir, "root.key")
+	if err := GenerateCA(CAOptions{CommonName: "Encrypted Root", Days: 30, KeyAlgorithm: KeyAlgorithmECDSAP256, KeyPassword: password}, rootCert, rootKey); err != nil {
+		t.Fatalf("GenerateCA failed: %v", err)
+	}
+	data, err := os.ReadFile(rootKey)
+	if err != nil {
+		t.Fatalf("Failed to read key: %v", err)
+	}
+	if !IsEncryptedPrivateKey(data) || !strings.Contains(string(data), "BEGIN ENCRYPTED PRIVATE KEY") {
+		t.Fatal("CA key should be written as an ENCRYPTED PRIVATE KEY")
+	}
+	if info, err := os.Stat(rootKey); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("key permissions = %o, want 600", info.Mode().Perm())
+	}
+
+	interCert, interKey := filepath.Join(dir, "inter.crt"), filepath.Join(dir, "inter.key")
+	interOpts := CAOptions{CommonName: "Encrypted Intermediate", Days: 30, KeyAlgorithm: KeyAlgorithmECDSAP256,
+		MaxPathLen: -1, ParentCert: rootCert, ParentKey: rootKey}
+	if err := GenerateCA(interOpts, interCert, interKey); err == nil || !strings.Contains(err.Error(), "password is required") {
+		t.Errorf("Expected a password error for the parent key, got %v", err)
+	}
+	interOpts.ParentKeyPassword = password
+	if err := GenerateCA(interOpts, interCert, interKey); err != nil {
+		t.Fatalf("GenerateCA with an encrypted parent failed: %v", err)
+	}
+	if data, _ := os.ReadFile(interKey); IsEncryptedPrivateKey(data) {
+		t.Error("Intermediate key should be unencrypted without a password")
+	}
+
+	// An encrypted CSR key, signed with the encrypted root
+	csrPath, csrKey := filepath.Join(dir, "leaf.csr"), filepath.Join(dir, "leaf.key")
+	if err := GenerateCSR(CSROptions{CommonName: "leaf.example.com", SANs: []string{"leaf.example.com"}, KeyAlgorithm: KeyAlgorithmECDSAP256, KeyPassword: "leaf"}, csrPath, csrKey); err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+	if data, _ := os.ReadFile(csrKey); !IsEncryptedPrivateKey(data) {
+		t.Error("CSR key should be encrypted")
+	}
+
+	leafCert := filepath.Join(dir, "leaf.crt")
+	signOpts := SignOptions{CSRPath: csrPath, CACert: rootCert, CAKey: rootKey, Days: 30}
+	for _, wrong := range []string{"", "wrong"} {
+		signOpts.CAKeyPassword = wrong
+		if err := SignCSR(signOpts, leafCert); err == nil {
+			t.Errorf("SignCSR with password %q should fail", wrong)
+		}
+	}
+	signOpts.CAKeyPassword = password
+	if err := SignCSR(signOpts, leafCert); err != nil {
+		t.Fatalf("SignCSR with an encrypted CA key failed: %v", err)
+	}
+
+	// verify --key with the encrypted leaf key
+	result, err := VerifyWithOptions(VerifyOptions{CertPath: leafCert, KeyPath: csrKey, KeyPassword: "leaf"})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if !result.KeyChecked || !result.KeyMatches {
+		t.Error("Encrypted key should match the certificate")
+	}
+	if _, err := VerifyWithOptions(VerifyOptions{CertPath: leafCert, KeyPath: csrKey}); err == nil {
+		t.Error("Expected an error checking an encrypted key without its password")
+	}
+
+	// Self-signed certificates
+	if err := Generate(GenerateOptions{CommonName: "self", Days: 1, KeyAlgorithm: KeyAlgorithmEd25519, OutputDir: dir, KeyPassword: "pw"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "self.key"))
+	if _, err := ParsePrivateKey(data, "pw"); err != nil {
+		t.Errorf("Failed to decrypt generated key: %v", err)
+	}
+}
+
+func TestIsEncryptedPrivateKey(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		want bool
+	}{
+		{"valid-encrypted.key", true},
+		{"valid.key", false},
+		{"valid.pem", false},
+	} {
+		data, err := os.ReadFile(testutil.TestdataPath(tt.file))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", tt.file, err)
+		}
+		if got := IsEncryptedPrivateKey(data); got != tt.want {
+			t.Errorf("IsEncryptedPrivateKey(%s) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+
+	data, err := os.ReadFile(testutil.TestdataPath("valid-encrypted.key"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if !IsEncryptedPrivateKey(block.Bytes) {
+		t.Error("DER-encoded encrypted keys should be detected")
+	}
+}
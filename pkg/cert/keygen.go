@@ -0,0 +1,141 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// Supported key algorithms for generated private keys
+const (
+	KeyAlgorithmRSA       = "rsa"
+	KeyAlgorithmECDSAP256 = "ecdsa-p256"
+	KeyAlgorithmECDSAP384 = "ecdsa-p384"
+	KeyAlgorithmECDSAP521 = "ecdsa-p521"
+	KeyAlgorithmEd25519   = "ed25519"
+)
+
+// KeyAlgorithms lists the accepted values for the key algorithm options,
+// in the order they are shown to users.
+var KeyAlgorithms = []string{
+	KeyAlgorithmRSA,
+	KeyAlgorithmECDSAP256,
+	KeyAlgorithmECDSAP384,
+	KeyAlgorithmECDSAP521,
+	KeyAlgorithmEd25519,
+}
+
+// normalizeKeyAlgorithm lowercases an algorithm name and maps the empty
+// string and common aliases to their canonical form.
+func normalizeKeyAlgorithm(algorithm string) string {
+	switch a := strings.ToLower(strings.TrimSpace(algorithm)); a {
+	case "":
+		return KeyAlgorithmRSA
+	case "ecdsa", "ec", "p256", "p-256", "ecdsa-p-256":
+		return KeyAlgorithmECDSAP256
+	case "p384", "p-384", "ecdsa-p-384":
+		return KeyAlgorithmECDSAP384
+	case "p521", "p-521", "ecdsa-p-521":
+		return KeyAlgorithmECDSAP521
+	default:
+		return a
+	}
+}
+
+// ValidateKeyAlgorithm returns an error if algorithm is not a supported key
+// algorithm. The empty string is accepted and means RSA.
+func ValidateKeyAlgorithm(algorithm string) error {
+	normalized := normalizeKeyAlgorithm(algorithm)
+	for _, a := range KeyAlgorithms {
+		if a == normalized {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported key algorithm %q (use %s)", algorithm, strings.Join(KeyAlgorithms, ", "))
+}
+
+// generatePrivateKey creates a new private key for the given algorithm.
+// rsaBits is only used for RSA keys.
+func generatePrivateKey(algorithm string, rsaBits int) (crypto.Signer, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+
+	switch normalizeKeyAlgorithm(algorithm) {
+	case KeyAlgorithmRSA:
+		key, err = rsa.GenerateKey(rand.Reader, rsaBits)
+	case KeyAlgorithmECDSAP256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyAlgorithmECDSAP384:
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyAlgorithmECDSAP521:
+		key, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case KeyAlgorithmEd25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, ValidateKeyAlgorithm(algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+	return key, nil
+}
+
+// signatureAlgorithmFor picks the signature algorithm that matches a
+// signing key: SHA-256 for RSA, the curve-sized hash for ECDSA (as
+// recommended by RFC 5480), and pure Ed25519.
+func signatureAlgorithmFor(pub crypto.PublicKey) x509.SignatureAlgorithm {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return x509.SHA256WithRSA
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P384():
+			return x509.ECDSAWithSHA384
+		case elliptic.P521():
+			return x509.ECDSAWithSHA512
+		default:
+			return x509.ECDSAWithSHA256
+		}
+	case ed25519.PublicKey:
+		return x509.PureEd25519
+	default:
+		return x509.UnknownSignatureAlgorithm
+	}
+}
+
+// leafKeyUsage returns the key usage for an end-entity certificate.
+// Key encipherment only applies to RSA key transport, so it is omitted
+// for ECDSA and Ed25519 keys.
+func leafKeyUsage(pub crypto.PublicKey) x509.KeyUsage {
+	if _, ok := pub.(*rsa.PublicKey); ok {
+		return x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
+	}
+	return x509.KeyUsageDigitalSignature
+}
+
+// KeyAlgorithmDisplayName returns a short human-readable name for a key
+// algorithm, e.g. "RSA" or "ECDSA P-256".
+func KeyAlgorithmDisplayName(algorithm string) string {
+	switch normalizeKeyAlgorithm(algorithm) {
+	case KeyAlgorithmRSA:
+		return "RSA"
+	case KeyAlgorithmECDSAP256:
+		return "ECDSA P-256"
+	case KeyAlgorithmECDSAP384:
+		return "ECDSA P-384"
+	case KeyAlgorithmECDSAP521:
+		return "ECDSA P-521"
+	case KeyAlgorithmEd25519:
+		return "Ed25519"
+	default:
+		return algorithm
+	}
+}
@@ -0,0 +1,196 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyAlgorithms(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		publicKey string
+		keySize   int
+		sigAlg    x509.SignatureAlgorithm
+	}{
+		{KeyAlgorithmRSA, "RSA", 2048, x509.SHA256WithRSA},
+		{KeyAlgorithmECDSAP256, "ECDSA", 256, x509.ECDSAWithSHA256},
+		{KeyAlgorithmECDSAP384, "ECDSA", 384, x509.ECDSAWithSHA384},
+		{KeyAlgorithmECDSAP521, "ECDSA", 521, x509.ECDSAWithSHA512},
+		{KeyAlgorithmEd25519, "Ed25519", 256, x509.PureEd25519},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			// Self-signed certificate
+			err := Generate(GenerateOptions{
+				CommonName:   "keys.local",
+				Days:         30,
+				KeyAlgorithm: tt.algorithm,
+				KeySize:      2048,
+				OutputDir:    tmpDir,
+			})
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			c, err := InspectFile(filepath.Join(tmpDir, "keys.local.crt"))
+			if err != nil {
+				t.Fatalf("InspectFile failed: %v", err)
+			}
+			if got := getPublicKeyAlgorithm(c.PublicKey); got != tt.publicKey {
+				t.Errorf("certificate key algorithm = %s, want %s", got, tt.publicKey)
+			}
+			if got := getPublicKeySize(c.PublicKey); got != tt.keySize {
+				t.Errorf("certificate key size = %d, want %d", got, tt.keySize)
+			}
+			if c.SignatureAlgorithm != tt.sigAlg {
+				t.Errorf("certificate signature algorithm = %s, want %s", c.SignatureAlgorithm, tt.sigAlg)
+			}
+			if _, isRSA := c.PublicKey.(*rsa.PublicKey); !isRSA && c.KeyUsage&x509.KeyUsageKeyEncipherment != 0 {
+				t.Error("non-RSA certificate should not have Key Encipherment usage")
+			}
+
+			// The written key must match the certificate
+			keyData, err := os.ReadFile(filepath.Join(tmpDir, "keys.local.key"))
+			if err != nil {
+				t.Fatalf("Failed to read key: %v", err)
+			}
+			key, err := ParsePrivateKey(keyData, "")
+			if err != nil {
+				t.Fatalf("ParsePrivateKey failed: %v", err)
+			}
+			if !publicKeysEqual(c.PublicKey, key.Public()) {
+				t.Error("generated key does not match certificate")
+			}
+
+			// CSR
+			csrPath := filepath.Join(tmpDir, "keys.csr")
+			err = GenerateCSR(CSROptions{
+				CommonName:   "keys.local",
+				KeyAlgorithm: tt.algorithm,
+				KeySize:      2048,
+			}, csrPath, filepath.Join(tmpDir, "csr.key"))
+			if err != nil {
+				t.Fatalf("GenerateCSR failed: %v", err)
+			}
+			csrData, err := os.ReadFile(csrPath)
+			if err != nil {
+				t.Fatalf("Failed to read CSR: %v", err)
+			}
+			info, err := ParseCSR(csrData)
+			if err != nil {
+				t.Fatalf("ParseCSR failed: %v", err)
+			}
+			if info.PublicKeyAlgorithm != tt.publicKey {
+				t.Errorf("CSR key algorithm = %s, want %s", info.PublicKeyAlgorithm, tt.publicKey)
+			}
+			if info.KeySize != tt.keySize {
+				t.Errorf("CSR key size = %d, want %d", info.KeySize, tt.keySize)
+			}
+			if info.SignatureAlgorithm != tt.sigAlg.String() {
+				t.Errorf("CSR signature algorithm = %s, want %s", info.SignatureAlgorithm, tt.sigAlg)
+			}
+
+			// CA, then sign the CSR with it
+			caCertPath := filepath.Join(tmpDir, "ca.crt")
+			caKeyPath := filepath.Join(tmpDir, "ca.key")
+			err = GenerateCA(CAOptions{
+				CommonName:   "Keys CA",
+				Days:         30,
+				KeyAlgorithm: tt.algorithm,
+				KeySize:      2048,
+			}, caCertPath, caKeyPath)
+			if err != nil {
+				t.Fatalf("GenerateCA failed: %v", err)
+			}
+			ca, err := InspectFile(caCertPath)
+			if err != nil {
+				t.Fatalf("InspectFile on CA failed: %v", err)
+			}
+			if ca.SignatureAlgorithm != tt.sigAlg {
+				t.Errorf("CA signature algorithm = %s, want %s", ca.SignatureAlgorithm, tt.sigAlg)
+			}
+
+			signedPath := filepath.Join(tmpDir, "signed.crt")
+			err = SignCSR(SignOptions{
+				CSRPath: csrPath,
+				CACert:  caCertPath,
+				CAKey:   caKeyPath,
+				Days:    30,
+			}, signedPath)
+			if err != nil {
+				t.Fatalf("SignCSR failed: %v", err)
+			}
+			signed, err := InspectFile(signedPath)
+			if err != nil {
+				t.Fatalf("InspectFile on signed cert failed: %v", err)
+			}
+			if err := signed.CheckSignatureFrom(ca.Certificate); err != nil {
+				t.Errorf("signed certificate does not verify against CA: %v", err)
+			}
+		})
+	}
+}
+
+func TestKeyAlgorithmCurveInfo(t *testing.T) {
+	tmpDir := t.TempDir()
+	csrPath := filepath.Join(tmpDir, "ec.csr")
+	if err := GenerateCSR(CSROptions{CommonName: "ec.local", KeyAlgorithm: "ecdsa-p384"}, csrPath, filepath.Join(tmpDir, "ec.key")); err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+	data, err := os.ReadFile(csrPath)
+	if err != nil {
+		t.Fatalf("Failed to read CSR: %v", err)
+	}
+	info, err := ParseCSR(data)
+	if err != nil {
+		t.Fatalf("ParseCSR failed: %v", err)
+	}
+	if info.Curve != "P-384" {
+		t.Errorf("Curve = %q, want P-384", info.Curve)
+	}
+}
+
+func TestValidateKeyAlgorithm(t *testing.T) {
+	valid := []string{"", "rsa", "RSA", "ecdsa-p256", "ecdsa", "p-384", "ecdsa-p521", "ed25519"}
+	for _, a := range valid {
+		if err := ValidateKeyAlgorithm(a); err != nil {
+			t.Errorf("ValidateKeyAlgorithm(%q) = %v, want nil", a, err)
+		}
+	}
+
+	invalid := []string{"dsa", "ecdsa-p224", "x25519"}
+	for _, a := range invalid {
+		if err := ValidateKeyAlgorithm(a); err == nil {
+			t.Errorf("ValidateKeyAlgorithm(%q) = nil, want error", a)
+		}
+	}
+
+	if _, err := generatePrivateKey("dsa", 2048); err == nil {
+		t.Error("generatePrivateKey should reject unsupported algorithms")
+	}
+}
+
+func TestGeneratePrivateKeyTypes(t *testing.T) {
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	if _, ok := key.(*ecdsa.PrivateKey); !ok {
+		t.Errorf("expected *ecdsa.PrivateKey, got %T", key)
+	}
+
+	key, err = generatePrivateKey(KeyAlgorithmEd25519, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	if _, ok := key.(ed25519.PrivateKey); !ok {
+		t.Errorf("expected ed25519.PrivateKey, got %T", key)
+	}
+}
@@ -0,0 +1,148 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// privateKeyFormatsTried names every encoding ParsePrivateKey attempts, for
+// the error returned when none of them match
+const privateKeyFormatsTried = "PKCS#8, encrypted PKCS#8, PKCS#1 (RSA), SEC1 (EC), and OpenSSH, as PEM or DER"
+
+// LoadPrivateKey reads a private key file in any format ParsePrivateKey
+// accepts. Encrypted keys are decrypted with password.
+func LoadPrivateKey(path, password string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	return ParsePrivateKey(data, password)
+}
+
+// ParsePrivateKey parses a private key in PKCS#8, PKCS#1 (RSA), SEC1 (EC),
+// or OpenSSH format, PEM- or DER-encoded. Encrypted PKCS#8, OpenSSH, and
+// legacy OpenSSL ("Proc-Type: 4,ENCRYPTED") keys are decrypted with
+// password; without one they fail with a password-required error. Other
+// PEM blocks, such as EC PARAMETERS or certificates, are skipped.
+func ParsePrivateKey(data []byte, password string) (crypto.Signer, error) {
+	der := data
+	decrypted := false
+	if block := findPrivateKeyBlock(data); block != nil {
+		switch {
+		case block.Type == "OPENSSH PRIVATE KEY":
+			return parseOpenSSHPrivateKey(pem.EncodeToMemory(block), password)
+		case x509.IsEncryptedPEMBlock(block): //nolint:staticcheck // legacy PEM encryption is still found in older keys
+			plaintext, err := decryptLegacyPEMBlock(block, password)
+			if err != nil {
+				return nil, err
+			}
+			der, decrypted = plaintext, true
+		default:
+			der = block.Bytes
+		}
+	} else if block, _ := pem.Decode(data); block != nil {
+		return nil, fmt.Errorf("no private key found in PEM data (found %s)", block.Type)
+	}
+
+	if isEncryptedPKCS8(der) {
+		plaintext, err := decryptPKCS8PrivateKey(der, password)
+		if err != nil {
+			return nil, err
+		}
+		der, decrypted = plaintext, true
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	if decrypted {
+		// Decryption only checks the padding, so a wrong password can get
+		// this far
+		return nil, errIncorrectPassword
+	}
+	return nil, fmt.Errorf("failed to parse private key (tried %s)", privateKeyFormatsTried)
+}
+
+// findPrivateKeyBlock returns the first PEM block holding a private key, or
+// nil if data has none
+func findPrivateKeyBlock(data []byte) *pem.Block {
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil
+		}
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return block
+		}
+	}
+}
+
+// decryptLegacyPEMBlock decrypts a PEM block encrypted with OpenSSL's
+// traditional DEK-Info scheme, as written by "openssl rsa -aes256 -traditional"
+func decryptLegacyPEMBlock(block *pem.Block, password string) ([]byte, error) {
+	if password == "" {
+		return nil, errPasswordRequired
+	}
+	der, err := x509.DecryptPEMBlock(block, []byte(password)) //nolint:staticcheck // see ParsePrivateKey
+	if errors.Is(err, x509.IncorrectPasswordError) {
+		return nil, errIncorrectPassword
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+	return der, nil
+}
+
+// parseOpenSSHPrivateKey parses an "OPENSSH PRIVATE KEY" PEM block, as
+// written by ssh-keygen, decrypting it with password if it is encrypted
+func parseOpenSSHPrivateKey(pemBytes []byte, password string) (crypto.Signer, error) {
+	key, err := ssh.ParseRawPrivateKey(pemBytes)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if password == "" {
+			return nil, errPasswordRequired
+		}
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(pemBytes, []byte(password))
+	}
+	if errors.Is(err, x509.IncorrectPasswordError) {
+		return nil, errIncorrectPassword
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenSSH private key: %w", err)
+	}
+
+	// ssh returns Ed25519 keys by pointer
+	if k, ok := key.(*ed25519.PrivateKey); ok {
+		return *k, nil
+	}
+	if signer, ok := key.(crypto.Signer); ok {
+		return signer, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", key)
+}
+
+// isEncryptedOpenSSHKey reports whether PEM data holds a passphrase-protected
+// OpenSSH private key
+func isEncryptedOpenSSHKey(pemBytes []byte) bool {
+	_, err := ssh.ParseRawPrivateKey(pemBytes)
+	var missing *ssh.PassphraseMissingError
+	return errors.As(err, &missing)
+}
@@ -0,0 +1,154 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"certwiz/internal/testutil"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParsePrivateKeyFormats(t *testing.T) {
+	const password = "s3cret"
+
+	for _, alg := range []string{KeyAlgorithmRSA, KeyAlgorithmECDSAP256, KeyAlgorithmEd25519} {
+		key, err := generatePrivateKey(alg, 2048)
+		if err != nil {
+			t.Fatalf("generatePrivateKey(%s) failed: %v", alg, err)
+		}
+
+		encodings := map[string][]byte{}
+		pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("MarshalPKCS8PrivateKey failed: %v", err)
+		}
+		encodings["PKCS8 PEM"] = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+		encodings["PKCS8 DER"] = pkcs8
+		if _, der, err := marshalPrivateKey(key, KeyFormatEncryptedPKCS8, password); err == nil {
+			encodings["encrypted PKCS8"] = der
+		}
+		if blockType, der, err := marshalPrivateKey(key, KeyFormatPKCS1, ""); err == nil {
+			encodings["PKCS1 PEM"] = pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+			encodings["PKCS1 DER"] = der
+			//nolint:staticcheck // legacy PEM encryption is what older tools write
+			legacy, err := x509.EncryptPEMBlock(rand.Reader, blockType, der, []byte(password), x509.PEMCipherAES256)
+			if err != nil {
+				t.Fatalf("EncryptPEMBlock failed: %v", err)
+			}
+			encodings["legacy encrypted PEM"] = pem.EncodeToMemory(legacy)
+		}
+		if blockType, der, err := marshalPrivateKey(key, KeyFormatSEC1, ""); err == nil {
+			encodings["SEC1 PEM"] = pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+			encodings["SEC1 DER"] = der
+			// openssl ecparam -genkey puts the curve parameters first
+			params := pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}})
+			encodings["SEC1 with EC PARAMETERS"] = append(params, encodings["SEC1 PEM"]...)
+		}
+		block, err := ssh.MarshalPrivateKey(key, "test")
+		if err != nil {
+			t.Fatalf("ssh.MarshalPrivateKey failed: %v", err)
+		}
+		encodings["OpenSSH"] = pem.EncodeToMemory(block)
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "test", []byte(password))
+		if err != nil {
+			t.Fatalf("ssh.MarshalPrivateKeyWithPassphrase failed: %v", err)
+		}
+		encodings["encrypted OpenSSH"] = pem.EncodeToMemory(block)
+
+		for name, data := range encodings {
+			t.Run(alg+"/"+name, func(t *testing.T) {
+				encrypted := strings.Contains(name, "encrypted")
+				if got := IsEncryptedPrivateKey(data); got != encrypted {
+					t.Errorf("IsEncryptedPrivateKey = %v, want %v", got, encrypted)
+				}
+				if encrypted {
+					if _, err := ParsePrivateKey(data, ""); err != errPasswordRequired {
+						t.Errorf("Expected errPasswordRequired without a password, got %v", err)
+					}
+					if _, err := ParsePrivateKey(data, "wrong"); err != errIncorrectPassword {
+						t.Errorf("Expected errIncorrectPassword with a wrong password, got %v", err)
+					}
+				}
+
+				parsed, err := ParsePrivateKey(data, password)
+				if err != nil {
+					t.Fatalf("ParsePrivateKey failed: %v", err)
+				}
+				if !publicKeysEqual(key.Public(), parsed.Public()) {
+					t.Error("Parsed key does not match the original")
+				}
+			})
+		}
+	}
+}
+
+func TestParsePrivateKeyErrors(t *testing.T) {
+	_, err := ParsePrivateKey([]byte("not a key"), "")
+	if err == nil {
+		t.Fatal("Expected an error for garbage input")
+	}
+	for _, format := range []string{"PKCS#8", "PKCS#1", "SEC1", "OpenSSH", "DER"} {
+		if !strings.Contains(err.Error(), format) {
+			t.Errorf("Error %q should list %s", err, format)
+		}
+	}
+
+	certData, err := os.ReadFile(testutil.TestdataPath("valid.pem"))
+	if err != nil {
+		t.Fatalf("Failed to read certificate: %v", err)
+	}
+	if _, err := ParsePrivateKey(certData, ""); err == nil || !strings.Contains(err.Error(), "found CERTIFICATE") {
+		t.Errorf("Expected a no-private-key error for a certificate, got %v", err)
+	}
+
+	if _, err := LoadPrivateKey(filepath.Join(t.TempDir(), "missing.key"), ""); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestSignCSRWithAnyCAKeyFormat(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key")
+	if err := GenerateCA(CAOptions{CommonName: "EC Root", Days: 30, KeyAlgorithm: KeyAlgorithmECDSAP256}, caCert, caKey); err != nil {
+		t.Fatalf("GenerateCA failed: %v", err)
+	}
+	csrPath, csrKey := filepath.Join(dir, "leaf.csr"), filepath.Join(dir, "leaf.key")
+	if err := GenerateCSR(CSROptions{CommonName: "leaf.example.com", SANs: []string{"leaf.example.com"}, KeyAlgorithm: KeyAlgorithmECDSAP256}, csrPath, csrKey); err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+
+	key, err := LoadPrivateKey(caKey, "")
+	if err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+	_, sec1, err := marshalPrivateKey(key, KeyFormatSEC1, "")
+	if err != nil {
+		t.Fatalf("marshalPrivateKey failed: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "ca")
+	if err != nil {
+		t.Fatalf("ssh.MarshalPrivateKey failed: %v", err)
+	}
+
+	for name, data := range map[string][]byte{
+		"SEC1 DER": sec1,
+		"OpenSSH":  pem.EncodeToMemory(block),
+	} {
+		t.Run(name, func(t *testing.T) {
+			keyPath := filepath.Join(dir, "ca-"+strings.ReplaceAll(name, " ", "-")+".key")
+			if err := os.WriteFile(keyPath, data, 0600); err != nil {
+				t.Fatalf("Failed to write key: %v", err)
+			}
+			leaf := filepath.Join(dir, "leaf-"+strings.ReplaceAll(name, " ", "-")+".crt")
+			if err := SignCSR(SignOptions{CSRPath: csrPath, CACert: caCert, CAKey: keyPath, Days: 30}, leaf); err != nil {
+				t.Fatalf("SignCSR failed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Lint severities, from most to least serious
+const (
+	LintSeverityError   = "error"   // violates a MUST of the Baseline Requirements or RFC 5280
+	LintSeverityWarning = "warning" // violates a SHOULD, or a practice clients enforce
+)
+
+// Which certificates a lint rule applies to
+const (
+	lintScopeAll  = "all"
+	lintScopeLeaf = "leaf" // end-entity certificates
+	lintScopeCA   = "ca"   // CA certificates
+)
+
+// maxLeafValidity is the longest validity the Baseline Requirements allow
+// for TLS server certificates issued since 2020-09-01 (section 6.3.2)
+const maxLeafValidity = 398 * 24 * time.Hour
+
+// Extension OIDs checked for criticality
+const (
+	oidSubjectKeyID     = "2.5.29.14"
+	oidKeyUsage         = "2.5.29.15"
+	oidSubjectAltName   = "2.5.29.17"
+	oidBasicConstraints = "2.5.29.19"
+	oidAuthorityKeyID   = "2.5.29.35"
+	oidExtendedKeyUsage = "2.5.29.37"
+)
+
+// LintRule is one best-practice check
+type LintRule struct {
+	ID          string // stable identifier used in config and output, e.g. "weak-key"
+	Severity    string // LintSeverityError or LintSeverityWarning
+	Reference   string // the requirement, e.g. "RFC 5280 4.2.1.9"
+	Description string
+	scope       string
+	check       func(c *x509.Certificate) []string // one message per problem found
+}
+
+// AppliesTo names the certificates the rule checks: all, leaf, or ca
+func (r *LintRule) AppliesTo() string {
+	return r.scope
+}
+
+// LintFinding is a problem a rule found in a certificate
+type LintFinding struct {
+	Rule    *LintRule
+	Message string
+}
+
+// LintOptions selects which rules run
+type LintOptions struct {
+	Select   []string // rule IDs to run; every rule when empty
+	Suppress []string // rule IDs to skip
+}
+
+// LintResult is the outcome of linting one certificate
+type LintResult struct {
+	Certificate *Certificate
+	Findings    []LintFinding // in rule order
+	Rules       []string      // IDs of the rules that ran
+}
+
+// Count returns the number of findings with the given severity
+func (r *LintResult) Count(severity string) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Rule.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// lintRules are every rule, in the order they run and are listed
+var lintRules = []*LintRule{
+	{
+		ID:          "validity-too-long",
+		Severity:    LintSeverityError,
+		Reference:   "CABF BR 6.3.2",
+		Description: "TLS server certificates must not be valid for more than 398 days",
+		scope:       lintScopeLeaf,
+		check: func(c *x509.Certificate) []string {
+			// Validity includes both NotBefore and NotAfter (RFC 5280 4.1.2.5)
+			validity := c.NotAfter.Sub(c.NotBefore) + time.Second
+			if validity <= maxLeafValidity {
+				return nil
+			}
+			days := int((validity + 24*time.Hour - 1) / (24 * time.Hour))
+			return []string{fmt.Sprintf("valid for %d days; the maximum is 398", days)}
+		},
+	},
+	{
+		ID:          "san-missing",
+		Severity:    LintSeverityError,
+		Reference:   "CABF BR 7.1.2.7.12",
+		Description: "End-entity certificates must have a Subject Alternative Name extension",
+		scope:       lintScopeLeaf,
+		check: func(c *x509.Certificate) []string {
+			if findExtension(c, oidSubjectAltName) == nil {
+				return []string{"no Subject Alternative Name extension; clients ignore the common name"}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "cn-not-in-san",
+		Severity:    LintSeverityError,
+		Reference:   "CABF BR 7.1.4",
+		Description: "The common name, if present, must be one of the SANs",
+		scope:       lintScopeLeaf,
+		check: func(c *x509.Certificate) []string {
+			cn := c.Subject.CommonName
+			if cn == "" {
+				return nil
+			}
+			for _, name := range c.DNSNames {
+				if strings.EqualFold(name, cn) {
+					return nil
+				}
+			}
+			if ip := net.ParseIP(cn); ip != nil {
+				for _, san := range c.IPAddresses {
+					if san.Equal(ip) {
+						return nil
+					}
+				}
+			}
+			return []string{fmt.Sprintf("common name %q is not in the SANs", cn)}
+		},
+	},
+	{
+		ID:          "weak-key",
+		Severity:    LintSeverityError,
+		Reference:   "CABF BR 6.1.5, 6.1.6",
+		Description: "RSA keys must be at least 2048 bits with an odd exponent of at least 3; ECDSA keys must use P-256, P-384, or P-521",
+		scope:       lintScopeAll,
+		check: func(c *x509.Certificate) []string {
+			var problems []string
+			switch key := c.PublicKey.(type) {
+			case *rsa.PublicKey:
+				bits := key.N.BitLen()
+				if bits < 2048 {
+					problems = append(problems, fmt.Sprintf("RSA key is %d bits; at least 2048 are required", bits))
+				} else if bits%8 != 0 {
+					problems = append(problems, fmt.Sprintf("RSA modulus is %d bits, not a multiple of 8", bits))
+				}
+				if key.E < 3 || key.E%2 == 0 {
+					problems = append(problems, fmt.Sprintf("RSA public exponent %d must be odd and at least 3", key.E))
+				}
+			case *ecdsa.PublicKey:
+				switch key.Curve.Params().Name {
+				case "P-256", "P-384", "P-521":
+				default:
+					problems = append(problems, fmt.Sprintf("ECDSA curve %s is not allowed; use P-256, P-384, or P-521", key.Curve.Params().Name))
+				}
+			}
+			if c.PublicKeyAlgorithm == x509.DSA {
+				problems = append(problems, "DSA keys are not allowed")
+			}
+			return problems
+		},
+	},
+	{
+		ID:          "sha1-signature",
+		Severity:    LintSeverityError,
+		Reference:   "CABF BR 7.1.3.2",
+		Description: "Certificates must not be signed with SHA-1 or MD5",
+		scope:       lintScopeAll,
+		check: func(c *x509.Certificate) []string {
+			if isSelfIssued(c) {
+				return nil // a trust anchor's own signature is never checked
+			}
+			switch c.SignatureAlgorithm {
+			case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1, x509.MD5WithRSA, x509.MD2WithRSA:
+				return []string{fmt.Sprintf("signed with %s, which clients reject", c.SignatureAlgorithm)}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "serial-length",
+		Severity:    LintSeverityError,
+		Reference:   "RFC 5280 4.1.2.2",
+		Description: "Serial numbers must be positive and at most 20 octets",
+		scope:       lintScopeAll,
+		check: func(c *x509.Certificate) []string {
+			serial := c.SerialNumber
+			if serial.Sign() <= 0 {
+				return []string{"serial number must be positive"}
+			}
+			octets := len(serial.Bytes())
+			if serial.BitLen()%8 == 0 {
+				octets++ // DER adds a leading zero byte to keep the integer positive
+			}
+			if octets > 20 {
+				return []string{fmt.Sprintf("serial number is %d octets; the maximum is 20", octets)}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "serial-entropy",
+		Severity:    LintSeverityWarning,
+		Reference:   "CABF BR 7.1",
+		Description: "Serial numbers should be long enough to hold 64 random bits",
+		scope:       lintScopeAll,
+		check: func(c *x509.Certificate) []string {
+			// Count octets, not bits: 64 random bits have a leading zero
+			// bit half the time
+			if serial := c.SerialNumber; serial.Sign() > 0 && len(serial.Bytes()) < 8 {
+				return []string{fmt.Sprintf("serial number is %d octets, too short to hold 64 random bits", len(serial.Bytes()))}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "aki-missing",
+		Severity:    LintSeverityError,
+		Reference:   "RFC 5280 4.2.1.1",
+		Description: "Certificates other than roots must have an Authority Key Identifier",
+		scope:       lintScopeAll,
+		check: func(c *x509.Certificate) []string {
+			if len(c.AuthorityKeyId) == 0 && !isSelfIssued(c) {
+				return []string{"no Authority Key Identifier extension"}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "ca-ski-missing",
+		Severity:    LintSeverityError,
+		Reference:   "RFC 5280 4.2.1.2",
+		Description: "CA certificates must have a Subject Key Identifier",
+		scope:       lintScopeCA,
+		check: func(c *x509.Certificate) []string {
+			if len(c.SubjectKeyId) == 0 {
+				return []string{"no Subject Key Identifier extension"}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "ski-missing",
+		Severity:    LintSeverityWarning,
+		Reference:   "RFC 5280 4.2.1.2",
+		Description: "End-entity certificates should have a Subject Key Identifier",
+		scope:       lintScopeLeaf,
+		check: func(c *x509.Certificate) []string {
+			if len(c.SubjectKeyId) == 0 {
+				return []string{"no Subject Key Identifier extension"}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "basic-constraints-not-critical",
+		Severity:    LintSeverityError,
+		Reference:   "RFC 5280 4.2.1.9",
+		Description: "Basic Constraints must be critical in CA certificates",
+		scope:       lintScopeCA,
+		check: func(c *x509.Certificate) []string {
+			if ext := findExtension(c, oidBasicConstraints); ext != nil && !ext.Critical {
+				return []string{"Basic Constraints extension is not critical"}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "key-identifier-critical",
+		Severity:    LintSeverityError,
+		Reference:   "RFC 5280 4.2.1.1, 4.2.1.2",
+		Description: "Authority and Subject Key Identifiers must not be critical",
+		scope:       lintScopeAll,
+		check: func(c *x509.Certificate) []string {
+			var problems []string
+			if ext := findExtension(c, oidAuthorityKeyID); ext != nil && ext.Critical {
+				problems = append(problems, "Authority Key Identifier extension is critical")
+			}
+			if ext := findExtension(c, oidSubjectKeyID); ext != nil && ext.Critical {
+				problems = append(problems, "Subject Key Identifier extension is critical")
+			}
+			return problems
+		},
+	},
+	{
+		ID:          "san-not-critical",
+		Severity:    LintSeverityError,
+		Reference:   "RFC 5280 4.2.1.6",
+		Description: "Subject Alternative Name must be critical when the subject is empty",
+		scope:       lintScopeAll,
+		check: func(c *x509.Certificate) []string {
+			if ext := findExtension(c, oidSubjectAltName); ext != nil && !ext.Critical && len(c.Subject.Names) == 0 {
+				return []string{"subject is empty but the Subject Alternative Name extension is not critical"}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "key-usage-not-critical",
+		Severity:    LintSeverityWarning,
+		Reference:   "RFC 5280 4.2.1.3",
+		Description: "Key Usage should be critical",
+		scope:       lintScopeAll,
+		check: func(c *x509.Certificate) []string {
+			if ext := findExtension(c, oidKeyUsage); ext != nil && !ext.Critical {
+				return []string{"Key Usage extension is not critical"}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "eku-critical",
+		Severity:    LintSeverityWarning,
+		Reference:   "CABF BR 7.1.2.7.10",
+		Description: "Extended Key Usage should not be critical",
+		scope:       lintScopeAll,
+		check: func(c *x509.Certificate) []string {
+			if ext := findExtension(c, oidExtendedKeyUsage); ext != nil && ext.Critical {
+				return []string{"Extended Key Usage extension is critical"}
+			}
+			return nil
+		},
+	},
+}
+
+// LintRules returns every lint rule, in the order they run
+func LintRules() []*LintRule {
+	return append([]*LintRule{}, lintRules...)
+}
+
+// findLintRule returns the rule with the given ID, or nil
+func findLintRule(id string) *LintRule {
+	for _, r := range lintRules {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// ValidateLintOptions checks that every selected and suppressed rule exists
+func ValidateLintOptions(opts LintOptions) error {
+	var unknown []string
+	for _, id := range append(append([]string{}, opts.Select...), opts.Suppress...) {
+		if findLintRule(id) == nil {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown lint rule(s): %s (see cert lint --list-rules)", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Lint checks c against the selected rules
+func Lint(c *Certificate, opts LintOptions) (*LintResult, error) {
+	if err := ValidateLintOptions(opts); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool)
+	for _, id := range opts.Select {
+		selected[id] = true
+	}
+	suppressed := make(map[string]bool)
+	for _, id := range opts.Suppress {
+		suppressed[id] = true
+	}
+
+	result := &LintResult{Certificate: c}
+	for _, rule := range lintRules {
+		if (len(selected) > 0 && !selected[rule.ID]) || suppressed[rule.ID] {
+			continue
+		}
+		if (rule.scope == lintScopeLeaf && c.IsCA) || (rule.scope == lintScopeCA && !c.IsCA) {
+			continue
+		}
+		result.Rules = append(result.Rules, rule.ID)
+		for _, msg := range rule.check(c.Certificate) {
+			result.Findings = append(result.Findings, LintFinding{Rule: rule, Message: msg})
+		}
+	}
+	return result, nil
+}
+
+// findExtension returns the extension with the given OID, or nil
+func findExtension(c *x509.Certificate, oid string) *pkix.Extension {
+	for i, ext := range c.Extensions {
+		if ext.Id.String() == oid {
+			return &c.Extensions[i]
+		}
+	}
+	return nil
+}
+
+// isSelfIssued reports whether c names itself as issuer, as roots do. The
+// signature isn't checked since Go refuses to verify SHA-1 signatures,
+// which old roots still use.
+func isSelfIssued(c *x509.Certificate) bool {
+	if !bytes.Equal(c.RawIssuer, c.RawSubject) {
+		return false
+	}
+	return len(c.AuthorityKeyId) == 0 || bytes.Equal(c.AuthorityKeyId, c.SubjectKeyId)
+}
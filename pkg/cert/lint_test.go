@@ -0,0 +1,355 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newLintTestCert issues a lint-clean leaf from issuer after applying
+// modify to its template
+func newLintTestCert(t *testing.T, issuer *testCA, modify func(*x509.Certificate)) *Certificate {
+	t.Helper()
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		t.Fatalf("newSerialNumber failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "lint.example.com"},
+		DNSNames:              []string{"lint.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(90 * 24 * time.Hour),
+		BasicConstraintsValid: true,
+		SubjectKeyId:          []byte{1, 2, 3, 4},
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if modify != nil {
+		modify(template)
+	}
+	return &Certificate{Certificate: issueTestCert(t, template, key, issuer).cert}
+}
+
+// mustMarshal DER-encodes v for hand-built extensions
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	der, err := asn1.Marshal(v)
+	if err != nil {
+		t.Fatalf("asn1.Marshal failed: %v", err)
+	}
+	return der
+}
+
+// findingRules returns the rule IDs of r's findings, in order
+func findingRules(r *LintResult) []string {
+	var ids []string
+	for _, f := range r.Findings {
+		ids = append(ids, f.Rule.ID)
+	}
+	return ids
+}
+
+func TestLintRules(t *testing.T) {
+	root := newTestCert(t, "Lint Root", true, nil, []byte{9, 9, 9})
+
+	tests := []struct {
+		name   string
+		modify func(*x509.Certificate)
+		want   []string
+	}{
+		{
+			name: "clean certificate",
+			want: nil,
+		},
+		{
+			name: "validity over 398 days",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.NotAfter = tmpl.NotBefore.Add(399 * 24 * time.Hour)
+			},
+			want: []string{"validity-too-long"},
+		},
+		{
+			name: "validity of exactly 398 days",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.NotAfter = tmpl.NotBefore.Add(398*24*time.Hour - time.Second)
+			},
+			want: nil,
+		},
+		{
+			name: "no SANs",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.DNSNames = nil
+			},
+			want: []string{"san-missing", "cn-not-in-san"},
+		},
+		{
+			name: "common name not in SANs",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.DNSNames = []string{"other.example.com"}
+			},
+			want: []string{"cn-not-in-san"},
+		},
+		{
+			name: "IP common name in SANs",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.Subject.CommonName = "192.0.2.1"
+				tmpl.DNSNames = nil
+				tmpl.IPAddresses = []net.IP{net.ParseIP("192.0.2.1")}
+			},
+			want: nil,
+		},
+		{
+			name: "short serial",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.SerialNumber = big.NewInt(1234)
+			},
+			want: []string{"serial-entropy"},
+		},
+		{
+			name: "64-bit serial with the top bit clear",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.SerialNumber = new(big.Int).SetUint64(0x7fffffffffffffff) // 63 bits in 8 octets
+			},
+			want: nil,
+		},
+		{
+			name: "serial over 20 octets",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.SerialNumber = new(big.Int).Lsh(big.NewInt(1), 159) // 20 octets plus a leading zero
+			},
+			want: []string{"serial-length"},
+		},
+		{
+			name: "no subject key identifier",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.SubjectKeyId = nil
+			},
+			want: []string{"ski-missing"},
+		},
+		{
+			name: "critical EKU and non-critical key usage",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.ExtKeyUsage = nil
+				tmpl.KeyUsage = 0
+				tmpl.ExtraExtensions = []pkix.Extension{
+					{Id: asn1.ObjectIdentifier{2, 5, 29, 37}, Critical: true, Value: mustMarshal(t, []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 1}})},
+					{Id: asn1.ObjectIdentifier{2, 5, 29, 15}, Critical: false, Value: mustMarshal(t, asn1.BitString{Bytes: []byte{0x80}, BitLength: 1})},
+				}
+			},
+			want: []string{"key-usage-not-critical", "eku-critical"},
+		},
+		{
+			name: "empty subject with non-critical SAN",
+			modify: func(tmpl *x509.Certificate) {
+				tmpl.Subject = pkix.Name{}
+				tmpl.DNSNames = nil
+				tmpl.ExtraExtensions = []pkix.Extension{
+					{Id: asn1.ObjectIdentifier{2, 5, 29, 17}, Value: mustMarshal(t, []asn1.RawValue{{Tag: 2, Class: asn1.ClassContextSpecific, Bytes: []byte("lint.example.com")}})},
+				}
+			},
+			want: []string{"san-not-critical"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newLintTestCert(t, root, tt.modify)
+			result, err := Lint(c, LintOptions{})
+			if err != nil {
+				t.Fatalf("Lint failed: %v", err)
+			}
+			got := findingRules(result)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("findings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintWeakKey(t *testing.T) {
+	root := newTestCert(t, "Lint Root", true, nil, []byte{9, 9, 9})
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	serial, _ := newSerialNumber()
+	leaf := issueTestCert(t, &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "weak.example.com"},
+		DNSNames:     []string{"weak.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		SubjectKeyId: []byte{1},
+	}, key, root)
+
+	result, err := Lint(&Certificate{Certificate: leaf.cert}, LintOptions{})
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if len(result.Findings) != 1 || result.Findings[0].Rule.ID != "weak-key" {
+		t.Fatalf("findings = %v, want weak-key", findingRules(result))
+	}
+	if !strings.Contains(result.Findings[0].Message, "1024 bits") {
+		t.Errorf("message = %q, want the key size", result.Findings[0].Message)
+	}
+}
+
+func TestLintCAScope(t *testing.T) {
+	root := newTestCert(t, "Lint Root", true, nil, []byte{9, 9, 9})
+
+	// Go always marks Basic Constraints critical, so supply our own
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	serial, _ := newSerialNumber()
+	intermediate := issueTestCert(t, &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Lint Intermediate"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(5 * 365 * 24 * time.Hour),
+		SubjectKeyId: []byte{8, 8, 8},
+		ExtraExtensions: []pkix.Extension{
+			{Id: asn1.ObjectIdentifier{2, 5, 29, 19}, Value: mustMarshal(t, struct{ IsCA bool }{true})},
+		},
+	}, key, root)
+
+	// Roots are exempt from AKI, and leaf-only rules such as the validity
+	// limit don't run on CAs
+	for _, ca := range []*testCA{root, intermediate} {
+		result, err := Lint(&Certificate{Certificate: ca.cert}, LintOptions{})
+		if err != nil {
+			t.Fatalf("Lint failed: %v", err)
+		}
+		for _, id := range result.Rules {
+			if id == "san-missing" || id == "validity-too-long" {
+				t.Errorf("%s: leaf rule %s ran on a CA", ca.cert.Subject.CommonName, id)
+			}
+		}
+		want := ""
+		if ca == intermediate {
+			want = "basic-constraints-not-critical"
+		}
+		if got := strings.Join(findingRules(result), ","); got != want {
+			t.Errorf("%s: findings = %s, want %q", ca.cert.Subject.CommonName, got, want)
+		}
+	}
+}
+
+func TestLintSelectSuppress(t *testing.T) {
+	root := newTestCert(t, "Lint Root", true, nil, []byte{9, 9, 9})
+	c := newLintTestCert(t, root, func(tmpl *x509.Certificate) {
+		tmpl.DNSNames = nil
+		tmpl.SubjectKeyId = nil
+	})
+
+	result, err := Lint(c, LintOptions{Select: []string{"san-missing", "ski-missing"}})
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if got := strings.Join(result.Rules, ","); got != "san-missing,ski-missing" {
+		t.Errorf("rules = %s, want san-missing,ski-missing", got)
+	}
+	if result.Count(LintSeverityError) != 1 || result.Count(LintSeverityWarning) != 1 {
+		t.Errorf("counts = %d errors, %d warnings; want 1 and 1",
+			result.Count(LintSeverityError), result.Count(LintSeverityWarning))
+	}
+
+	result, err = Lint(c, LintOptions{Suppress: []string{"san-missing", "cn-not-in-san"}})
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if got := strings.Join(findingRules(result), ","); got != "ski-missing" {
+		t.Errorf("findings = %s, want ski-missing", got)
+	}
+
+	_, err = Lint(c, LintOptions{Select: []string{"weak-key", "no-such-rule"}, Suppress: []string{"also-missing"}})
+	if err == nil || !strings.Contains(err.Error(), "also-missing, no-such-rule") {
+		t.Errorf("error = %v, want the unknown rule IDs", err)
+	}
+}
+
+func TestLintReportToJSON(t *testing.T) {
+	root := newTestCert(t, "Lint Root", true, nil, []byte{9, 9, 9})
+	clean := newLintTestCert(t, root, nil)
+	bad := newLintTestCert(t, root, func(tmpl *x509.Certificate) {
+		tmpl.SubjectKeyId = nil
+		tmpl.NotAfter = tmpl.NotBefore.Add(500 * 24 * time.Hour)
+	})
+
+	var results []*LintResult
+	for _, c := range []*Certificate{clean, bad} {
+		r, err := Lint(c, LintOptions{})
+		if err != nil {
+			t.Fatalf("Lint failed: %v", err)
+		}
+		results = append(results, r)
+	}
+
+	report := LintReportToJSON(results)
+	if report.Passed || report.Errors != 1 || report.Warnings != 1 {
+		t.Errorf("report = passed %v, %d errors, %d warnings; want failed with 1 and 1",
+			report.Passed, report.Errors, report.Warnings)
+	}
+	if report.Certificates[0].Findings == nil {
+		t.Error("clean certificate findings should be an empty list, not null")
+	}
+	f := report.Certificates[1].Findings[0]
+	if f.Rule != "validity-too-long" || f.Severity != LintSeverityError || f.Reference == "" {
+		t.Errorf("finding = %+v, want validity-too-long error with a reference", f)
+	}
+
+	if !LintReportToJSON(results[:1]).Passed {
+		t.Error("a report with only clean certificates should pass")
+	}
+}
+
+func TestVerifyWithLintWarnings(t *testing.T) {
+	dir := t.TempDir()
+	root := newTestCert(t, "Lint Root", true, nil, []byte{9, 9, 9})
+	c := newLintTestCert(t, root, func(tmpl *x509.Certificate) {
+		tmpl.SubjectKeyId = nil
+	})
+	path := filepath.Join(dir, "leaf.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	result, err := VerifyWithOptions(VerifyOptions{CertPath: path, Lint: &LintOptions{}})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if !result.IsValid {
+		t.Errorf("lint findings should not invalidate the certificate: %v", result.Errors)
+	}
+	if !containsMessage(result.Warnings, "(ski-missing)") {
+		t.Errorf("warnings = %v, want the ski-missing finding", result.Warnings)
+	}
+
+	result, err = VerifyWithOptions(VerifyOptions{CertPath: path, Lint: &LintOptions{Suppress: []string{"ski-missing"}}})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("warnings = %v, want none with ski-missing suppressed", result.Warnings)
+	}
+
+	if _, err := VerifyWithOptions(VerifyOptions{CertPath: path, Lint: &LintOptions{Select: []string{"bogus"}}}); err == nil {
+		t.Error("expected an error for an unknown lint rule")
+	}
+}
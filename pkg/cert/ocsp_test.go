@@ -0,0 +1,447 @@
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// testOCSPResponder is a local OCSP responder that answers from a status map
+type testOCSPResponder struct {
+	issuer   *testCA
+	signer   *testCA // signs responses; the issuer unless delegated
+	statuses map[string]int
+	server   *httptest.Server
+}
+
+func newTestOCSPResponder(t *testing.T, issuer *testCA) *testOCSPResponder {
+	t.Helper()
+	r := &testOCSPResponder{issuer: issuer, signer: issuer, statuses: map[string]int{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ocsp", r.serveOCSP)
+	mux.HandleFunc("/issuer.der", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(issuer.cert.Raw)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusInternalServerError)
+	})
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *testOCSPResponder) serveOCSP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ocspReq, err := ocsp.ParseRequest(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, ok := r.statuses[ocspReq.SerialNumber.String()]
+	if !ok {
+		status = ocsp.Unknown
+	}
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: ocspReq.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   time.Now().Add(24 * time.Hour),
+	}
+	if status == ocsp.Revoked {
+		template.RevokedAt = time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+		template.RevocationReason = ocsp.KeyCompromise
+	}
+	if r.signer != r.issuer {
+		template.Certificate = r.signer.cert
+	}
+
+	resp, err := ocsp.CreateResponse(r.issuer.cert, r.signer.cert, template, r.signer.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/ocsp-response")
+	_, _ = w.Write(resp)
+}
+
+// newOCSPLeaf issues a leaf that points at the responder in its AIA extension
+func newOCSPLeaf(t *testing.T, issuer *testCA, responderURL, issuerURL string) *testCA {
+	t.Helper()
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		t.Fatalf("newSerialNumber failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "ocsp.example.com"},
+		DNSNames:     []string{"ocsp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if responderURL != "" {
+		template.OCSPServer = []string{responderURL}
+	}
+	if issuerURL != "" {
+		template.IssuingCertificateURL = []string{issuerURL}
+	}
+	return issueTestCert(t, template, key, issuer)
+}
+
+func TestCheckOCSP(t *testing.T) {
+	root := newTestCert(t, "OCSP Test CA", true, nil, nil)
+	responder := newTestOCSPResponder(t, root)
+	ocspURL := responder.server.URL + "/ocsp"
+
+	good := newOCSPLeaf(t, root, ocspURL, "")
+	revoked := newOCSPLeaf(t, root, ocspURL, "")
+	unknown := newOCSPLeaf(t, root, ocspURL, "")
+	responder.statuses[good.cert.SerialNumber.String()] = ocsp.Good
+	responder.statuses[revoked.cert.SerialNumber.String()] = ocsp.Revoked
+
+	tests := []struct {
+		name   string
+		leaf   *testCA
+		status string
+	}{
+		{"Good", good, OCSPStatusGood},
+		{"Revoked", revoked, OCSPStatusRevoked},
+		{"Unknown", unknown, OCSPStatusUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := CheckOCSP(tt.leaf.cert, root.cert, OCSPOptions{})
+			if err != nil {
+				t.Fatalf("CheckOCSP failed: %v", err)
+			}
+			if result.Status != tt.status {
+				t.Errorf("Status = %s, want %s", result.Status, tt.status)
+			}
+			if result.Responder != ocspURL {
+				t.Errorf("Responder = %s, want %s", result.Responder, ocspURL)
+			}
+			if result.ThisUpdate.IsZero() || result.NextUpdate.IsZero() || result.IsStale() {
+				t.Errorf("Unexpected update times: this=%v next=%v", result.ThisUpdate, result.NextUpdate)
+			}
+			if tt.status == OCSPStatusRevoked {
+				if result.RevokedAt.IsZero() || RevocationReasonName(result.RevocationReason) != "keyCompromise" {
+					t.Errorf("Revocation details = %v, %d", result.RevokedAt, result.RevocationReason)
+				}
+				jo := result.ToJSON()
+				if jo.RevokedAt == nil || jo.RevocationReason != "keyCompromise" {
+					t.Errorf("JSON revocation details = %+v", jo)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckOCSPDelegatedResponder(t *testing.T) {
+	root := newTestCert(t, "OCSP Delegating CA", true, nil, nil)
+	responder := newTestOCSPResponder(t, root)
+
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	responder.signer = issueTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "OCSP Responder"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}, key, root)
+
+	leaf := newOCSPLeaf(t, root, responder.server.URL+"/ocsp", "")
+	responder.statuses[leaf.cert.SerialNumber.String()] = ocsp.Good
+
+	result, err := CheckOCSP(leaf.cert, root.cert, OCSPOptions{})
+	if err != nil {
+		t.Fatalf("CheckOCSP failed: %v", err)
+	}
+	if result.Status != OCSPStatusGood || result.ResponderName != "CN=OCSP Responder" {
+		t.Errorf("Result = %s signed by %q, want good signed by the delegated responder", result.Status, result.ResponderName)
+	}
+
+	// Any other certificate from the CA must not be able to forge responses
+	responder.signer = newOCSPLeaf(t, root, "", "")
+	if _, err := CheckOCSP(leaf.cert, root.cert, OCSPOptions{}); err == nil || !strings.Contains(err.Error(), "not authorized for OCSP signing") {
+		t.Errorf("Response signed by a plain leaf: got %v, want a rejection", err)
+	}
+	responder.signer = issueTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(43),
+		Subject:      pkix.Name{CommonName: "Expired OCSP Responder"},
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     time.Now().Add(-24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}, key, root)
+	if _, err := CheckOCSP(leaf.cert, root.cert, OCSPOptions{}); err == nil || !strings.Contains(err.Error(), "not valid at") {
+		t.Errorf("Response signed by an expired responder: got %v, want a rejection", err)
+	}
+}
+
+func TestCheckOCSPErrors(t *testing.T) {
+	root := newTestCert(t, "OCSP Error CA", true, nil, nil)
+	other := newTestCert(t, "Other CA", true, nil, nil)
+	responder := newTestOCSPResponder(t, root)
+
+	// Responses signed by an unrelated key are rejected
+	forged := newTestOCSPResponder(t, root)
+	forged.signer = other
+	leaf := newOCSPLeaf(t, root, forged.server.URL+"/ocsp", "")
+	forged.statuses[leaf.cert.SerialNumber.String()] = ocsp.Good
+	if _, err := CheckOCSP(leaf.cert, root.cert, OCSPOptions{}); err == nil {
+		t.Error("Expected error for response signed by an unrelated key")
+	}
+
+	// No responder URL
+	noAIA := newOCSPLeaf(t, root, "", "")
+	if _, err := CheckOCSP(noAIA.cert, root.cert, OCSPOptions{}); err == nil || !strings.Contains(err.Error(), "no OCSP responder") {
+		t.Errorf("Expected missing responder error, got %v", err)
+	}
+
+	// The responder can be overridden
+	responder.statuses[noAIA.cert.SerialNumber.String()] = ocsp.Good
+	if result, err := CheckOCSP(noAIA.cert, root.cert, OCSPOptions{Responder: responder.server.URL + "/ocsp"}); err != nil || result.Status != OCSPStatusGood {
+		t.Errorf("Responder override failed: %v", err)
+	}
+
+	// HTTP errors
+	broken := newOCSPLeaf(t, root, responder.server.URL+"/broken", "")
+	if _, err := CheckOCSP(broken.cert, root.cert, OCSPOptions{}); err == nil {
+		t.Error("Expected error for HTTP 500 from responder")
+	}
+
+	// Missing issuer
+	if _, err := CheckOCSP(leaf.cert, nil, OCSPOptions{}); err == nil {
+		t.Error("Expected error without an issuer")
+	}
+}
+
+func TestFindIssuer(t *testing.T) {
+	root := newTestCert(t, "Issuer Lookup CA", true, nil, nil)
+	other := newTestCert(t, "Unrelated CA", true, nil, nil)
+	responder := newTestOCSPResponder(t, root)
+
+	leaf := newOCSPLeaf(t, root, "", responder.server.URL+"/issuer.der")
+
+	issuer, err := FindIssuer(leaf.cert, []*x509.Certificate{other.cert, root.cert}, 0)
+	if err != nil || !issuer.Equal(root.cert) {
+		t.Errorf("FindIssuer from candidates = %v, %v", issuer, err)
+	}
+
+	// Falls back to the AIA CA Issuers URL
+	issuer, err = FindIssuer(leaf.cert, []*x509.Certificate{other.cert}, 0)
+	if err != nil || !issuer.Equal(root.cert) {
+		t.Errorf("FindIssuer from AIA = %v, %v", issuer, err)
+	}
+
+	noAIA := newOCSPLeaf(t, root, "", "")
+	if _, err := FindIssuer(noAIA.cert, nil, 0); err == nil {
+		t.Error("Expected error when the issuer cannot be found")
+	}
+}
+
+func TestVerifyWithOCSP(t *testing.T) {
+	root := newTestCert(t, "Verify OCSP CA", true, nil, nil)
+	responder := newTestOCSPResponder(t, root)
+	ocspURL := responder.server.URL + "/ocsp"
+
+	writeChain := func(t *testing.T, leaf *testCA) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "chain.pem")
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.cert.Raw})
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.cert.Raw})...)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		return path
+	}
+
+	good := newOCSPLeaf(t, root, ocspURL, "")
+	responder.statuses[good.cert.SerialNumber.String()] = ocsp.Good
+	result, err := VerifyWithOptions(VerifyOptions{CertPath: writeChain(t, good), OCSP: true})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if !result.IsValid || result.OCSP == nil || result.OCSP.Status != OCSPStatusGood {
+		t.Errorf("Good certificate: valid=%v ocsp=%+v errors=%v", result.IsValid, result.OCSP, result.Errors)
+	}
+
+	revoked := newOCSPLeaf(t, root, ocspURL, "")
+	responder.statuses[revoked.cert.SerialNumber.String()] = ocsp.Revoked
+	result, err = VerifyWithOptions(VerifyOptions{CertPath: writeChain(t, revoked), OCSP: true})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if result.IsValid {
+		t.Error("Revoked certificate should fail verification")
+	}
+	if !containsMessage(result.Errors, "revoked") {
+		t.Errorf("Expected a revocation error, got %v", result.Errors)
+	}
+	jr := result.ToJSON()
+	if jr.OCSP == nil || jr.OCSP.Status != OCSPStatusRevoked {
+		t.Errorf("JSON OCSP = %+v, want revoked", jr.OCSP)
+	}
+
+	unknown := newOCSPLeaf(t, root, ocspURL, "")
+	result, err = VerifyWithOptions(VerifyOptions{CertPath: writeChain(t, unknown), OCSP: true})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if !result.IsValid || !containsMessage(result.Warnings, "does not know") {
+		t.Errorf("Unknown status should be a warning: valid=%v warnings=%v", result.IsValid, result.Warnings)
+	}
+
+	// Without OCSP the check is skipped
+	result, err = VerifyWithOptions(VerifyOptions{CertPath: writeChain(t, revoked)})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions failed: %v", err)
+	}
+	if result.OCSP != nil || result.ToJSON().OCSP != nil {
+		t.Error("OCSP should only be checked when requested")
+	}
+}
+
+// newTestStaple creates an OCSP response for leaf signed by signer, which
+// is embedded in the response when embed is set
+func newTestStaple(t *testing.T, issuer, signer, leaf *testCA, embed bool) []byte {
+	t.Helper()
+	template := ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: leaf.cert.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   time.Now().Add(24 * time.Hour),
+	}
+	if embed {
+		template.Certificate = signer.cert
+	}
+	resp, err := ocsp.CreateResponse(issuer.cert, signer.cert, template, signer.key)
+	if err != nil {
+		t.Fatalf("ocsp.CreateResponse failed: %v", err)
+	}
+	return resp
+}
+
+func TestParseOCSPStaple(t *testing.T) {
+	root := newTestCert(t, "Staple Test CA", true, nil, nil)
+	other := newTestCert(t, "Other CA", true, nil, nil)
+	intermediate := newTestCert(t, "Staple Test Intermediate", true, root, nil)
+	leaf := newOCSPLeaf(t, root, "", "")
+	intermediateLeaf := newOCSPLeaf(t, intermediate, "", "")
+	otherLeaf := newOCSPLeaf(t, root, "", "")
+	responderKey, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	responder := issueTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(44),
+		Subject:      pkix.Name{CommonName: "Staple OCSP Responder"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}, responderKey, root)
+
+	tests := []struct {
+		name      string
+		leaf      *testCA
+		staple    []byte
+		chain     []*x509.Certificate
+		wantValid bool
+		wantError string
+	}{
+		{"signed by issuer", leaf, newTestStaple(t, root, root, leaf, false), []*x509.Certificate{root.cert}, true, ""},
+		{"intermediate issuer embedded", intermediateLeaf, newTestStaple(t, intermediate, intermediate, intermediateLeaf, true), []*x509.Certificate{intermediate.cert, root.cert}, true, ""},
+		{"delegated responder", leaf, newTestStaple(t, root, responder, leaf, true), []*x509.Certificate{root.cert}, true, ""},
+		{"signed by a plain leaf", leaf, newTestStaple(t, root, otherLeaf, leaf, true), []*x509.Certificate{root.cert}, false, "not authorized for OCSP signing"},
+		{"signed by another CA", leaf, newTestStaple(t, root, other, leaf, false), []*x509.Certificate{root.cert}, false, "bad OCSP signature"},
+		{"issuer not served", leaf, newTestStaple(t, root, root, leaf, false), nil, false, "issuer not in the served chain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staple := parseOCSPStaple(tt.staple, tt.leaf.cert, tt.chain)
+			if staple.SignatureValid != tt.wantValid || !strings.Contains(staple.Error, tt.wantError) {
+				t.Errorf("SignatureValid = %v, Error = %q; want %v, %q", staple.SignatureValid, staple.Error, tt.wantValid, tt.wantError)
+			}
+			if staple.Result == nil || staple.Result.Status != OCSPStatusGood || staple.Result.Responder != OCSPResponderStapled {
+				t.Fatalf("Result = %+v, want a decoded good response", staple.Result)
+			}
+		})
+	}
+
+	if staple := parseOCSPStaple([]byte("garbage"), leaf.cert, []*x509.Certificate{root.cert}); staple.Result != nil || !strings.Contains(staple.Error, "invalid OCSP response") {
+		t.Errorf("garbage staple: %+v", staple)
+	}
+}
+
+func TestInspectURLOCSPStaple(t *testing.T) {
+	root := newTestCert(t, "Staple Test CA", true, nil, nil)
+	leaf := newOCSPLeaf(t, root, "", "")
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{leaf.cert.Raw, root.cert.Raw},
+			PrivateKey:  leaf.key,
+			OCSPStaple:  newTestStaple(t, root, root, leaf, false),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("tls.Listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	c, _, err := InspectURLWithConnectOptions(host, portNum, ConnectOptions{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("InspectURLWithConnectOptions failed: %v", err)
+	}
+	if c.OCSPStaple == nil || !c.OCSPStaple.SignatureValid || c.OCSPStaple.Result.Status != OCSPStatusGood {
+		t.Fatalf("OCSPStaple = %+v, want a valid good staple", c.OCSPStaple)
+	}
+
+	jc := c.ToJSON()
+	if jc.OCSPStaple == nil || jc.OCSPStaple.JSONOCSPResult == nil || jc.OCSPStaple.Status != OCSPStatusGood || !jc.OCSPStaple.SignatureValid {
+		t.Errorf("JSON ocsp_staple = %+v", jc.OCSPStaple)
+	}
+}
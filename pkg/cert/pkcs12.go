@@ -0,0 +1,159 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// FormatPKCS12 identifies PKCS#12 (PFX) archives
+const FormatPKCS12 = "PKCS12"
+
+// PKCS12Bundle holds the contents of a decoded PKCS#12 (PFX) archive
+type PKCS12Bundle struct {
+	Source      string
+	PrivateKey  crypto.Signer     // nil for certificate-only trust stores
+	Certificate *x509.Certificate // leaf certificate matching the private key, if any
+	CACerts     []*x509.Certificate
+}
+
+// IsPKCS12Path reports whether a path has a PKCS#12 file extension (.p12 or .pfx)
+func IsPKCS12Path(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".p12", ".pfx":
+		return true
+	default:
+		return false
+	}
+}
+
+// isPKCS12Format reports whether a --format value names PKCS#12 output
+func isPKCS12Format(format string) bool {
+	switch strings.ToLower(format) {
+	case "p12", "pkcs12", "pfx":
+		return true
+	default:
+		return false
+	}
+}
+
+// DecodePKCS12 decodes a DER-encoded PKCS#12 archive. Archives with a
+// private key return the certificate matching that key as the leaf;
+// Java-style trust stores without a key return only CA certificates.
+func DecodePKCS12(data []byte, password string) (*PKCS12Bundle, error) {
+	key, leaf, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		if errors.Is(err, pkcs12.ErrIncorrectPassword) || errors.Is(err, pkcs12.ErrDecryption) {
+			return nil, fmt.Errorf("incorrect PKCS#12 password")
+		}
+		certs, tsErr := pkcs12.DecodeTrustStore(data, password)
+		if tsErr != nil {
+			return nil, fmt.Errorf("failed to decode PKCS#12: %w", err)
+		}
+		return &PKCS12Bundle{CACerts: certs}, nil
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T in PKCS#12", key)
+	}
+
+	// Some tools don't store the leaf first; pick the certificate matching the key
+	if !publicKeysEqual(leaf.PublicKey, signer.Public()) {
+		for i, c := range caCerts {
+			if publicKeysEqual(c.PublicKey, signer.Public()) {
+				caCerts[i] = leaf
+				leaf = c
+				break
+			}
+		}
+	}
+
+	return &PKCS12Bundle{
+		PrivateKey:  signer,
+		Certificate: leaf,
+		CACerts:     caCerts,
+	}, nil
+}
+
+// Certificates returns every certificate in the bundle, leaf first
+func (b *PKCS12Bundle) Certificates() []*x509.Certificate {
+	var certs []*x509.Certificate
+	if b.Certificate != nil {
+		certs = append(certs, b.Certificate)
+	}
+	return append(certs, b.CACerts...)
+}
+
+// KeyMatches reports whether the bundle's private key matches its leaf certificate
+func (b *PKCS12Bundle) KeyMatches() bool {
+	if b.PrivateKey == nil || b.Certificate == nil {
+		return false
+	}
+	return publicKeysEqual(b.Certificate.PublicKey, b.PrivateKey.Public())
+}
+
+// InspectPKCS12 reads a PKCS#12 file and returns its decoded contents along
+// with inspectable certificates (leaf first).
+func InspectPKCS12(path, password string) (*PKCS12Bundle, []*Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	bundle, err := DecodePKCS12(data, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	bundle.Source = path
+
+	var certs []*Certificate
+	for _, c := range bundle.Certificates() {
+		certs = append(certs, &Certificate{
+			Certificate:     c,
+			Source:          path,
+			Format:          FormatPKCS12,
+			IsExpired:       c.NotAfter.Before(time.Now()),
+			DaysUntilExpiry: int(time.Until(c.NotAfter).Hours() / 24),
+		})
+	}
+	if len(certs) == 0 {
+		return nil, nil, fmt.Errorf("no certificates found in PKCS#12 file")
+	}
+	return bundle, certs, nil
+}
+
+// encodePKCS12 builds a PKCS#12 archive from a key, leaf certificate, and chain.
+// The modern encoder uses AES-256-CBC with PBKDF2 and a SHA-256 MAC; legacy
+// mode uses 3DES and SHA-1 for older Windows and Java versions.
+func encodePKCS12(key crypto.Signer, leaf *x509.Certificate, chain []*x509.Certificate, password string, legacy bool) ([]byte, error) {
+	encoder := pkcs12.Modern
+	if legacy {
+		encoder = pkcs12.LegacyDES
+	}
+
+	if key == nil {
+		// Certificate-only archives are written as trust stores
+		data, err := encoder.EncodeTrustStore(append([]*x509.Certificate{leaf}, chain...), password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode PKCS#12: %w", err)
+		}
+		return data, nil
+	}
+
+	if !publicKeysEqual(leaf.PublicKey, key.Public()) {
+		return nil, fmt.Errorf("private key does not match certificate")
+	}
+	data, err := encoder.Encode(key, leaf, chain, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PKCS#12: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,205 @@
+package cert
+
+import (
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"certwiz/internal/testutil"
+)
+
+func TestInspectPKCS12Fixture(t *testing.T) {
+	// bundle.p12 is produced by openssl: server cert, intermediate, and key
+	bundle, certs, err := InspectPKCS12(testutil.TestdataPath("bundle.p12"), "changeit")
+	if err != nil {
+		t.Fatalf("InspectPKCS12 failed: %v", err)
+	}
+	if bundle.PrivateKey == nil {
+		t.Fatal("Expected a private key in bundle.p12")
+	}
+	if !bundle.KeyMatches() {
+		t.Error("Private key should match the leaf certificate")
+	}
+	if len(certs) != 2 {
+		t.Fatalf("Expected 2 certificates, got %d", len(certs))
+	}
+	if certs[0].Subject.CommonName != "chain.example.com" {
+		t.Errorf("Leaf CN = %s, want chain.example.com", certs[0].Subject.CommonName)
+	}
+	if certs[0].Format != FormatPKCS12 {
+		t.Errorf("Format = %s, want %s", certs[0].Format, FormatPKCS12)
+	}
+
+	jb := bundle.ToJSON()
+	if !jb.HasPrivateKey || jb.KeyMatches == nil || !*jb.KeyMatches {
+		t.Error("JSON output should report a matching private key")
+	}
+	if jb.Certificate == nil || jb.Certificate.Subject.CommonName != "chain.example.com" {
+		t.Error("JSON output should include the leaf certificate")
+	}
+	if len(jb.Chain) != 1 {
+		t.Errorf("JSON chain length = %d, want 1", len(jb.Chain))
+	}
+
+	if _, _, err := InspectPKCS12(testutil.TestdataPath("bundle.p12"), "wrong"); err == nil {
+		t.Error("Expected error for wrong password")
+	} else if !strings.Contains(err.Error(), "password") {
+		t.Errorf("Wrong password error should mention the password: %v", err)
+	}
+}
+
+func TestConvertPKCS12RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	p12Path := filepath.Join(tmpDir, "server.p12")
+
+	// PEM bundle + key -> PKCS#12
+	err := ConvertWithOptions(ConvertOptions{
+		InputPath:  testutil.TestdataPath("fullchain.pem"),
+		OutputPath: p12Path,
+		Format:     "p12",
+		KeyPath:    testutil.TestdataPath("chain-server.key"),
+		Password:   "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("Convert to PKCS#12 failed: %v", err)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(p12Path)
+		if err != nil {
+			t.Fatalf("Failed to stat output: %v", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("PKCS#12 with a key should be written 0600, got %v", info.Mode().Perm())
+		}
+	}
+
+	data, err := os.ReadFile(p12Path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	bundle, err := DecodePKCS12(data, "s3cret")
+	if err != nil {
+		t.Fatalf("DecodePKCS12 failed: %v", err)
+	}
+	if !bundle.KeyMatches() {
+		t.Error("Round-tripped key should match certificate")
+	}
+	if len(bundle.CACerts) != 2 {
+		t.Errorf("Expected 2 chain certificates, got %d", len(bundle.CACerts))
+	}
+
+	// PKCS#12 -> PEM writes the certificates and the key
+	pemPath := filepath.Join(tmpDir, "server.pem")
+	err = ConvertWithOptions(ConvertOptions{
+		InputPath:  p12Path,
+		OutputPath: pemPath,
+		Format:     "pem",
+		Password:   "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("Convert from PKCS#12 failed: %v", err)
+	}
+	pemData, err := os.ReadFile(pemPath)
+	if err != nil {
+		t.Fatalf("Failed to read PEM output: %v", err)
+	}
+	var certBlocks, keyBlocks int
+	for rest := pemData; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			certBlocks++
+		case "PRIVATE KEY":
+			keyBlocks++
+		}
+	}
+	if certBlocks != 3 || keyBlocks != 1 {
+		t.Errorf("PEM output has %d certificates and %d keys, want 3 and 1", certBlocks, keyBlocks)
+	}
+
+	// PKCS#12 -> DER writes the leaf first
+	derPath := filepath.Join(tmpDir, "server.der")
+	if err := ConvertWithOptions(ConvertOptions{InputPath: p12Path, OutputPath: derPath, Format: "der", Password: "s3cret"}); err != nil {
+		t.Fatalf("Convert PKCS#12 to DER failed: %v", err)
+	}
+	leaf, err := InspectFile(derPath)
+	if err != nil {
+		t.Fatalf("InspectFile on DER output failed: %v", err)
+	}
+	if leaf.Subject.CommonName != "chain.example.com" {
+		t.Errorf("DER output CN = %s, want chain.example.com", leaf.Subject.CommonName)
+	}
+}
+
+func TestConvertPKCS12Options(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Legacy encryption still decodes
+	legacyPath := filepath.Join(tmpDir, "legacy.pfx")
+	err := ConvertWithOptions(ConvertOptions{
+		InputPath:  testutil.TestdataPath("valid.pem"),
+		OutputPath: legacyPath,
+		Format:     "pfx",
+		KeyPath:    testutil.TestdataPath("valid.key"),
+		Password:   "legacy",
+		Legacy:     true,
+	})
+	if err != nil {
+		t.Fatalf("Legacy PKCS#12 conversion failed: %v", err)
+	}
+	if _, _, err := InspectPKCS12(legacyPath, "legacy"); err != nil {
+		t.Errorf("Failed to read legacy PKCS#12: %v", err)
+	}
+
+	// Mismatched key is rejected
+	err = ConvertWithOptions(ConvertOptions{
+		InputPath:  testutil.TestdataPath("valid.pem"),
+		OutputPath: filepath.Join(tmpDir, "mismatch.p12"),
+		Format:     "p12",
+		KeyPath:    testutil.TestdataPath("strong.key"),
+	})
+	if err == nil {
+		t.Error("Expected error for mismatched key")
+	}
+
+	// Without a key the output is a certificate-only trust store
+	trustPath := filepath.Join(tmpDir, "trust.p12")
+	err = ConvertWithOptions(ConvertOptions{
+		InputPath:  testutil.TestdataPath("ca.pem"),
+		OutputPath: trustPath,
+		Format:     "pkcs12",
+		Password:   "changeit",
+	})
+	if err != nil {
+		t.Fatalf("Trust store conversion failed: %v", err)
+	}
+	bundle, certs, err := InspectPKCS12(trustPath, "changeit")
+	if err != nil {
+		t.Fatalf("InspectPKCS12 on trust store failed: %v", err)
+	}
+	if bundle.PrivateKey != nil || len(certs) != 1 {
+		t.Errorf("Trust store should have no key and 1 certificate, got key=%v certs=%d", bundle.PrivateKey != nil, len(certs))
+	}
+}
+
+func TestIsPKCS12Path(t *testing.T) {
+	tests := map[string]bool{
+		"server.p12":  true,
+		"server.PFX":  true,
+		"server.pem":  false,
+		"server.p12x": false,
+	}
+	for path, want := range tests {
+		if got := IsPKCS12Path(path); got != want {
+			t.Errorf("IsPKCS12Path(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultProfile is the profile cert sign uses when none is given. It
+// matches what SignCSR issued before profiles existed.
+const DefaultProfile = "peer"
+
+// oidOCSPNoCheck is id-pkix-ocsp-nocheck (RFC 6960, section 4.2.2.2.1)
+var oidOCSPNoCheck = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+
+// Profile is a set of extensions applied to certificates issued by
+// SignCSR: key usage, extended key usage, and basic constraints
+type Profile struct {
+	Name        string
+	Description string
+
+	// KeyUsage is always set; with KeyEncipherment, RSA keys also get
+	// keyEncipherment (for RSA key exchange)
+	KeyUsage        x509.KeyUsage
+	KeyEncipherment bool
+
+	ExtKeyUsage        []x509.ExtKeyUsage
+	UnknownExtKeyUsage []asn1.ObjectIdentifier
+
+	// OCSPNoCheck adds id-pkix-ocsp-nocheck, so clients don't check the
+	// revocation status of an OCSP responder certificate
+	OCSPNoCheck bool
+
+	// SAN types the certificate must carry
+	RequireHostSAN  bool // a DNS name or IP address
+	RequireEmailSAN bool
+
+	Days    int // default validity in days; 0 leaves it to the caller
+	Builtin bool
+}
+
+// BuiltinProfiles returns the predefined issuance profiles
+func BuiltinProfiles() []*Profile {
+	return []*Profile{
+		{
+			Name:            "server",
+			Description:     "TLS server",
+			KeyUsage:        x509.KeyUsageDigitalSignature,
+			KeyEncipherment: true,
+			ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			RequireHostSAN:  true,
+			Builtin:         true,
+		},
+		{
+			Name:        "client",
+			Description: "TLS client (mTLS)",
+			KeyUsage:    x509.KeyUsageDigitalSignature,
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			Builtin:     true,
+		},
+		{
+			Name:            "peer",
+			Description:     "TLS server and client",
+			KeyUsage:        x509.KeyUsageDigitalSignature,
+			KeyEncipherment: true,
+			ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			Builtin:         true,
+		},
+		{
+			Name:        "code-signing",
+			Description: "Code signing",
+			KeyUsage:    x509.KeyUsageDigitalSignature,
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			Builtin:     true,
+		},
+		{
+			Name:            "email",
+			Description:     "S/MIME signing and encryption",
+			KeyUsage:        x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+			KeyEncipherment: true,
+			ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+			RequireEmailSAN: true,
+			Builtin:         true,
+		},
+		{
+			Name:        "ocsp-signing",
+			Description: "Delegated OCSP responder",
+			KeyUsage:    x509.KeyUsageDigitalSignature,
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+			OCSPNoCheck: true,
+			Builtin:     true,
+		},
+	}
+}
+
+// keyUsageNames maps key usage names, as OpenSSL spells them, to flags
+var keyUsageNames = map[string]x509.KeyUsage{
+	"digitalsignature":  x509.KeyUsageDigitalSignature,
+	"nonrepudiation":    x509.KeyUsageContentCommitment,
+	"contentcommitment": x509.KeyUsageContentCommitment,
+	"keyencipherment":   x509.KeyUsageKeyEncipherment,
+	"dataencipherment":  x509.KeyUsageDataEncipherment,
+	"keyagreement":      x509.KeyUsageKeyAgreement,
+	"keycertsign":       x509.KeyUsageCertSign,
+	"crlsign":           x509.KeyUsageCRLSign,
+	"encipheronly":      x509.KeyUsageEncipherOnly,
+	"decipheronly":      x509.KeyUsageDecipherOnly,
+}
+
+// extKeyUsageNames maps extended key usage names, as OpenSSL spells them
+var extKeyUsageNames = map[string]x509.ExtKeyUsage{
+	"any":                  x509.ExtKeyUsageAny,
+	"serverauth":           x509.ExtKeyUsageServerAuth,
+	"clientauth":           x509.ExtKeyUsageClientAuth,
+	"codesigning":          x509.ExtKeyUsageCodeSigning,
+	"emailprotection":      x509.ExtKeyUsageEmailProtection,
+	"ipsecendsystem":       x509.ExtKeyUsageIPSECEndSystem,
+	"ipsectunnel":          x509.ExtKeyUsageIPSECTunnel,
+	"ipsecuser":            x509.ExtKeyUsageIPSECUser,
+	"timestamping":         x509.ExtKeyUsageTimeStamping,
+	"ocspsigning":          x509.ExtKeyUsageOCSPSigning,
+	"msservergatedcrypto":  x509.ExtKeyUsageMicrosoftServerGatedCrypto,
+	"nsservergatedcrypto":  x509.ExtKeyUsageNetscapeServerGatedCrypto,
+	"mscommercialcodesign": x509.ExtKeyUsageMicrosoftCommercialCodeSigning,
+	"mskernelcodesigning":  x509.ExtKeyUsageMicrosoftKernelCodeSigning,
+}
+
+// normalizeUsageName lowercases a usage name and drops separators, so
+// "digitalSignature", "digital-signature" and "Digital Signature" match
+func normalizeUsageName(name string) string {
+	return strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// NewProfile builds a user-defined profile from key usage and extended key
+// usage names (e.g. digitalSignature, serverAuth) or dotted OIDs for
+// extended key usages. Without key usages, the profile gets the same key
+// usage as the default profile.
+func NewProfile(name, description string, keyUsage, extKeyUsage []string, days int) (*Profile, error) {
+	p := &Profile{Name: name, Description: description, Days: days}
+	if days < 0 {
+		return nil, fmt.Errorf("profile %s: days must not be negative", name)
+	}
+
+	for _, u := range keyUsage {
+		flag, ok := keyUsageNames[normalizeUsageName(u)]
+		if !ok {
+			return nil, fmt.Errorf("profile %s: unknown key usage %q", name, u)
+		}
+		if flag == x509.KeyUsageCertSign {
+			return nil, fmt.Errorf("profile %s: keyCertSign is only for CA certificates (use 'cert ca')", name)
+		}
+		p.KeyUsage |= flag
+	}
+	if p.KeyUsage == 0 {
+		p.KeyUsage = x509.KeyUsageDigitalSignature
+		p.KeyEncipherment = true
+	}
+
+	for _, u := range extKeyUsage {
+		if eku, ok := extKeyUsageNames[normalizeUsageName(u)]; ok {
+			p.ExtKeyUsage = append(p.ExtKeyUsage, eku)
+			continue
+		}
+		oid, err := parseOID(u)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: unknown extended key usage %q", name, u)
+		}
+		p.UnknownExtKeyUsage = append(p.UnknownExtKeyUsage, oid)
+	}
+	if p.Description == "" {
+		p.Description = "Custom profile"
+	}
+	return p, nil
+}
+
+// parseOID parses a dotted OID such as 1.3.6.1.5.5.7.3.1
+func parseOID(s string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q", s)
+	}
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid OID %q", s)
+		}
+		oid[i] = n
+	}
+	return oid, nil
+}
+
+// FindProfile looks up a profile by name among the built-in profiles and
+// the given custom ones
+func FindProfile(name string, custom []*Profile) (*Profile, error) {
+	all := append(BuiltinProfiles(), custom...)
+	for _, p := range all {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	names := make([]string, 0, len(all))
+	for _, p := range all {
+		names = append(names, p.Name)
+	}
+	return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// SortProfiles orders custom profiles by name and rejects any that reuse
+// a built-in profile's name
+func SortProfiles(custom []*Profile) error {
+	for _, p := range custom {
+		for _, b := range BuiltinProfiles() {
+			if p.Name == b.Name {
+				return fmt.Errorf("profile %s in the config file conflicts with the built-in profile of the same name", p.Name)
+			}
+		}
+	}
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Name < custom[j].Name })
+	return nil
+}
+
+// keyUsageFor returns the profile's key usage for a public key
+func (p *Profile) keyUsageFor(pub crypto.PublicKey) x509.KeyUsage {
+	usage := p.KeyUsage
+	if _, ok := pub.(*rsa.PublicKey); ok && p.KeyEncipherment {
+		usage |= x509.KeyUsageKeyEncipherment
+	}
+	return usage
+}
+
+// apply sets the profile's extensions on an end-entity certificate template
+func (p *Profile) apply(template *x509.Certificate, pub crypto.PublicKey) {
+	template.KeyUsage = p.keyUsageFor(pub)
+	template.ExtKeyUsage = p.ExtKeyUsage
+	template.UnknownExtKeyUsage = p.UnknownExtKeyUsage
+	template.BasicConstraintsValid = true
+	template.IsCA = false
+	if p.OCSPNoCheck {
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{
+			Id:    oidOCSPNoCheck,
+			Value: asn1.NullBytes,
+		})
+	}
+}
+
+// checkSANs checks that a template has the SAN types the profile requires
+func (p *Profile) checkSANs(template *x509.Certificate) error {
+	if p.RequireHostSAN && len(template.DNSNames) == 0 && len(template.IPAddresses) == 0 {
+		return fmt.Errorf("the %s profile requires a DNS or IP SAN (add one to the CSR or use --san)", p.Name)
+	}
+	if p.RequireEmailSAN && len(template.EmailAddresses) == 0 {
+		return fmt.Errorf("the %s profile requires an email SAN (add one to the CSR or use --san email:user@example.com)", p.Name)
+	}
+	return nil
+}
+
+// KeyUsageNames returns the names of the profile's key usages; RSA-only
+// keyEncipherment is marked as such
+func (p *Profile) KeyUsageNames() []string {
+	names := getKeyUsageStrings(p.KeyUsage)
+	if p.KeyEncipherment && p.KeyUsage&x509.KeyUsageKeyEncipherment == 0 {
+		names = append(names, "Key Encipherment (RSA)")
+	}
+	return names
+}
+
+// ExtKeyUsageNames returns the names of the profile's extended key usages
+func (p *Profile) ExtKeyUsageNames() []string {
+	names := getExtKeyUsageStrings(p.ExtKeyUsage)
+	for _, oid := range p.UnknownExtKeyUsage {
+		names = append(names, oid.String())
+	}
+	return names
+}
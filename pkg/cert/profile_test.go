@@ -0,0 +1,172 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignWithProfiles(t *testing.T) {
+	dir := t.TempDir()
+	caCert := filepath.Join(dir, "ca.crt")
+	caKey := filepath.Join(dir, "ca.key")
+	if err := GenerateCA(CAOptions{CommonName: "Profile Test CA", Days: 365, KeyAlgorithm: KeyAlgorithmECDSAP256}, caCert, caKey); err != nil {
+		t.Fatalf("GenerateCA failed: %v", err)
+	}
+
+	tests := []struct {
+		profile     string
+		keyAlg      string
+		sans        []string
+		wantUsage   x509.KeyUsage
+		wantEKU     []x509.ExtKeyUsage
+		wantNoCheck bool
+	}{
+		{"server", KeyAlgorithmRSA, []string{"www.example.com"}, x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, false},
+		{"server", KeyAlgorithmECDSAP256, []string{"IP:10.0.0.1"}, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, false},
+		{"client", KeyAlgorithmRSA, nil, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, false},
+		{"peer", KeyAlgorithmECDSAP256, []string{"node1.example.com"}, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, false},
+		{"code-signing", KeyAlgorithmEd25519, nil, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}, false},
+		{"email", KeyAlgorithmRSA, []string{"email:alice@example.com"}, x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment | x509.KeyUsageKeyEncipherment, []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection}, false},
+		{"ocsp-signing", KeyAlgorithmECDSAP256, nil, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile+"/"+tt.keyAlg, func(t *testing.T) {
+			profile, err := FindProfile(tt.profile, nil)
+			if err != nil {
+				t.Fatalf("FindProfile failed: %v", err)
+			}
+			csrPath := filepath.Join(dir, tt.profile+"-"+tt.keyAlg+".csr")
+			if err := GenerateCSR(CSROptions{CommonName: "Profile Test", SANs: tt.sans, KeyAlgorithm: tt.keyAlg, KeySize: 2048}, csrPath, filepath.Join(dir, tt.profile+"-"+tt.keyAlg+".key")); err != nil {
+				t.Fatalf("GenerateCSR failed: %v", err)
+			}
+			certPath := filepath.Join(dir, tt.profile+"-"+tt.keyAlg+".crt")
+			if err := SignCSR(SignOptions{CSRPath: csrPath, CACert: caCert, CAKey: caKey, Days: 30, Profile: profile}, certPath); err != nil {
+				t.Fatalf("SignCSR failed: %v", err)
+			}
+
+			c, err := InspectFile(certPath)
+			if err != nil {
+				t.Fatalf("InspectFile failed: %v", err)
+			}
+			if c.KeyUsage != tt.wantUsage {
+				t.Errorf("KeyUsage = %v, want %v", getKeyUsageStrings(c.KeyUsage), getKeyUsageStrings(tt.wantUsage))
+			}
+			if len(c.ExtKeyUsage) != len(tt.wantEKU) {
+				t.Fatalf("ExtKeyUsage = %v, want %v", c.ExtKeyUsage, tt.wantEKU)
+			}
+			for i := range tt.wantEKU {
+				if c.ExtKeyUsage[i] != tt.wantEKU[i] {
+					t.Errorf("ExtKeyUsage = %v, want %v", c.ExtKeyUsage, tt.wantEKU)
+				}
+			}
+			if !c.BasicConstraintsValid || c.IsCA {
+				t.Error("Issued certificate must have basic constraints with CA:FALSE")
+			}
+			noCheck := false
+			for _, ext := range c.Extensions {
+				if ext.Id.Equal(oidOCSPNoCheck) {
+					noCheck = true
+				}
+			}
+			if noCheck != tt.wantNoCheck {
+				t.Errorf("ocsp-nocheck present = %v, want %v", noCheck, tt.wantNoCheck)
+			}
+		})
+	}
+}
+
+func TestSignProfileSANRequirements(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey, _ := newTestCAFiles(t, dir, "SAN Test CA")
+	csrPath := filepath.Join(dir, "nosan.csr")
+	if err := GenerateCSR(CSROptions{CommonName: "No SANs", KeyAlgorithm: KeyAlgorithmECDSAP256}, csrPath, filepath.Join(dir, "nosan.key")); err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+
+	for _, name := range []string{"server", "email"} {
+		profile, err := FindProfile(name, nil)
+		if err != nil {
+			t.Fatalf("FindProfile failed: %v", err)
+		}
+		if err := SignCSR(SignOptions{CSRPath: csrPath, CACert: caCert, CAKey: caKey, Days: 30, Profile: profile}, filepath.Join(dir, name+".crt")); err == nil {
+			t.Errorf("Expected error for the %s profile without the required SAN", name)
+		}
+	}
+
+	// --san satisfies the requirement
+	profile, _ := FindProfile("email", nil)
+	if err := SignCSR(SignOptions{CSRPath: csrPath, CACert: caCert, CAKey: caKey, Days: 30, Profile: profile, SANs: []string{"email:bob@example.com"}}, filepath.Join(dir, "email.crt")); err != nil {
+		t.Errorf("SignCSR with an email SAN failed: %v", err)
+	}
+}
+
+func TestNewProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		keyUsage    []string
+		extKeyUsage []string
+		days        int
+		wantUsage   x509.KeyUsage
+		wantEKU     int
+		wantUnknown int
+		wantErr     bool
+	}{
+		{"defaults", nil, []string{"serverAuth"}, 0, x509.KeyUsageDigitalSignature, 1, 0, false},
+		{"spellings", []string{"digitalSignature", "key-agreement", "Non Repudiation"}, []string{"client_auth", "TimeStamping"}, 90, x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement | x509.KeyUsageContentCommitment, 2, 0, false},
+		{"OID", nil, []string{"1.3.6.1.5.5.7.3.17"}, 0, x509.KeyUsageDigitalSignature, 0, 1, false},
+		{"unknown key usage", []string{"signEverything"}, nil, 0, 0, 0, 0, true},
+		{"CA key usage", []string{"keyCertSign"}, nil, 0, 0, 0, 0, true},
+		{"unknown EKU", nil, []string{"serverAuthz"}, 0, 0, 0, 0, true},
+		{"negative days", nil, nil, -1, 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewProfile(tt.name, "", tt.keyUsage, tt.extKeyUsage, tt.days)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewProfile error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if p.KeyUsage != tt.wantUsage || len(p.ExtKeyUsage) != tt.wantEKU || len(p.UnknownExtKeyUsage) != tt.wantUnknown || p.Days != tt.days {
+				t.Errorf("Profile = %+v", p)
+			}
+			if p.Builtin || p.Description == "" {
+				t.Errorf("Custom profile = %+v", p)
+			}
+		})
+	}
+
+	oidProfile, _ := NewProfile("ipsec", "", nil, []string{"1.3.6.1.5.5.7.3.17"}, 0)
+	if !oidProfile.UnknownExtKeyUsage[0].Equal(asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 17}) {
+		t.Errorf("OID = %v", oidProfile.UnknownExtKeyUsage[0])
+	}
+}
+
+func TestFindProfile(t *testing.T) {
+	custom, err := NewProfile("vpn", "VPN", nil, []string{"clientAuth"}, 0)
+	if err != nil {
+		t.Fatalf("NewProfile failed: %v", err)
+	}
+	if p, err := FindProfile("vpn", []*Profile{custom}); err != nil || p != custom {
+		t.Errorf("FindProfile(vpn) = %v, %v", p, err)
+	}
+	if p, err := FindProfile(DefaultProfile, nil); err != nil || !p.Builtin {
+		t.Errorf("FindProfile(default) = %v, %v", p, err)
+	}
+	if _, err := FindProfile("vpn", nil); err == nil {
+		t.Error("Expected error for an unknown profile")
+	}
+
+	shadow, _ := NewProfile("server", "", nil, nil, 0)
+	if err := SortProfiles([]*Profile{custom, shadow}); err == nil {
+		t.Error("Expected error for a custom profile named like a built-in one")
+	}
+	other, _ := NewProfile("alpha", "", nil, nil, 0)
+	profiles := []*Profile{custom, other}
+	if err := SortProfiles(profiles); err != nil || profiles[0].Name != "alpha" {
+		t.Errorf("SortProfiles = %v, %v", profiles, err)
+	}
+}
@@ -32,3 +32,17 @@ func splitSANs(sans []string) (dns []string, ips []net.IP, emails []string, uris
     return
 }
 
+// joinSANs is the inverse of splitSANs: it renders SANs with the same prefixes.
+func joinSANs(dns []string, ips []net.IP, emails []string, uris []*url.URL) []string {
+    sans := append([]string{}, dns...)
+    for _, ip := range ips {
+        sans = append(sans, "IP:"+ip.String())
+    }
+    for _, email := range emails {
+        sans = append(sans, "email:"+email)
+    }
+    for _, uri := range uris {
+        sans = append(sans, "uri:"+uri.String())
+    }
+    return sans
+}
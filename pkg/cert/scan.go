@@ -0,0 +1,281 @@
+package cert
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scan target kinds
+const (
+	ScanKindFile = "file"
+	ScanKindHost = "host"
+)
+
+// DefaultScanWorkers is the default size of the scan worker pool
+const DefaultScanWorkers = 10
+
+// scanExtensions are the file extensions picked up when walking a directory.
+// Files named explicitly are scanned regardless of extension.
+var scanExtensions = map[string]bool{
+	".pem":  true,
+	".crt":  true,
+	".cer":  true,
+	".cert": true,
+	".der":  true,
+}
+
+// ScanOptions configures a batch expiry scan
+type ScanOptions struct {
+	Paths     []string      // files or directories (walked recursively)
+	Hosts     []string      // hostnames, host:port pairs, or URLs
+	Port      int           // default port for hosts without one
+	Timeout   time.Duration // per-host connection timeout
+	Workers   int           // maximum concurrent inspections
+	Threshold time.Duration // flag certificates expiring within this window
+}
+
+// ScanResult is one scanned certificate, or a target that could not be scanned
+type ScanResult struct {
+	Target          string // file path or host as given
+	Kind            string // ScanKindFile or ScanKindHost
+	Index           int    // position of the certificate within a file bundle
+	Certificate     *Certificate
+	Error           error
+	WithinThreshold bool // expired, or expires within the scan threshold
+}
+
+// ScanReport holds the results of a scan, sorted by days until expiry
+type ScanReport struct {
+	Results   []ScanResult
+	Threshold time.Duration
+}
+
+// Expiring returns the number of certificates expired or within the threshold
+func (r *ScanReport) Expiring() int {
+	n := 0
+	for _, res := range r.Results {
+		if res.WithinThreshold {
+			n++
+		}
+	}
+	return n
+}
+
+// Errors returns the number of targets that could not be scanned
+func (r *ScanReport) Errors() int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Error != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed reports whether any certificate is within the threshold or any
+// target failed, which callers surface as a non-zero exit.
+func (r *ScanReport) Failed() bool {
+	return r.Expiring() > 0 || r.Errors() > 0
+}
+
+// scanTarget is a single unit of work for the scan worker pool
+type scanTarget struct {
+	kind     string
+	name     string
+	explicit bool // named on the command line rather than found by walking
+}
+
+// Scan inspects every certificate file under opts.Paths and every host in
+// opts.Hosts using a bounded worker pool. Results are sorted by days until
+// expiry (soonest first) with failed targets last.
+func Scan(opts ScanOptions) (*ScanReport, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultScanWorkers
+	}
+	if opts.Port == 0 {
+		opts.Port = 443
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultDialTimeout
+	}
+
+	var targets []scanTarget
+	for _, p := range opts.Paths {
+		found, err := collectScanFiles(p)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, found...)
+	}
+	for _, h := range opts.Hosts {
+		targets = append(targets, scanTarget{kind: ScanKindHost, name: h, explicit: true})
+	}
+
+	// Each worker writes only to its target's slot, so no locking is needed
+	results := make([][]ScanResult, len(targets))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = scanOne(targets[i], opts)
+			}
+		}()
+	}
+	for i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	report := &ScanReport{Threshold: opts.Threshold}
+	for _, rs := range results {
+		report.Results = append(report.Results, rs...)
+	}
+	sortScanResults(report.Results)
+	return report, nil
+}
+
+// collectScanFiles expands a path into scan targets. Directories are walked
+// recursively for files with a certificate extension.
+func collectScanFiles(path string) ([]scanTarget, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot scan %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []scanTarget{{kind: ScanKindFile, name: path, explicit: true}}, nil
+	}
+
+	var targets []scanTarget
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Skip hidden directories such as .git
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if scanExtensions[strings.ToLower(filepath.Ext(p))] {
+			targets = append(targets, scanTarget{kind: ScanKindFile, name: p})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", path, err)
+	}
+	return targets, nil
+}
+
+// scanOne inspects a single target and returns one result per certificate
+func scanOne(t scanTarget, opts ScanOptions) []ScanResult {
+	if t.kind == ScanKindHost {
+		host, port := SplitHostTarget(t.name, opts.Port)
+		c, _, err := InspectURLWithOptions(host, port, "", opts.Timeout, "")
+		if err != nil {
+			return []ScanResult{{Target: t.name, Kind: t.kind, Error: err}}
+		}
+		return []ScanResult{newScanResult(t, 0, c, opts.Threshold)}
+	}
+
+	data, err := os.ReadFile(t.name)
+	if err != nil {
+		return []ScanResult{{Target: t.name, Kind: t.kind, Error: err}}
+	}
+	// Walked .pem files often hold keys or CSRs; only report those by name
+	if !t.explicit && isPEMWithoutCertificate(data) {
+		return nil
+	}
+	certs, err := InspectData(data, t.name)
+	if err != nil {
+		return []ScanResult{{Target: t.name, Kind: t.kind, Error: err}}
+	}
+
+	results := make([]ScanResult, 0, len(certs))
+	for i, c := range certs {
+		results = append(results, newScanResult(t, i, c, opts.Threshold))
+	}
+	return results
+}
+
+func newScanResult(t scanTarget, index int, c *Certificate, threshold time.Duration) ScanResult {
+	return ScanResult{
+		Target:          t.name,
+		Kind:            t.kind,
+		Index:           index,
+		Certificate:     c,
+		WithinThreshold: c.IsExpired || time.Until(c.NotAfter) <= threshold,
+	}
+}
+
+// isPEMWithoutCertificate reports whether data is PEM that holds no certificate
+func isPEMWithoutCertificate(data []byte) bool {
+	return bytes.Contains(data, []byte("-----BEGIN ")) && !bytes.Contains(data, []byte("-----BEGIN CERTIFICATE-----"))
+}
+
+// sortScanResults orders results by days until expiry, then by target.
+// Failed targets sort last.
+func sortScanResults(results []ScanResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Certificate == nil) != (b.Certificate == nil) {
+			return a.Certificate != nil
+		}
+		if a.Certificate != nil && !a.Certificate.NotAfter.Equal(b.Certificate.NotAfter) {
+			return a.Certificate.NotAfter.Before(b.Certificate.NotAfter)
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Index < b.Index
+	})
+}
+
+// SplitHostTarget extracts an explicit port from a host target such as
+// "example.com:8443" or "[::1]:443". URLs are returned unchanged, since
+// InspectURLWithOptions reads their port itself.
+func SplitHostTarget(target string, defaultPort int) (string, int) {
+	if !strings.Contains(target, "://") {
+		if h, p, err := net.SplitHostPort(target); err == nil {
+			if pn, err := strconv.Atoi(p); err == nil {
+				return h, pn
+			}
+		}
+	}
+	return target, defaultPort
+}
+
+// ReadHostList reads one host per line, ignoring blank lines and # comments
+func ReadHostList(r io.Reader) ([]string, error) {
+	var hosts []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			hosts = append(hosts, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read host list: %w", err)
+	}
+	return hosts, nil
+}
@@ -0,0 +1,198 @@
+package cert
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"certwiz/internal/testutil"
+)
+
+// writeScanFixtures generates certificates with different lifetimes under dir
+func writeScanFixtures(t *testing.T, dir string) {
+	t.Helper()
+	fixtures := []struct {
+		cn   string
+		days int
+		sub  string
+	}{
+		{"soon.local", 10, ""},
+		{"later.local", 200, "nested"},
+		{"middle.local", 60, ""},
+	}
+	for _, f := range fixtures {
+		out := filepath.Join(dir, f.sub)
+		if err := os.MkdirAll(out, 0755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := Generate(GenerateOptions{CommonName: f.cn, Days: f.days, KeyAlgorithm: KeyAlgorithmECDSAP256, OutputDir: out}); err != nil {
+			t.Fatalf("Generate %s failed: %v", f.cn, err)
+		}
+	}
+}
+
+func TestScanDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeScanFixtures(t, dir)
+
+	// Hidden directories are skipped
+	hidden := filepath.Join(dir, ".git")
+	if err := os.MkdirAll(hidden, 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(hidden, "x.pem"), []byte("garbage"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	report, err := Scan(ScanOptions{Paths: []string{dir}, Threshold: 30 * 24 * time.Hour, Workers: 2})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	// Keys (.key) are not picked up by extension; only the three certificates
+	if len(report.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(report.Results))
+	}
+	want := []string{"soon.local", "middle.local", "later.local"}
+	for i, cn := range want {
+		res := report.Results[i]
+		if res.Error != nil {
+			t.Fatalf("Result %d has error: %v", i, res.Error)
+		}
+		if res.Certificate.Subject.CommonName != cn {
+			t.Errorf("Result %d CN = %s, want %s (sorted by expiry)", i, res.Certificate.Subject.CommonName, cn)
+		}
+	}
+	if !report.Results[0].WithinThreshold || report.Results[1].WithinThreshold {
+		t.Error("Only the 10-day certificate should be within a 30-day threshold")
+	}
+	if report.Expiring() != 1 || report.Errors() != 0 || !report.Failed() {
+		t.Errorf("Expiring=%d Errors=%d Failed=%v, want 1, 0, true", report.Expiring(), report.Errors(), report.Failed())
+	}
+
+	// With a shorter threshold nothing fails
+	report, err = Scan(ScanOptions{Paths: []string{dir}, Threshold: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if report.Failed() {
+		t.Error("No certificate expires within a day")
+	}
+}
+
+func TestScanBundlesAndErrors(t *testing.T) {
+	report, err := Scan(ScanOptions{
+		Paths: []string{
+			testutil.TestdataPath("fullchain.pem"),
+			testutil.TestdataPath("expired.pem"),
+			testutil.TestdataPath("invalid.pem"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	// Every certificate in the bundle is reported, plus the expired
+	// certificate and the invalid file
+	if len(report.Results) != 5 {
+		t.Fatalf("Expected 5 results, got %d", len(report.Results))
+	}
+	if c := report.Results[0].Certificate; c == nil || !c.IsExpired {
+		t.Error("Expired certificate should sort first")
+	}
+	if !report.Results[0].WithinThreshold {
+		t.Error("Expired certificate should be within any threshold")
+	}
+	last := report.Results[len(report.Results)-1]
+	if last.Error == nil || !strings.HasSuffix(last.Target, "invalid.pem") {
+		t.Errorf("Failed targets should sort last, got %+v", last)
+	}
+
+	indexes := map[int]bool{}
+	for _, res := range report.Results {
+		if strings.HasSuffix(res.Target, "fullchain.pem") {
+			indexes[res.Index] = true
+		}
+	}
+	if len(indexes) != 3 {
+		t.Errorf("Expected 3 distinct bundle indexes, got %v", indexes)
+	}
+
+	jr := report.ToJSON()
+	if jr.Total != 5 || jr.Errors != 1 || jr.Expiring < 1 {
+		t.Errorf("JSON summary = %+v", jr)
+	}
+	if jr.Results[len(jr.Results)-1].Error == "" {
+		t.Error("JSON should include the error for the invalid file")
+	}
+
+	if _, err := Scan(ScanOptions{Paths: []string{"/nonexistent/certs"}}); err == nil {
+		t.Error("Expected error for missing path")
+	}
+}
+
+func TestScanHosts(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	report, err := Scan(ScanOptions{
+		Hosts:   []string{host, server.URL, "127.0.0.1:1"},
+		Timeout: 2 * time.Second,
+		Workers: 3,
+	})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(report.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(report.Results))
+	}
+	for _, res := range report.Results[:2] {
+		if res.Error != nil || res.Kind != ScanKindHost {
+			t.Errorf("Expected a successful host result, got %+v", res)
+		}
+	}
+	if report.Results[2].Error == nil {
+		t.Error("Expected connection error for closed port")
+	}
+}
+
+func TestSplitHostTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		wantHost string
+		wantPort int
+	}{
+		{"example.com", "example.com", 443},
+		{"example.com:8443", "example.com", 8443},
+		{"[::1]:9443", "::1", 9443},
+		{"https://example.com:8443/path", "https://example.com:8443/path", 443},
+	}
+	for _, tt := range tests {
+		host, port := SplitHostTarget(tt.target, 443)
+		if host != tt.wantHost || port != tt.wantPort {
+			t.Errorf("SplitHostTarget(%q) = %s, %d, want %s, %d", tt.target, host, port, tt.wantHost, tt.wantPort)
+		}
+	}
+}
+
+func TestReadHostList(t *testing.T) {
+	input := "example.com\n\n# comment\n  github.com:443  # trailing\nhttps://example.org\n"
+	hosts, err := ReadHostList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadHostList failed: %v", err)
+	}
+	want := []string{"example.com", "github.com:443", "https://example.org"}
+	if len(hosts) != len(want) {
+		t.Fatalf("got %v, want %v", hosts, want)
+	}
+	for i := range want {
+		if hosts[i] != want[i] {
+			t.Errorf("hosts[%d] = %q, want %q", i, hosts[i], want[i])
+		}
+	}
+}
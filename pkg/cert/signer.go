@@ -0,0 +1,359 @@
+package cert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// externalSignerTimeout bounds each request to an exec: or unix: signer
+const externalSignerTimeout = 30 * time.Second
+
+// Signer is a CA signing key held by any backend: a key file, a PKCS#11
+// token, or an external signing process. It is a crypto.Signer, so x509
+// can sign with it directly; Close releases the backend.
+type Signer struct {
+	crypto.Signer
+	URI   string // where the key came from, for messages
+	close func() error
+}
+
+// NewSigner wraps a crypto.Signer that needs no cleanup
+func NewSigner(s crypto.Signer, uri string) *Signer {
+	return &Signer{Signer: s, URI: uri}
+}
+
+// Close releases the backend, such as a PKCS#11 session
+func (s *Signer) Close() error {
+	if s == nil || s.close == nil {
+		return nil
+	}
+	return s.close()
+}
+
+// OpenSigner opens a signing key from a key URI:
+//
+//	file:/path/to/ca.key          a private key file in any LoadPrivateKey format
+//	pkcs11:token=CA;object=root   a key on a PKCS#11 token (RFC 7512)
+//	exec:/usr/local/bin/signer -x an external command, run once per request
+//	unix:/run/signer.sock         an external signer listening on a socket
+//
+// password decrypts an encrypted key file, or is the PKCS#11 user PIN when
+// the URI has no pin-value or pin-source.
+func OpenSigner(uri, password string) (*Signer, error) {
+	scheme, rest, ok := strings.Cut(uri, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid key URI %q: expected file:, pkcs11:, exec:, or unix:", uri)
+	}
+	switch strings.ToLower(scheme) {
+	case "file":
+		key, err := LoadPrivateKey(uriPath(rest), password)
+		if err != nil {
+			return nil, err
+		}
+		return NewSigner(key, uri), nil
+	case "pkcs11":
+		u, err := parsePKCS11URI(uri)
+		if err != nil {
+			return nil, err
+		}
+		pin, err := u.pin()
+		if err != nil {
+			return nil, err
+		}
+		if pin == "" {
+			pin = password
+		}
+		signer, err := openPKCS11Signer(u, pin)
+		if err != nil {
+			return nil, err
+		}
+		signer.URI = pinValuePattern.ReplaceAllString(uri, "${1}pin-value=...")
+		return signer, nil
+	case "exec":
+		args := strings.Fields(rest)
+		if len(args) == 0 {
+			return nil, fmt.Errorf("invalid key URI %q: no command given", uri)
+		}
+		return openExternalSigner(uri, func(req []byte) ([]byte, error) {
+			return runExecSigner(args, req)
+		})
+	case "unix":
+		path := uriPath(rest)
+		if path == "" {
+			return nil, fmt.Errorf("invalid key URI %q: no socket path given", uri)
+		}
+		return openExternalSigner(uri, func(req []byte) ([]byte, error) {
+			return callSocketSigner(path, req)
+		})
+	default:
+		return nil, fmt.Errorf("unsupported key URI scheme %q (use file:, pkcs11:, exec:, or unix:)", scheme)
+	}
+}
+
+// pinValuePattern finds a PIN in a PKCS#11 URI, to keep it out of messages
+var pinValuePattern = regexp.MustCompile(`([;?&])pin-value=[^;&]*`)
+
+// uriPath returns the path of a file: or unix: URI, which may be written
+// with or without the empty authority (file:///ca.key or file:/ca.key)
+func uriPath(rest string) string {
+	if strings.HasPrefix(rest, "///") {
+		return rest[2:]
+	}
+	return rest
+}
+
+// pkcs11URI holds the attributes of an RFC 7512 PKCS#11 URI that select a
+// private key
+type pkcs11URI struct {
+	ModulePath string
+	Token      string
+	Serial     string
+	SlotID     *uint
+	Object     string
+	ID         []byte
+	PinValue   string
+	PinSource  string
+}
+
+// parsePKCS11URI parses a PKCS#11 URI such as
+// pkcs11:token=CA;object=root?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-value=1234
+func parsePKCS11URI(uri string) (*pkcs11URI, error) {
+	rest, ok := strings.CutPrefix(uri, "pkcs11:")
+	if !ok {
+		return nil, fmt.Errorf("invalid PKCS#11 URI %q", uri)
+	}
+	path, query, _ := strings.Cut(rest, "?")
+
+	u := &pkcs11URI{}
+	attrs := func(s, sep string) error {
+		for _, attr := range strings.Split(s, sep) {
+			if attr == "" {
+				continue
+			}
+			name, raw, _ := strings.Cut(attr, "=")
+			value, err := url.PathUnescape(raw)
+			if err != nil {
+				return fmt.Errorf("invalid PKCS#11 URI attribute %q: %w", attr, err)
+			}
+			switch name {
+			case "module-path":
+				u.ModulePath = value
+			case "token":
+				u.Token = value
+			case "serial":
+				u.Serial = value
+			case "slot-id":
+				id, err := strconv.ParseUint(value, 10, 0)
+				if err != nil {
+					return fmt.Errorf("invalid PKCS#11 slot-id %q", value)
+				}
+				slot := uint(id)
+				u.SlotID = &slot
+			case "object":
+				u.Object = value
+			case "id":
+				u.ID = []byte(value)
+			case "pin-value":
+				u.PinValue = value
+			case "pin-source":
+				u.PinSource = value
+			case "type":
+				if value != "private" {
+					return fmt.Errorf("PKCS#11 URI must select a private key, not type=%s", value)
+				}
+			}
+			// Other attributes (manufacturer, model, library-*) don't narrow
+			// the search enough to matter and are ignored
+		}
+		return nil
+	}
+	if err := attrs(path, ";"); err != nil {
+		return nil, err
+	}
+	if err := attrs(query, "&"); err != nil {
+		return nil, err
+	}
+
+	if u.ModulePath == "" {
+		u.ModulePath = os.Getenv("PKCS11_MODULE")
+	}
+	if u.ModulePath == "" {
+		return nil, fmt.Errorf("PKCS#11 URI has no module-path (or set PKCS11_MODULE)")
+	}
+	if u.Object == "" && u.ID == nil {
+		return nil, fmt.Errorf("PKCS#11 URI must name the key with object= or id=")
+	}
+	return u, nil
+}
+
+// pin returns the user PIN from pin-value or the file named by pin-source
+func (u *pkcs11URI) pin() (string, error) {
+	if u.PinSource == "" {
+		return u.PinValue, nil
+	}
+	data, err := os.ReadFile(strings.TrimPrefix(u.PinSource, "file:"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read PKCS#11 pin-source: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// externalSignerRequest is one request to an exec: or unix: signer. A
+// command reads it as JSON on stdin; a socket receives it as one line.
+type externalSignerRequest struct {
+	Operation string `json:"operation"`        // "public_key" or "sign"
+	Hash      string `json:"hash,omitempty"`   // e.g. SHA-256; empty when the message is signed as is (Ed25519)
+	PSS       bool   `json:"pss,omitempty"`    // RSA-PSS instead of PKCS#1 v1.5
+	Digest    []byte `json:"digest,omitempty"` // base64 in JSON
+}
+
+// externalSignerResponse is an external signer's reply: a PEM public key
+// (or certificate), an ASN.1 signature as crypto.Signer returns it, or an error
+type externalSignerResponse struct {
+	PublicKey string `json:"public_key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// externalSigner is a crypto.Signer backed by an exec: or unix: signer
+type externalSigner struct {
+	uri    string
+	public crypto.PublicKey
+	call   func(req []byte) ([]byte, error)
+}
+
+// openExternalSigner asks an external signer for its public key
+func openExternalSigner(uri string, call func(req []byte) ([]byte, error)) (*Signer, error) {
+	s := &externalSigner{uri: uri, call: call}
+	resp, err := s.request(externalSignerRequest{Operation: "public_key"})
+	if err != nil {
+		return nil, err
+	}
+	if s.public, err = parsePublicKeyPEM([]byte(resp.PublicKey)); err != nil {
+		return nil, fmt.Errorf("external signer %s: %w", uri, err)
+	}
+	return &Signer{Signer: s, URI: uri}, nil
+}
+
+func (s *externalSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *externalSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	req := externalSignerRequest{Operation: "sign", Digest: digest}
+	if hash := opts.HashFunc(); hash != 0 {
+		req.Hash = hash.String()
+	}
+	if _, ok := opts.(*rsa.PSSOptions); ok {
+		req.PSS = true
+	}
+	resp, err := s.request(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Signature) == 0 {
+		return nil, fmt.Errorf("external signer %s returned no signature", s.uri)
+	}
+	return resp.Signature, nil
+}
+
+// request sends one request and decodes the reply, surfacing its error
+func (s *externalSigner) request(req externalSignerRequest) (*externalSignerResponse, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.call(data)
+	if err != nil {
+		return nil, fmt.Errorf("external signer %s: %w", s.uri, err)
+	}
+	var resp externalSignerResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("external signer %s returned an invalid response: %w", s.uri, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("external signer %s: %s", s.uri, resp.Error)
+	}
+	return &resp, nil
+}
+
+// runExecSigner runs a signer command with the request on stdin
+func runExecSigner(args []string, req []byte) ([]byte, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(req)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%w: %s", err, msg)
+			}
+			return nil, err
+		}
+	case <-time.After(externalSignerTimeout):
+		_ = cmd.Process.Kill()
+		return nil, fmt.Errorf("timed out after %s", externalSignerTimeout)
+	}
+	return stdout.Bytes(), nil
+}
+
+// callSocketSigner sends the request as one line on a new connection and
+// reads one line back
+func callSocketSigner(path string, req []byte) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", path, externalSignerTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(externalSignerTimeout))
+
+	if _, err := conn.Write(append(req, '\n')); err != nil {
+		return nil, err
+	}
+	var resp json.RawMessage
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return resp, nil
+}
+
+// parsePublicKeyPEM reads a PEM "PUBLIC KEY" or the key of a PEM certificate
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM public key in response")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return c.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %s for a public key", block.Type)
+	}
+}
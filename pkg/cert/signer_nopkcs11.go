@@ -0,0 +1,10 @@
+//go:build !pkcs11
+
+package cert
+
+import "fmt"
+
+// openPKCS11Signer is unavailable without cgo; see signer_pkcs11.go
+func openPKCS11Signer(_ *pkcs11URI, _ string) (*Signer, error) {
+	return nil, fmt.Errorf("PKCS#11 support is not built in; rebuild certwiz with -tags pkcs11 (requires cgo)")
+}
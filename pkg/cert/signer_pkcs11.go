@@ -0,0 +1,322 @@
+//go:build pkcs11
+
+package cert
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+)
+
+// PKCS#11 3.0 EdDSA constants, which github.com/miekg/pkcs11 doesn't define
+const (
+	ckkECEdwards = 0x00000040
+	ckmEdDSA     = 0x00001057
+)
+
+// oidPublicKeyECDSA is id-ecPublicKey, for rebuilding an EC public key
+var oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+// digestInfoPrefixes are the DER DigestInfo headers CKM_RSA_PKCS needs in
+// front of a digest for PKCS#1 v1.5 signatures
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// pssMechanisms maps a hash to its PKCS#11 hash mechanism and MGF1 variant
+var pssMechanisms = map[crypto.Hash][2]uint{
+	crypto.SHA256: {pkcs11.CKM_SHA256, pkcs11.CKG_MGF1_SHA256},
+	crypto.SHA384: {pkcs11.CKM_SHA384, pkcs11.CKG_MGF1_SHA384},
+	crypto.SHA512: {pkcs11.CKM_SHA512, pkcs11.CKG_MGF1_SHA512},
+}
+
+// pkcs11Signer signs with a private key object on a PKCS#11 token. One
+// session is shared, so signing is serialized.
+type pkcs11Signer struct {
+	mu      sync.Mutex
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	key     pkcs11.ObjectHandle
+	keyType uint
+	public  crypto.PublicKey
+}
+
+// openPKCS11Signer loads the module, finds the token and key named by the
+// URI, and logs in with pin
+func openPKCS11Signer(u *pkcs11URI, pin string) (*Signer, error) {
+	ctx := pkcs11.New(u.ModulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", u.ModulePath)
+	}
+	if err := ctx.Initialize(); err != nil && !isPKCS11Error(err, pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		ctx.Destroy()
+		return nil, fmt.Errorf("failed to initialize PKCS#11 module: %w", err)
+	}
+	s := &pkcs11Signer{ctx: ctx}
+	if err := s.open(u, pin); err != nil {
+		s.close()
+		return nil, err
+	}
+	return &Signer{Signer: s, close: s.close}, nil
+}
+
+func (s *pkcs11Signer) open(u *pkcs11URI, pin string) error {
+	slot, err := s.findSlot(u)
+	if err != nil {
+		return err
+	}
+	if s.session, err = s.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION); err != nil {
+		return fmt.Errorf("failed to open PKCS#11 session: %w", err)
+	}
+	if pin != "" {
+		if err := s.ctx.Login(s.session, pkcs11.CKU_USER, pin); err != nil && !isPKCS11Error(err, pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+			return fmt.Errorf("PKCS#11 login failed: %w", err)
+		}
+	}
+
+	template := []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY)}
+	template = append(template, keyAttributes(u.Object, u.ID)...)
+	if s.key, err = s.findObject(template); err != nil {
+		if pin == "" {
+			return fmt.Errorf("%w (private keys are usually only visible after login: set pin-value or pin-source)", err)
+		}
+		return err
+	}
+
+	attrs, err := s.ctx.GetAttributeValue(s.session, s.key, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read PKCS#11 key attributes: %w", err)
+	}
+	s.keyType = bytesToUint(attrs[0].Value)
+	s.public, err = s.publicKey(attrs[1].Value, string(attrs[2].Value))
+	return err
+}
+
+// findSlot returns the slot whose token matches the URI's token, serial,
+// and slot-id; the URI must identify exactly one
+func (s *pkcs11Signer) findSlot(u *pkcs11URI) (uint, error) {
+	slots, err := s.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list PKCS#11 slots: %w", err)
+	}
+	var matches []uint
+	for _, slot := range slots {
+		if u.SlotID != nil && slot != *u.SlotID {
+			continue
+		}
+		info, err := s.ctx.GetTokenInfo(slot)
+		if err != nil {
+			continue
+		}
+		if u.Token != "" && strings.TrimRight(info.Label, " \x00") != u.Token {
+			continue
+		}
+		if u.Serial != "" && strings.TrimRight(info.SerialNumber, " \x00") != u.Serial {
+			continue
+		}
+		matches = append(matches, slot)
+	}
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("no PKCS#11 token matches the URI")
+	case 1:
+		return matches[0], nil
+	default:
+		return 0, fmt.Errorf("%d PKCS#11 tokens match the URI; add token=, serial=, or slot-id=", len(matches))
+	}
+}
+
+// findObject returns the single object matching template
+func (s *pkcs11Signer) findObject(template []*pkcs11.Attribute) (pkcs11.ObjectHandle, error) {
+	if err := s.ctx.FindObjectsInit(s.session, template); err != nil {
+		return 0, fmt.Errorf("PKCS#11 object search failed: %w", err)
+	}
+	objects, _, err := s.ctx.FindObjects(s.session, 2)
+	_ = s.ctx.FindObjectsFinal(s.session)
+	if err != nil {
+		return 0, fmt.Errorf("PKCS#11 object search failed: %w", err)
+	}
+	switch len(objects) {
+	case 0:
+		return 0, fmt.Errorf("no matching key found on the PKCS#11 token")
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, fmt.Errorf("more than one key on the PKCS#11 token matches the URI; add id= or object=")
+	}
+}
+
+// publicKey reads the public half: RSA from the private key object, EC and
+// Ed25519 from the public key object with the same ID or label
+func (s *pkcs11Signer) publicKey(id []byte, label string) (crypto.PublicKey, error) {
+	switch s.keyType {
+	case pkcs11.CKK_RSA:
+		attrs, err := s.ctx.GetAttributeValue(s.session, s.key, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RSA public key: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(attrs[0].Value),
+			E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+		}, nil
+	case pkcs11.CKK_EC, ckkECEdwards:
+		template := []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY)}
+		template = append(template, keyAttributes(label, id)...)
+		pub, err := s.findObject(template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the public key for the PKCS#11 key: %w", err)
+		}
+		attrs, err := s.ctx.GetAttributeValue(s.session, pub, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read EC public key: %w", err)
+		}
+		// CKA_EC_POINT is a DER OCTET STRING, though some tokens omit the wrapper
+		point := attrs[1].Value
+		var unwrapped []byte
+		if rest, err := asn1.Unmarshal(point, &unwrapped); err == nil && len(rest) == 0 {
+			point = unwrapped
+		}
+		if s.keyType == ckkECEdwards {
+			if len(point) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("unsupported Edwards curve key (only Ed25519 is supported)")
+			}
+			return ed25519.PublicKey(point), nil
+		}
+		// Rebuild the SubjectPublicKeyInfo and let x509 check the curve
+		spki, err := asn1.Marshal(struct {
+			Algorithm pkix.AlgorithmIdentifier
+			PublicKey asn1.BitString
+		}{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: attrs[0].Value}},
+			PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return x509.ParsePKIXPublicKey(spki)
+	default:
+		return nil, fmt.Errorf("unsupported PKCS#11 key type %#x", s.keyType)
+	}
+}
+
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *pkcs11Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var (
+		mechanism *pkcs11.Mechanism
+		data      = digest
+	)
+	hash := opts.HashFunc()
+	switch s.keyType {
+	case pkcs11.CKK_RSA:
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			mech, ok := pssMechanisms[hash]
+			if !ok {
+				return nil, fmt.Errorf("unsupported hash %s for RSA-PSS", hash)
+			}
+			saltLength := pss.SaltLength
+			if saltLength <= 0 {
+				saltLength = hash.Size()
+			}
+			mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_PSS, pkcs11.NewPSSParams(mech[0], mech[1], uint(saltLength)))
+		} else {
+			prefix, ok := digestInfoPrefixes[hash]
+			if !ok {
+				return nil, fmt.Errorf("unsupported hash %s for RSA", hash)
+			}
+			mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)
+			data = append(append([]byte{}, prefix...), digest...)
+		}
+	case pkcs11.CKK_EC:
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)
+	case ckkECEdwards:
+		if hash != 0 {
+			return nil, errors.New("an Ed25519 key signs the message itself, not a digest")
+		}
+		mechanism = pkcs11.NewMechanism(ckmEdDSA, nil)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ctx.SignInit(s.session, []*pkcs11.Mechanism{mechanism}, s.key); err != nil {
+		return nil, fmt.Errorf("PKCS#11 sign failed: %w", err)
+	}
+	sig, err := s.ctx.Sign(s.session, data)
+	if err != nil {
+		return nil, fmt.Errorf("PKCS#11 sign failed: %w", err)
+	}
+	if s.keyType != pkcs11.CKK_EC {
+		return sig, nil
+	}
+
+	// CKM_ECDSA returns r || s; crypto.Signer returns the ASN.1 form
+	half := len(sig) / 2
+	return asn1.Marshal(struct{ R, S *big.Int }{
+		R: new(big.Int).SetBytes(sig[:half]),
+		S: new(big.Int).SetBytes(sig[half:]),
+	})
+}
+
+// close logs out and releases the session and module
+func (s *pkcs11Signer) close() error {
+	if s.session != 0 {
+		_ = s.ctx.Logout(s.session)
+		_ = s.ctx.CloseSession(s.session)
+	}
+	err := s.ctx.Finalize()
+	s.ctx.Destroy()
+	return err
+}
+
+// keyAttributes narrows a key search by label and ID
+func keyAttributes(label string, id []byte) []*pkcs11.Attribute {
+	var attrs []*pkcs11.Attribute
+	if label != "" {
+		attrs = append(attrs, pkcs11.NewAttribute(pkcs11.CKA_LABEL, label))
+	}
+	if id != nil {
+		attrs = append(attrs, pkcs11.NewAttribute(pkcs11.CKA_ID, id))
+	}
+	return attrs
+}
+
+// isPKCS11Error reports whether err is the PKCS#11 return value code
+func isPKCS11Error(err error, code uint) bool {
+	var e pkcs11.Error
+	return errors.As(err, &e) && uint(e) == code
+}
+
+// bytesToUint decodes a CK_ULONG attribute in host byte order
+func bytesToUint(b []byte) uint {
+	var n uint
+	for i := len(b) - 1; i >= 0; i-- {
+		n = n<<8 | uint(b[i])
+	}
+	return n
+}
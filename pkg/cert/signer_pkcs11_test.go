@@ -0,0 +1,138 @@
+//go:build pkcs11
+
+package cert
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/pkcs11"
+)
+
+// TestPKCS11Signer runs against a real token, such as SoftHSM:
+//
+//	softhsm2-util --init-token --free --label certwiz --so-pin 0000 --pin 1234
+//	CERTWIZ_TEST_PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so \
+//	CERTWIZ_TEST_PKCS11_TOKEN=certwiz CERTWIZ_TEST_PKCS11_PIN=1234 \
+//	go test -tags pkcs11 -run PKCS11 ./pkg/cert
+func TestPKCS11Signer(t *testing.T) {
+	module := os.Getenv("CERTWIZ_TEST_PKCS11_MODULE")
+	token := os.Getenv("CERTWIZ_TEST_PKCS11_TOKEN")
+	pin := os.Getenv("CERTWIZ_TEST_PKCS11_PIN")
+	if module == "" || token == "" {
+		t.Skip("set CERTWIZ_TEST_PKCS11_MODULE, CERTWIZ_TEST_PKCS11_TOKEN, and CERTWIZ_TEST_PKCS11_PIN to test against a PKCS#11 token")
+	}
+
+	label := fmt.Sprintf("certwiz-test-%d", time.Now().UnixNano())
+	generatePKCS11TestKey(t, module, token, pin, label)
+
+	dir := t.TempDir()
+	uri := fmt.Sprintf("pkcs11:token=%s;object=%s?module-path=%s", token, label, module)
+
+	// A root for the token key, then a leaf signed with it
+	caCert := filepath.Join(dir, "hsm-ca.crt")
+	if err := GenerateCA(CAOptions{CommonName: "HSM Root", Days: 30, MaxPathLen: -1, KeyURI: uri, KeyPassword: pin}, caCert, ""); err != nil {
+		t.Fatalf("GenerateCA with a PKCS#11 key failed: %v", err)
+	}
+	csrPath, csrKey := filepath.Join(dir, "leaf.csr"), filepath.Join(dir, "leaf.key")
+	if err := GenerateCSR(CSROptions{CommonName: "leaf.example.com", SANs: []string{"leaf.example.com"}}, csrPath, csrKey); err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+	leafPath := filepath.Join(dir, "leaf.crt")
+	if err := SignCSR(SignOptions{CSRPath: csrPath, CACert: caCert, CAKeyURI: uri + "&pin-value=" + pin, Days: 30}, leafPath); err != nil {
+		t.Fatalf("SignCSR with a PKCS#11 key failed: %v", err)
+	}
+	leaf, err := InspectFile(leafPath)
+	if err != nil {
+		t.Fatalf("Failed to read signed certificate: %v", err)
+	}
+	ca, err := InspectFile(caCert)
+	if err != nil {
+		t.Fatalf("Failed to read CA: %v", err)
+	}
+	if err := leaf.CheckSignatureFrom(ca.Certificate); err != nil {
+		t.Errorf("Signed certificate doesn't verify against the HSM CA: %v", err)
+	}
+
+	if _, err := OpenSigner(uri+"&pin-value=wrong", ""); err == nil {
+		t.Error("Expected a login error with the wrong PIN")
+	}
+}
+
+// generatePKCS11TestKey creates a P-256 key pair with label on the token
+func generatePKCS11TestKey(t *testing.T, module, token, pin, label string) {
+	t.Helper()
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		t.Fatalf("failed to load %s", module)
+	}
+	defer ctx.Destroy()
+	if err := ctx.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer ctx.Finalize()
+
+	u := &pkcs11URI{ModulePath: module, Token: token}
+	s := &pkcs11Signer{ctx: ctx}
+	slot, err := s.findSlot(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		t.Fatalf("OpenSession failed: %v", err)
+	}
+	defer ctx.CloseSession(session)
+	if err := ctx.Login(session, pkcs11.CKU_USER, pin); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	defer ctx.Logout(session)
+
+	curve, _ := asn1.Marshal(asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7})
+	id := []byte(label)
+	_, _, err = ctx.GenerateKeyPair(session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, curve),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, id),
+		},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, id),
+		})
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx := pkcs11.New(module)
+		if ctx == nil || ctx.Initialize() != nil {
+			return
+		}
+		defer ctx.Destroy()
+		defer ctx.Finalize()
+		session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+		if err != nil {
+			return
+		}
+		defer ctx.CloseSession(session)
+		_ = ctx.Login(session, pkcs11.CKU_USER, pin)
+		if ctx.FindObjectsInit(session, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_LABEL, label)}) != nil {
+			return
+		}
+		objects, _, _ := ctx.FindObjects(session, 10)
+		_ = ctx.FindObjectsFinal(session)
+		for _, o := range objects {
+			_ = ctx.DestroyObject(session, o)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package cert
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// serveSignerRequest answers one external signer request with key, as an
+// exec: or unix: signer would
+func serveSignerRequest(key crypto.Signer, data []byte) []byte {
+	var req externalSignerRequest
+	var resp externalSignerResponse
+	if err := json.Unmarshal(data, &req); err != nil {
+		resp.Error = err.Error()
+	}
+	switch req.Operation {
+	case "public_key":
+		der, _ := x509.MarshalPKIXPublicKey(key.Public())
+		resp.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	case "sign":
+		var opts crypto.SignerOpts = crypto.Hash(0)
+		for _, h := range []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+			if h.String() == req.Hash {
+				opts = h
+			}
+		}
+		if req.PSS {
+			opts = &rsa.PSSOptions{Hash: opts.HashFunc(), SaltLength: rsa.PSSSaltLengthEqualsHash}
+		}
+		sig, err := key.Sign(rand.Reader, req.Digest, opts)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		resp.Signature = sig
+	default:
+		resp.Error = fmt.Sprintf("unknown operation %q", req.Operation)
+	}
+	out, _ := json.Marshal(resp)
+	return out
+}
+
+// TestExecSignerHelper is the exec: signer run by TestOpenSignerExec; it
+// does nothing unless started as one
+func TestExecSignerHelper(t *testing.T) {
+	keyPath := os.Getenv("CERTWIZ_TEST_SIGNER_KEY")
+	if keyPath == "" {
+		return
+	}
+	key, err := LoadPrivateKey(keyPath, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	req, _ := io.ReadAll(os.Stdin)
+	_, _ = os.Stdout.Write(serveSignerRequest(key, req))
+	os.Exit(0)
+}
+
+// signWithKeyURI signs a fresh CSR with the CA key at uri and checks the
+// result chains to the CA
+func signWithKeyURI(t *testing.T, dir, caCert, uri string) {
+	t.Helper()
+	leafPath := signTestCSRWith(t, dir, SignOptions{CACert: caCert, CAKeyURI: uri, Days: 30}, "leaf.example.com")
+	leaf, err := InspectFile(leafPath)
+	if err != nil {
+		t.Fatalf("Failed to read signed certificate: %v", err)
+	}
+	ca, err := InspectFile(caCert)
+	if err != nil {
+		t.Fatalf("Failed to read CA certificate: %v", err)
+	}
+	if err := leaf.CheckSignatureFrom(ca.Certificate); err != nil {
+		t.Errorf("Signed certificate doesn't verify against the CA: %v", err)
+	}
+}
+
+func TestOpenSignerFile(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey, _ := newTestCAFiles(t, dir, "Signer Test CA")
+
+	for _, uri := range []string{"file:" + caKey, "file://" + caKey} {
+		signer, err := OpenSigner(uri, "")
+		if err != nil {
+			t.Fatalf("OpenSigner(%s) failed: %v", uri, err)
+		}
+		signer.Close()
+	}
+	signWithKeyURI(t, dir, caCert, "file:"+caKey)
+}
+
+func TestOpenSignerSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets are not available")
+	}
+	dir := t.TempDir()
+	caCert, caKey, _ := newTestCAFiles(t, dir, "Signer Test CA")
+	key, err := LoadPrivateKey(caKey, "")
+	if err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+
+	socket := filepath.Join(dir, "signer.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			line, err := bufio.NewReader(conn).ReadBytes('\n')
+			if err == nil {
+				_, _ = conn.Write(append(serveSignerRequest(key, line), '\n'))
+			}
+			conn.Close()
+		}
+	}()
+
+	signWithKeyURI(t, dir, caCert, "unix:"+socket)
+
+	// The CA itself can be created for the external key: a root signed by
+	// it, with no key file written
+	rootCert, rootKey := filepath.Join(dir, "root.crt"), filepath.Join(dir, "root.key")
+	if err := GenerateCA(CAOptions{CommonName: "External Root", Days: 30, MaxPathLen: -1, KeyURI: "unix:" + socket}, rootCert, rootKey); err != nil {
+		t.Fatalf("GenerateCA with KeyURI failed: %v", err)
+	}
+	if _, err := os.Stat(rootKey); !os.IsNotExist(err) {
+		t.Error("GenerateCA with KeyURI should not write a key file")
+	}
+	root, err := InspectFile(rootCert)
+	if err != nil {
+		t.Fatalf("Failed to read root: %v", err)
+	}
+	if !publicKeysEqual(root.Certificate.PublicKey, key.Public()) {
+		t.Error("Root certificate should hold the external key")
+	}
+
+	// An intermediate signed by the external parent key
+	interCert, interKey := filepath.Join(dir, "inter.crt"), filepath.Join(dir, "inter.key")
+	if err := GenerateCA(CAOptions{CommonName: "Issuing CA", Days: 30, KeyAlgorithm: KeyAlgorithmECDSAP256, MaxPathLen: -1,
+		ParentCert: rootCert, ParentKeyURI: "unix:" + socket}, interCert, interKey); err != nil {
+		t.Fatalf("GenerateCA with ParentKeyURI failed: %v", err)
+	}
+	inter, err := InspectFile(interCert)
+	if err != nil {
+		t.Fatalf("Failed to read intermediate: %v", err)
+	}
+	if err := inter.CheckSignatureFrom(root.Certificate); err != nil {
+		t.Errorf("Intermediate doesn't verify against the root: %v", err)
+	}
+
+	// A key that doesn't match the CA certificate is rejected
+	other, _, _ := newTestCAFiles(t, t.TempDir(), "Other Signer CA")
+	err = SignCSR(SignOptions{CSRPath: filepath.Join(dir, "leaf.csr"), CACert: other, CAKeyURI: "unix:" + socket, Days: 30}, filepath.Join(dir, "x.crt"))
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected a key mismatch error, got %v", err)
+	}
+}
+
+func TestOpenSignerExec(t *testing.T) {
+	if strings.ContainsAny(os.Args[0], " \t") {
+		t.Skip("test binary path contains spaces")
+	}
+	dir := t.TempDir()
+	caCert, caKey, _ := newTestCAFiles(t, dir, "Signer Test CA")
+	t.Setenv("CERTWIZ_TEST_SIGNER_KEY", caKey)
+
+	signWithKeyURI(t, dir, caCert, "exec:"+os.Args[0]+" -test.run=^TestExecSignerHelper$")
+
+	// Failures are reported with the command's stderr
+	t.Setenv("CERTWIZ_TEST_SIGNER_KEY", filepath.Join(dir, "missing.key"))
+	_, err := OpenSigner("exec:"+os.Args[0]+" -test.run=^TestExecSignerHelper$", "")
+	if err == nil || !strings.Contains(err.Error(), "missing.key") {
+		t.Errorf("Expected the helper's error, got %v", err)
+	}
+}
+
+func TestOpenSignerErrors(t *testing.T) {
+	tests := []struct {
+		uri     string
+		wantErr string
+	}{
+		{"ca.key", "invalid key URI"},
+		{"vault:ca", "unsupported key URI scheme"},
+		{"exec:", "no command given"},
+		{"unix:", "no socket path given"},
+		{"exec:/nonexistent/signer", "external signer"},
+		{"pkcs11:token=CA", "module-path"},
+		{"pkcs11:token=CA?module-path=/lib/p11.so", "object= or id="},
+		{"pkcs11:object=root;type=public?module-path=/lib/p11.so", "private key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			t.Setenv("PKCS11_MODULE", "")
+			_, err := OpenSigner(tt.uri, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("OpenSigner(%q) error = %v, want %q", tt.uri, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParsePKCS11URI(t *testing.T) {
+	u, err := parsePKCS11URI("pkcs11:token=My%20CA;object=root;id=%01%02;slot-id=3?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-value=1234")
+	if err != nil {
+		t.Fatalf("parsePKCS11URI failed: %v", err)
+	}
+	if u.Token != "My CA" || u.Object != "root" || string(u.ID) != "\x01\x02" || u.SlotID == nil || *u.SlotID != 3 {
+		t.Errorf("Unexpected path attributes: %+v", u)
+	}
+	if u.ModulePath != "/usr/lib/softhsm/libsofthsm2.so" || u.PinValue != "1234" {
+		t.Errorf("Unexpected query attributes: %+v", u)
+	}
+
+	pinFile := filepath.Join(t.TempDir(), "pin")
+	if err := os.WriteFile(pinFile, []byte("5678\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PKCS11_MODULE", "/opt/p11.so")
+	u, err = parsePKCS11URI("pkcs11:object=root?pin-source=file:" + pinFile)
+	if err != nil {
+		t.Fatalf("parsePKCS11URI failed: %v", err)
+	}
+	if u.ModulePath != "/opt/p11.so" {
+		t.Errorf("ModulePath = %q, want PKCS11_MODULE", u.ModulePath)
+	}
+	if pin, err := u.pin(); err != nil || pin != "5678" {
+		t.Errorf("pin() = %q, %v; want 5678", pin, err)
+	}
+
+	if got := pinValuePattern.ReplaceAllString("pkcs11:object=a?module-path=/m.so&pin-value=1234", "${1}pin-value=..."); strings.Contains(got, "1234") {
+		t.Errorf("PIN not redacted: %s", got)
+	}
+}
@@ -0,0 +1,330 @@
+package cert
+
+import (
+	"bufio"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// Protocols that can be upgraded to TLS with STARTTLS
+const (
+	StartTLSSMTP     = "smtp"
+	StartTLSIMAP     = "imap"
+	StartTLSPOP3     = "pop3"
+	StartTLSLDAP     = "ldap"
+	StartTLSFTP      = "ftp"
+	StartTLSPostgres = "postgres"
+)
+
+// StartTLSProtocols lists the protocols accepted by --starttls
+var StartTLSProtocols = []string{StartTLSSMTP, StartTLSIMAP, StartTLSPOP3, StartTLSLDAP, StartTLSFTP, StartTLSPostgres}
+
+// startTLSPorts are the standard plaintext ports of each protocol
+var startTLSPorts = map[string]int{
+	StartTLSSMTP:     25,
+	StartTLSIMAP:     143,
+	StartTLSPOP3:     110,
+	StartTLSLDAP:     389,
+	StartTLSFTP:      21,
+	StartTLSPostgres: 5432,
+}
+
+// ldapStartTLSOID is the LDAP StartTLS extended operation (RFC 4511, section 4.14)
+const ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// postgresSSLRequest is the code of PostgreSQL's SSLRequest message
+const postgresSSLRequest = 80877103
+
+// ValidateStartTLS checks a --starttls protocol name
+func ValidateStartTLS(protocol string) error {
+	if protocol == "" {
+		return nil
+	}
+	if _, ok := startTLSPorts[protocol]; ok {
+		return nil
+	}
+	return fmt.Errorf("unsupported STARTTLS protocol %q (use one of: %s)", protocol, strings.Join(StartTLSProtocols, ", "))
+}
+
+// StartTLSDefaultPort returns the standard port for a STARTTLS protocol,
+// or 0 for unknown protocols
+func StartTLSDefaultPort(protocol string) int {
+	return startTLSPorts[protocol]
+}
+
+// startTLS runs the protocol's plaintext exchange on conn, leaving it
+// ready for the TLS handshake
+func startTLS(conn net.Conn, protocol string) error {
+	r := bufio.NewReader(conn)
+	switch protocol {
+	case StartTLSSMTP:
+		return startTLSSMTP(conn, r)
+	case StartTLSIMAP:
+		return startTLSIMAP(conn, r)
+	case StartTLSPOP3:
+		return startTLSPOP3(conn, r)
+	case StartTLSLDAP:
+		return startTLSLDAP(conn, r)
+	case StartTLSFTP:
+		return startTLSFTP(conn, r)
+	case StartTLSPostgres:
+		return startTLSPostgres(conn, r)
+	default:
+		return ValidateStartTLS(protocol)
+	}
+}
+
+// readLine reads a CRLF- or LF-terminated line without the terminator
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readReply reads a (possibly multi-line) SMTP or FTP reply, where
+// continuation lines look like "250-..." and the last like "250 ...". It
+// returns the code and all lines' text.
+func readReply(r *bufio.Reader) (string, []string, error) {
+	var lines []string
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(line) < 3 {
+			return "", nil, fmt.Errorf("malformed reply %q", line)
+		}
+		lines = append(lines, line)
+		if len(line) == 3 || line[3] == ' ' {
+			return line[:3], lines, nil
+		}
+	}
+}
+
+// expectReply reads a reply and checks its code
+func expectReply(r *bufio.Reader, code string) ([]string, error) {
+	got, lines, err := readReply(r)
+	if err != nil {
+		return nil, err
+	}
+	if got != code {
+		return nil, fmt.Errorf("unexpected reply %q", strings.Join(lines, " | "))
+	}
+	return lines, nil
+}
+
+// startTLSSMTP greets the server with EHLO and sends STARTTLS (RFC 3207)
+func startTLSSMTP(w io.Writer, r *bufio.Reader) error {
+	if _, err := expectReply(r, "220"); err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+	if _, err := io.WriteString(w, "EHLO certwiz\r\n"); err != nil {
+		return err
+	}
+	lines, err := expectReply(r, "250")
+	if err != nil {
+		return fmt.Errorf("EHLO: %w", err)
+	}
+	advertised := false
+	for _, line := range lines {
+		// A final line may be a bare "250" (RFC 5321, 4.2.1)
+		if len(line) > 4 && strings.EqualFold(strings.TrimSpace(line[4:]), "STARTTLS") {
+			advertised = true
+		}
+	}
+	if !advertised {
+		return fmt.Errorf("server does not advertise STARTTLS")
+	}
+	if _, err := io.WriteString(w, "STARTTLS\r\n"); err != nil {
+		return err
+	}
+	if _, err := expectReply(r, "220"); err != nil {
+		return fmt.Errorf("STARTTLS: %w", err)
+	}
+	return nil
+}
+
+// startTLSIMAP sends a tagged STARTTLS command (RFC 3501, section 6.2.1)
+func startTLSIMAP(w io.Writer, r *bufio.Reader) error {
+	greeting, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(strings.ToUpper(greeting), "* OK") {
+		return fmt.Errorf("unexpected greeting %q", greeting)
+	}
+	if _, err := io.WriteString(w, "a001 STARTTLS\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "a001 ") {
+			continue // untagged responses
+		}
+		if strings.HasPrefix(strings.ToUpper(line), "A001 OK") {
+			return nil
+		}
+		return fmt.Errorf("server refused STARTTLS: %q", line)
+	}
+}
+
+// startTLSPOP3 sends STLS (RFC 2595, section 4)
+func startTLSPOP3(w io.Writer, r *bufio.Reader) error {
+	greeting, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return fmt.Errorf("unexpected greeting %q", greeting)
+	}
+	if _, err := io.WriteString(w, "STLS\r\n"); err != nil {
+		return err
+	}
+	reply, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(reply, "+OK") {
+		return fmt.Errorf("server refused STLS: %q", reply)
+	}
+	return nil
+}
+
+// startTLSFTP sends AUTH TLS (RFC 4217, section 4)
+func startTLSFTP(w io.Writer, r *bufio.Reader) error {
+	if _, err := expectReply(r, "220"); err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+	if _, err := io.WriteString(w, "AUTH TLS\r\n"); err != nil {
+		return err
+	}
+	if _, err := expectReply(r, "234"); err != nil {
+		return fmt.Errorf("AUTH TLS: %w", err)
+	}
+	return nil
+}
+
+// startTLSPostgres sends an SSLRequest and expects 'S' in reply
+func startTLSPostgres(w io.Writer, r *bufio.Reader) error {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint32(msg[0:4], 8)
+	binary.BigEndian.PutUint32(msg[4:8], postgresSSLRequest)
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	reply, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch reply {
+	case 'S':
+		return nil
+	case 'N':
+		return fmt.Errorf("server does not accept SSL connections")
+	default:
+		return fmt.Errorf("unexpected reply to SSLRequest: %q", reply)
+	}
+}
+
+// ldapMessage is the envelope of an LDAP message (RFC 4511, section 4.1.1)
+type ldapMessage struct {
+	MessageID  int
+	ProtocolOp asn1.RawValue
+	Controls   asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// startTLSLDAP sends the StartTLS extended request (RFC 4511, section 4.14)
+func startTLSLDAP(w io.Writer, r *bufio.Reader) error {
+	request, err := asn1.Marshal(ldapMessage{
+		MessageID: 1,
+		ProtocolOp: asn1.RawValue{
+			Class:      asn1.ClassApplication,
+			Tag:        23, // ExtendedRequest
+			IsCompound: true,
+			Bytes:      mustMarshalLDAPOID(ldapStartTLSOID),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(request); err != nil {
+		return err
+	}
+
+	element, err := readBERElement(r)
+	if err != nil {
+		return err
+	}
+	var msg ldapMessage
+	if _, err := asn1.Unmarshal(element, &msg); err != nil {
+		return fmt.Errorf("malformed LDAP response: %w", err)
+	}
+	if msg.ProtocolOp.Class != asn1.ClassApplication || msg.ProtocolOp.Tag != 24 {
+		return fmt.Errorf("unexpected LDAP response (tag %d)", msg.ProtocolOp.Tag)
+	}
+	var resultCode asn1.Enumerated
+	rest, err := asn1.Unmarshal(msg.ProtocolOp.Bytes, &resultCode)
+	if err != nil {
+		return fmt.Errorf("malformed LDAP response: %w", err)
+	}
+	if resultCode != 0 {
+		// matchedDN and diagnosticMessage follow the result code
+		var matchedDN, diagnostic []byte
+		if rest, err = asn1.Unmarshal(rest, &matchedDN); err == nil {
+			_, _ = asn1.Unmarshal(rest, &diagnostic)
+		}
+		if len(diagnostic) > 0 {
+			return fmt.Errorf("server refused StartTLS (result code %d: %s)", resultCode, diagnostic)
+		}
+		return fmt.Errorf("server refused StartTLS (result code %d)", resultCode)
+	}
+	return nil
+}
+
+// mustMarshalLDAPOID encodes requestName [0] LDAPOID
+func mustMarshalLDAPOID(oid string) []byte {
+	b, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: []byte(oid)})
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// readBERElement reads one BER element (tag, length, contents) with a
+// definite length
+func readBERElement(r *bufio.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 3 {
+			return nil, fmt.Errorf("unsupported BER length encoding")
+		}
+		lenBytes := make([]byte, n)
+		if _, err := io.ReadFull(r, lenBytes); err != nil {
+			return nil, err
+		}
+		header = append(header, lenBytes...)
+		length = 0
+		for _, b := range lenBytes {
+			length = length<<8 | int(b)
+		}
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return append(header, content...), nil
+}
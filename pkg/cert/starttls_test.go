@@ -0,0 +1,252 @@
+package cert
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTLSScript plays the server side of a plaintext upgrade
+type startTLSScript func(conn net.Conn, r *bufio.Reader) error
+
+// newStartTLSServer starts a server that runs script on each connection and
+// then completes a TLS handshake with a certificate for mail.example.com
+func newStartTLSServer(t *testing.T, script startTLSScript) (string, int) {
+	t.Helper()
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	leaf := issueTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mail.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"mail.example.com"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, key, nil)
+	config := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{leaf.cert.Raw}, PrivateKey: key}}}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+				if err := script(conn, bufio.NewReader(conn)); err != nil {
+					return
+				}
+				_ = tls.Server(conn, config).Handshake()
+			}()
+		}
+	}()
+
+	return "127.0.0.1", ln.Addr().(*net.TCPAddr).Port
+}
+
+// expectLine reads a line from the client and checks it
+func expectLine(r *bufio.Reader, want string) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if got := strings.TrimRight(line, "\r\n"); got != want {
+		return fmt.Errorf("got %q, want %q", got, want)
+	}
+	return nil
+}
+
+// smtpScript advertises capabilities in a multi-line EHLO reply; an empty
+// capability is sent as a bare "250"
+func smtpScript(capabilities ...string) startTLSScript {
+	return func(conn net.Conn, r *bufio.Reader) error {
+		fmt.Fprint(conn, "220-mail.example.com ESMTP\r\n220 ready\r\n")
+		if err := expectLine(r, "EHLO certwiz"); err != nil {
+			return err
+		}
+		lines := append([]string{"mail.example.com"}, capabilities...)
+		for i, line := range lines {
+			sep := "-"
+			if i == len(lines)-1 {
+				sep = " "
+			}
+			if line == "" {
+				sep = ""
+			}
+			fmt.Fprintf(conn, "250%s%s\r\n", sep, line)
+		}
+		if err := expectLine(r, "STARTTLS"); err != nil {
+			return err
+		}
+		fmt.Fprint(conn, "220 Ready to start TLS\r\n")
+		return nil
+	}
+}
+
+func imapScript(reply string) startTLSScript {
+	return func(conn net.Conn, r *bufio.Reader) error {
+		fmt.Fprint(conn, "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n")
+		if err := expectLine(r, "a001 STARTTLS"); err != nil {
+			return err
+		}
+		fmt.Fprint(conn, "* CAPABILITY IMAP4rev1 STARTTLS\r\n"+reply+"\r\n")
+		return nil
+	}
+}
+
+func pop3Script(conn net.Conn, r *bufio.Reader) error {
+	fmt.Fprint(conn, "+OK POP3 ready\r\n")
+	if err := expectLine(r, "STLS"); err != nil {
+		return err
+	}
+	fmt.Fprint(conn, "+OK Begin TLS negotiation\r\n")
+	return nil
+}
+
+func ftpScript(conn net.Conn, r *bufio.Reader) error {
+	fmt.Fprint(conn, "220-Welcome\r\n220 FTP ready\r\n")
+	if err := expectLine(r, "AUTH TLS"); err != nil {
+		return err
+	}
+	fmt.Fprint(conn, "234 AUTH TLS OK\r\n")
+	return nil
+}
+
+func postgresScript(reply byte) startTLSScript {
+	return func(conn net.Conn, r *bufio.Reader) error {
+		msg := make([]byte, 8)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint32(msg[0:4]) != 8 || binary.BigEndian.Uint32(msg[4:8]) != postgresSSLRequest {
+			return fmt.Errorf("not an SSLRequest: %x", msg)
+		}
+		_, err := conn.Write([]byte{reply})
+		return err
+	}
+}
+
+// ldapScript answers the StartTLS extended request with resultCode
+func ldapScript(resultCode byte) startTLSScript {
+	return func(conn net.Conn, r *bufio.Reader) error {
+		request, err := readBERElement(r)
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(request, []byte(ldapStartTLSOID)) {
+			return fmt.Errorf("not a StartTLS request: %x", request)
+		}
+		// ExtendedResponse: messageID 1, resultCode, matchedDN "", diagnosticMessage
+		diagnostic := []byte("no")
+		op := append([]byte{0x0a, 0x01, resultCode, 0x04, 0x00, 0x04, byte(len(diagnostic))}, diagnostic...)
+		msg := append([]byte{0x02, 0x01, 0x01, 0x78, byte(len(op))}, op...)
+		_, err = conn.Write(append([]byte{0x30, byte(len(msg))}, msg...))
+		return err
+	}
+}
+
+func TestInspectURLStartTLS(t *testing.T) {
+	tests := []struct {
+		protocol string
+		script   startTLSScript
+	}{
+		{StartTLSSMTP, smtpScript("PIPELINING", "STARTTLS", "8BITMIME")},
+		{StartTLSIMAP, imapScript("a001 OK Begin TLS negotiation now")},
+		{StartTLSPOP3, pop3Script},
+		{StartTLSLDAP, ldapScript(0)},
+		{StartTLSFTP, ftpScript},
+		{StartTLSPostgres, postgresScript('S')},
+	}
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			host, port := newStartTLSServer(t, tt.script)
+			c, _, err := InspectURLWithConnectOptions("mail.example.com", port, ConnectOptions{
+				ConnectHost: host,
+				Timeout:     2 * time.Second,
+				StartTLS:    tt.protocol,
+			})
+			if err != nil {
+				t.Fatalf("InspectURLWithConnectOptions failed: %v", err)
+			}
+			if c.Subject.CommonName != "mail.example.com" {
+				t.Errorf("CommonName = %q", c.Subject.CommonName)
+			}
+			if want := tt.protocol + "://mail.example.com"; c.Source != want {
+				t.Errorf("Source = %q, want %q", c.Source, want)
+			}
+		})
+	}
+}
+
+func TestInspectURLStartTLSRefused(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		script   startTLSScript
+		wantErr  string
+	}{
+		{"SMTP without STARTTLS", StartTLSSMTP, smtpScript("PIPELINING"), "does not advertise STARTTLS"},
+		{"SMTP with a bare 250", StartTLSSMTP, smtpScript("PIPELINING", ""), "does not advertise STARTTLS"},
+		{"IMAP BAD", StartTLSIMAP, imapScript("a001 BAD STARTTLS not available"), "refused STARTTLS"},
+		{"LDAP error", StartTLSLDAP, ldapScript(2), "result code 2: no"},
+		{"Postgres without SSL", StartTLSPostgres, postgresScript('N'), "does not accept SSL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port := newStartTLSServer(t, tt.script)
+			_, _, err := InspectURLWithConnectOptions(host, port, ConnectOptions{Timeout: 2 * time.Second, StartTLS: tt.protocol})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTLSVersionsStartTLS(t *testing.T) {
+	host, port := newStartTLSServer(t, smtpScript("STARTTLS"))
+	result, err := CheckTLSVersionsWithOptions(host, port, ConnectOptions{Timeout: 2 * time.Second, StartTLS: StartTLSSMTP})
+	if err != nil {
+		t.Fatalf("CheckTLSVersionsWithOptions failed: %v", err)
+	}
+	if result.MaxSupported != TLSVersionTLS13 {
+		t.Errorf("MaxSupported = %v, want TLS 1.3", result.MaxSupported)
+	}
+
+	// A failed upgrade fails the whole check rather than each version
+	host, port = newStartTLSServer(t, smtpScript())
+	if _, err := CheckTLSVersionsWithOptions(host, port, ConnectOptions{Timeout: 2 * time.Second, StartTLS: StartTLSSMTP}); err == nil {
+		t.Error("Expected error when the server does not offer STARTTLS")
+	}
+}
+
+func TestValidateStartTLS(t *testing.T) {
+	for _, protocol := range append([]string{""}, StartTLSProtocols...) {
+		if err := ValidateStartTLS(protocol); err != nil {
+			t.Errorf("ValidateStartTLS(%q) = %v", protocol, err)
+		}
+	}
+	if err := ValidateStartTLS("smtps"); err == nil {
+		t.Error("Expected error for an unknown protocol")
+	}
+	if got := StartTLSDefaultPort(StartTLSPostgres); got != 5432 {
+		t.Errorf("StartTLSDefaultPort(postgres) = %d, want 5432", got)
+	}
+}
@@ -1,6 +1,11 @@
 package cert
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
 	"testing"
 	"time"
 )
@@ -122,3 +127,142 @@ func TestTLSVersionComparison(t *testing.T) {
 		t.Error("TLS 1.2 should be less than TLS 1.3")
 	}
 }
+
+// newCipherTestServer starts a TLS 1.2 server accepting only the given suites
+func newCipherTestServer(t *testing.T, suites []uint16) (string, int) {
+	t.Helper()
+	key, err := generatePrivateKey(KeyAlgorithmRSA, 2048)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	leaf := issueTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, key, nil)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{leaf.cert.Raw}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: suites,
+	})
+	if err != nil {
+		t.Fatalf("tls.Listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return "127.0.0.1", addr.Port
+}
+
+func TestCheckTLSCiphers(t *testing.T) {
+	host, port := newCipherTestServer(t, []uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+		tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	})
+
+	result, err := CheckTLSCiphers(host, port, ConnectOptions{Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("CheckTLSCiphers failed: %v", err)
+	}
+	if !result.CiphersChecked {
+		t.Error("CiphersChecked = false")
+	}
+
+	want := map[uint16]string{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256: CipherGradeSecure,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA:    CipherGradeWeak,
+		tls.TLS_RSA_WITH_AES_128_GCM_SHA256:       CipherGradeWeak,
+		tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA:   CipherGradeInsecure,
+	}
+	for _, v := range result.Versions {
+		if v.Version != TLSVersionTLS12 {
+			if v.Supported || len(v.Ciphers) != 0 {
+				t.Errorf("%s: supported %v with %d ciphers, want unsupported", v.Name, v.Supported, len(v.Ciphers))
+			}
+			continue
+		}
+		if !v.CiphersEnumerated || len(v.Ciphers) != len(want) {
+			t.Fatalf("TLS 1.2 ciphers = %+v, want %d", v.Ciphers, len(want))
+		}
+		for _, c := range v.Ciphers {
+			if grade, ok := want[c.ID]; !ok || c.Grade != grade {
+				t.Errorf("%s graded %q, want %q", c.Name, c.Grade, grade)
+			}
+		}
+		if v.CipherOrder == "" {
+			t.Error("CipherOrder not determined")
+		}
+	}
+
+	counts := result.CipherGradeCounts()
+	if counts[CipherGradeSecure] != 1 || counts[CipherGradeWeak] != 2 || counts[CipherGradeInsecure] != 1 {
+		t.Errorf("CipherGradeCounts = %v", counts)
+	}
+	jr := result.ToJSON()
+	if jr.CipherGrades[CipherGradeWeak] != 2 || len(jr.Versions[2].Ciphers) != 4 || jr.Versions[2].CiphersEnumerated == nil {
+		t.Errorf("JSON = %+v", jr)
+	}
+	if jr.Versions[0].Ciphers != nil || jr.Versions[0].CiphersEnumerated != nil {
+		t.Errorf("Unsupported version has cipher data in JSON: %+v", jr.Versions[0])
+	}
+}
+
+func TestCipherOrder(t *testing.T) {
+	gcm := tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+	cbc := tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA
+
+	// crypto/tls always offers AEAD suites before CBC ones
+	if got := cipherOrder(TLSVersionTLS12, []uint16{gcm, cbc}); got != CipherOrderClient {
+		t.Errorf("cipherOrder(GCM, CBC) = %q, want %q", got, CipherOrderClient)
+	}
+	if got := cipherOrder(TLSVersionTLS12, []uint16{cbc, gcm}); got != CipherOrderServer {
+		t.Errorf("cipherOrder(CBC, GCM) = %q, want %q", got, CipherOrderServer)
+	}
+	if got := cipherOrder(TLSVersionTLS12, []uint16{gcm}); got != "" {
+		t.Errorf("cipherOrder(single) = %q, want undetermined", got)
+	}
+}
+
+func TestGradeCipherSuite(t *testing.T) {
+	tests := []struct {
+		id    uint16
+		grade string
+	}{
+		{tls.TLS_AES_128_GCM_SHA256, CipherGradeSecure},
+		{tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256, CipherGradeSecure},
+		{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, CipherGradeSecure},
+		{tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256, CipherGradeWeak},
+		{tls.TLS_RSA_WITH_AES_256_GCM_SHA384, CipherGradeWeak},
+		{tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA, CipherGradeInsecure},
+		{tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA, CipherGradeInsecure},
+	}
+	for _, tt := range tests {
+		info := newCipherSuiteInfo(tt.id)
+		if info.Grade != tt.grade {
+			t.Errorf("%s graded %q, want %q", info.Name, info.Grade, tt.grade)
+		}
+		if (info.Reason == "") != (tt.grade == CipherGradeSecure) {
+			t.Errorf("%s reason = %q", info.Name, info.Reason)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cert
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Watch event types
+const (
+	WatchEventInitial   = "initial"   // first certificate seen for a target
+	WatchEventUnchanged = "unchanged" // same certificate as the last poll
+	WatchEventChanged   = "changed"   // a different certificate is served
+	WatchEventError     = "error"     // the target could not be inspected
+)
+
+// DefaultWatchInterval is the default time between polls
+const DefaultWatchInterval = 30 * time.Second
+
+// watchedFields are the diff fields reported when a served certificate changes
+var watchedFields = map[string]bool{
+	"subject":            true,
+	"issuer":             true,
+	"serial_number":      true,
+	"not_after":          true,
+	"fingerprint_sha256": true,
+}
+
+// WatchOptions configures a watch of remote endpoints
+type WatchOptions struct {
+	Targets  []string       // hostnames, host:port pairs, or URLs
+	Port     int            // default port for targets without one
+	Interval time.Duration  // time between polls
+	Count    int            // number of polls; 0 polls until the context is done
+	Connect  ConnectOptions // timeout, proxy, and other connection settings
+}
+
+// WatchEvent is the result of polling one target
+type WatchEvent struct {
+	Time        time.Time
+	Target      string
+	Type        string // one of the WatchEvent* constants
+	Certificate *Certificate
+	Previous    *Certificate // the certificate served before a change
+	Changes     []FieldDiff  // changed fingerprint, issuer, expiry, subject, or serial
+	Error       error
+}
+
+// Watcher polls targets and compares each certificate with the one seen
+// on the previous successful poll
+type Watcher struct {
+	opts WatchOptions
+	last map[string]*Certificate
+
+	inspect func(host string, port int) (*Certificate, error)
+}
+
+// NewWatcher returns a Watcher for opts
+func NewWatcher(opts WatchOptions) *Watcher {
+	if opts.Port == 0 {
+		opts.Port = 443
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchInterval
+	}
+	w := &Watcher{opts: opts, last: make(map[string]*Certificate)}
+	w.inspect = func(host string, port int) (*Certificate, error) {
+		c, _, err := InspectURLWithConnectOptions(host, port, opts.Connect)
+		return c, err
+	}
+	return w
+}
+
+// Poll inspects every target concurrently and returns one event per
+// target, in target order
+func (w *Watcher) Poll() []WatchEvent {
+	events := make([]WatchEvent, len(w.opts.Targets))
+	var wg sync.WaitGroup
+	for i, target := range w.opts.Targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			host, port := SplitHostTarget(target, w.opts.Port)
+			c, err := w.inspect(host, port)
+			events[i] = WatchEvent{Time: time.Now(), Target: target, Certificate: c, Error: err}
+		}(i, target)
+	}
+	wg.Wait()
+
+	for i := range events {
+		e := &events[i]
+		previous := w.last[e.Target]
+		switch {
+		case e.Error != nil:
+			e.Type = WatchEventError
+			continue
+		case previous == nil:
+			e.Type = WatchEventInitial
+		case string(previous.Raw) == string(e.Certificate.Raw):
+			e.Type = WatchEventUnchanged
+		default:
+			e.Type = WatchEventChanged
+			e.Previous = previous
+			for _, f := range DiffCertificates(previous, e.Certificate).Changes() {
+				if watchedFields[f.Key] {
+					e.Changes = append(e.Changes, f)
+				}
+			}
+		}
+		w.last[e.Target] = e.Certificate
+	}
+	return events
+}
+
+// Run polls every interval, passing each event to handle, until Count polls
+// are done or ctx is cancelled
+func (w *Watcher) Run(ctx context.Context, handle func(WatchEvent)) {
+	for round := 1; ; round++ {
+		for _, e := range w.Poll() {
+			handle(e)
+		}
+		if w.opts.Count > 0 && round >= w.opts.Count {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.opts.Interval):
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package cert
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	key, err := generatePrivateKey(KeyAlgorithmECDSAP256, 0)
+	if err != nil {
+		t.Fatalf("generatePrivateKey failed: %v", err)
+	}
+	issue := func(serial int64, issuer string) *Certificate {
+		c := issueTestCert(t, &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: issuer},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Duration(serial) * 24 * time.Hour),
+		}, key, nil)
+		return &Certificate{Certificate: c.cert}
+	}
+	first, renewed := issue(30, "Old CA"), issue(90, "New CA")
+
+	// Each poll serves the next certificate (or error) from the script
+	script := []struct {
+		c   *Certificate
+		err error
+	}{{first, nil}, {first, nil}, {nil, errors.New("connection refused")}, {renewed, nil}}
+	w := NewWatcher(WatchOptions{Targets: []string{"example.com:8443"}})
+	polls := 0
+	w.inspect = func(host string, port int) (*Certificate, error) {
+		if host != "example.com" || port != 8443 {
+			t.Errorf("inspect(%q, %d)", host, port)
+		}
+		step := script[polls]
+		polls++
+		return step.c, step.err
+	}
+
+	want := []string{WatchEventInitial, WatchEventUnchanged, WatchEventError, WatchEventChanged}
+	var events []WatchEvent
+	for range want {
+		events = append(events, w.Poll()...)
+	}
+	for i, e := range events {
+		if e.Type != want[i] {
+			t.Errorf("poll %d: event %q, want %q", i+1, e.Type, want[i])
+		}
+	}
+
+	// The change is measured against the last certificate seen, across the error
+	changed := events[3]
+	if changed.Previous != first {
+		t.Error("Previous is not the certificate served before the change")
+	}
+	fields := map[string]bool{}
+	for _, f := range changed.Changes {
+		fields[f.Key] = true
+	}
+	for _, key := range []string{"fingerprint_sha256", "issuer", "not_after", "serial_number"} {
+		if !fields[key] {
+			t.Errorf("change to %s not reported", key)
+		}
+	}
+	if fields["validity_days"] || fields["extensions"] {
+		t.Errorf("unwatched fields reported: %v", fields)
+	}
+
+	je := changed.ToJSON()
+	if je.Event != WatchEventChanged || je.PreviousFingerprintSHA256 != first.FingerprintSHA256() || len(je.Changes) != len(changed.Changes) {
+		t.Errorf("JSON event = %+v", je)
+	}
+	if je := events[2].ToJSON(); je.Error != "connection refused" || je.FingerprintSHA256 != "" {
+		t.Errorf("JSON error event = %+v", je)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	w := NewWatcher(WatchOptions{Targets: []string{"a.example.com", "b.example.com"}, Interval: time.Millisecond, Count: 3})
+	w.inspect = func(host string, port int) (*Certificate, error) {
+		return nil, errors.New("unreachable")
+	}
+	var events []WatchEvent
+	w.Run(context.Background(), func(e WatchEvent) { events = append(events, e) })
+	if len(events) != 6 {
+		t.Errorf("got %d events, want 6", len(events))
+	}
+	if events[0].Target != "a.example.com" || events[1].Target != "b.example.com" {
+		t.Errorf("events out of target order: %s, %s", events[0].Target, events[1].Target)
+	}
+
+	// A cancelled context stops after the current poll
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = NewWatcher(WatchOptions{Targets: []string{"a.example.com"}, Interval: time.Hour})
+	w.inspect = func(host string, port int) (*Certificate, error) { return nil, errors.New("unreachable") }
+	count := 0
+	w.Run(ctx, func(WatchEvent) { count++ })
+	if count != 1 {
+		t.Errorf("got %d events after cancel, want 1", count)
+	}
+}
@@ -2,11 +2,13 @@ package ui
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -178,6 +180,9 @@ func DisplayCertificate(cert *cert.Certificate, showFull bool) {
 	if cert.CipherSuite != 0 {
 		table = append(table, []string{"Cipher Suite", tls.CipherSuiteName(cert.CipherSuite)})
 	}
+	if cert.TLSVersion != 0 {
+		table = append(table, []string{"OCSP Staple", formatOCSPStaple(cert.OCSPStaple)})
+	}
 
 	// Add SANs if present
 	if len(cert.DNSNames) > 0 || len(cert.IPAddresses) > 0 {
@@ -223,6 +228,7 @@ func DisplayCertificate(cert *cert.Certificate, showFull bool) {
 
 	if showFull {
 		displayExtensions(cert.Certificate)
+		displayCertificateTransparency(cert)
 	}
 }
 
@@ -256,6 +262,45 @@ func DisplayConversionResult(inputPath, outputPath, fromFormat, toFormat string)
 	fmt.Println(getPanelStyle().Render(content))
 }
 
+// DisplayPKCS12Bundle shows a summary of a PKCS#12 archive's contents
+func DisplayPKCS12Bundle(bundle *cert.PKCS12Bundle) {
+	fmt.Println(getTitleStyle().Render(fmt.Sprintf("PKCS#12 Bundle %s", bundle.Source)))
+	fmt.Println()
+
+	checkmark := getEmoji("✓", "[OK]")
+	crossMark := getEmoji("✗", "[X]")
+
+	table := [][]string{}
+	if bundle.PrivateKey != nil {
+		table = append(table, []string{"Private Key", formatPublicKey(bundle.PrivateKey.Public())})
+		if bundle.KeyMatches() {
+			table = append(table, []string{"Key Match", getSuccessStyle().Render(checkmark + " Matches certificate")})
+		} else {
+			table = append(table, []string{"Key Match", getErrorStyle().Render(crossMark + " Does not match certificate")})
+		}
+	} else {
+		table = append(table, []string{"Private Key", "None (certificate-only trust store)"})
+	}
+	table = append(table, []string{"Certificates", fmt.Sprintf("%d", len(bundle.Certificates()))})
+	if bundle.Certificate != nil {
+		table = append(table, []string{"Leaf", formatSubject(bundle.Certificate.Subject)})
+	}
+	for i, c := range bundle.CACerts {
+		table = append(table, []string{fmt.Sprintf("Chain[%d]", i+1), formatSubject(c.Subject)})
+	}
+
+	width, _, err := term.GetSize(0)
+	if err != nil || width <= 0 {
+		width = 80
+	}
+
+	panel := getPanelStyle().
+		BorderForeground(cyan).
+		Width(width - 4)
+	fmt.Println(panel.Render(formatTable(table)))
+	fmt.Println()
+}
+
 // DisplayVerificationResult shows certificate verification results
 func DisplayVerificationResult(result *cert.VerificationResult) {
 	title := "Verification Results"
@@ -318,6 +363,26 @@ func DisplayVerificationResult(result *cert.VerificationResult) {
 		}
 	}
 
+	// Revocation check
+	if result.OCSP != nil {
+		switch result.OCSP.Status {
+		case certpkg.OCSPStatusGood:
+			checks = append(checks, []string{checkmark2, "OCSP status", getSuccessStyle().Render("GOOD")})
+		case certpkg.OCSPStatusRevoked:
+			checks = append(checks, []string{crossMark2, "OCSP status", getErrorStyle().Render("REVOKED")})
+		default:
+			checks = append(checks, []string{getEmoji("⚠", "[!]"), "OCSP status", getWarningStyle().Render("UNKNOWN")})
+		}
+	}
+
+	if result.CRL != nil {
+		if result.CRL.Revoked != nil {
+			checks = append(checks, []string{crossMark2, "CRL status", getErrorStyle().Render("REVOKED")})
+		} else {
+			checks = append(checks, []string{checkmark2, "CRL status", getSuccessStyle().Render("NOT REVOKED")})
+		}
+	}
+
 	if len(checks) > 0 {
 		fmt.Println(getHeaderStyle().Render("Validation Checks:"))
 		for _, check := range checks {
@@ -326,6 +391,148 @@ func DisplayVerificationResult(result *cert.VerificationResult) {
 	}
 }
 
+// DisplayOCSPResult shows the revocation status returned by an OCSP responder
+func DisplayOCSPResult(result *cert.OCSPResult) {
+	fmt.Println()
+	fmt.Println(getTitleStyle().Render("OCSP Status"))
+	fmt.Println()
+
+	var status string
+	var borderColor lipgloss.Color
+	switch result.Status {
+	case cert.OCSPStatusGood:
+		status = getSuccessStyle().Render(fmt.Sprintf("%s Good", getEmoji("✓", "[OK]")))
+		borderColor = green
+	case cert.OCSPStatusRevoked:
+		status = getErrorStyle().Render(fmt.Sprintf("%s Revoked", getEmoji("✗", "[X]")))
+		borderColor = red
+	default:
+		status = getWarningStyle().Render(fmt.Sprintf("%s Unknown", getEmoji("⚠", "[!]")))
+		borderColor = yellow
+	}
+
+	table := [][]string{
+		{"Status", status},
+		{"Responder", result.Responder},
+	}
+	if result.ResponderName != "" {
+		table = append(table, []string{"Signed By", result.ResponderName})
+	}
+	if result.Status == cert.OCSPStatusRevoked {
+		table = append(table,
+			[]string{"Revoked At", result.RevokedAt.UTC().Format("2006-01-02 15:04:05 UTC")},
+			[]string{"Reason", cert.RevocationReasonName(result.RevocationReason)})
+	}
+	table = append(table, []string{"This Update", result.ThisUpdate.UTC().Format("2006-01-02 15:04:05 UTC")})
+	if !result.NextUpdate.IsZero() {
+		next := result.NextUpdate.UTC().Format("2006-01-02 15:04:05 UTC")
+		if result.IsStale() {
+			next = getWarningStyle().Render(next + " (stale)")
+		}
+		table = append(table, []string{"Next Update", next})
+	}
+
+	width, _, err := term.GetSize(0)
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	panel := getPanelStyle().
+		BorderForeground(borderColor).
+		Width(width - 4)
+	fmt.Println(panel.Render(formatTable(table)))
+}
+
+// DisplayClientCertRequest shows a server's request for a client
+// certificate: the CA names and signature schemes it accepts
+func DisplayClientCertRequest(req *cert.ClientCertRequest) {
+	fmt.Println()
+	fmt.Println(getTitleStyle().Render("Client Certificate Request"))
+	fmt.Println()
+
+	// Continuation lines line up with the values of formatTable
+	indent := "\n" + strings.Repeat(" ", len("Signature Schemes")+2)
+
+	sent := getWarningStyle().Render(fmt.Sprintf("%s No", getEmoji("⚠", "[!]")))
+	borderColor := yellow
+	if req.CertificateSent {
+		sent = getSuccessStyle().Render(fmt.Sprintf("%s Yes", getEmoji("✓", "[OK]")))
+		borderColor = cyan
+	}
+	acceptableCAs := "Any (no CA names sent)"
+	if len(req.AcceptableCAs) > 0 {
+		acceptableCAs = strings.Join(req.AcceptableCAs, indent)
+	}
+	table := [][]string{
+		{"Certificate Sent", sent},
+		{"Acceptable CAs", acceptableCAs},
+		{"Signature Schemes", strings.Join(req.SignatureSchemeNames(), indent)},
+	}
+
+	width, _, err := term.GetSize(0)
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	panel := getPanelStyle().
+		BorderForeground(borderColor).
+		Width(width - 4)
+	fmt.Println(panel.Render(formatTable(table)))
+}
+
+// DisplayCRL shows a certificate revocation list and its revoked entries
+func DisplayCRL(info *cert.CRLInfo) {
+	fmt.Println(getTitleStyle().Render("Certificate Revocation List"))
+	fmt.Println()
+
+	borderColor := green
+	table := [][]string{
+		{"Issuer", formatSubject(info.Issuer)},
+	}
+	if info.Number != nil {
+		table = append(table, []string{"CRL Number", info.Number.String()})
+	}
+	table = append(table, []string{"This Update", info.ThisUpdate.UTC().Format("2006-01-02 15:04:05 UTC")})
+	if !info.NextUpdate.IsZero() {
+		next := info.NextUpdate.UTC().Format("2006-01-02 15:04:05 UTC")
+		if info.IsStale() {
+			next = getWarningStyle().Render(next + " (stale)")
+			borderColor = yellow
+		}
+		table = append(table, []string{"Next Update", next})
+	}
+	table = append(table, []string{"Signature Algorithm", info.SignatureAlgorithm})
+	switch {
+	case !info.SignatureChecked:
+		table = append(table, []string{"Signature", getWarningStyle().Render("not checked (use --ca)")})
+	case info.SignatureValid:
+		table = append(table, []string{"Signature", getSuccessStyle().Render(fmt.Sprintf("%s Valid", getEmoji("✓", "[OK]")))})
+	default:
+		table = append(table, []string{"Signature", getErrorStyle().Render(fmt.Sprintf("%s Invalid", getEmoji("✗", "[X]")))})
+		borderColor = red
+	}
+	table = append(table, []string{"Revoked", fmt.Sprintf("%d certificate(s)", len(info.Entries))})
+
+	width, _, err := term.GetSize(0)
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	panel := getPanelStyle().
+		BorderForeground(borderColor).
+		Width(width - 4)
+	fmt.Println(panel.Render(formatTable(table)))
+
+	if len(info.Entries) > 0 {
+		fmt.Println()
+		fmt.Println(getHeaderStyle().Render("Revoked Certificates:"))
+		for _, e := range info.Entries {
+			fmt.Printf("  %s %s  %s  %s\n",
+				getErrorStyle().Render(getEmoji("✗", "[X]")),
+				getValueStyle().Render(e.SerialNumber.Text(16)),
+				e.RevokedAt.UTC().Format("2006-01-02 15:04:05 UTC"),
+				getKeyStyle().Render(cert.RevocationReasonName(e.Reason)))
+		}
+	}
+}
+
 // ShowError displays an error message
 func ShowError(message string) {
 	fmt.Println(getErrorStyle().Render(fmt.Sprintf("Error: %s", message)))
@@ -418,11 +625,25 @@ func formatPublicKey(pubKey interface{}) string {
 		return fmt.Sprintf("RSA %d bits", key.Size()*8)
 	case *ecdsa.PublicKey:
 		return fmt.Sprintf("ECDSA %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "Ed25519"
 	default:
 		return "Unknown"
 	}
 }
 
+// formatCSRPublicKey formats the public key summary of a parsed CSR
+func formatCSRPublicKey(info *cert.CSRInfo) string {
+	switch {
+	case info.Curve != "":
+		return fmt.Sprintf("%s %s", info.PublicKeyAlgorithm, info.Curve)
+	case info.PublicKeyAlgorithm == "Ed25519":
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%s %d bits", info.PublicKeyAlgorithm, info.KeySize)
+	}
+}
+
 // wrapFingerprint wraps a colon-separated fingerprint on byte boundaries
 // so it fits the panel, indenting continuation lines to the value column
 // (same 22-space alignment as formatSANs).
@@ -573,6 +794,242 @@ func DisplayCertificateChain(chain []*cert.Certificate) {
 	}
 }
 
+// DisplayChainAnalysis shows chain order, completeness, trust, and the
+// validated path to a trust anchor
+func DisplayChainAnalysis(a *cert.ChainAnalysis) {
+	fmt.Println()
+	fmt.Println(getTitleStyle().Render("Chain Analysis"))
+	fmt.Println()
+
+	checkmark := getEmoji("✓", "[OK]")
+	crossMark := getEmoji("✗", "[X]")
+	status := func(ok bool, good, bad string) string {
+		if ok {
+			return getSuccessStyle().Render(fmt.Sprintf("%s %s", checkmark, good))
+		}
+		return getErrorStyle().Render(fmt.Sprintf("%s %s", crossMark, bad))
+	}
+
+	table := [][]string{
+		{"Order", status(a.Ordered, "Correct", "Out of order")},
+		{"Completeness", status(a.Complete, "Complete", "Missing intermediates")},
+		{"Trust", status(a.Trusted, "Trusted by "+a.TrustSource, "Not trusted by "+a.TrustSource)},
+	}
+
+	arrow := getEmoji("→", "->")
+	for i, c := range a.Path {
+		label := "Path"
+		if i > 0 {
+			label = ""
+		}
+		name := formatSubject(c.Subject)
+		switch {
+		case i == len(a.Path)-1 && !a.PathSent[i]:
+			name += " (trust store)"
+		case !a.PathSent[i]:
+			name += " (not sent)"
+		}
+		table = append(table, []string{label, fmt.Sprintf("%s %s", arrow, name)})
+	}
+
+	borderColor := green
+	if !a.Trusted || len(a.Errors) > 0 {
+		borderColor = red
+	} else if len(a.Warnings) > 0 {
+		borderColor = yellow
+	}
+
+	width, _, err := term.GetSize(0)
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	panel := getPanelStyle().
+		BorderForeground(borderColor).
+		Width(width - 4)
+	fmt.Println(panel.Render(formatTable(table)))
+
+	if len(a.Errors) > 0 {
+		fmt.Println()
+		for _, e := range a.Errors {
+			fmt.Printf("  %s %s\n", getErrorStyle().Render(crossMark), e)
+		}
+	}
+	if len(a.Warnings) > 0 {
+		fmt.Println()
+		warnSymbol := getEmoji("⚠", "[!]")
+		for _, w := range a.Warnings {
+			fmt.Printf("  %s %s\n", getWarningStyle().Render(warnSymbol), w)
+		}
+	}
+}
+
+// DisplayCertificateDiff shows a field-by-field comparison of two
+// certificates: removed values in red, added values in green
+func DisplayCertificateDiff(d *cert.CertificateDiff) {
+	fmt.Println(getTitleStyle().Render("Certificate Diff"))
+	fmt.Println()
+
+	minus := getErrorStyle().Render("-")
+	plus := getSuccessStyle().Render("+")
+	changes := d.Changes()
+
+	borderColor := green
+	summary := getSuccessStyle().Render(fmt.Sprintf("%s Identical", getEmoji("✓", "[OK]")))
+	if len(changes) > 0 {
+		borderColor = yellow
+		summary = getWarningStyle().Render(fmt.Sprintf("%d of %d fields changed", len(changes), len(d.Fields)))
+	} else if !d.Identical() {
+		summary = getSuccessStyle().Render(fmt.Sprintf("%s No field changes (re-encoded or re-signed)", getEmoji("✓", "[OK]")))
+	}
+	table := [][]string{
+		{"Old", fmt.Sprintf("%s %s", minus, d.Old.Source)},
+		{"New", fmt.Sprintf("%s %s", plus, d.New.Source)},
+		{"Result", summary},
+	}
+
+	width, _, err := term.GetSize(0)
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	panel := getPanelStyle().
+		BorderForeground(borderColor).
+		Width(width - 4)
+	fmt.Println(panel.Render(formatTable(table)))
+
+	for _, f := range changes {
+		fmt.Println()
+		fmt.Println(getKeyStyle().Render(f.Name))
+		removed, added := f.Old, f.New
+		if f.List {
+			removed, added = f.Removed, f.Added
+		}
+		for _, v := range removed {
+			fmt.Printf("  %s %s\n", minus, getErrorStyle().Render(v))
+		}
+		for _, v := range added {
+			fmt.Printf("  %s %s\n", plus, getSuccessStyle().Render(v))
+		}
+		if kept := len(f.New) - len(f.Added); f.List && kept > 0 {
+			fmt.Printf("    (%d unchanged)\n", kept)
+		}
+	}
+
+	var unchanged []string
+	for _, f := range d.Fields {
+		if !f.Changed() {
+			unchanged = append(unchanged, f.Name)
+		}
+	}
+	if len(unchanged) > 0 && len(changes) > 0 {
+		fmt.Println()
+		fmt.Printf("%s %s\n", getKeyStyle().Render("Unchanged:"), strings.Join(unchanged, ", "))
+	}
+}
+
+// DisplayWatchEvent prints one line per poll of a watched target. Changes
+// to the served certificate are highlighted with the old and new values.
+func DisplayWatchEvent(e *cert.WatchEvent) {
+	prefix := fmt.Sprintf("%s  %s", e.Time.Format("15:04:05"), getKeyStyle().Render(e.Target))
+
+	switch e.Type {
+	case cert.WatchEventError:
+		fmt.Printf("%s  %s\n", prefix, getErrorStyle().Render(fmt.Sprintf("%s %s", getEmoji("✗", "[X]"), e.Error)))
+		return
+	case cert.WatchEventUnchanged:
+		fmt.Printf("%s  %s unchanged, expires %s\n", prefix, getEmoji("·", "-"), formatWatchExpiry(e.Certificate))
+		return
+	}
+
+	c := e.Certificate
+	if e.Type == cert.WatchEventChanged {
+		fmt.Printf("%s  %s\n", prefix, getWarningStyle().Render(fmt.Sprintf("%s Certificate changed", getEmoji("⚠", "[!]"))))
+		minus := getErrorStyle().Render("-")
+		plus := getSuccessStyle().Render("+")
+		for _, f := range e.Changes {
+			fmt.Printf("    %s\n", getKeyStyle().Render(f.Name))
+			fmt.Printf("      %s %s\n", minus, getErrorStyle().Render(f.Old[0]))
+			fmt.Printf("      %s %s\n", plus, getSuccessStyle().Render(f.New[0]))
+		}
+		fmt.Printf("    Expires %s\n", formatWatchExpiry(c))
+		return
+	}
+
+	fmt.Printf("%s  %s %s\n", prefix, getSuccessStyle().Render(getEmoji("✓", "[OK]")), formatSubject(c.Subject))
+	table := [][]string{
+		{"Issuer", formatSubject(c.Issuer)},
+		{"Expires", formatWatchExpiry(c)},
+		{"SHA-256", c.FingerprintSHA256()},
+	}
+	for _, line := range strings.Split(formatTable(table), "\n") {
+		fmt.Printf("    %s\n", line)
+	}
+}
+
+// formatOCSPStaple summarizes a stapled OCSP response: its status, when it
+// was produced, and how long until the next update
+func formatOCSPStaple(staple *cert.OCSPStaple) string {
+	if staple == nil {
+		return "Not stapled"
+	}
+	if staple.Result == nil {
+		return getErrorStyle().Render("INVALID: " + staple.Error)
+	}
+
+	r := staple.Result
+	var status string
+	switch r.Status {
+	case cert.OCSPStatusGood:
+		status = getSuccessStyle().Render("GOOD")
+	case cert.OCSPStatusRevoked:
+		status = getErrorStyle().Render("REVOKED")
+	default:
+		status = getWarningStyle().Render("UNKNOWN")
+	}
+
+	var freshness string
+	switch {
+	case r.NextUpdate.IsZero():
+		freshness = "no next update"
+	case r.IsStale():
+		freshness = getWarningStyle().Render("stale for " + formatApproxDuration(time.Since(r.NextUpdate)))
+	default:
+		freshness = "next update in " + formatApproxDuration(time.Until(r.NextUpdate))
+	}
+
+	indent := "\n" + strings.Repeat(" ", 22)
+	text := fmt.Sprintf("%s, %s%sproduced %s", status, freshness, indent, r.ProducedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	if !staple.SignatureValid {
+		text += indent + getWarningStyle().Render(fmt.Sprintf("%s Unverified: %s", getEmoji("⚠", "[!]"), staple.Error))
+	}
+	return text
+}
+
+// formatApproxDuration renders a duration in days, or hours and minutes
+// when under two days
+func formatApproxDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%d days", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d minutes", int(d.Minutes()))
+}
+
+// formatWatchExpiry renders a certificate's expiry date and days remaining,
+// colored like the certificate status
+func formatWatchExpiry(c *cert.Certificate) string {
+	date := c.NotAfter.UTC().Format("2006-01-02")
+	text := fmt.Sprintf("%s (%d days)", date, c.DaysUntilExpiry)
+	switch {
+	case c.IsExpired:
+		return getErrorStyle().Render(date + " (expired)")
+	case c.DaysUntilExpiry < 30:
+		return getWarningStyle().Render(text)
+	}
+	return text
+}
+
 // displayExtensions shows certificate extensions (for --full output)
 func displayExtensions(cert *x509.Certificate) {
 	if len(cert.Extensions) == 0 {
@@ -590,6 +1047,65 @@ func displayExtensions(cert *x509.Certificate) {
 	displayUnparsedExtensions(cert)
 }
 
+// displayCertificateTransparency shows the certificate's SCTs and, once
+// they have been verified against a log list, the CT policy result
+func displayCertificateTransparency(c *cert.Certificate) {
+	if len(c.SCTs) == 0 && c.CTPolicy == nil {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(getHeaderStyle().Render("Certificate Transparency"))
+	fmt.Println()
+
+	arrow := getEmoji("→", "->")
+	if len(c.SCTs) == 0 {
+		fmt.Println("  No SCTs found")
+	}
+	for _, sct := range c.SCTs {
+		log := sct.LogIDBase64()
+		if sct.Log != nil {
+			log = fmt.Sprintf("%s (%s)", sct.Log.Description, sct.Log.Operator)
+		}
+		fmt.Printf("  %s %s\n", getValueStyle().Render(arrow), log)
+		fmt.Printf("      Source: %s, Timestamp: %s, Signature: %s\n",
+			sct.Source, sct.Timestamp.UTC().Format("2006-01-02 15:04:05 UTC"), sct.SignatureAlgorithmName())
+
+		var status string
+		switch sct.Status {
+		case cert.SCTStatusValid:
+			status = getSuccessStyle().Render(fmt.Sprintf("%s Valid", getEmoji("✓", "[OK]")))
+		case cert.SCTStatusInvalid:
+			status = getErrorStyle().Render(fmt.Sprintf("%s Invalid", getEmoji("✗", "[X]")))
+		case cert.SCTStatusUnknownLog:
+			status = getWarningStyle().Render(fmt.Sprintf("%s Unknown log", getEmoji("⚠", "[!]")))
+		default:
+			status = "Not verified"
+		}
+		if sct.Error != "" {
+			status += ": " + sct.Error
+		}
+		fmt.Printf("      Status: %s\n", status)
+	}
+
+	if c.CTPolicy == nil {
+		fmt.Println()
+		fmt.Println(getValueStyle().Render("  Use --ct-log-list to verify SCTs and check the browser CT policy"))
+		return
+	}
+	fmt.Println()
+	policy := c.CTPolicy
+	summary := fmt.Sprintf("%d of %d SCTs (%s), %d operator(s)", policy.Valid, policy.Required, policy.Source, policy.Operators)
+	if policy.Compliant {
+		fmt.Printf("  CT Policy: %s\n", getSuccessStyle().Render(fmt.Sprintf("%s Compliant", getEmoji("✓", "[OK]")))+" - "+summary)
+		return
+	}
+	fmt.Printf("  CT Policy: %s\n", getErrorStyle().Render(fmt.Sprintf("%s Not compliant", getEmoji("✗", "[X]")))+" - "+summary)
+	for _, problem := range policy.Problems {
+		fmt.Printf("    %s %s\n", getValueStyle().Render(arrow), problem)
+	}
+}
+
 // displayParsedExtensions shows well-known extensions with their values
 func displayParsedExtensions(cert *x509.Certificate) {
 	// Key Usage
@@ -625,6 +1141,13 @@ func displayParsedExtensions(cert *x509.Certificate) {
 		fmt.Println()
 	}
 
+	// Name Constraints
+	if hasNameConstraints(cert) {
+		fmt.Println(getKeyStyle().Render("Name Constraints") + getCriticalLabel(isExtensionCritical(cert, "2.5.29.30")))
+		displayNameConstraints(cert)
+		fmt.Println()
+	}
+
 	// Subject Alternative Names (skip if already shown in main display)
 	// We show a summary here since full list is in main display
 	if len(cert.DNSNames) > 0 || len(cert.IPAddresses) > 0 || len(cert.EmailAddresses) > 0 || len(cert.URIs) > 0 {
@@ -691,6 +1214,45 @@ func displayParsedExtensions(cert *x509.Certificate) {
 	}
 }
 
+// hasNameConstraints reports whether the certificate constrains any names
+func hasNameConstraints(cert *x509.Certificate) bool {
+	return len(cert.PermittedDNSDomains)+len(cert.ExcludedDNSDomains)+
+		len(cert.PermittedIPRanges)+len(cert.ExcludedIPRanges)+
+		len(cert.PermittedEmailAddresses)+len(cert.ExcludedEmailAddresses)+
+		len(cert.PermittedURIDomains)+len(cert.ExcludedURIDomains) > 0
+}
+
+// displayNameConstraints shows the permitted and excluded name subtrees
+func displayNameConstraints(cert *x509.Certificate) {
+	checkmark := getEmoji("✓", "[OK]")
+	crossMark := getEmoji("✗", "[X]")
+	show := func(mark, label string, values []string, excluded bool) {
+		style := getSuccessStyle()
+		if excluded {
+			style = getErrorStyle()
+		}
+		for _, v := range values {
+			fmt.Printf("  %s %s %s\n", style.Render(mark), getValueStyle().Render(label), v)
+		}
+	}
+	ipRanges := func(nets []*net.IPNet) []string {
+		var out []string
+		for _, n := range nets {
+			out = append(out, n.String())
+		}
+		return out
+	}
+
+	show(checkmark, "Permitted DNS:", cert.PermittedDNSDomains, false)
+	show(checkmark, "Permitted IP:", ipRanges(cert.PermittedIPRanges), false)
+	show(checkmark, "Permitted Email:", cert.PermittedEmailAddresses, false)
+	show(checkmark, "Permitted URI:", cert.PermittedURIDomains, false)
+	show(crossMark, "Excluded DNS:", cert.ExcludedDNSDomains, true)
+	show(crossMark, "Excluded IP:", ipRanges(cert.ExcludedIPRanges), true)
+	show(crossMark, "Excluded Email:", cert.ExcludedEmailAddresses, true)
+	show(crossMark, "Excluded URI:", cert.ExcludedURIDomains, true)
+}
+
 // displayKeyUsage shows the key usage flags
 func displayKeyUsage(usage x509.KeyUsage) {
 	checkmark := getEmoji("✓", "[OK]")
@@ -774,6 +1336,7 @@ func displayUnparsedExtensions(cert *x509.Certificate) {
 		"2.5.29.17":         true, // SAN
 		"2.5.29.19":         true, // Basic Constraints
 		"2.5.29.37":         true, // Extended Key Usage
+		"2.5.29.30":         hasNameConstraints(cert),
 		"2.5.29.31":         true, // CRL Distribution Points
 		"2.5.29.32":         true, // Certificate Policies
 		"1.3.6.1.5.5.7.1.1": true, // Authority Info Access
@@ -846,7 +1409,7 @@ func DisplayCSRInfo(info *cert.CSRInfo) {
 	table := [][]string{
 		{"Subject", formatSubject(info.Subject)},
 		{"Signature Algorithm", info.SignatureAlgorithm},
-		{"Public Key", fmt.Sprintf("%s %d bits", info.PublicKeyAlgorithm, info.KeySize)},
+		{"Public Key", formatCSRPublicKey(info)},
 	}
 
 	// Add SANs if present
@@ -911,6 +1474,13 @@ func DisplayTLSVersionResults(result *cert.TLSResult) {
 		Width(width - 4)
 	fmt.Println(panel.Render(content))
 
+	if result.CiphersChecked {
+		displayCipherSuites(result)
+	}
+	if result.ClientCertRequest != nil {
+		DisplayClientCertRequest(result.ClientCertRequest)
+	}
+
 	// Show summary
 	fmt.Println()
 	fmt.Println(getHeaderStyle().Render("Summary"))
@@ -929,9 +1499,20 @@ func DisplayTLSVersionResults(result *cert.TLSResult) {
 	// Security recommendations
 	fmt.Println()
 	recommendations := []string{}
+	legacyVersions := false
 	for _, v := range result.Versions {
 		if v.Supported && (v.Version == cert.TLSVersionTLS10 || v.Version == cert.TLSVersionTLS11) {
 			recommendations = append(recommendations, fmt.Sprintf(" %s is enabled but deprecated", v.Name))
+			legacyVersions = true
+		}
+	}
+	counts := result.CipherGradeCounts()
+	if result.CiphersChecked {
+		if counts[cert.CipherGradeInsecure] > 0 {
+			recommendations = append(recommendations, fmt.Sprintf(" %d insecure cipher suite(s) accepted (RC4, 3DES)", counts[cert.CipherGradeInsecure]))
+		}
+		if counts[cert.CipherGradeWeak] > 0 {
+			recommendations = append(recommendations, fmt.Sprintf(" %d weak cipher suite(s) accepted (CBC mode or no forward secrecy)", counts[cert.CipherGradeWeak]))
 		}
 	}
 
@@ -942,8 +1523,272 @@ func DisplayTLSVersionResults(result *cert.TLSResult) {
 			fmt.Printf("  %s%s\n", getWarningStyle().Render(arrow), rec)
 		}
 		fmt.Println()
-		fmt.Println(getKeyStyle().Render("Recommendation: Consider disabling TLS 1.0 and TLS 1.1 for improved security."))
+		if legacyVersions {
+			fmt.Println(getKeyStyle().Render("Recommendation: Consider disabling TLS 1.0 and TLS 1.1 for improved security."))
+		}
+		if counts[cert.CipherGradeInsecure]+counts[cert.CipherGradeWeak] > 0 {
+			fmt.Println(getKeyStyle().Render("Recommendation: Only offer ECDHE suites with AES-GCM or ChaCha20-Poly1305."))
+		}
+	}
+}
+
+// displayCipherSuites shows the accepted cipher suites of each supported
+// version, graded, in the order the server picked them
+func displayCipherSuites(result *cert.TLSResult) {
+	for _, v := range result.Versions {
+		if !v.Supported {
+			continue
+		}
+		fmt.Println()
+		title := v.Name + " Cipher Suites"
+		switch v.CipherOrder {
+		case cert.CipherOrderServer:
+			title += " (server preference order)"
+		case cert.CipherOrderClient:
+			title += " (server follows client order)"
+		}
+		fmt.Println(getHeaderStyle().Render(title))
+		fmt.Println()
+
+		if len(v.Ciphers) == 0 {
+			fmt.Println(getWarningStyle().Render("  No cipher suites could be enumerated"))
+			continue
+		}
+
+		header := []string{"#", "CIPHER SUITE", "GRADE", "NOTE"}
+		rows := make([][]string, 0, len(v.Ciphers))
+		styles := make([]lipgloss.Style, 0, len(v.Ciphers))
+		for i, c := range v.Ciphers {
+			rows = append(rows, []string{fmt.Sprintf("%d", i+1), c.Name, c.Grade, c.Reason})
+			switch c.Grade {
+			case cert.CipherGradeInsecure:
+				styles = append(styles, getErrorStyle())
+			case cert.CipherGradeWeak:
+				styles = append(styles, getWarningStyle())
+			default:
+				styles = append(styles, getSuccessStyle())
+			}
+		}
+		printColumns(header, rows, styles)
+		if !v.CiphersEnumerated {
+			fmt.Println(getValueStyle().Render("  Only the negotiated suite is shown; TLS 1.3 suites can't be restricted by the client"))
+		}
+	}
+}
+
+// DisplayScanReport shows a batch expiry scan as a table sorted by expiry
+func DisplayScanReport(report *cert.ScanReport) {
+	fmt.Println(getTitleStyle().Render("Certificate Expiry Scan"))
+	fmt.Println()
+
+	if len(report.Results) == 0 {
+		fmt.Println(getWarningStyle().Render("No certificates found"))
+		return
+	}
+
+	header := []string{"DAYS", "EXPIRES", "SUBJECT", "SOURCE"}
+	rows := make([][]string, 0, len(report.Results))
+	styles := make([]lipgloss.Style, 0, len(report.Results))
+	for _, res := range report.Results {
+		source := res.Target
+		if res.Index > 0 {
+			source = fmt.Sprintf("%s [%d]", source, res.Index)
+		}
+
+		if res.Error != nil {
+			rows = append(rows, []string{"-", "ERROR", res.Error.Error(), source})
+			styles = append(styles, getErrorStyle())
+			continue
+		}
+
+		c := res.Certificate
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", c.DaysUntilExpiry),
+			c.NotAfter.Format("2006-01-02"),
+			formatSubject(c.Subject),
+			source,
+		})
+		switch {
+		case c.IsExpired:
+			styles = append(styles, getErrorStyle())
+		case res.WithinThreshold:
+			styles = append(styles, getWarningStyle())
+		default:
+			styles = append(styles, getValueStyle())
+		}
+	}
+
+	printColumns(header, rows, styles)
+
+	// Summary
+	fmt.Println()
+	thresholdDays := int(report.Threshold.Hours() / 24)
+	summary := fmt.Sprintf("%d certificate(s) scanned, %d expired or expiring within %d days, %d error(s)",
+		len(report.Results)-report.Errors(), report.Expiring(), thresholdDays, report.Errors())
+	if report.Failed() {
+		fmt.Println(getWarningStyle().Render(fmt.Sprintf("%s %s", getEmoji("⚠", "[!]"), summary)))
+	} else {
+		fmt.Println(getSuccessStyle().Render(fmt.Sprintf("%s %s", getEmoji("✓", "[OK]"), summary)))
+	}
+}
+
+// printColumns prints rows under a header as aligned columns, rendering
+// each row in its style. Widths come from the unstyled text so ANSI codes
+// don't skew alignment.
+func printColumns(header []string, rows [][]string, styles []lipgloss.Style) {
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	formatRow := func(row []string) string {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if i == len(row)-1 {
+				cells[i] = cell
+			} else {
+				cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+			}
+		}
+		return strings.Join(cells, "  ")
+	}
+
+	fmt.Println(getHeaderStyle().Render(formatRow(header)))
+	for i, row := range rows {
+		fmt.Println(styles[i].Render(formatRow(row)))
+	}
+}
+
+// DisplayCAIndex shows certificates from a CA's issuance index
+func DisplayCAIndex(caSubject string, entries []cert.IndexEntry) {
+	fmt.Println(getTitleStyle().Render("Issued Certificates"))
+	fmt.Println()
+	fmt.Printf("%s %s\n", getKeyStyle().Render("CA:"), caSubject)
+	fmt.Println()
+
+	if len(entries) == 0 {
+		fmt.Println(getWarningStyle().Render("No matching certificates"))
+		return
+	}
+
+	now := time.Now()
+	header := []string{"SERIAL", "STATUS", "EXPIRES", "DAYS", "SUBJECT"}
+	rows := make([][]string, 0, len(entries))
+	styles := make([]lipgloss.Style, 0, len(entries))
+	counts := map[string]int{}
+	for i := range entries {
+		e := &entries[i]
+		status := e.Status(now)
+		counts[status]++
+		subject := e.Subject
+		if len(e.SANs) > 0 {
+			subject = fmt.Sprintf("%s (%s)", subject, strings.Join(e.SANs, ", "))
+		}
+		rows = append(rows, []string{
+			e.SerialNumber.Text(16),
+			status,
+			e.NotAfter.Format("2006-01-02"),
+			fmt.Sprintf("%d", int(e.NotAfter.Sub(now).Hours()/24)),
+			subject,
+		})
+		switch status {
+		case cert.CertStatusRevoked, cert.CertStatusExpired:
+			styles = append(styles, getErrorStyle())
+		default:
+			styles = append(styles, getValueStyle())
+		}
+	}
+	printColumns(header, rows, styles)
+
+	fmt.Println()
+	fmt.Printf("%d certificate(s): %d valid, %d expired, %d revoked\n", len(entries),
+		counts[cert.CertStatusValid], counts[cert.CertStatusExpired], counts[cert.CertStatusRevoked])
+}
+
+// DisplayProfiles shows the issuance profiles available to cert sign
+func DisplayProfiles(profiles []*cert.Profile, defaultProfile string) {
+	fmt.Println(getTitleStyle().Render("Issuance Profiles"))
+	fmt.Println()
+
+	header := []string{"PROFILE", "KEY USAGE", "EXTENDED KEY USAGE", "DESCRIPTION"}
+	rows := make([][]string, 0, len(profiles))
+	styles := make([]lipgloss.Style, 0, len(profiles))
+	for _, p := range profiles {
+		name := p.Name
+		if name == defaultProfile {
+			name += " (default)"
+		}
+		description := p.Description
+		if !p.Builtin {
+			description += " [config]"
+		}
+		if p.Days > 0 {
+			description += fmt.Sprintf(", %d days", p.Days)
+		}
+		rows = append(rows, []string{
+			name,
+			strings.Join(p.KeyUsageNames(), ", "),
+			strings.Join(p.ExtKeyUsageNames(), ", "),
+			description,
+		})
+		styles = append(styles, getValueStyle())
+	}
+	printColumns(header, rows, styles)
+}
+
+// DisplayLintResult shows the lint findings for one certificate
+func DisplayLintResult(r *cert.LintResult) {
+	title := fmt.Sprintf("Lint: %s", formatSubject(r.Certificate.Subject))
+	if r.Certificate.Source != "" {
+		title += fmt.Sprintf(" (%s)", r.Certificate.Source)
+	}
+	fmt.Println(getTitleStyle().Render(title))
+	fmt.Println()
+
+	errors, warnings := r.Count(cert.LintSeverityError), r.Count(cert.LintSeverityWarning)
+	summary := fmt.Sprintf("%d error(s), %d warning(s) from %d rule(s)", errors, warnings, len(r.Rules))
+	if len(r.Findings) == 0 {
+		fmt.Println(getSuccessStyle().Render(fmt.Sprintf("%s No problems found (%d rules checked)", getEmoji("✓", "[OK]"), len(r.Rules))))
+		return
+	}
+
+	header := []string{"SEVERITY", "RULE", "MESSAGE"}
+	rows := make([][]string, 0, len(r.Findings))
+	styles := make([]lipgloss.Style, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		rows = append(rows, []string{f.Rule.Severity, f.Rule.ID, f.Message})
+		if f.Rule.Severity == cert.LintSeverityError {
+			styles = append(styles, getErrorStyle())
+		} else {
+			styles = append(styles, getWarningStyle())
+		}
+	}
+	printColumns(header, rows, styles)
+
+	fmt.Println()
+	if errors > 0 {
+		fmt.Println(getErrorStyle().Render(fmt.Sprintf("%s %s", getEmoji("✗", "[X]"), summary)))
+	} else {
+		fmt.Println(getWarningStyle().Render(fmt.Sprintf("%s %s", getEmoji("⚠", "[!]"), summary)))
+	}
+}
+
+// DisplayLintRules lists the available lint rules
+func DisplayLintRules(rules []*cert.LintRule) {
+	fmt.Println(getTitleStyle().Render("Lint Rules"))
+	fmt.Println()
+
+	header := []string{"RULE", "SEVERITY", "APPLIES TO", "REFERENCE", "DESCRIPTION"}
+	rows := make([][]string, 0, len(rules))
+	styles := make([]lipgloss.Style, 0, len(rules))
+	for _, r := range rules {
+		rows = append(rows, []string{r.ID, r.Severity, r.AppliesTo(), r.Reference, r.Description})
+		styles = append(styles, getValueStyle())
 	}
+	printColumns(header, rows, styles)
 }
 
 // tlsVersionNames is a helper to get version names
@@ -3,9 +3,11 @@ package ui
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"fmt"
@@ -122,6 +124,14 @@ func TestFormatPublicKey(t *testing.T) {
 			}(),
 			expected: "ECDSA P-384",
 		},
+		{
+			name: "Ed25519",
+			key: func() interface{} {
+				pub, _, _ := ed25519.GenerateKey(rand.Reader)
+				return pub
+			}(),
+			expected: "Ed25519",
+		},
 		{
 			name:     "Unknown key type",
 			key:      "not a key",
@@ -139,6 +149,26 @@ func TestFormatPublicKey(t *testing.T) {
 	}
 }
 
+func TestFormatCSRPublicKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     cert.CSRInfo
+		expected string
+	}{
+		{"RSA", cert.CSRInfo{PublicKeyAlgorithm: "RSA", KeySize: 2048}, "RSA 2048 bits"},
+		{"ECDSA", cert.CSRInfo{PublicKeyAlgorithm: "ECDSA", KeySize: 256, Curve: "P-256"}, "ECDSA P-256"},
+		{"Ed25519", cert.CSRInfo{PublicKeyAlgorithm: "Ed25519", KeySize: 256}, "Ed25519"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatCSRPublicKey(&tt.info); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestFormatSANs(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -424,6 +454,36 @@ func TestDisplayCertificate(t *testing.T) {
 		DaysUntilExpiry: 30,
 	}
 
+	sctCert := *testCert
+	sctCert.SCTs = []*cert.SCT{{
+		Source:             cert.SCTSourceEmbedded,
+		Timestamp:          now,
+		HashAlgorithm:      4,
+		SignatureAlgorithm: 3,
+		Log:                &cert.CTLog{Description: "Example Log", Operator: "Example Operator"},
+		Status:             cert.SCTStatusValid,
+	}}
+	sctCert.CTPolicy = &cert.CTPolicyResult{
+		Source:    cert.SCTSourceEmbedded,
+		Required:  2,
+		Valid:     1,
+		Operators: 1,
+		Problems:  []string{"SCTs from 1 log operator(s); 2 required"},
+	}
+
+	stapledCert := *testCert
+	stapledCert.TLSVersion = tls.VersionTLS13
+	stapledCert.OCSPStaple = &cert.OCSPStaple{
+		Result: &cert.OCSPResult{
+			Status:     cert.OCSPStatusGood,
+			ProducedAt: now.Add(-time.Hour),
+			NextUpdate: now.Add(-time.Minute),
+		},
+		Error: "issuer not in the served chain",
+	}
+	unstapledCert := *testCert
+	unstapledCert.TLSVersion = tls.VersionTLS13
+
 	tests := []struct {
 		name     string
 		cert     *cert.Certificate
@@ -455,6 +515,42 @@ func TestDisplayCertificate(t *testing.T) {
 				"test.example.com",
 			},
 		},
+		{
+			name:     "Stale unverified OCSP staple",
+			cert:     &stapledCert,
+			showFull: false,
+			checks: []string{
+				"OCSP Staple",
+				"GOOD",
+				"stale for",
+				"produced",
+				"Unverified: issuer not in the served chain",
+			},
+		},
+		{
+			name:     "No OCSP staple",
+			cert:     &unstapledCert,
+			showFull: false,
+			checks: []string{
+				"OCSP Staple",
+				"Not stapled",
+			},
+		},
+		{
+			name:     "Full display with SCTs",
+			cert:     &sctCert,
+			showFull: true,
+			checks: []string{
+				"Certificate Transparency",
+				"Example Log (Example Operator)",
+				"Source: embedded",
+				"ECDSA-SHA256",
+				"Valid",
+				"Not compliant",
+				"1 of 2 SCTs",
+				"2 required",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -527,6 +623,110 @@ func TestDisplayCertificateChain(t *testing.T) {
 	}
 }
 
+func TestDisplayCertificateDiff(t *testing.T) {
+	now := time.Now()
+	oldCert := &cert.Certificate{
+		Certificate: &x509.Certificate{
+			Raw:          []byte("old"),
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "example.com"},
+			NotBefore:    now,
+			NotAfter:     now.Add(90 * 24 * time.Hour),
+			DNSNames:     []string{"example.com", "old.example.com"},
+		},
+		Source: "old.pem",
+	}
+	newCert := &cert.Certificate{
+		Certificate: &x509.Certificate{
+			Raw:          []byte("new"),
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "example.com"},
+			NotBefore:    now,
+			NotAfter:     now.Add(90 * 24 * time.Hour),
+			DNSNames:     []string{"example.com", "new.example.com"},
+		},
+		Source: "new.pem",
+	}
+
+	output := captureOutput(func() {
+		DisplayCertificateDiff(cert.DiffCertificates(oldCert, newCert))
+	})
+
+	checks := []string{
+		"Certificate Diff",
+		"old.pem",
+		"new.pem",
+		"SANs",
+		"- old.example.com",
+		"+ new.example.com",
+		"(1 unchanged)",
+		"Unchanged:",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("Output should contain %q", check)
+		}
+	}
+	if strings.Contains(output, "\nSubject\n") {
+		t.Error("Unchanged subject should not be listed as a change")
+	}
+}
+
+func TestDisplayLintResult(t *testing.T) {
+	now := time.Now()
+	c := &cert.Certificate{
+		Certificate: &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "lint.example.com"},
+			NotBefore:    now,
+			NotAfter:     now.Add(90 * 24 * time.Hour),
+		},
+		Source: "lint.pem",
+	}
+
+	tests := []struct {
+		name   string
+		opts   cert.LintOptions
+		checks []string
+	}{
+		{
+			name:   "Findings",
+			checks: []string{"Lint: CN=lint.example.com (lint.pem)", "SEVERITY", "san-missing", "serial-entropy", "ski-missing", "error(s)"},
+		},
+		{
+			name:   "Clean",
+			opts:   cert.LintOptions{Select: []string{"validity-too-long"}},
+			checks: []string{"No problems found (1 rules checked)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := cert.Lint(c, tt.opts)
+			if err != nil {
+				t.Fatalf("Lint failed: %v", err)
+			}
+			output := captureOutput(func() {
+				DisplayLintResult(result)
+			})
+			for _, check := range tt.checks {
+				if !strings.Contains(output, check) {
+					t.Errorf("Output should contain %q", check)
+				}
+			}
+		})
+	}
+
+	output := captureOutput(func() {
+		DisplayLintRules(cert.LintRules())
+	})
+	for _, check := range []string{"Lint Rules", "APPLIES TO", "weak-key", "RFC 5280 4.2.1.9"} {
+		if !strings.Contains(output, check) {
+			t.Errorf("Rule list should contain %q", check)
+		}
+	}
+}
+
 func TestFormatTable(t *testing.T) {
 	data := [][]string{
 		{"Key1", "Value1"},
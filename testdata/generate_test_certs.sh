@@ -64,10 +64,17 @@ openssl x509 -req -in chain-server.csr -CA intermediate.pem -CAkey intermediate.
 # Create full chain file
 cat chain-server.pem intermediate.pem ca.pem > fullchain.pem
 
+# Create a PKCS#12 bundle (server key, cert, and intermediate)
+openssl pkcs12 -export -in chain-server.pem -inkey chain-server.key -certfile intermediate.pem \
+  -out bundle.p12 -passout pass:changeit 2>/dev/null
+
+# Create a PBES2/AES-256 encrypted PKCS#8 copy of the valid key
+openssl pkcs8 -topk8 -in valid.key -out valid-encrypted.key -v2 aes-256-cbc -passout pass:changeit
+
 # Clean up CSR files
 rm -f *.csr *.srl
 
 echo "Test certificates generated successfully!"
 echo ""
 echo "Generated files:"
-ls -la *.pem *.der *.key 2>/dev/null | awk '{print "  " $9}'
\ No newline at end of file
+ls -la *.pem *.der *.key *.p12 2>/dev/null | awk '{print "  " $9}'
\ No newline at end of file